@@ -0,0 +1,164 @@
+// Package admin serves an HTTP admin endpoint for long-running commands,
+// such as servers, on an address configured with the same flags as the rest
+// of the command:
+//
+//	var App = xflags.NewCommand("server", "").
+//		Flags(...).
+//		HandleContext(serve)
+//
+//	func init() {
+//		(&admin.Server{}).Enable(App)
+//	}
+//
+//	$ server --admin-listen=localhost:9090
+//
+// The endpoint is enabled by the --admin-listen, --admin-port,
+// --admin-unix-socket and --admin-systemd-socket flags registered with
+// xflags.CommandBuilder.ListenFlags and serves:
+//
+//   - /healthz, which responds "ok" or, if the Health function of the Server
+//     returns an error, 503 Service Unavailable.
+//   - /metrics, which lists the numeric variables published with expvar in
+//     the Prometheus text format.
+//   - /config, which lists the effective configuration of the invoked
+//     command as JSON, as printed by --print-config, with secrets masked.
+//
+// The endpoint is served while the handler of the command runs.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+// listenPrefix is the prefix of the flags registered by Enable.
+const listenPrefix = "admin"
+
+// shutdownTimeout is how long requests to the endpoint may take to complete
+// after the handler returns.
+const shutdownTimeout = 5 * time.Second
+
+// Server configures the admin endpoint.
+type Server struct {
+	// Health reports whether the program is healthy. If it is nil, the
+	// program is healthy while the handler of the command runs.
+	Health func(ctx context.Context) error
+
+	// Mux, if not nil, is called to add handlers to the endpoint.
+	Mux func(mux *http.ServeMux)
+}
+
+// Enable registers the admin flags for cmd and its subcommands and serves the
+// endpoint while the handler of the invoked command runs if one of the flags
+// is specified.
+func (c *Server) Enable(cmd *xflags.CommandBuilder) *xflags.CommandBuilder {
+	return cmd.
+		ListenFlags(listenPrefix).
+		Use(func(next xflags.ContextHandlerFunc) xflags.ContextHandlerFunc {
+			return func(ctx context.Context, args []string) int {
+				l := xflags.ListenerFrom(ctx, listenPrefix)
+				if l == nil {
+					return next(ctx, args)
+				}
+				inv := xflags.InvocationFrom(ctx)
+				srv := &http.Server{Handler: c.Handler(inv.Target())}
+				errc := make(chan error, 1)
+				go func() { errc <- srv.Serve(l) }()
+				exitCode := next(ctx, args)
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+				defer cancel()
+				srv.Shutdown(shutdownCtx)
+				if err := <-errc; err != nil && err != http.ErrServerClosed {
+					fmt.Fprintf(xflags.OutputFrom(ctx).Stderr, "Error: admin endpoint: %v\n", err)
+					if exitCode == 0 {
+						exitCode = 1
+					}
+				}
+				return exitCode
+			}
+		})
+}
+
+// Handler returns the handler of the admin endpoint for cmd, which is a
+// command returned by Parse.
+func (c *Server) Handler(cmd *xflags.Command) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if c.Health != nil {
+			if err := c.Health(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		io.WriteString(w, "ok\n")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w)
+	})
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(cmd.Config())
+	})
+	if c.Mux != nil {
+		c.Mux(mux)
+	}
+	return mux
+}
+
+// writeMetrics writes the numeric expvar variables to w in the Prometheus
+// text format. Maps are written with a "key" label and the numeric fields of
+// objects, such as memstats, as metrics named after the variable and field.
+func writeMetrics(w io.Writer) {
+	expvar.Do(func(kv expvar.KeyValue) {
+		name := metricName(kv.Key)
+		var v interface{}
+		if err := json.Unmarshal([]byte(kv.Value.String()), &v); err != nil {
+			return
+		}
+		switch v := v.(type) {
+		case float64:
+			fmt.Fprintf(w, "%s %v\n", name, v)
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			_, isMap := kv.Value.(*expvar.Map)
+			for _, key := range keys {
+				f, ok := v[key].(float64)
+				switch {
+				case !ok:
+				case isMap:
+					fmt.Fprintf(w, "%s{key=%q} %v\n", name, key, f)
+				default:
+					fmt.Fprintf(w, "%s_%s %v\n", name, metricName(key), f)
+				}
+			}
+		}
+	})
+}
+
+// metricName returns s with the characters that are not valid in Prometheus
+// metric names replaced by underscores.
+func metricName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+var (
+	requests = expvar.NewInt("admin_test.requests")
+	statuses = expvar.NewMap("admin_test_statuses")
+)
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+func TestServer(t *testing.T) {
+	requests.Set(3)
+	statuses.Add("200", 2)
+	var healthy error
+	var token string
+	srv := &Server{Health: func(ctx context.Context) error { return healthy }}
+	cmd := srv.Enable(
+		xflags.NewCommand("server", "").
+			Output(io.Discard, io.Discard).
+			Flags(
+				xflags.String(nil, "name", "default", ""),
+				xflags.String(&token, "token", "", "").Secret(),
+			).
+			HandleContext(func(ctx context.Context, args []string) int {
+				base := "http://" + xflags.ListenerFrom(ctx, "admin").Addr().String()
+
+				code, body := get(t, base+"/healthz")
+				if code != http.StatusOK || body != "ok\n" {
+					t.Errorf("/healthz: got %d %q", code, body)
+				}
+				healthy = errors.New("database unavailable")
+				code, body = get(t, base+"/healthz")
+				if code != http.StatusServiceUnavailable || body != "database unavailable\n" {
+					t.Errorf("/healthz: got %d %q", code, body)
+				}
+
+				_, body = get(t, base+"/metrics")
+				for _, line := range []string{
+					"admin_test_requests 3\n",
+					"admin_test_statuses{key=\"200\"} 2\n",
+					"memstats_Alloc ",
+				} {
+					if !strings.Contains(body, line) {
+						t.Errorf("/metrics: missing %q in:\n%s", line, body)
+					}
+				}
+
+				_, body = get(t, base+"/config")
+				var entries []xflags.ConfigEntry
+				if err := json.Unmarshal([]byte(body), &entries); err != nil {
+					t.Fatal(err)
+				}
+				values := make(map[string]string)
+				for _, e := range entries {
+					values[e.Flag] = e.Value
+				}
+				if values["name"] != "web" || values["token"] != "********" {
+					t.Errorf("/config: got %q", values)
+				}
+				return 0
+			}),
+	).Must()
+	code := cmd.Run([]string{"--admin-listen=127.0.0.1:0", "--name=web", "--token=abc"})
+	if code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+}
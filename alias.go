@@ -0,0 +1,310 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// aliasKey is the key of the configuration of each alias.
+const aliasKey = "alias"
+
+// aliasOptions is the configuration of Aliases.
+type aliasOptions struct {
+	path string // path of the alias file, if any
+}
+
+// Aliases enables user-defined aliases for the subcommands of this command, in
+// the style of git aliases. If the name of a subcommand is not recognized, it
+// is replaced by the arguments of the alias with the same name, which are
+// split like a POSIX shell command line by SplitCommandLine, and parsing
+// continues. For example, given the alias "co = checkout --track", the
+// command line "app co main" is parsed as "app checkout --track main".
+// Aliases may refer to other aliases, but an alias that refers to itself is
+// an error. Aliases cannot replace existing subcommands.
+//
+// Aliases are read from the alias file at path, if path is not empty, and
+// from the sources of the command, such as configuration files, at the path
+// "alias.NAME":
+//
+//	alias:
+//	  co: checkout --track
+//
+// Aliases also adds an "alias" subcommand that lists the aliases with
+// "alias", prints an alias with "alias NAME" and, if path is not empty,
+// defines an alias with "alias NAME ARGS..." or removes it with
+// "alias --unset NAME". Arguments of the alias which begin with a dash must
+// follow "--", as in "alias co -- checkout --track", or be quoted, as in
+// "alias co 'checkout --track'". The alias file is a TOML file with an [alias] table
+// that is created if necessary and may also be read by ConfigFile.
+func (c *CommandBuilder) Aliases(path string) *CommandBuilder {
+	c.cmd.aliasOptions = &aliasOptions{path: path}
+	return c.Subcommands(newAliasCommand())
+}
+
+// hasAliases reports whether c or any of its parents enabled aliases.
+func (c *Command) hasAliases() bool {
+	for p := c; p != nil; p = p.Parent {
+		if p.aliasOptions != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupAlias returns the arguments of the named alias of the subcommands of
+// c.
+func (c *Command) lookupAlias(name string) (string, bool, error) {
+	if path := c.aliasOptions.path; path != "" {
+		aliases, err := readAliasFile(path)
+		if err != nil {
+			return "", false, &xflagsErr{Text: path, Err: err}
+		}
+		if s, ok := aliases.values[name]; ok {
+			return s.(string), true, nil
+		}
+	}
+	for p := c; p != nil; p = p.Parent {
+		for _, src := range p.Sources {
+			s, ok, err := lookup(src, aliasKey+"."+name)
+			if err != nil {
+				return "", false, sourceErr(src, err)
+			}
+			if ok {
+				return s, true, nil
+			}
+		}
+	}
+	return "", false, nil
+}
+
+// aliases returns the aliases of the subcommands of c that are defined in the
+// alias file or a configuration file read by ConfigFile, sorted by name.
+func (c *Command) aliases() (*orderedMap, error) {
+	aliases := &orderedMap{values: make(map[string]interface{})}
+	add := func(name, s string) {
+		if _, ok := aliases.values[name]; !ok {
+			aliases.keys = append(aliases.keys, name)
+			aliases.values[name] = s
+		}
+	}
+	if path := c.aliasOptions.path; path != "" {
+		m, err := readAliasFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range m.keys {
+			add(name, m.values[name].(string))
+		}
+	}
+	for p := c; p != nil; p = p.Parent {
+		for _, src := range p.Sources {
+			cfg, ok := src.(*configFileSource)
+			if !ok {
+				continue
+			}
+			doc, err := cfg.load()
+			if err != nil {
+				return nil, sourceErr(src, err)
+			}
+			for _, key := range doc.keys {
+				if name := strings.TrimPrefix(key, aliasKey+"."); name != key {
+					add(name, strings.Join(doc.values[key], ","))
+				}
+			}
+		}
+	}
+	sort.Strings(aliases.keys)
+	return aliases, nil
+}
+
+// expandAlias replaces the unrecognized subcommand name with the arguments of
+// the alias of the same name and reports whether the alias was found.
+func (c *argParser) expandAlias(name string) (bool, error) {
+	if c.cmd.aliasOptions == nil || c.strict {
+		return false, nil
+	}
+	s, ok, err := c.cmd.lookupAlias(name)
+	if err != nil {
+		return false, wrapArgErr(err, c.cmd, nil, name)
+	}
+	if !ok {
+		return false, nil
+	}
+	for i, seen := range c.aliasChain {
+		if seen == name {
+			chain := append(c.aliasChain[i:len(c.aliasChain):len(c.aliasChain)], name)
+			return false, newArgErr(
+				c.cmd,
+				nil,
+				name,
+				"alias refers to itself: %s",
+				strings.Join(chain, " -> "),
+			)
+		}
+	}
+	c.aliasChain = append(c.aliasChain, name)
+	args := SplitCommandLine(s)
+	if len(args) == 0 {
+		return false, newArgErr(c.cmd, nil, name, "empty alias: %s", name)
+	}
+	root := c.cmd
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	tokens, _ := normalizeIndexed(
+		args,
+		root.WithTerminator,
+		c.cmd.index().shortNames,
+		root.PrefixShortNames,
+	)
+	indexes := make([]int, len(tokens))
+	for i := range indexes {
+		indexes[i] = c.index
+	}
+	c.tokens = append(tokens, c.tokens...)
+	c.indexes = append(indexes, c.indexes...)
+	return true, nil
+}
+
+// readAliasFile returns the aliases defined in the alias file at path. If the
+// file does not exist, it defines no aliases.
+func readAliasFile(path string) (*orderedMap, error) {
+	aliases := &orderedMap{values: make(map[string]interface{})}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return aliases, nil
+		}
+		return nil, err
+	}
+	m, err := parseTOMLConfig(b)
+	if err != nil {
+		return nil, err
+	}
+	if table, ok := m.values[aliasKey].(*orderedMap); ok {
+		for _, name := range table.keys {
+			if s, ok := table.values[name].(string); ok {
+				aliases.keys = append(aliases.keys, name)
+				aliases.values[name] = s
+			}
+		}
+	}
+	return aliases, nil
+}
+
+// writeAliasFile replaces the alias file at path with the given aliases.
+func writeAliasFile(path, program string, aliases *orderedMap) error {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# Aliases managed by \"%s alias\".\n[%s]\n", program, aliasKey)
+	for _, name := range aliases.keys {
+		fmt.Fprintf(buf, "%s = %s\n", name, strconv.Quote(aliases.values[name].(string)))
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// validAliasName reports whether name may be used as the name of an alias.
+func validAliasName(name string) bool {
+	if name == "" || name[0] == '-' {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func newAliasCommand() *CommandBuilder {
+	return NewCommand("alias", "List, show or define command aliases").
+		WithTerminator().
+		Flags(
+			Bool(nil, "unset", false, "Remove the alias").builtin(),
+			String(nil, "NAME", "", "Name of the alias").
+				Positional().
+				NArgs(0, 1).
+				builtin(),
+			Strings(nil, "ARGS", nil, "Arguments that the alias is replaced with").
+				Positional().
+				NArgs(0, 0).
+				builtin(),
+		).
+		HandleE(func(ctx context.Context, rest []string) error {
+			inv := InvocationFrom(ctx)
+			unset := inv.Get("unset").(bool)
+			name := inv.Get("NAME").(string)
+			args := inv.Get("ARGS").([]string)
+			expansion := append(args[:len(args):len(args)], rest...)
+			parent := inv.Target().Parent
+			out := OutputFrom(ctx)
+			if name == "" {
+				if unset {
+					return errorf("--unset requires the name of an alias")
+				}
+				aliases, err := parent.aliases()
+				if err != nil {
+					return err
+				}
+				aw := newAggregatedWriter(out.Stdout)
+				for _, name := range aliases.keys {
+					fmt.Fprintf(aw, "%s = %s\n", name, aliases.values[name])
+				}
+				return aw.Err()
+			}
+			if len(expansion) == 0 && !unset {
+				s, ok, err := parent.lookupAlias(name)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errorf("no such alias: %s", name)
+				}
+				fmt.Fprintln(out.Stdout, s)
+				return nil
+			}
+			path := parent.aliasOptions.path
+			if path == "" {
+				return errorf("aliases are read-only")
+			}
+			if unset && len(expansion) > 0 {
+				return errorf("--unset does not accept arguments")
+			}
+			if !validAliasName(name) {
+				return errorf("invalid alias name: %q", name)
+			}
+			if _, ok := parent.index().subcommands[name]; ok {
+				return errorf("alias would be hidden by the command of the same name: %s", name)
+			}
+			aliases, err := readAliasFile(path)
+			if err != nil {
+				return err
+			}
+			if unset {
+				if _, ok := aliases.values[name]; !ok {
+					return errorf("no such alias: %s", name)
+				}
+				delete(aliases.values, name)
+			} else {
+				aliases.values[name] = quotePOSIX(expansion)
+				if len(expansion) == 1 {
+					aliases.values[name] = expansion[0]
+				}
+			}
+			aliases.keys = aliases.keys[:0]
+			for name := range aliases.values {
+				aliases.keys = append(aliases.keys, name)
+			}
+			sort.Strings(aliases.keys)
+			program := strings.Join(commandPath(parent), " ")
+			return writeAliasFile(path, program, aliases)
+		})
+}
@@ -0,0 +1,121 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newAliasTestCommand(stdout *bytes.Buffer, path string, sources ...Source) *Command {
+	return NewCommand("app", "").
+		Output(stdout, new(bytes.Buffer)).
+		Aliases(path).
+		StrictConfig().
+		Sources(sources...).
+		Flags(String(nil, "region", "", "")).
+		Subcommands(
+			NewCommand("checkout", "").
+				Flags(
+					Bool(nil, "track", false, "").ShortName("t"),
+					String(nil, "BRANCH", "", "").Positional(),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+}
+
+func TestAliases(t *testing.T) {
+	src := MapSource("config", map[string]string{
+		"alias.co":    "checkout -t",
+		"alias.eu":    "--region 'eu west' co",
+		"alias.loop":  "loop2",
+		"alias.loop2": "loop",
+		"alias.empty": "",
+	})
+	cmd := newAliasTestCommand(new(bytes.Buffer), "", src)
+	target, err := cmd.Parse([]string{"eu", "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "checkout", target.Name)
+	inv := target.newInvocation(nil)
+	assertStrings(
+		t,
+		[]string{"app", "--region=eu west", "checkout", "--track=true", "main"},
+		inv.Command(),
+	)
+
+	// errors refer to the alias on the command line
+	_, err = newAliasTestCommand(new(bytes.Buffer), "", src).Parse([]string{"loop"})
+	var argErr *ArgumentError
+	assertErrorAs(t, err, &argErr)
+	assertString(t, "xflags: alias refers to itself: loop -> loop2 -> loop", err.Error())
+	assertString(t, "loop", argErr.Token)
+
+	_, err = newAliasTestCommand(new(bytes.Buffer), "", src).Parse([]string{"empty"})
+	assertErrorAs(t, err, &argErr)
+
+	_, err = newAliasTestCommand(new(bytes.Buffer), "", src).Parse([]string{"nope"})
+	assertErrorAs(t, err, &argErr)
+	assertString(t, "xflags: unrecognized command: nope", err.Error())
+}
+
+func TestAliasCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "aliases.toml")
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte("alias:\n  ct: checkout --track\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout := new(bytes.Buffer)
+	run := func(args ...string) int {
+		return newAliasTestCommand(stdout, path, ConfigFile(config)).Run(args)
+	}
+	assertInt64(t, 0, int64(run("alias", "co", "checkout", "--", "--track")))
+	assertInt64(t, 0, int64(run("alias", "br", "checkout -t")))
+	assertInt64(t, 1, int64(run("alias", "checkout", "co")))
+	assertInt64(t, 1, int64(run("alias", "a.b", "co")))
+	assertInt64(t, 0, int64(run("alias")))
+	assertString(t, "br = checkout -t\nco = checkout --track\nct = checkout --track\n", stdout.String())
+
+	stdout.Reset()
+	assertInt64(t, 0, int64(run("alias", "co")))
+	assertString(t, "checkout --track\n", stdout.String())
+	assertInt64(t, 0, int64(run("co", "main")))
+
+	assertInt64(t, 0, int64(run("alias", "--unset", "co")))
+	assertInt64(t, 1, int64(run("alias", "--unset", "co")))
+	assertInt64(t, 1, int64(run("co", "main")))
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "[alias]\nbr = \"checkout -t\"\n") {
+		t.Errorf("unexpected alias file:\n%s", b)
+	}
+
+	// aliases in configuration files are read-only
+	assertInt64(t, 1, int64(newAliasTestCommand(stdout, "").Run([]string{"alias", "x", "checkout"})))
+}
+
+func TestAliasCommandExec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.toml")
+	cmd := newAliasTestCommand(new(bytes.Buffer), path)
+	exec := func(stdout io.Writer, args ...string) int {
+		return cmd.Exec(context.Background(), args, nil, stdout, nil)
+	}
+	assertInt64(t, 0, int64(exec(nil, "alias", "co", "checkout")))
+	assertInt64(t, 0, int64(exec(nil, "alias", "br", "checkout", "--", "-t")))
+	stdout := new(bytes.Buffer)
+	assertInt64(t, 0, int64(exec(stdout, "alias")))
+	assertString(t, "br = checkout -t\nco = checkout\n", stdout.String())
+
+	assertInt64(t, 0, int64(exec(nil, "alias", "--unset", "co")))
+	stdout.Reset()
+	assertInt64(t, 0, int64(exec(stdout, "alias")))
+	assertString(t, "br = checkout -t\n", stdout.String())
+}
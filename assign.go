@@ -0,0 +1,430 @@
+package xflags
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Assignments returns a FlagBuilder that can be used to define a flag that
+// sets values at dot-separated paths in a map or struct, in the manner of the
+// --set flag of Helm, with specified name and usage string:
+//
+//	--set image.tag=v2 --set replicas=3,servers[0].port=80
+//
+// The argument p must be a non-nil pointer to a map with string keys or to a
+// struct. The flag may be specified multiple times and each argument may
+// contain several assignments separated by commas. Assignments are applied in
+// order on top of the current contents of the target.
+//
+// Each element of a path is a map key, a struct field or, in brackets, a list
+// index. Struct fields are matched by the name in their "json" struct tag or
+// by their name, ignoring case, dashes and underscores. Missing maps, lists
+// and pointers are allocated as needed.
+//
+// Values are converted to the type of their destination using the parsers of
+// NewValue, so that "30s" may be assigned to a time.Duration field. Values
+// assigned to untyped destinations, such as map[string]interface{}, are typed
+// as in Helm: "true" and "false" become bools, decimal integers without
+// leading zeros become int64s, "null" removes the key and any other value is
+// a string. A value in braces, such as "{a,b}", is a list.
+//
+// The characters ".", "[", ",", "=" and "\" are escaped with a backslash in
+// keys and values, as in "annotations.example\.com/team=core".
+func Assignments(p any, name, usage string) *FlagBuilder {
+	rv := reflect.ValueOf(p)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || !isAssignTarget(rv.Elem().Type()) {
+		c := Var(nil, name, usage)
+		c.err = errorf("%s: value must be a non-nil pointer to a map or struct: %T", name, p)
+		return c
+	}
+	return Var(&assignmentsValue{p: rv}, name, usage).NArgs(0, 0)
+}
+
+func isAssignTarget(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct || t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+}
+
+type assignmentsValue struct {
+	p           reflect.Value // pointer to the target
+	assignments []string      // the assignments that were set
+}
+
+func (c *assignmentsValue) String() string { return strings.Join(c.assignments, ",") }
+
+func (c *assignmentsValue) Get() interface{} { return c.p.Elem().Interface() }
+
+func (c *assignmentsValue) Set(s string) error {
+	for _, assignment := range splitUnescaped(s, ',', true) {
+		key, value, ok := cutUnescaped(assignment, '=')
+		if !ok || key == "" {
+			return errorf("invalid assignment: %q (expected key=value)", assignment)
+		}
+		path, err := parseAssignPath(key)
+		if err != nil {
+			return errorf("invalid assignment: %q: %v", assignment, errStr(err))
+		}
+		if err := assignPath(c.p.Elem(), path, value); err != nil {
+			return errorf("invalid assignment: %q: %v", assignment, errStr(err))
+		}
+		c.assignments = append(c.assignments, assignment)
+	}
+	return nil
+}
+
+// maxAssignIndex is the largest list index accepted in the path of an
+// assignment, which limits the memory allocated for lists.
+const maxAssignIndex = 65535
+
+// assignStep is an element of the path of an assignment: a map key or struct
+// field if index is negative, or a list index.
+type assignStep struct {
+	key   string
+	index int
+}
+
+func (c assignStep) String() string {
+	if c.index < 0 {
+		return c.key
+	}
+	return "[" + strconv.Itoa(c.index) + "]"
+}
+
+// parseAssignPath parses a path such as "servers[0].port" into its steps.
+func parseAssignPath(s string) ([]assignStep, error) {
+	var path []assignStep
+	var key strings.Builder
+	afterIndex := false
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\\' && i+1 < len(s):
+			i++
+			key.WriteByte(s[i])
+		case ch == '.':
+			if key.Len() == 0 && !afterIndex {
+				return nil, errorf("empty key in path: %s", s)
+			}
+			if key.Len() > 0 {
+				path = append(path, assignStep{key: key.String(), index: -1})
+				key.Reset()
+			}
+			afterIndex = false
+		case ch == '[':
+			if key.Len() > 0 {
+				path = append(path, assignStep{key: key.String(), index: -1})
+				key.Reset()
+			}
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, errorf("unterminated index in path: %s", s)
+			}
+			index, err := strconv.Atoi(s[i+1 : i+end])
+			if err != nil || index < 0 || index > maxAssignIndex {
+				return nil, errorf("invalid index in path: %s", s[i:i+end+1])
+			}
+			path = append(path, assignStep{index: index})
+			i += end
+			afterIndex = true
+		default:
+			if afterIndex {
+				return nil, errorf("expected . or [ after index in path: %s", s)
+			}
+			key.WriteByte(ch)
+		}
+	}
+	if key.Len() > 0 {
+		path = append(path, assignStep{key: key.String(), index: -1})
+	} else if !afterIndex {
+		return nil, errorf("empty key in path: %s", s)
+	}
+	return path, nil
+}
+
+// assignPath assigns the value s at path in v, which must be settable.
+func assignPath(v reflect.Value, path []assignStep, s string) error {
+	if len(path) == 0 {
+		return assignLeaf(v, s)
+	}
+	step := path[0]
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return assignPath(v.Elem(), path, s)
+
+	case reflect.Interface:
+		// untyped values are maps and lists of interfaces
+		current := v.Elem()
+		if v.IsNil() || current.Kind() != reflect.Map && current.Kind() != reflect.Slice {
+			current = reflect.ValueOf(make(map[string]interface{}))
+			if step.index >= 0 {
+				current = reflect.ValueOf([]interface{}{})
+			}
+		}
+		elem := reflect.New(current.Type()).Elem()
+		elem.Set(current)
+		if err := assignPath(elem, path, s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Map:
+		if step.index >= 0 || v.Type().Key().Kind() != reflect.String {
+			return errorf("cannot index %s with %s", v.Type(), step)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.ValueOf(step.key).Convert(v.Type().Key())
+		if len(path) == 1 && s == "null" && v.Type().Elem().Kind() == reflect.Interface {
+			v.SetMapIndex(key, reflect.Value{}) // delete
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := assignPath(elem, path[1:], s); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+
+	case reflect.Slice:
+		if step.index < 0 {
+			return errorf("cannot index %s with %s", v.Type(), step)
+		}
+		if step.index >= v.Len() {
+			grown := reflect.MakeSlice(v.Type(), step.index+1, step.index+1)
+			reflect.Copy(grown, v)
+			v.Set(grown)
+		}
+		return assignPath(v.Index(step.index), path[1:], s)
+
+	case reflect.Struct:
+		if step.index >= 0 {
+			return errorf("cannot index %s with %s", v.Type(), step)
+		}
+		field, ok := assignField(v, step.key)
+		if !ok {
+			return errorf("no field %s in %s", step.key, v.Type())
+		}
+		return assignPath(field, path[1:], s)
+	}
+	return errorf("cannot index %s with %s", v.Type(), step)
+}
+
+// assignField returns the exported field of the struct v that matches key by
+// its json tag or name, including fields of embedded structs.
+func assignField(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if f, ok := assignField(v.Field(i), key); ok {
+				return f, true
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == key || name == "" && bindKey(field.Name) == bindKey(key) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// assignLeaf converts s to the type of v and assigns it.
+func assignLeaf(v reflect.Value, s string) error {
+	if s == "null" {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return errorf("cannot assign to %s", v.Type())
+		}
+		if list, ok := assignList(s); ok {
+			a := make([]interface{}, 0, len(list))
+			for _, elem := range list {
+				a = append(a, assignLiteral(elem))
+			}
+			v.Set(reflect.ValueOf(a))
+			return nil
+		}
+		if lit := assignLiteral(unescape(s)); lit != nil {
+			v.Set(reflect.ValueOf(lit))
+		}
+		return nil
+
+	case reflect.Slice:
+		if _, err := NewValue(v.Addr().Interface()); err != nil || v.Type().Elem().Kind() == reflect.String {
+			list, ok := assignList(s)
+			if !ok {
+				list = []string{unescape(s)}
+			}
+			a := reflect.MakeSlice(v.Type(), len(list), len(list))
+			for i, elem := range list {
+				if err := assignLeaf(a.Index(i), elem); err != nil {
+					return err
+				}
+			}
+			v.Set(a)
+			return nil
+		}
+	}
+	return setReflect(v, unescape(s))
+}
+
+// setReflect converts s to the type of v, which must be addressable, using
+// the parsers of NewValue or, for other numeric kinds, strconv, and assigns
+// it. Pointers are allocated.
+func setReflect(v reflect.Value, s string) error {
+	if value, err := NewValue(v.Addr().Interface()); err == nil {
+		return value.Set(s)
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setReflect(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseInt(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseUint(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	}
+	return errorf("cannot assign to %s", v.Type())
+}
+
+// assignLiteral returns the untyped value of s: a bool, an int64, nil for
+// "null" or s.
+func assignLiteral(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if s == "0" || s != "" && s[0] != '0' && !strings.HasPrefix(s, "-0") && !strings.HasPrefix(s, "+") {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	}
+	return s
+}
+
+// assignList returns the unescaped elements of s if it is a list in braces.
+func assignList(s string) ([]string, bool) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' || s[len(s)-2] == '\\' {
+		return nil, false
+	}
+	s = s[1 : len(s)-1]
+	if s == "" {
+		return []string{}, true
+	}
+	list := splitUnescaped(s, ',', false)
+	for i := range list {
+		list[i] = unescape(list[i])
+	}
+	return list, true
+}
+
+// splitUnescaped splits s around each instance of sep that is not escaped by
+// a backslash and, if braces is set, not inside braces. Escapes are retained.
+func splitUnescaped(s string, sep byte, braces bool) []string {
+	var a []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			if braces {
+				depth++
+			}
+		case '}':
+			if braces && depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				a = append(a, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(a, s[start:])
+}
+
+// cutUnescaped slices s around the first instance of sep that is not escaped
+// by a backslash.
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// unescape removes the backslashes that escape characters in s.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
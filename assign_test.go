@@ -0,0 +1,108 @@
+package xflags
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAssignmentsMap(t *testing.T) {
+	values := map[string]interface{}{
+		"image":   map[string]interface{}{"repository": "nginx", "tag": "v1"},
+		"removed": "x",
+	}
+	flag := Assignments(&values, "set", "").Must()
+	if !assertFlagParses(
+		t,
+		flag,
+		"--set", "image.tag=v2",
+		"--set", "replicas=3,debug=true,zip=007",
+		"--set", `servers[1].port=80,hosts={a,b},removed=null`,
+		"--set", `annotations.example\.com/team=core\,ops`,
+	) {
+		return
+	}
+	expect := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "v2"},
+		"replicas": int64(3),
+		"debug":    true,
+		"zip":      "007",
+		"servers": []interface{}{
+			nil,
+			map[string]interface{}{"port": int64(80)},
+		},
+		"hosts":       []interface{}{"a", "b"},
+		"annotations": map[string]interface{}{"example.com/team": "core,ops"},
+	}
+	if !reflect.DeepEqual(expect, values) {
+		t.Errorf("expected %v, got %v", expect, values)
+	}
+}
+
+func TestAssignmentsStruct(t *testing.T) {
+	type image struct {
+		Repository string
+		Tag        string `json:"tag"`
+	}
+	type server struct {
+		Port uint16 `json:"port"`
+	}
+	var values struct {
+		Image      image
+		Replicas   int32
+		Timeout    time.Duration
+		PullPolicy *string
+		Servers    []server `json:"servers"`
+		Hosts      []string
+		Labels     map[string]string
+	}
+	flag := Assignments(&values, "set", "").Must()
+	if !assertFlagParses(
+		t,
+		flag,
+		"--set", "image.repository=nginx,image.tag=v2,replicas=3,timeout=30s",
+		"--set", "pull-policy=Always,servers[0].port=8080,hosts={a,b},labels.app=web",
+	) {
+		return
+	}
+	assertString(t, "nginx", values.Image.Repository)
+	assertString(t, "v2", values.Image.Tag)
+	assertInt64(t, 3, int64(values.Replicas))
+	assertDuration(t, 30*time.Second, values.Timeout)
+	if assertBool(t, true, values.PullPolicy != nil) {
+		assertString(t, "Always", *values.PullPolicy)
+	}
+	if assertInt64(t, 1, int64(len(values.Servers))) {
+		assertUint64(t, 8080, uint64(values.Servers[0].Port))
+	}
+	assertStrings(t, []string{"a", "b"}, values.Hosts)
+	assertString(t, "web", values.Labels["app"])
+	assertString(t, "image.repository=nginx,image.tag=v2,replicas=3,timeout=30s,"+
+		"pull-policy=Always,servers[0].port=8080,hosts={a,b},labels.app=web", valueString(flag.Value))
+
+	tests := []struct {
+		Arg    string
+		Expect string
+	}{
+		{"replicas", `xflags: --set: invalid assignment: "replicas" (expected key=value)`},
+		{"missing=1", `xflags: --set: invalid assignment: "missing=1": no field missing in ` +
+			reflect.TypeOf(values).String()},
+		{"replicas=x", `xflags: --set: invalid assignment: "replicas=x": strconv.ParseInt: parsing "x": invalid syntax`},
+		{"servers[x].port=1", `xflags: --set: invalid assignment: "servers[x].port=1": invalid index in path: [x]`},
+		{"image[0]=x", `xflags: --set: invalid assignment: "image[0]=x": cannot index xflags.image with [0]`},
+		{"servers[70000].port=1", `xflags: --set: invalid assignment: "servers[70000].port=1": invalid index in path: [70000]`},
+		{"a..b=1", `xflags: --set: invalid assignment: "a..b=1": empty key in path: a..b`},
+	}
+	for _, test := range tests {
+		err := parseFlag(flag, "--set", test.Arg)
+		if err == nil {
+			t.Errorf("%s: expected error", test.Arg)
+			continue
+		}
+		assertString(t, test.Expect, err.Error())
+	}
+
+	if _, err := Assignments(values, "set", "").Flag(); err == nil {
+		t.Error("expected error for non-pointer")
+	}
+}
@@ -0,0 +1,117 @@
+package xflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes one invocation of a command for an audit log.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+
+	// User is the name of the user who invoked the command, as given with
+	// WithUser, or the user who runs the program.
+	User string `json:"user"`
+
+	// Command is the path of the invoked command from the root command, such
+	// as ["app", "widgets", "create"].
+	Command []string `json:"command"`
+
+	// Flags maps the path of each flag that was set from the command line,
+	// environment or a source to its value. The values of flags declared as
+	// Secret are masked.
+	Flags map[string]string `json:"flags,omitempty"`
+
+	ExitCode   int        `json:"exit_code"`
+	ErrorClass ErrorClass `json:"error,omitempty"`
+}
+
+// Audit appends an AuditRecord to w, as a line of JSON, after each invocation
+// of this command or any of its subcommands by Command.Run or Command.Exec.
+// Programs that run commands on behalf of other users with Exec, such as chat
+// bots, should pass the name of the user in the context with WithUser. To keep
+// a log that survives across invocations, w is usually a file opened with
+// os.O_APPEND.
+//
+// Records are written even if the command line could not be parsed. Errors
+// writing a record are printed to os.Stderr.
+func (c *CommandBuilder) Audit(w io.Writer) *CommandBuilder {
+	if w == nil {
+		return c.error(errorf("%s: nil audit writer", c.cmd.Name))
+	}
+	var mu sync.Mutex
+	return c.AuditFunc(func(r AuditRecord) {
+		b, err := json.Marshal(r)
+		if err == nil {
+			mu.Lock()
+			_, err = w.Write(append(b, '\n'))
+			mu.Unlock()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: audit: %v\n", err)
+		}
+	})
+}
+
+// AuditFunc calls fn with an AuditRecord after each invocation of this command
+// or any of its subcommands by Command.Run or Command.Exec, so that records can be sent to a
+// structured logger or a remote service.
+func (c *CommandBuilder) AuditFunc(fn func(r AuditRecord)) *CommandBuilder {
+	if fn == nil {
+		return c.error(errorf("%s: nil audit func", c.cmd.Name))
+	}
+	return c.Instrument(ReporterFunc(func(e *Event) {
+		r := AuditRecord{
+			Time:       e.Start,
+			User:       e.user,
+			Command:    e.Command,
+			Flags:      make(map[string]string, len(e.Flags)+len(e.secrets)),
+			ExitCode:   e.ExitCode,
+			ErrorClass: e.ErrorClass,
+		}
+		if r.User == "" {
+			r.User = currentUser()
+		}
+		for path, value := range e.Flags {
+			r.Flags[path] = value
+		}
+		for path, value := range e.secrets {
+			r.Flags[path] = value
+		}
+		fn(r)
+	}))
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx that carries the name of the user on whose
+// behalf a command is invoked with Command.Exec, such as the sender of a chat
+// message. It is recorded by Audit instead of the user who runs the program.
+func WithUser(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, userKey{}, name)
+}
+
+// UserFrom returns the name of the user given with WithUser, or an empty
+// string.
+func UserFrom(ctx context.Context) string {
+	name, _ := ctx.Value(userKey{}).(string)
+	return name
+}
+
+// currentUser returns the name of the user who runs the program, or "" if it
+// is not known.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
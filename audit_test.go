@@ -0,0 +1,82 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	log := new(bytes.Buffer)
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		Audit(log).
+		Flags(String(nil, "token", "", "").Secret()).
+		Subcommands(
+			NewCommand("delete", "").
+				Flags(String(nil, "name", "", "").Positional()).
+				HandleFunc(func(args []string) int { return 2 }),
+		).
+		Must()
+	assertInt64(t, 2, int64(cmd.Run([]string{"--token=abc", "delete", "db"})))
+	assertInt64(t, 1, int64(cmd.Run([]string{"remove"})))
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if !assertInt64(t, 2, int64(len(lines))) {
+		return
+	}
+	var r AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.User == "" || r.Time.IsZero() {
+		t.Errorf("expected user and time, got %q, %v", r.User, r.Time)
+	}
+	assertStrings(t, []string{"app", "delete"}, r.Command)
+	assertString(t, "********", r.Flags["token"])
+	assertString(t, "db", r.Flags["delete.name"])
+	assertInt64(t, 2, int64(r.ExitCode))
+	assertString(t, string(ErrorExit), string(r.ErrorClass))
+	if strings.Contains(lines[0], "abc") {
+		t.Errorf("secret written to audit log: %s", lines[0])
+	}
+
+	r = AuditRecord{}
+	if err := json.Unmarshal([]byte(lines[1]), &r); err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"app"}, r.Command)
+	assertString(t, string(ErrorArgument), string(r.ErrorClass))
+}
+
+func TestAuditExec(t *testing.T) {
+	var records []AuditRecord
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		AuditFunc(func(r AuditRecord) { records = append(records, r) }).
+		Flags(String(nil, "name", "", "")).
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+
+	ctx := WithUser(context.Background(), "ann")
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"--name=db"}, nil, nil, nil)))
+	assertInt64(t, 1, int64(cmd.Exec(ctx, []string{"--nope"}, nil, nil, nil)))
+	if !assertInt64(t, 2, int64(len(records))) {
+		return
+	}
+	assertString(t, "ann", records[0].User)
+	assertStrings(t, []string{"app"}, records[0].Command)
+	assertString(t, "db", records[0].Flags["name"])
+	assertString(t, "ann", records[1].User)
+	assertString(t, string(ErrorArgument), string(records[1].ErrorClass))
+
+	// the user who runs the program is recorded by default
+	records = nil
+	cmd.Exec(context.Background(), nil, nil, nil, nil)
+	if len(records) != 1 || records[0].User == "" || records[0].User == "ann" {
+		t.Errorf("expected the current user, got %+v", records)
+	}
+}
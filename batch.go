@@ -0,0 +1,137 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BatchOptions configures Batch.
+type BatchOptions struct {
+	// Parallelism is the maximum number of invocations that run at once. If
+	// it is zero, it is GOMAXPROCS.
+	Parallelism int
+
+	// FailFast stops Batch from starting more invocations after one fails.
+	FailFast bool
+
+	// Stdout and Stderr receive the output of each invocation when it
+	// finishes, so that the output of concurrent invocations is not
+	// interleaved. If they are nil, the output of the command is used.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Prefix prefixes each line of output with the arguments of the
+	// invocation that wrote it, as in "[deploy --target=a] done".
+	Prefix bool
+}
+
+// BatchResult is the result of one invocation run by Batch.
+type BatchResult struct {
+	Args     []string
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+
+	// Skipped is true if the invocation was not started because another
+	// invocation failed with FailFast set or ctx was canceled.
+	Skipped bool
+}
+
+// Batch runs the command line of each element of args on the command tree of
+// cmd concurrently, with at most opts.Parallelism invocations at once, as in
+// running "deploy --target=X" for many targets. It returns the result of each
+// command line, in the order of args, and an aggregated exit code, which is
+// the first non-zero exit code in the order of args, or zero if all
+// invocations succeeded.
+//
+// Command lines are parsed with ParseInvocation, so the caveats of
+// ParseInvocation apply: handlers must read their flags from their
+// Invocation rather than from the variables that the flags were defined with.
+// Each handler writes to its own Output, which is copied to opts.Stdout and
+// opts.Stderr when it returns. Invocations that are skipped have the exit
+// code 1.
+func Batch(ctx context.Context, cmd *Command, args [][]string, opts BatchOptions) ([]BatchResult, int) {
+	n := opts.Parallelism
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	stdout, stderr := cmd.output()
+	if opts.Stdout != nil {
+		stdout = opts.Stdout
+	}
+	if opts.Stderr != nil {
+		stderr = opts.Stderr
+	}
+
+	results := make([]BatchResult, len(args))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var mu sync.Mutex // guards output
+	sem := make(chan struct{}, n)
+	for i := range args {
+		results[i] = BatchResult{Args: args[i], ExitCode: 1, Skipped: true}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		if ctx.Err() != nil {
+			<-sem
+			continue
+		}
+		wg.Add(1)
+		go func(r *BatchResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.Skipped = false
+			r.ExitCode, r.Stdout, r.Stderr = cmd.runInvocation(ctx, r.Args)
+			if r.ExitCode != 0 && opts.FailFast {
+				cancel()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			prefix := ""
+			if opts.Prefix {
+				prefix = "[" + quotePOSIX(r.Args) + "] "
+			}
+			writePrefixed(stdout, prefix, r.Stdout)
+			writePrefixed(stderr, prefix, r.Stderr)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	exitCode := 0
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			exitCode = r.ExitCode
+			break
+		}
+	}
+	return results, exitCode
+}
+
+// runInvocation runs args with Exec with its output written to buffers.
+func (c *Command) runInvocation(ctx context.Context, args []string) (exitCode int, stdout, stderr []byte) {
+	outBuf, errBuf := new(bytes.Buffer), new(bytes.Buffer)
+	exitCode = c.Exec(ctx, args, nil, outBuf, errBuf)
+	return exitCode, outBuf.Bytes(), errBuf.Bytes()
+}
+
+// writePrefixed writes b to w with prefix before each line.
+func writePrefixed(w io.Writer, prefix string, b []byte) {
+	if prefix == "" || len(b) == 0 {
+		w.Write(b)
+		return
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	for _, line := range lines {
+		if line != "" {
+			io.WriteString(w, prefix+line)
+		}
+	}
+}
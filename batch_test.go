@@ -0,0 +1,80 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	var running, maxRunning int32
+	cmd := NewCommand("app", "").
+		Subcommands(
+			NewCommand("deploy", "").
+				Flags(String(nil, "target", "", "")).
+				HandleContext(func(ctx context.Context, args []string) int {
+					n := atomic.AddInt32(&running, 1)
+					defer atomic.AddInt32(&running, -1)
+					for {
+						max := atomic.LoadInt32(&maxRunning)
+						if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+							break
+						}
+					}
+					target := InvocationFrom(ctx).Get("target").(string)
+					out := OutputFrom(ctx)
+					if target == "bad" {
+						fmt.Fprintf(out.Stderr, "failed\n")
+						return 3
+					}
+					fmt.Fprintf(out.Stdout, "deployed %s\n", target)
+					return 0
+				}),
+		).
+		Must()
+
+	var args [][]string
+	for i := 0; i < 20; i++ {
+		args = append(args, []string{"deploy", fmt.Sprintf("--target=t%02d", i)})
+	}
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	results, exitCode := Batch(context.Background(), cmd, args, BatchOptions{
+		Parallelism: 4,
+		Stdout:      stdout,
+		Stderr:      stderr,
+	})
+	assertInt64(t, 0, int64(exitCode))
+	assertInt64(t, 20, int64(len(results)))
+	assertString(t, "deployed t07\n", string(results[7].Stdout))
+	if maxRunning > 4 {
+		t.Errorf("expected at most 4 concurrent invocations, got %d", maxRunning)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	sort.Strings(lines)
+	assertInt64(t, 20, int64(len(lines)))
+	assertString(t, "deployed t00", lines[0])
+
+	// the first failure determines the exit code
+	stdout.Reset()
+	results, exitCode = Batch(context.Background(), cmd, [][]string{
+		{"deploy", "--target=a"},
+		{"deploy", "--target=bad"},
+		{"deploy", "--nope"},
+	}, BatchOptions{Stdout: stdout, Stderr: stderr, Prefix: true})
+	assertInt64(t, 3, int64(exitCode))
+	assertInt64(t, 1, int64(results[2].ExitCode))
+	assertString(t, "Argument error: unrecognized argument: --nope\n", string(results[2].Stderr))
+	assertString(t, "[deploy --target=a] deployed a\n", stdout.String())
+
+	// invocations are skipped after a failure with FailFast
+	results, exitCode = Batch(context.Background(), cmd, [][]string{
+		{"deploy", "--target=bad"},
+		{"deploy", "--target=a"},
+	}, BatchOptions{Parallelism: 1, FailFast: true, Stdout: stdout, Stderr: stderr})
+	assertInt64(t, 3, int64(exitCode))
+	assertBool(t, true, results[1].Skipped)
+}
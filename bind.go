@@ -0,0 +1,142 @@
+package xflags
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Bind copies the value of each flag of this command and its parents into the
+// matching field of the struct that v points to. Bind should be called after
+// the command line is parsed, typically from the command's handler.
+//
+// A field matches a flag if its "xflags" struct tag is the name of the flag,
+// or if it has no tag and its name matches the name of the flag, ignoring
+// case, dashes and underscores. For example, the field DryRun matches the flag
+// --dry-run. An error is returned if a field without a tag matches more than
+// one flag. Fields with the tag `xflags:"-"` are ignored. Fields of embedded
+// structs are matched as if they were fields of v.
+//
+// Flag values must implement Getter and be assignable or convertible to the
+// type of the matching field. Since every Value provided by this package
+// allocates its own variable if given a nil pointer, flags may be declared
+// without any variables and read from a single configuration struct:
+//
+//	type Config struct {
+//		DryRun bool
+//		N      int `xflags:"replicas"`
+//	}
+//
+//	cmd, err := xflags.NewCommand("app", "").
+//		Flags(
+//			xflags.Bool(nil, "dry-run", false, "Print changes only"),
+//			xflags.Int(nil, "replicas", 1, "Number of replicas"),
+//		).
+//		Must().
+//		Parse(os.Args[1:])
+//	if err != nil {
+//		...
+//	}
+//	var cfg Config
+//	if err := cmd.Bind(&cfg); err != nil {
+//		...
+//	}
+func (c *Command) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errorf("%s: bind target must be a non-nil pointer to a struct", c.Name)
+	}
+	flags := make([]*Flag, 0)
+	flagsByName := make(map[string]*Flag)
+	for p := c; p != nil; p = p.Parent {
+		for _, group := range p.FlagGroups {
+			for _, flag := range group.Flags {
+				name := flag.name()
+				if _, ok := flagsByName[name]; !ok {
+					flags = append(flags, flag)
+					flagsByName[name] = flag
+				}
+			}
+		}
+	}
+	return c.bindStruct(rv.Elem(), flags, flagsByName)
+}
+
+// bindStruct binds the fields of rv to flags, which are in order of
+// declaration, nearest command first.
+func (c *Command) bindStruct(rv reflect.Value, flags []*Flag, flagsByName map[string]*Flag) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := c.bindStruct(rv.Field(i), flags, flagsByName); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		tag := field.Tag.Get("xflags")
+		if tag == "-" {
+			continue
+		}
+		var flag *Flag
+		if tag != "" {
+			flag = flagsByName[tag]
+			if flag == nil {
+				return errorf("%s: no flag for field %s: %s", c.Name, field.Name, tag)
+			}
+		} else {
+			key := bindKey(field.Name)
+			for _, f := range flags {
+				if bindKey(f.name()) != key {
+					continue
+				}
+				if flag != nil {
+					return errorf(
+						"%s: field %s matches both %s and %s; specify the flag with a struct tag",
+						c.Name, field.Name, flag, f,
+					)
+				}
+				flag = f
+			}
+			if flag == nil {
+				continue
+			}
+		}
+		if err := bindField(rv.Field(i), flag); err != nil {
+			return errorf("%s: cannot bind %s to field %s: %v", c.Name, flag, field.Name, err)
+		}
+	}
+	return nil
+}
+
+func bindField(field reflect.Value, flag *Flag) error {
+	getter, ok := flag.Value.(Getter)
+	if !ok {
+		return errors.New("value does not implement Getter")
+	}
+	v := reflect.ValueOf(getter.Get())
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().AssignableTo(field.Type()) {
+		field.Set(v)
+		return nil
+	}
+	isNumberToString := field.Kind() == reflect.String && v.Kind() != reflect.String
+	if v.Type().ConvertibleTo(field.Type()) && !isNumberToString {
+		field.Set(v.Convert(field.Type()))
+		return nil
+	}
+	return fmt.Errorf("%s is not assignable to %s", v.Type(), field.Type())
+}
+
+// bindKey normalizes a field or flag name for comparison.
+func bindKey(s string) string {
+	s = strings.Replace(s, "-", "", -1)
+	s = strings.Replace(s, "_", "", -1)
+	return strings.ToLower(s)
+}
@@ -0,0 +1,105 @@
+package xflags
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBind(t *testing.T) {
+	type Embedded struct {
+		Timeout time.Duration
+	}
+	type Config struct {
+		Embedded
+		Verbose  bool
+		DryRun   bool
+		Replicas int `xflags:"n"`
+		Names    []string
+		Ignored  string `xflags:"-"`
+		Missing  string
+		count    uint
+	}
+	cmd, err := NewCommand("test", "").
+		Flags(
+			Bool(nil, "verbose", false, ""),
+			Duration(nil, "timeout", time.Second, ""),
+		).
+		Subcommands(
+			NewCommand("sub", "").Flags(
+				Bool(nil, "dry_run", false, ""),
+				Int(nil, "", 1, "").ShortName("n"),
+				Strings(nil, "names", nil, ""),
+				String(nil, "ignored", "", ""),
+				Uint(nil, "count", 0, ""),
+			),
+		).
+		Must().
+		Parse([]string{
+			"sub", "--verbose", "--dry_run", "-n=3", "--names=foo", "--names=bar",
+			"--ignored=foo", "--count=1",
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{}
+	if err := cmd.Bind(cfg); err != nil {
+		t.Fatal(err)
+	}
+	assertDuration(t, time.Second, cfg.Timeout)
+	assertBool(t, true, cfg.Verbose)
+	assertBool(t, true, cfg.DryRun)
+	assertInt64(t, 3, int64(cfg.Replicas))
+	assertStrings(t, []string{"foo", "bar"}, cfg.Names)
+	assertString(t, "", cfg.Ignored)
+	assertInt64(t, 0, int64(cfg.count))
+}
+
+func TestBindErrors(t *testing.T) {
+	cmd := NewCommand("test", "").
+		Flags(
+			String(nil, "foo", "", ""),
+			Func("bar", "", func(s string) error { return nil }),
+		).
+		Must()
+	var s string
+	testCases := []interface{}{
+		nil,
+		s,
+		&s,
+		&struct{ Foo int }{},
+		&struct{ Bar string }{},
+		&struct {
+			Baz string `xflags:"baz"`
+		}{},
+	}
+	for _, v := range testCases {
+		if err := cmd.Bind(v); err == nil {
+			t.Errorf("expected error binding %T", v)
+		}
+	}
+}
+
+func TestBindAmbiguous(t *testing.T) {
+	cmd := NewCommand("test", "").
+		Flags(
+			String(nil, "dry-run", "", ""),
+			String(nil, "dry_run", "", ""),
+		).
+		Must()
+	var v struct{ DryRun string }
+	err := cmd.Bind(&v)
+	if err == nil {
+		t.Fatal("expected error binding a field that matches two flags")
+	}
+	assertString(
+		t,
+		"xflags: test: field DryRun matches both --dry-run and --dry_run; specify the flag with a struct tag",
+		err.Error(),
+	)
+	var tagged struct {
+		DryRun string `xflags:"dry_run"`
+	}
+	if err := cmd.Bind(&tagged); err != nil {
+		t.Error(err)
+	}
+}
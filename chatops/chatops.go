@@ -0,0 +1,243 @@
+// Package chatops runs the commands of a program from chat messages, such as
+// Slack slash commands, and formats their output as message blocks:
+//
+//	var App = xflags.NewCommand("myapp", "").
+//		Subcommands(
+//			chatops.Expose(deployCommand),
+//			adminCommand,
+//		)
+//
+//	bot := &chatops.Bot{Command: App.Must()}
+//	reply := bot.Handle(ctx, chatops.Message{User: "ann", Text: "/myapp deploy --env prod"})
+//
+// Messages are split into arguments like a POSIX shell command line, with
+// xflags.SplitCommandLine, and parsed by the command tree. Only commands that
+// are exposed with Expose, or whose parent is exposed, may be run from chat;
+// other commands are reported as unavailable.
+//
+// Handlers may retrieve the Message that invoked them with MessageFrom, for
+// example to map chat users to the roles of xflags.RoleGuard. The user who
+// sent the message is passed to xflags.WithUser, so that it is recorded by
+// xflags.CommandBuilder.Audit. Commands read
+// an empty input and must read their flags from their xflags.Invocation, as
+// each message is parsed with xflags.Command.Exec.
+package chatops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+// Annotation is the annotation of commands that are exposed to chat, set by
+// Expose and Conceal.
+const Annotation = "chatops.exposed"
+
+// defaultMaxOutput is the default of Bot.MaxOutput, which is the maximum
+// length of the text of a Slack block.
+const defaultMaxOutput = 3000
+
+// Expose allows the command and its subcommands to be run from chat.
+func Expose(cmd *xflags.CommandBuilder) *xflags.CommandBuilder {
+	return cmd.Annotate(Annotation, "true")
+}
+
+// Conceal prevents the command and its subcommands from being run from chat,
+// even if a parent command is exposed.
+func Conceal(cmd *xflags.CommandBuilder) *xflags.CommandBuilder {
+	return cmd.Annotate(Annotation, "false")
+}
+
+// Exposed reports whether cmd may be run from chat: the nearest of cmd and its
+// parents that is annotated with Expose or Conceal decides.
+func Exposed(cmd *xflags.Command) bool {
+	for p := cmd; p != nil; p = p.Parent {
+		if v, ok := p.Annotations[Annotation]; ok {
+			return v == "true"
+		}
+	}
+	return false
+}
+
+// Message is a chat message.
+type Message struct {
+	User    string // the user who sent the message
+	Channel string // the channel the message was sent to
+	Text    string // the text of the message, such as "/myapp deploy"
+}
+
+type messageKey struct{}
+
+// MessageFrom returns the Message that invoked the command of ctx or nil if the
+// command was not invoked from chat.
+func MessageFrom(ctx context.Context) *Message {
+	msg, _ := ctx.Value(messageKey{}).(*Message)
+	return msg
+}
+
+// BlockKind is the kind of a Block.
+type BlockKind int
+
+const (
+	// TextBlock is a block of formatted text.
+	TextBlock BlockKind = iota + 1
+
+	// CodeBlock is a block of preformatted text, such as the output of a
+	// command.
+	CodeBlock
+)
+
+// Block is a part of a Reply.
+type Block struct {
+	Kind BlockKind
+	Text string
+}
+
+// Reply is the reply to a Message.
+type Reply struct {
+	ExitCode int
+	Blocks   []Block
+}
+
+// Markdown formats the reply as Markdown, with code blocks fenced by "```",
+// which is also understood by Slack.
+func (r *Reply) Markdown() string {
+	var sb strings.Builder
+	for i, block := range r.Blocks {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		switch block.Kind {
+		case CodeBlock:
+			sb.WriteString("```\n" + strings.TrimSuffix(block.Text, "\n") + "\n```\n")
+		default:
+			sb.WriteString(block.Text + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Bot runs commands from chat messages.
+type Bot struct {
+	// Command is the root of the command tree. Its name is the name that
+	// messages must start with, optionally preceded by "/" or "@", as in
+	// "/myapp deploy".
+	Command *xflags.Command
+
+	// MaxOutput is the maximum length of the stdout and stderr blocks of a
+	// Reply. Longer output is truncated. If it is zero, it is 3000 bytes.
+	MaxOutput int
+}
+
+// Handle runs the command line of msg and returns the reply, or nil if msg is
+// not addressed to the bot.
+func (b *Bot) Handle(ctx context.Context, msg Message) *Reply {
+	args := xflags.SplitCommandLine(msg.Text)
+	if len(args) == 0 || strings.TrimLeft(args[0], "/@") != b.Command.Name {
+		return nil
+	}
+	args = args[1:]
+	if cmd := b.target(args); !Exposed(cmd) {
+		return &Reply{
+			ExitCode: 1,
+			Blocks: []Block{{
+				Kind: TextBlock,
+				Text: fmt.Sprintf("'%s' is not available in chat", commandPath(cmd)),
+			}},
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	ctx = context.WithValue(ctx, messageKey{}, &msg)
+	ctx = xflags.WithUser(ctx, msg.User)
+	exitCode := b.Command.Exec(ctx, args, strings.NewReader(""), &stdout, &stderr)
+	reply := &Reply{ExitCode: exitCode}
+	for _, s := range []string{stdout.String(), stderr.String()} {
+		if s != "" {
+			reply.Blocks = append(reply.Blocks, Block{Kind: CodeBlock, Text: b.truncate(s)})
+		}
+	}
+	switch {
+	case exitCode != 0:
+		reply.Blocks = append(reply.Blocks, Block{Kind: TextBlock, Text: fmt.Sprintf("Exited with code %d", exitCode)})
+	case len(reply.Blocks) == 0:
+		reply.Blocks = append(reply.Blocks, Block{Kind: TextBlock, Text: "Done"})
+	}
+	return reply
+}
+
+// target returns the command that args invoke, or the command that reported
+// an error for them.
+func (b *Bot) target(args []string) *xflags.Command {
+	inv, err := b.Command.ParseInvocation(args)
+	var helpErr *xflags.HelpError
+	var argErr *xflags.ArgumentError
+	switch {
+	case err == nil:
+		return inv.Target()
+	case errors.As(err, &helpErr):
+		return helpErr.Cmd
+	case errors.As(err, &argErr) && argErr.Cmd != nil:
+		return argErr.Cmd
+	}
+	return b.Command
+}
+
+// truncate shortens s to MaxOutput bytes without splitting a character.
+func (b *Bot) truncate(s string) string {
+	max := b.MaxOutput
+	if max <= 0 {
+		max = defaultMaxOutput
+	}
+	const suffix = "\n... (truncated)"
+	if len(s) <= max || max <= len(suffix) {
+		return s
+	}
+	n := max - len(suffix)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + suffix
+}
+
+// ServeHTTP handles Slack slash commands, which are POSTed as forms with the
+// fields "command", "text", "user_name" and "channel_name", and responds with
+// the reply as an ephemeral message. Requests must be authenticated, for
+// example by verifying their signature, before they are passed to the Bot.
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	msg := Message{
+		User:    r.PostFormValue("user_name"),
+		Channel: r.PostFormValue("channel_name"),
+		Text:    r.PostFormValue("command") + " " + r.PostFormValue("text"),
+	}
+	reply := b.Handle(r.Context(), msg)
+	if reply == nil {
+		http.Error(w, "unknown command", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"response_type": "ephemeral",
+		"text":          reply.Markdown(),
+	})
+}
+
+func commandPath(cmd *xflags.Command) string {
+	var path []string
+	for p := cmd; p != nil; p = p.Parent {
+		path = append([]string{p.Name}, path...)
+	}
+	return strings.Join(path, " ")
+}
@@ -0,0 +1,127 @@
+package chatops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+func newBot() *Bot {
+	cmd := xflags.NewCommand("myapp", "").
+		Output(io.Discard, io.Discard).
+		Subcommands(
+			Expose(xflags.NewCommand("deploy", "").
+				Flags(xflags.String(nil, "env", "staging", "")).
+				HandleContext(func(ctx context.Context, args []string) int {
+					env := xflags.InvocationFrom(ctx).Get("env").(string)
+					if env == "bad" {
+						fmt.Fprintf(xflags.OutputFrom(ctx).Stderr, "unknown env\n")
+						return 2
+					}
+					fmt.Fprintf(xflags.OutputFrom(ctx).Stdout, "%s deployed to %s\n", MessageFrom(ctx).User, env)
+					return 0
+				}).
+				Subcommands(
+					Conceal(xflags.NewCommand("force", "").
+						HandleFunc(func(args []string) int { return 0 })),
+				)),
+			xflags.NewCommand("spam", "").
+				HandleFunc(func(args []string) int { return 0 }),
+			Expose(xflags.NewCommand("noop", "").
+				HandleFunc(func(args []string) int { return 0 })),
+		).
+		Must()
+	return &Bot{Command: cmd}
+}
+
+func TestBotHandle(t *testing.T) {
+	bot := newBot()
+	tests := []struct {
+		text     string
+		exitCode int
+		markdown string
+	}{
+		{"/myapp deploy --env 'prod eu'", 0, "```\nann deployed to prod eu\n```\n"},
+		{"@myapp deploy --env=bad", 2, "```\nunknown env\n```\n\nExited with code 2\n"},
+		{"myapp deploy --nope", 1, "```\nArgument error: unrecognized argument: --nope\n```\n\nExited with code 1\n"},
+		{"/myapp noop", 0, "Done\n"},
+		{"/myapp spam", 1, "'myapp spam' is not available in chat\n"},
+		{"/myapp spam --help", 1, "'myapp spam' is not available in chat\n"},
+		{"/myapp deploy force", 1, "'myapp deploy force' is not available in chat\n"},
+		{"/myapp", 1, "'myapp' is not available in chat\n"},
+	}
+	for _, test := range tests {
+		reply := bot.Handle(context.Background(), Message{User: "ann", Text: test.text})
+		if reply == nil {
+			t.Errorf("%s: expected a reply", test.text)
+			continue
+		}
+		if reply.ExitCode != test.exitCode {
+			t.Errorf("%s: expected exit code %d, got %d", test.text, test.exitCode, reply.ExitCode)
+		}
+		if s := reply.Markdown(); s != test.markdown {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.text, test.markdown, s)
+		}
+	}
+
+	if reply := bot.Handle(context.Background(), Message{Text: "/other deploy"}); reply != nil {
+		t.Errorf("expected no reply, got %+v", reply)
+	}
+}
+
+func TestBotTruncate(t *testing.T) {
+	bot := &Bot{MaxOutput: 21}
+	s := bot.truncate(strings.Repeat("é", 20))
+	if s != "éé\n... (truncated)" {
+		t.Errorf("got %q", s)
+	}
+	if s := bot.truncate("short"); s != "short" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestBotServeHTTP(t *testing.T) {
+	srv := httptest.NewServer(newBot())
+	defer srv.Close()
+	resp, err := http.PostForm(srv.URL, url.Values{
+		"command":   {"/myapp"},
+		"text":      {"deploy --env prod"},
+		"user_name": {"bob"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["text"] != "```\nbob deployed to prod\n```\n" || body["response_type"] != "ephemeral" {
+		t.Errorf("got %q", body)
+	}
+}
+
+func TestBotAudit(t *testing.T) {
+	var records []xflags.AuditRecord
+	cmd := xflags.NewCommand("myapp", "").
+		Output(io.Discard, io.Discard).
+		AuditFunc(func(r xflags.AuditRecord) { records = append(records, r) }).
+		Subcommands(
+			Expose(xflags.NewCommand("noop", "").
+				HandleFunc(func(args []string) int { return 0 })),
+		).
+		Must()
+	bot := &Bot{Command: cmd}
+	bot.Handle(context.Background(), Message{User: "ann", Text: "/myapp noop"})
+	if len(records) != 1 || records[0].User != "ann" {
+		t.Errorf("expected a record for ann, got %+v", records)
+	}
+}
@@ -0,0 +1,76 @@
+package xflags
+
+import "strings"
+
+// SplitCommandLine splits s into arguments using the quoting rules of a POSIX
+// shell, so that command lines read from configuration files, chat messages
+// or an interactive prompt may be parsed by a Command.
+//
+// Arguments are separated by unquoted spaces, tabs and newlines. Characters
+// enclosed in single quotes are preserved literally. In double quotes, a
+// backslash escapes only a double quote, a backslash, "$", "`" or a newline
+// and is otherwise preserved. Outside of quotes, a backslash preserves the
+// next character literally and a backslash followed by a newline is removed.
+// Quotes may appear within an argument and an empty pair of quotes produces an
+// empty argument.
+//
+// No expansion of variables, globs or other shell syntax is performed. An
+// unterminated quote extends to the end of s.
+func SplitCommandLine(s string) []string {
+	args := make([]string, 0)
+	var sb strings.Builder
+	inArg := false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+		case ch == '\\':
+			inArg = true
+			if i+1 == len(s) {
+				sb.WriteByte(ch)
+				break
+			}
+			i++
+			if s[i] == '\n' {
+				// line continuation
+				inArg = sb.Len() > 0
+				break
+			}
+			sb.WriteByte(s[i])
+		case ch == '\'':
+			inArg = true
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				j = len(s) - i - 1
+			}
+			sb.WriteString(s[i+1 : i+1+j])
+			i += j + 1
+		case ch == '"':
+			inArg = true
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					switch s[i+1] {
+					case '"', '\\', '$', '`':
+						i++
+					case '\n':
+						i++
+						continue
+					}
+				}
+				sb.WriteByte(s[i])
+			}
+		default:
+			inArg = true
+			sb.WriteByte(ch)
+		}
+	}
+	if inArg {
+		args = append(args, sb.String())
+	}
+	return args
+}
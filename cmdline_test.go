@@ -0,0 +1,63 @@
+package xflags
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Expect []string
+	}{
+		{"", []string{}},
+		{"  \t\n ", []string{}},
+		{"deploy --replicas 3", []string{"deploy", "--replicas", "3"}},
+		{"  a  b\tc\n", []string{"a", "b", "c"}},
+		{`--name 'hello world'`, []string{"--name", "hello world"}},
+		{`--name "hello world"`, []string{"--name", "hello world"}},
+		{`--name=hello\ world`, []string{"--name=hello world"}},
+		{`--name="a b"c'd e'`, []string{"--name=a bcd e"}},
+		{`'' ""`, []string{"", ""}},
+		{`'a\b' "a\b" a\b`, []string{`a\b`, `a\b`, "ab"}},
+		{`"a\"b\\c\$d\` + "`" + `e"`, []string{"a\"b\\c$d`e"}},
+		{`'it'\''s'`, []string{"it's"}},
+		{"a\\\nb c", []string{"ab", "c"}},
+		{"\"a\\\nb\"", []string{"ab"}},
+		{`a\`, []string{`a\`}},
+		{`'unterminated`, []string{"unterminated"}},
+		{`"unterminated \"`, []string{`unterminated "`}},
+		{`$HOME *.go`, []string{"$HOME", "*.go"}},
+	}
+	for _, test := range tests {
+		actual := SplitCommandLine(test.Input)
+		if !assertStrings(t, test.Expect, actual) {
+			t.Logf("input: %q", test.Input)
+		}
+	}
+}
+
+func FuzzSplitCommandLine(f *testing.F) {
+	for _, s := range []string{
+		"deploy --replicas 3",
+		`--name 'hello world' "a\"b" c\ d`,
+		"a\\\nb",
+		`'unterminated "`,
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		args := SplitCommandLine(s)
+		if !strings.ContainsAny(s, `'"\`) {
+			fields := strings.FieldsFunc(s, func(r rune) bool {
+				return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+			})
+			assertStrings(t, fields, args)
+		}
+		for _, arg := range args {
+			if len(arg) > len(s) {
+				t.Fatalf("argument longer than input: %q", arg)
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package xflags
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterValueFunc(ParseColor, FormatColor)
+}
+
+// namedColors are the colors accepted by name by ParseColor: the basic colors
+// of CSS and a few common extended colors.
+var namedColors = map[string]color.RGBA{
+	"black":       {0x00, 0x00, 0x00, 0xff},
+	"silver":      {0xc0, 0xc0, 0xc0, 0xff},
+	"gray":        {0x80, 0x80, 0x80, 0xff},
+	"grey":        {0x80, 0x80, 0x80, 0xff},
+	"white":       {0xff, 0xff, 0xff, 0xff},
+	"maroon":      {0x80, 0x00, 0x00, 0xff},
+	"red":         {0xff, 0x00, 0x00, 0xff},
+	"purple":      {0x80, 0x00, 0x80, 0xff},
+	"fuchsia":     {0xff, 0x00, 0xff, 0xff},
+	"magenta":     {0xff, 0x00, 0xff, 0xff},
+	"green":       {0x00, 0x80, 0x00, 0xff},
+	"lime":        {0x00, 0xff, 0x00, 0xff},
+	"olive":       {0x80, 0x80, 0x00, 0xff},
+	"yellow":      {0xff, 0xff, 0x00, 0xff},
+	"navy":        {0x00, 0x00, 0x80, 0xff},
+	"blue":        {0x00, 0x00, 0xff, 0xff},
+	"teal":        {0x00, 0x80, 0x80, 0xff},
+	"aqua":        {0x00, 0xff, 0xff, 0xff},
+	"cyan":        {0x00, 0xff, 0xff, 0xff},
+	"orange":      {0xff, 0xa5, 0x00, 0xff},
+	"pink":        {0xff, 0xc0, 0xcb, 0xff},
+	"brown":       {0xa5, 0x2a, 0x2a, 0xff},
+	"gold":        {0xff, 0xd7, 0x00, 0xff},
+	"indigo":      {0x4b, 0x00, 0x82, 0xff},
+	"violet":      {0xee, 0x82, 0xee, 0xff},
+	"transparent": {0x00, 0x00, 0x00, 0x00},
+}
+
+// Color returns a FlagBuilder that can be used to define a color flag with
+// specified name, default value, and usage string. The argument p points to a
+// color.RGBA variable in which to store the value of the flag. The flag
+// accepts the notations of ParseColor.
+func Color(p *color.RGBA, name string, value color.RGBA, usage string) *FlagBuilder {
+	return Of(p, name, value, usage)
+}
+
+// ParseColor parses a color in one of the following notations of CSS:
+//
+//   - "#RGB", "#RRGGBB" or "#RRGGBBAA" in hexadecimal.
+//   - "rgb(R, G, B)" or "rgba(R, G, B, A)", where R, G and B are integers
+//     from 0 to 255 or percentages and A is a number from 0 to 1 or a
+//     percentage.
+//   - A name, such as "red", "orange" or "transparent".
+//
+// ParseColor is registered with RegisterValueFunc, so flags of type
+// color.RGBA may also be defined with Of.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+	var c color.NRGBA
+	var ok bool
+	switch {
+	case strings.HasPrefix(s, "#"):
+		c, ok = parseHexColor(s[1:])
+	case strings.HasPrefix(s, "rgb(") || strings.HasPrefix(s, "rgba("):
+		c, ok = parseRGBColor(s)
+	}
+	if !ok {
+		return color.RGBA{}, errorf("invalid color: %q", s)
+	}
+	return color.RGBAModel.Convert(c).(color.RGBA), nil
+}
+
+// FormatColor formats c as "#rrggbb", or "#rrggbbaa" if c is not opaque.
+// Since color.RGBA is alpha-premultiplied, the components of colors that are
+// not opaque may differ slightly from those that were parsed.
+func FormatColor(c color.RGBA) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+}
+
+func parseHexColor(s string) (color.NRGBA, bool) {
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return color.NRGBA{}, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+}
+
+func parseRGBColor(s string) (color.NRGBA, bool) {
+	fn, args, _ := strings.Cut(s, "(")
+	if !strings.HasSuffix(args, ")") {
+		return color.NRGBA{}, false
+	}
+	parts := strings.Split(strings.TrimSuffix(args, ")"), ",")
+	if fn == "rgb" && len(parts) != 3 || fn == "rgba" && len(parts) != 4 {
+		return color.NRGBA{}, false
+	}
+	c := color.NRGBA{A: 0xff}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		scale := 255.0 // of color components
+		if i == 3 {
+			scale = 1 // of alpha
+		}
+		if p := strings.TrimSuffix(part, "%"); p != part {
+			part, scale = p, 100
+		}
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil || f < 0 || f > scale {
+			return color.NRGBA{}, false
+		}
+		v := uint8(math.Round(f / scale * 255))
+		switch i {
+		case 0:
+			c.R = v
+		case 1:
+			c.G = v
+		case 2:
+			c.B = v
+		case 3:
+			c.A = v
+		}
+	}
+	return c, true
+}
@@ -0,0 +1,46 @@
+package xflags
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		Arg    string
+		Expect string
+		OK     bool
+	}{
+		{"#ff8800", "#ff8800", true},
+		{"#F80", "#ff8800", true},
+		{"#ff000080", "#ff000080", true},
+		{"rgb(255, 136, 0)", "#ff8800", true},
+		{"rgb(100%, 0%, 50%)", "#ff0080", true},
+		{"rgba(0, 0, 255, 0.5)", "#0000ff80", true},
+		{"Orange", "#ffa500", true},
+		{"transparent", "#00000000", true},
+		{"#ff88", "", false},
+		{"#gg8800", "", false},
+		{"rgb(256, 0, 0)", "", false},
+		{"rgb(0, 0)", "", false},
+		{"rgba(0, 0, 0, 2)", "", false},
+		{"chartreuse-ish", "", false},
+	}
+	for _, test := range tests {
+		var c color.RGBA
+		err := parseFlag(Color(&c, "fg", color.RGBA{}, "").Must(), "--fg", test.Arg)
+		if assertBool(t, test.OK, err == nil) && test.OK {
+			assertString(t, test.Expect, FormatColor(c))
+		}
+	}
+
+	// the default value is formatted in help messages
+	flag := Color(nil, "bg", color.RGBA{0xff, 0xff, 0xff, 0xff}, "").Must()
+	assertString(t, "#ffffff", valueString(flag.Value))
+
+	// the color registry is used by Of
+	var c color.RGBA
+	if assertFlagParses(t, Of(&c, "fg", color.RGBA{}, "").Must(), "--fg=red") {
+		assertString(t, "#ff0000", FormatColor(c))
+	}
+}
@@ -1,11 +1,16 @@
 package xflags
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // TODO: Allow packages to declare global flags that are accessible on init.
@@ -25,25 +30,130 @@ type Commander interface {
 // terminator if it is enabled.
 type HandlerFunc func(args []string) int
 
+// An ErrorHandlerFunc is a ContextHandlerFunc that returns an error instead
+// of an exit code. See CommandBuilder.HandleE.
+type ErrorHandlerFunc func(ctx context.Context, args []string) error
+
+// A ContextHandlerFunc is a HandlerFunc that also receives a context which
+// carries the Invocation, Output and Printer of the invocation. See
+// InvocationFrom, OutputFrom and PrinterFrom.
+type ContextHandlerFunc func(ctx context.Context, args []string) int
+
 // Command describes a command that users may invoke from the command line.
 //
 // Programs should not create Command directly and instead use the Command
 // function to build one with proper error checking.
 type Command struct {
-	Parent         *Command
-	Name           string
-	Usage          string
-	Synopsis       string
-	Hidden         bool
-	WithTerminator bool
-	FlagGroups     []*FlagGroup
-	Subcommands    []*Command
-	FormatFunc     FormatFunc
-	HandlerFunc    HandlerFunc
-	Stdout         io.Writer
-	Stderr         io.Writer
-
-	args []string
+	Parent           *Command
+	Name             string
+	Usage            string
+	UsageFunc        func() string
+	Synopsis         string
+	SynopsisFunc     func() string
+	Hidden           bool
+	WithTerminator   bool
+	MaxSections      int
+	BoolSyntax       BoolSyntax
+	PrefixShortNames bool
+	POSIXStrict      bool
+	Expression       bool
+	StrictConfig     bool
+	NoUsageHint      bool
+	FlagGroups       []*FlagGroup
+	Subcommands      []*Command
+	FormatFunc       FormatFunc
+	HandlerFunc      HandlerFunc
+	ContextHandler   ContextHandlerFunc
+	ErrorHandler     ErrorHandlerFunc
+	Middleware       []Middleware
+	Sources          []Source
+	Annotations      map[string]string
+	ExitCodes        map[int]string
+	Feature          string
+	FeatureGate      FeatureGate
+	Reporters        []Reporter
+	Version          string
+	CheckVersionFunc CheckVersionFunc
+	Stdin            io.Reader
+	Stdout           io.Writer
+	Stderr           io.Writer
+	Env              []string
+
+	args               []string
+	sections           [][]string
+	parser             *argParser
+	outputOptions      *outputOptions
+	formatOptions      *formatOptions
+	pagerOptions       *pagerOptions
+	confirmOptions     *confirmOptions
+	dryRunOptions      *dryRunOptions
+	printConfigOptions *printConfigOptions
+	profileOptions     *profileOptions
+	historyOptions     *historyOptions
+	usageStatsOptions  *usageStatsOptions
+	aliasOptions       *aliasOptions
+	presetOptions      *presetOptions
+	tlsOptions         []*tlsOptions
+	httpClientOptions  []*httpClientOptions
+	listenOptions      []*listenOptions
+	credentialOptions  *credentialOptions
+	verbosityOptions   *verbosityOptions
+	usageSyntax        string
+	onUnknownCommand   func(name string, args []string) int
+	guards             []GuardFunc
+	syntax             *syntaxNode  // compiled usageSyntax
+	idx                atomic.Value // *commandIndex
+}
+
+// helpLevel is the amount of detail shown in a help message.
+type helpLevel int
+
+const (
+	helpFull  helpLevel = iota // --help shows all flags except those hidden by VisibleIf
+	helpBrief                  // -h shows only Common flags
+	helpAll                    // --help-all shows all flags
+)
+
+// BoolSyntax specifies the forms in which the value of a boolean flag may be
+// specified on the command line.
+type BoolSyntax int
+
+const (
+	// BoolDefault inherits the syntax of the parent command, or BoolAttached
+	// for the root command.
+	BoolDefault BoolSyntax = iota
+
+	// BoolAttached permits only "--flag", which sets the flag to true, and
+	// "--flag=value", where value is any value accepted by the flag.
+	BoolAttached
+
+	// BoolSeparate permits the forms of BoolAttached and "--flag value" where
+	// value is "true" or "false". Any other value following the flag is parsed
+	// as the next argument.
+	BoolSeparate
+)
+
+// BoolForm describes the form in which a boolean flag was specified on the
+// command line.
+type BoolForm int
+
+const (
+	BoolFormNone     BoolForm = iota // not specified on the command line
+	BoolFormBare                     // --flag
+	BoolFormAttached                 // --flag=value
+	BoolFormSeparate                 // --flag value
+)
+
+func (f BoolForm) String() string {
+	switch f {
+	case BoolFormBare:
+		return "bare"
+	case BoolFormAttached:
+		return "attached"
+	case BoolFormSeparate:
+		return "separate"
+	}
+	return "none"
 }
 
 // Command implements the Commander interface.
@@ -87,11 +197,36 @@ func (c *Command) Command() (*Command, error) {
 			}
 		}
 	}
+	if c.usageSyntax != "" {
+		syntax, err := compileSyntax(c, c.usageSyntax)
+		if err != nil {
+			return nil, err
+		}
+		c.syntax = syntax
+	}
 	return c, nil
 }
 
 func (c *Command) String() string { return c.Name }
 
+// UsageText returns the one-line usage string of the command, calling
+// UsageFunc if it is set.
+func (c *Command) UsageText() string {
+	if c.UsageFunc != nil {
+		return c.UsageFunc()
+	}
+	return c.Usage
+}
+
+// SynopsisText returns the detailed help message of the command, calling
+// SynopsisFunc if it is set.
+func (c *Command) SynopsisText() string {
+	if c.SynopsisFunc != nil {
+		return c.SynopsisFunc()
+	}
+	return c.Synopsis
+}
+
 // Args returns any command line arguments specified after the "--" terminator
 // if it was enabled. Args is only populated after the command line is
 // successfully parsed.
@@ -107,21 +242,51 @@ func (c *Command) Arg(i int) string {
 	return c.args[i]
 }
 
+// Sections returns the arguments specified after the "--" terminator, split
+// into sections by any subsequent "--" arguments, if the command was
+// configured with WithSections. Sections is only populated after the command
+// line is successfully parsed.
+func (c *Command) Sections() [][]string { return c.sections }
+
+// Section returns the i'th section of arguments specified after the "--"
+// terminator. Section(0) is the first section. Section returns nil if the
+// requested section does not exist.
+func (c *Command) Section(i int) []string {
+	if i < 0 || i >= len(c.sections) {
+		return nil
+	}
+	return c.sections[i]
+}
+
+// BoolForm returns the form in which the named boolean flag was last specified
+// on the command line so that it may be recorded for auditing. BoolForm
+// returns BoolFormNone if the flag was not specified on the command line or
+// the command line has not been parsed.
+func (c *Command) BoolForm(name string) BoolForm {
+	if c.parser == nil {
+		return BoolFormNone
+	}
+	return c.parser.boolForm(name)
+}
+
 // Parse parses the given set of command line arguments and stores the value of
 // each argument in each command flag's target. The rules for each flag are
 // checked and any errors are returned.
 //
-// If -h or --help are specified, a HelpError will be returned containing the
-// subcommand that was specified.
+// If -h, --help or --help-all are specified, a HelpError will be returned
+// containing the subcommand that was specified.
 //
 // The returned *Command will be this command or one of its subcommands if
 // specified by the command line arguments.
 func (c *Command) Parse(args []string) (*Command, error) {
-	cmd, args, err := newArgParser(c, args).Parse()
+	parser := newArgParser(c, args)
+	cmd, args, err := parser.Parse()
 	if err != nil {
 		return nil, err
 	}
 	cmd.args = args
+	cmd.sections = splitSections(args, cmd.MaxSections)
+	cmd.parser = parser
 	return cmd, nil
 }
 
@@ -141,24 +306,183 @@ func (c *Command) output() (stdout, stderr io.Writer) {
 // Run parses the given set of command line arguments and calls the handler
 // for the command or subcommand specified by the arguments.
 //
-// If -h or --help are specified, usage information will be printed to os.Stdout
-// and the return code will be 0.
+// If -h, --help or --help-all are specified, usage information will be printed
+// to the output of the command, os.Stdout by default, and the return code
+// will be 0.
 //
 // If a command is invoked that has no handler, usage information will be
 // printed to os.Stderr and the return code will be non-zero.
+//
+// If the first argument is "__complete", the candidates to complete the
+// remaining arguments are printed instead, as described by Complete.
+//
+// Run never exits the program, so it may also be called by hosts such as
+// WebAssembly playgrounds, which pass the arguments, output, environment
+// (with CommandBuilder.Environ) and configuration files (with ConfigFileFS)
+// of each run. When compiled to WebAssembly, no signals are handled.
 func (c *Command) Run(args []string) int {
+	if len(args) > 0 && args[0] == completeCommand {
+		return c.printCompletions(args[1:])
+	}
+	start := time.Now()
 	target, err := c.Parse(args)
 	if err != nil {
-		return c.handleErr(err)
+		exitCode := c.handleErr(err)
+		c.reportErr(context.Background(), err, start, exitCode)
+		return exitCode
 	}
-	if target.HandlerFunc == nil {
-		_, stderr := target.output()
-		if err := target.WriteUsage(stderr); err != nil {
+	defer target.parser.release()
+	inv := target.newInvocation(target.args)
+	return inv.dispatch(context.Background(), start, func(ctx context.Context, inv *Invocation) int {
+		notify := target.checkVersion()
+		defer notify()
+		return target.handleAndRelease(ctx, inv)
+	})
+}
+
+// dispatch calls handle with the Invocation unless it is denied by a guard,
+// the invoked command is unknown, --print-config was specified or the command
+// has no handler, and sends an Event describing the invocation to the
+// reporters of the command. Run and Exec share dispatch so that their builtin
+// behavior is the same.
+func (c *Invocation) dispatch(
+	ctx context.Context,
+	start time.Time,
+	handle func(ctx context.Context, inv *Invocation) int,
+) int {
+	target := c.cmd
+	code, class := 0, ErrorNone
+	format, diff := target.printConfigFormat(c.parser)
+	guardErr := c.guard(ctx)
+	switch {
+	case guardErr != nil:
+		_, stderr := c.output()
+		fmt.Fprintf(stderr, "Error: %s\n", errStr(guardErr))
+		code, class = exitCode(guardErr), ErrorExit
+	case c.parser != nil && c.parser.unknownCommand != "":
+		code = target.unknownCommandHandler()(c.parser.unknownCommand, c.args)
+		if code != 0 {
+			class = ErrorExit
+		}
+	case format != "":
+		code = c.printConfig(format, diff)
+	case !target.HasHandler():
+		_, stderr := c.output()
+		if len(target.Subcommands) > 0 && target.usageHint() {
+			fmt.Fprintf(stderr, "Error: missing command\n")
+			target.writeUsageHint(stderr)
+		} else if err := target.WriteUsage(stderr); err != nil {
 			panic(err)
 		}
+		code, class = 1, ErrorUsage
+	default:
+		code = handle(ctx, c)
+		switch {
+		case c.aborted:
+			class = ErrorAborted
+		case code != 0:
+			class = ErrorExit
+		}
+	}
+	target.report(ctx, c.parser, start, code, class)
+	return code
+}
+
+// reportErr reports an invocation of c whose command line could not be parsed
+// and that exited with exitCode.
+func (c *Command) reportErr(ctx context.Context, err error, start time.Time, exitCode int) {
+	class := ErrorArgument
+	if exitCode == 0 {
+		class = ErrorNone // help was shown
+	}
+	if cmd := errorCommand(err); cmd != nil {
+		c = cmd
+	}
+	c.report(ctx, nil, start, exitCode, class)
+}
+
+// unknownCommandHandler returns the nearest OnUnknownCommand function of c or
+// its parents, or nil.
+func (c *Command) unknownCommandHandler() func(name string, args []string) int {
+	for p := c; p != nil; p = p.Parent {
+		if p.onUnknownCommand != nil {
+			return p.onUnknownCommand
+		}
+	}
+	return nil
+}
+
+// HasHandler reports whether the command has a HandlerFunc, ContextHandler or
+// ErrorHandler.
+func (c *Command) HasHandler() bool {
+	return c.HandlerFunc != nil || c.ContextHandler != nil || c.ErrorHandler != nil
+}
+
+// Handle calls the handler of the command and any middleware with args. The
+// handler receives a context derived from ctx that carries the Invocation,
+// Output and Printer of the command. Handle should only be called on a command
+// returned by Parse.
+//
+// Files opened by the parser for flags created by Reader, Writer and OpenFile
+// and listeners created by ListenFlags are closed after the handler returns.
+// If a file cannot be closed, the error is printed and Handle returns a
+// non-zero exit code.
+func (c *Command) Handle(ctx context.Context, args []string) int {
+	return c.handleAndRelease(ctx, c.newInvocation(args))
+}
+
+// handleAndRelease handles inv and releases the resources of the parser of c.
+func (c *Command) handleAndRelease(ctx context.Context, inv *Invocation) int {
+	code := c.handle(ctx, inv)
+	if c.parser != nil {
+		if err := c.parser.release(); err != nil && code == 0 {
+			_, stderr := c.output()
+			fmt.Fprintf(stderr, "Error: %v\n", errStr(err))
+			code = 1
+		}
+	}
+	return code
+}
+
+func (c *Command) handle(ctx context.Context, inv *Invocation) int {
+	out := c.newOutput(inv.parser)
+	if inv.stdin != nil {
+		out.Stdin = inv.stdin
+	}
+	if inv.stdout != nil {
+		out.Stdout, out.IsTTY, out.ANSI = inv.stdout, false, false
+	}
+	if inv.stderr != nil {
+		out.Stderr = inv.stderr
+	}
+	if err := inv.guard(ctx); err != nil {
+		fmt.Fprintf(out.Stderr, "Error: %s\n", errStr(err))
+		return exitCode(err)
+	}
+	out.assumeYes = c.assumeYes(inv)
+	if !c.confirm(inv, out) {
+		inv.aborted = true
+		return 1
+	}
+	args := inv.args
+	ctx = context.WithValue(ctx, invocationKey{}, inv)
+	ctx = context.WithValue(ctx, outputKey{}, out)
+	if p := c.newPrinter(inv, out); p != nil {
+		ctx = context.WithValue(ctx, printerKey{}, p)
+	}
+	ctx = c.withTLSConfigs(ctx, inv)
+	ctx = c.withHTTPClients(ctx, inv)
+	ctx, closeListeners, err := c.withListeners(ctx, inv)
+	if err != nil {
+		fmt.Fprintf(out.Stderr, "Argument error: %s\n", errStr(err))
 		return 1
 	}
-	return target.HandlerFunc(target.args)
+	defer closeListeners()
+	code := c.handler()(ctx, args)
+	if inv.err != nil {
+		fmt.Fprintf(out.Stderr, "Error: %v\n", errStr(inv.err))
+	}
+	return code
 }
 
 func (c *Command) handleErr(err error) int {
@@ -167,15 +491,15 @@ func (c *Command) handleErr(err error) int {
 	}
 	var helpErr *HelpError
 	if errors.As(err, &helpErr) {
-		stdout, _ := helpErr.Cmd.output()
-		if stdout != os.Stdout {
-			if f, ok := stdout.(*os.File); ok {
-				panic(f.Name())
-			}
-			panic(stdout)
+		out := helpErr.Cmd.newOutput(nil)
+		w := out.Pager()
+		err := helpErr.Cmd.writeUsage(w, helpErr.level())
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
 		}
-		if err := helpErr.Cmd.WriteUsage(stdout); err != nil {
-			panic(err)
+		if err != nil {
+			fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+			return 1
 		}
 		return 0
 	}
@@ -183,6 +507,12 @@ func (c *Command) handleErr(err error) int {
 	if errors.As(err, &argErr) {
 		_, stderr := argErr.Cmd.output()
 		fmt.Fprintf(stderr, "Argument error: %s\n", argErr.String())
+		if s := argErr.Highlight(); s != "" && isTerminal(stderr) {
+			fmt.Fprintf(stderr, "%s\n", s)
+		}
+		if argErr.Cmd.usageHint() {
+			argErr.Cmd.writeUsageHint(stderr)
+		}
 		return 1
 	}
 	_, stderr := c.output()
@@ -190,15 +520,37 @@ func (c *Command) handleErr(err error) int {
 	return 1
 }
 
+// usageHint reports whether a hint that refers to the help of c is printed
+// after usage errors.
+func (c *Command) usageHint() bool {
+	for p := c; p != nil; p = p.Parent {
+		if p.NoUsageHint {
+			return false
+		}
+	}
+	return true
+}
+
+// writeUsageHint prints a hint that refers to the help of c.
+func (c *Command) writeUsageHint(w io.Writer) {
+	fmt.Fprintf(w, "See '%s --help'.\n", strings.Join(commandPath(c), " "))
+}
+
 // WriteUsage prints a help message to the given Writer using the configured
 // Formatter.
 func (c *Command) WriteUsage(w io.Writer) error {
+	return c.writeUsage(w, helpFull)
+}
+
+// writeUsage prints a help message with the given level of detail, which only
+// applies to the default Formatter.
+func (c *Command) writeUsage(w io.Writer, level helpLevel) error {
 	f := c.FormatFunc
 	for p := c; f == nil && p != nil; p = p.Parent {
 		f = p.FormatFunc
 	}
 	if f == nil {
-		f = Format
+		return format(w, c, level)
 	}
 	return f(w, c)
 }
@@ -210,6 +562,7 @@ type CommandBuilder struct {
 	cmd         Command
 	flagGroups  []*flagGroupBuilder
 	subcommands []Commander
+	compact     bool
 	err         error
 }
 
@@ -242,6 +595,23 @@ func (c *CommandBuilder) Synopsis(s string) *CommandBuilder {
 	return c
 }
 
+// UsageFunc specifies a function that returns the one-line usage string of
+// this command. It overrides the usage string given to NewCommand and is
+// called only when a help message is printed.
+func (c *CommandBuilder) UsageFunc(fn func() string) *CommandBuilder {
+	c.cmd.UsageFunc = fn
+	return c
+}
+
+// SynopsisFunc specifies a function that returns the detailed help message
+// for this command. It overrides Synopsis and is called only when a help
+// message is printed, so that long or generated help text is not built on
+// every invocation.
+func (c *CommandBuilder) SynopsisFunc(fn func() string) *CommandBuilder {
+	c.cmd.SynopsisFunc = fn
+	return c
+}
+
 // HandleFunc registers the handler for the command. If no handler is specified
 // and the command is invoked, it will print usage information to stderr.
 func (c *CommandBuilder) HandleFunc(
@@ -251,6 +621,36 @@ func (c *CommandBuilder) HandleFunc(
 		return c.error(errorf("%s: nil handler", c.cmd.Name))
 	}
 	c.cmd.HandlerFunc = handler
+	c.cmd.ContextHandler = nil
+	c.cmd.ErrorHandler = nil
+	return c
+}
+
+// HandleContext registers a handler for the command which receives a context
+// that carries the Invocation, Output and Printer of each invocation. It
+// replaces any handler registered with HandleFunc.
+func (c *CommandBuilder) HandleContext(handler ContextHandlerFunc) *CommandBuilder {
+	if handler == nil {
+		return c.error(errorf("%s: nil handler", c.cmd.Name))
+	}
+	c.cmd.HandlerFunc = nil
+	c.cmd.ContextHandler = handler
+	c.cmd.ErrorHandler = nil
+	return c
+}
+
+// HandleE registers a handler for the command which returns an error instead
+// of an exit code. If the handler returns an error, it is printed to stderr
+// and the exit code is 1, or the code returned by the error if it implements
+// ExitCoder. Middleware may read the error from Invocation.Err. It replaces any
+// handler registered with HandleFunc or HandleContext.
+func (c *CommandBuilder) HandleE(handler ErrorHandlerFunc) *CommandBuilder {
+	if handler == nil {
+		return c.error(errorf("%s: nil handler", c.cmd.Name))
+	}
+	c.cmd.HandlerFunc = nil
+	c.cmd.ContextHandler = nil
+	c.cmd.ErrorHandler = handler
 	return c
 }
 
@@ -261,6 +661,23 @@ func (c *CommandBuilder) Hidden() *CommandBuilder {
 	return c
 }
 
+// OnUnknownCommand specifies a function that handles the invocation of an
+// unknown subcommand of this command or its subcommands instead of the
+// "unrecognized command" error, so that programs may implement their own
+// dispatch, such as treating unknown names as host names as ssh does, or
+// running external plugins.
+//
+// When Run or Exec parses an unknown subcommand name, parsing stops and fn is
+// called with the name and the arguments that follow it, unparsed, and its
+// return value is the exit code. Flags that precede the name are parsed as
+// usual. The nearest OnUnknownCommand function of the command or its parents
+// is used. If the command has no subcommands, its own function handles any
+// positional argument that is not accepted by a positional flag.
+func (c *CommandBuilder) OnUnknownCommand(fn func(name string, args []string) int) *CommandBuilder {
+	c.cmd.onUnknownCommand = fn
+	return c
+}
+
 // Flag adds command line flags to the default FlagGroup for this command.
 func (c *CommandBuilder) Flags(flags ...Flagger) *CommandBuilder {
 	c.flagGroups[0].append(flags...)
@@ -283,7 +700,12 @@ func (c *CommandBuilder) FlagGroup(
 // To import any globally defined flags, import flag.CommandLine.
 func (c *CommandBuilder) FlagSet(flagSet *flag.FlagSet) *CommandBuilder {
 	flagSet.VisitAll(func(f *flag.Flag) {
-		flag, err := Var(f.Value, f.Name, f.Usage).Flag()
+		builder := Var(f.Value, f.Name, f.Usage)
+		if len(f.Name) == 1 {
+			// single character names are specified with one dash, as in "-v"
+			builder = Var(f.Value, "", f.Usage).ShortName(f.Name)
+		}
+		flag, err := builder.Flag()
 		if err != nil {
 			c.err = err
 			return
@@ -306,6 +728,15 @@ func (c *CommandBuilder) FormatFunc(fn FormatFunc) *CommandBuilder {
 	return c
 }
 
+// NoUsageHint disables the one-line hint that refers to the help of the
+// command, such as "See 'app deploy --help'.", which is printed after argument
+// errors by this command and its subcommands, and instead prints the full help
+// message of a command that was invoked without a required subcommand.
+func (c *CommandBuilder) NoUsageHint() *CommandBuilder {
+	c.cmd.NoUsageHint = true
+	return c
+}
+
 // WithTerminator specifies that any command line argument after "--" will be
 // passed through to the args parameter of the command's handler without any
 // further processing.
@@ -314,6 +745,156 @@ func (c *CommandBuilder) WithTerminator() *CommandBuilder {
 	return c
 }
 
+// BoolSyntax specifies the forms in which boolean flags of this command and
+// its subcommands may be specified. See BoolSyntax for the permitted forms.
+func (c *CommandBuilder) BoolSyntax(syntax BoolSyntax) *CommandBuilder {
+	if syntax < BoolDefault || syntax > BoolSeparate {
+		return c.error(errorf("%s: invalid bool syntax: %d", c.cmd.Name, syntax))
+	}
+	c.cmd.BoolSyntax = syntax
+	return c
+}
+
+// PrefixShortNames allows values to be attached directly to short names that
+// are longer than one character, in the style of Java options such as
+// "-Xmx512m" for a flag with the short name "Xmx". Otherwise, multi-character
+// short names must be followed by "=" or a separate value argument.
+//
+// The longest matching short name declared by the command or any of its
+// subcommands is used. PrefixShortNames only affects the root command.
+func (c *CommandBuilder) PrefixShortNames() *CommandBuilder {
+	c.cmd.PrefixShortNames = true
+	return c
+}
+
+// POSIXStrict disables the lenient behaviors of the parser so that scripts
+// parse identically regardless of the version of the program and the
+// configuration of the user:
+//
+//   - Options must precede operands, as in POSIX getopt: after the first
+//     positional argument, all remaining arguments are positional, even if
+//     they begin with a dash.
+//   - Boolean flags only accept "--flag" and "--flag=value", even if
+//     BoolSeparate is specified.
+//   - Values are not attached to multi-character short names without "=",
+//     even if PrefixShortNames is specified.
+//   - Flags of a subcommand must follow its name, rather than being set
+//     when the parser reaches the subcommand.
+//   - Aliases are not expanded, since they are defined by users.
+//   - Decimal commas are not accepted, since they depend on the locale,
+//     even if FlagBuilder.DecimalComma is specified.
+//
+// POSIXStrict only affects the root command. See the package documentation
+// for the guarantees of the default mode.
+func (c *CommandBuilder) POSIXStrict() *CommandBuilder {
+	c.cmd.POSIXStrict = true
+	return c
+}
+
+// Expression specifies that the regular flags of this command are predicates
+// of an expression that may be combined with operators and parentheses, in the
+// style of find(1). The parsed expression is available from Command.Expr. See
+// Expr for the expression syntax.
+//
+// Predicate flags that may be specified more than once should be declared
+// with NArgs(0, 0). Positional arguments may be interleaved with the
+// expression and are not part of it.
+func (c *CommandBuilder) Expression() *CommandBuilder {
+	c.cmd.Expression = true
+	return c
+}
+
+// Sources adds sources which may provide values for any flag of this command
+// or its subcommands that is not specified on the command line or by an
+// environment variable. See Source for the order of precedence.
+func (c *CommandBuilder) Sources(sources ...Source) *CommandBuilder {
+	for _, src := range sources {
+		if src == nil {
+			return c.error(errorf("%s: nil source", c.cmd.Name))
+		}
+	}
+	c.cmd.Sources = append(c.cmd.Sources, sources...)
+	return c
+}
+
+// WithSections enables the "--" terminator and splits the arguments that follow
+// it into at most n sections, separated by further "--" arguments. Any "--"
+// arguments in the final section are not treated as separators. This allows
+// commands to accept several independent argument lists, such as:
+//
+//	myapp run --cpu 2 -- ./server --port 80 -- --extra
+//
+// The handler's args parameter receives all arguments after the first
+// terminator while the individual sections are available from
+// Invocation.Sections, or from Command.Sections after Parse.
+func (c *CommandBuilder) WithSections(n int) *CommandBuilder {
+	if n < 1 {
+		return c.error(errorf("%s: invalid number of sections: %d", c.cmd.Name, n))
+	}
+	c.cmd.WithTerminator = true
+	c.cmd.MaxSections = n
+	return c
+}
+
+// FeatureFlag ties the command to the named feature. The command is hidden
+// and cannot be invoked until the feature is enabled by the FeatureGate of its
+// parents.
+func (c *CommandBuilder) FeatureFlag(feature string) *CommandBuilder {
+	c.cmd.Feature = feature
+	return c
+}
+
+// FeatureGate specifies the FeatureGate that enables the features of this
+// command and its subcommands and their flags. Commands without a FeatureGate
+// inherit the gate of their parent. If no gate is specified, all features are
+// disabled.
+func (c *CommandBuilder) FeatureGate(gate FeatureGate) *CommandBuilder {
+	c.cmd.FeatureGate = gate
+	return c
+}
+
+// Annotate attaches metadata to the command which is not used by xflags but
+// may be read from Command.Annotations by other systems such as completion
+// scripts, documentation generators or policy engines.
+func (c *CommandBuilder) Annotate(key, value string) *CommandBuilder {
+	if c.cmd.Annotations == nil {
+		c.cmd.Annotations = make(map[string]string)
+	}
+	c.cmd.Annotations[key] = value
+	return c
+}
+
+// ExitCodes documents the exit codes of the command and its subcommands and
+// their meaning, such as {2: "usage error", 3: "partial failure"}, so that
+// scripts may handle them. Exit codes are listed in help messages and written
+// by WriteSpec. Codes documented by a subcommand replace the same codes
+// documented by its parents. ExitCodes may be called more than once.
+func (c *CommandBuilder) ExitCodes(codes map[int]string) *CommandBuilder {
+	if c.cmd.ExitCodes == nil {
+		c.cmd.ExitCodes = make(map[int]string, len(codes))
+	}
+	for code, usage := range codes {
+		c.cmd.ExitCodes[code] = usage
+	}
+	return c
+}
+
+// exitCodes returns the documented exit codes of c and its parents, sorted by
+// code.
+func (c *Command) exitCodes() (codes []int, usage map[int]string) {
+	usage = make(map[int]string)
+	for p := c; p != nil; p = p.Parent {
+		for code, s := range p.ExitCodes {
+			if _, ok := usage[code]; !ok {
+				usage[code] = s
+				codes = append(codes, code)
+			}
+		}
+	}
+	sort.Ints(codes)
+	return codes, usage
+}
+
 // Output sets the destination for usage and error messages.
 func (c *CommandBuilder) Output(stdout, stderr io.Writer) *CommandBuilder {
 	c.cmd.Stdout, c.cmd.Stderr = stdout, stderr
@@ -322,6 +903,19 @@ func (c *CommandBuilder) Output(stdout, stderr io.Writer) *CommandBuilder {
 
 // Command implements the Commander interface and produces a new Command.
 func (c *CommandBuilder) Command() (*Command, error) {
+	cmd, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConditions(cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// build returns the command without checking the conditions of RequiredIf,
+// which may refer to flags of parents that are not yet built.
+func (c *CommandBuilder) build() (*Command, error) {
 	if c.err != nil {
 		return nil, c.err
 	}
@@ -334,16 +928,66 @@ func (c *CommandBuilder) Command() (*Command, error) {
 		cmd.FlagGroups = append(cmd.FlagGroups, group)
 	}
 	for _, commandBuilder := range c.subcommands {
-		sub, err := commandBuilder.Command()
+		var sub *Command
+		var err error
+		if b, ok := commandBuilder.(*CommandBuilder); ok {
+			sub, err = b.build()
+		} else {
+			sub, err = commandBuilder.Command()
+		}
 		if err != nil {
 			return nil, err
 		}
 		cmd.Subcommands = append(cmd.Subcommands, sub)
 		sub.Parent = &cmd
 	}
+	if c.compact {
+		return compact(&cmd).Command()
+	}
 	return cmd.Command()
 }
 
+// checkConditions returns an error if a condition of RequiredIf of a flag of
+// cmd or its subcommands refers to a flag that is not declared by the command
+// of the flag or its parents.
+func checkConditions(cmd *Command) error {
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			for _, cond := range flag.requiredIf {
+				if !declaresFlag(cmd, cond.name) {
+					return errorf(
+						"%s: %s: RequiredIf refers to an undeclared flag: %s",
+						cmd.Name,
+						flag,
+						cond.name,
+					)
+				}
+			}
+		}
+	}
+	for _, sub := range cmd.Subcommands {
+		if err := checkConditions(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declaresFlag reports whether cmd or its parents declare a flag with the
+// given name or short name.
+func declaresFlag(cmd *Command, name string) bool {
+	for p := cmd; p != nil; p = p.Parent {
+		for _, group := range p.FlagGroups {
+			for _, flag := range group.Flags {
+				if flag.Name == name || flag.ShortName == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // Must is a helper that calls Command and panics if the error is non-nil.
 func (c *CommandBuilder) Must() *Command {
 	cmd, err := c.Command()
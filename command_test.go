@@ -1,6 +1,7 @@
 package xflags
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os/exec"
@@ -191,7 +192,7 @@ func ExampleCommandBuilder_FlagGroup() {
 
 	cmd := NewCommand("helloworld", "").
 		// n flag defines how many times to print "Hello, World!".
-		Flags(Int(&n, "n", 1, "Print n times")).
+		Flags(Int(&n, "", 1, "Print n times").ShortName("n")).
 
 		// Create a flag group for language-related flags.
 		FlagGroup(
@@ -265,7 +266,7 @@ func ExampleCommandBuilder_Subcommands() {
 
 	// configure the main command with two subcommands and a global "n" flag.
 	cmd := NewCommand("widgets", "").
-		Flags(Int(&n, "n", 1, "Affect n widgets")).
+		Flags(Int(&n, "", 1, "Affect n widgets").ShortName("n")).
 		Subcommands(create, destroy)
 
 	// Print the help page
@@ -300,7 +301,7 @@ func ExampleCommandBuilder_Synopsis() {
 			"This utility prints \"Hello, World!\" to the standard output.\n" +
 				"Print more than once with -n.",
 		).
-		Flags(Int(&n, "n", 1, "Print n times"))
+		Flags(Int(&n, "", 1, "Print n times").ShortName("n"))
 
 	// Print the help page
 	RunWithArgs(cmd, "--help")
@@ -322,7 +323,7 @@ func ExampleCommandBuilder_WithTerminator() {
 	// create a command that passes arguments to /bin/echo
 	cmd := NewCommand("echo_wrapper", "calls /bin/echo").
 		Flags(
-			Bool(&verbose, "v", false, "Print verbose output"),
+			Bool(&verbose, "", false, "Print verbose output").ShortName("v"),
 		).
 		WithTerminator(). // enable the "--" terminator
 		HandleFunc(func(args []string) (exitCode int) {
@@ -347,3 +348,138 @@ func ExampleCommandBuilder_WithTerminator() {
 	// + /bin/echo Hello, World!
 	// Hello, World!
 }
+
+func TestAnnotations(t *testing.T) {
+	var force bool
+	cmd := NewCommand("delete", "").
+		Annotate("requires-auth", "true").
+		Flags(
+			Bool(&force, "force", false, "").
+				Annotate("cost", "expensive").
+				Annotate("policy", "admin"),
+		).
+		Must()
+	assertString(t, "true", cmd.Annotations["requires-auth"])
+	flag := cmd.FlagGroups[0].Flags[0]
+	assertString(t, "expensive", flag.Annotations["cost"])
+	assertString(t, "admin", flag.Annotations["policy"])
+}
+
+func TestUsageFunc(t *testing.T) {
+	calls := 0
+	usage := func(s string) func() string {
+		return func() string {
+			calls++
+			return s
+		}
+	}
+	cmd := NewCommand("app", "").
+		UsageFunc(usage("Lazy usage")).
+		SynopsisFunc(usage("Lazy synopsis")).
+		Flags(
+			Bool(nil, "verbose", false, "").UsageFunc(usage("Lazy flag usage")),
+		).
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+	cmd.Stdout, cmd.Stderr = new(bytes.Buffer), new(bytes.Buffer)
+	assertInt64(t, 0, int64(cmd.Run([]string{"--verbose"})))
+	assertInt64(t, 0, int64(calls))
+
+	w := new(bytes.Buffer)
+	if err := cmd.WriteUsage(w); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Lazy usage", "Lazy synopsis", "Lazy flag usage"} {
+		if !strings.Contains(w.String(), s) {
+			t.Errorf("expected help to contain %q, got:\n%s", s, w)
+		}
+	}
+	assertInt64(t, 3, int64(calls))
+}
+
+func TestUsageHint(t *testing.T) {
+	newCmd := func(stderr *bytes.Buffer) *CommandBuilder {
+		return NewCommand("app", "").
+			Output(new(bytes.Buffer), stderr).
+			Subcommands(
+				NewCommand("deploy", "").
+					Subcommands(
+						NewCommand("start", "").
+							HandleFunc(func(args []string) int { return 0 }),
+					),
+			)
+	}
+	stderr := new(bytes.Buffer)
+	cmd := newCmd(stderr).Must()
+	assertInt64(t, 1, int64(cmd.Run([]string{"deploy", "--nope"})))
+	assertString(t, "Argument error: unrecognized argument: --nope\nSee 'app deploy --help'.\n", stderr.String())
+
+	stderr.Reset()
+	assertInt64(t, 1, int64(cmd.Run([]string{"deploy"})))
+	assertString(t, "Error: missing command\nSee 'app deploy --help'.\n", stderr.String())
+
+	stderr.Reset()
+	cmd = newCmd(stderr).NoUsageHint().Must()
+	assertInt64(t, 1, int64(cmd.Run([]string{"deploy", "--nope"})))
+	assertString(t, "Argument error: unrecognized argument: --nope\n", stderr.String())
+
+	stderr.Reset()
+	assertInt64(t, 1, int64(cmd.Run([]string{"deploy"})))
+	if !strings.HasPrefix(stderr.String(), "Usage: app deploy COMMAND\n") {
+		t.Errorf("expected full usage, got:\n%s", stderr)
+	}
+}
+
+func TestOnUnknownCommand(t *testing.T) {
+	var gotName string
+	var gotArgs []string
+	var verbose bool
+	handler := func(name string, args []string) int {
+		gotName, gotArgs = name, args
+		return 3
+	}
+	newCommand := func() *Command {
+		return NewCommand("app", "").
+			Flags(Bool(&verbose, "verbose", false, "")).
+			Subcommands(
+				NewCommand("config", "").
+					Subcommands(NewCommand("get", "").HandleFunc(func(args []string) int { return 0 })),
+			).
+			OnUnknownCommand(handler).
+			Must()
+	}
+
+	assertInt64(t, 3, int64(newCommand().Run([]string{"--verbose", "example.com", "-p", "22", "uptime"})))
+	assertBool(t, true, verbose)
+	assertString(t, "example.com", gotName)
+	assertStrings(t, []string{"-p", "22", "uptime"}, gotArgs)
+
+	// handlers are inherited by subcommands
+	assertInt64(t, 3, int64(newCommand().Run([]string{"config", "set", "x"})))
+	assertString(t, "set", gotName)
+	assertStrings(t, []string{"x"}, gotArgs)
+
+	// known commands are unaffected
+	gotName = ""
+	assertInt64(t, 0, int64(newCommand().Run([]string{"config", "get"})))
+	assertString(t, "", gotName)
+
+	// commands without subcommands
+	cmd := NewCommand("ssh", "").OnUnknownCommand(handler).Must()
+	assertInt64(t, 3, int64(cmd.Run([]string{"host", "ls"})))
+	assertString(t, "host", gotName)
+	assertStrings(t, []string{"ls"}, gotArgs)
+}
+
+func TestRunHelpWithOutput(t *testing.T) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := NewCommand("app", "An example").
+		Output(stdout, stderr).
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+	assertInt64(t, 0, int64(cmd.Run([]string{"--help"})))
+	if !strings.Contains(stdout.String(), "An example") {
+		t.Errorf("expected usage, got: %q", stdout.String())
+	}
+	assertString(t, "", stderr.String())
+}
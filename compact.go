@@ -0,0 +1,116 @@
+package xflags
+
+import "sync/atomic"
+
+// Compact specifies that the command tree built by this CommandBuilder is
+// stored compactly. All commands, flag groups and flags of the tree are
+// copied into a few contiguous slices and duplicate strings, such as usage
+// strings that are repeated by many commands, are stored once.
+//
+// This reduces the resident memory and garbage collection cost of very large
+// command trees, such as those generated from an API with tens of thousands of
+// flags, at the cost of copying the tree once when it is built. Flag values are
+// not copied.
+func (c *CommandBuilder) Compact() *CommandBuilder {
+	c.compact = true
+	return c
+}
+
+// compactor copies a command tree into contiguous storage.
+type compactor struct {
+	strings   map[string]string
+	commands  []Command
+	subs      []*Command
+	groups    []FlagGroup
+	groupPtrs []*FlagGroup
+	flags     []Flag
+	flagPtrs  []*Flag
+}
+
+// compact returns a compact copy of the command tree of cmd.
+func compact(cmd *Command) *Command {
+	c := &compactor{strings: make(map[string]string)}
+	c.count(cmd)
+	return c.command(cmd, cmd.Parent)
+}
+
+// count allocates storage for all of the commands, groups and flags of cmd.
+func (c *compactor) count(cmd *Command) {
+	var commands, groups, flags int
+	var walk func(cmd *Command)
+	walk = func(cmd *Command) {
+		commands++
+		groups += len(cmd.FlagGroups)
+		for _, group := range cmd.FlagGroups {
+			flags += len(group.Flags)
+		}
+		for _, sub := range cmd.Subcommands {
+			walk(sub)
+		}
+	}
+	walk(cmd)
+	c.commands = make([]Command, 0, commands)
+	c.subs = make([]*Command, 0, commands)
+	c.groups = make([]FlagGroup, 0, groups)
+	c.groupPtrs = make([]*FlagGroup, 0, groups)
+	c.flags = make([]Flag, 0, flags)
+	c.flagPtrs = make([]*Flag, 0, flags)
+}
+
+func (c *compactor) command(cmd *Command, parent *Command) *Command {
+	c.commands = append(c.commands, *cmd)
+	dst := &c.commands[len(c.commands)-1]
+	dst.Parent = parent
+	dst.idx = atomic.Value{}
+	dst.Name = c.intern(dst.Name)
+	dst.Usage = c.intern(dst.Usage)
+	dst.Synopsis = c.intern(dst.Synopsis)
+	dst.Feature = c.intern(dst.Feature)
+
+	groupStart := len(c.groupPtrs)
+	for _, group := range cmd.FlagGroups {
+		c.groups = append(c.groups, *group)
+		g := &c.groups[len(c.groups)-1]
+		g.Name = c.intern(g.Name)
+		g.Usage = c.intern(g.Usage)
+		flagStart := len(c.flagPtrs)
+		for _, flag := range group.Flags {
+			c.flags = append(c.flags, *flag)
+			f := &c.flags[len(c.flags)-1]
+			f.Name = c.intern(f.Name)
+			f.ShortName = c.intern(f.ShortName)
+			f.Usage = c.intern(f.Usage)
+			f.EnvVar = c.intern(f.EnvVar)
+			f.Feature = c.intern(f.Feature)
+			c.flagPtrs = append(c.flagPtrs, f)
+		}
+		g.Flags = c.flagPtrs[flagStart:len(c.flagPtrs):len(c.flagPtrs)]
+		c.groupPtrs = append(c.groupPtrs, g)
+	}
+	dst.FlagGroups = c.groupPtrs[groupStart:len(c.groupPtrs):len(c.groupPtrs)]
+	if dst.syntax != nil {
+		// refer to the copies of the flags
+		dst.syntax, _ = compileSyntax(dst, dst.usageSyntax)
+	}
+
+	// reserve contiguous storage for the subcommands before descending
+	subStart, n := len(c.subs), len(cmd.Subcommands)
+	c.subs = c.subs[:subStart+n]
+	for i, sub := range cmd.Subcommands {
+		c.subs[subStart+i] = c.command(sub, dst)
+	}
+	dst.Subcommands = c.subs[subStart : subStart+n : subStart+n]
+	return dst
+}
+
+// intern returns a shared copy of s.
+func (c *compactor) intern(s string) string {
+	if s == "" {
+		return s
+	}
+	if t, ok := c.strings[s]; ok {
+		return t
+	}
+	c.strings[s] = s
+	return s
+}
@@ -0,0 +1,71 @@
+package xflags
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+	"unsafe"
+)
+
+func newGeneratedCommand(compact bool, values map[string]*string) *CommandBuilder {
+	cmd := NewCommand("api", "Generated API client")
+	if compact {
+		cmd.Compact()
+	}
+	for i := 0; i < 20; i++ {
+		name := "op-" + strconv.Itoa(i)
+		op := NewCommand(name, "Call operation "+name)
+		for j := 0; j < 10; j++ {
+			flagName := "param-" + strconv.Itoa(j)
+			p := new(string)
+			values[name+" "+flagName] = p
+			op.Flags(String(p, flagName, "", "Parameter "+strconv.Itoa(j)))
+		}
+		op.HandleFunc(func(args []string) int { return 0 })
+		cmd.Subcommands(op)
+	}
+	return cmd
+}
+
+func TestCompact(t *testing.T) {
+	values := make(map[string]*string)
+	expect := newGeneratedCommand(false, values).Must()
+	actual := newGeneratedCommand(true, values).Must()
+
+	// flags are stored contiguously
+	first := actual.Subcommands[0].FlagGroups[0].Flags[0]
+	last := actual.Subcommands[19].FlagGroups[0].Flags[9]
+	size := unsafe.Sizeof(Flag{})
+	if uintptr(unsafe.Pointer(last))-uintptr(unsafe.Pointer(first)) != 199*size {
+		t.Errorf("expected flags to be stored contiguously")
+	}
+
+	// usage strings are interned
+	usage := func(flag *Flag) uintptr {
+		return (*reflect.StringHeader)(unsafe.Pointer(&flag.Usage)).Data
+	}
+	if usage(first) != usage(actual.Subcommands[19].FlagGroups[0].Flags[0]) {
+		t.Errorf("expected usage strings to be interned")
+	}
+
+	for _, cmd := range actual.Subcommands {
+		if cmd.Parent != actual {
+			t.Errorf("%s: unexpected parent: %p", cmd.Name, cmd.Parent)
+		}
+	}
+
+	target, err := actual.Parse([]string{"op-7", "--param-3", "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "op-7", target.Name)
+	assertString(t, "foo", *values["op-7 param-3"])
+
+	for _, args := range [][]string{nil, {"op-7"}} {
+		expectHelp, actualHelp := new(bytes.Buffer), new(bytes.Buffer)
+		assertHelp(t, expect, args, expectHelp)
+		assertHelp(t, actual, args, actualHelp)
+		assertString(t, expectHelp.String(), actualHelp.String())
+	}
+}
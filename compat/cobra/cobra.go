@@ -0,0 +1,281 @@
+// Package cobra exports xflags commands as cobra commands.
+//
+// Commands are authored with xflags builders and converted with Export so
+// they can be embedded in programs and ecosystems that expect a
+// *cobra.Command, such as kubectl plugins that rely on cobra's completion
+// hooks.
+//
+// Regular flags are exported as pflags, which are persistent if the command
+// has subcommands, as the flags of xflags commands may be specified after
+// their subcommands. Positional arguments are assigned from cobra's arguments
+// and all xflags rules for argument counts, validation and environment
+// variables are enforced before the handler is called.
+package cobra
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cavaliergopher/xflags"
+	spfcobra "github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ExitError is returned by the RunE function of an exported command if the
+// xflags handler returns a non-zero exit code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Export builds cmd and converts it and all of its subcommands into a
+// *cobra.Command. Export panics if cmd cannot be built.
+func Export(cmd xflags.Commander) *spfcobra.Command {
+	c, err := cmd.Command()
+	if err != nil {
+		panic(err)
+	}
+	e := newExporter()
+	root := e.export(c)
+	root.SetFlagErrorFunc(func(c *spfcobra.Command, err error) error {
+		e.reset()
+		return err
+	})
+	return root
+}
+
+type exporter struct {
+	counts map[*xflags.Flag]int
+}
+
+func newExporter() *exporter {
+	return &exporter{counts: make(map[*xflags.Flag]int)}
+}
+
+// reset clears the number of times that each flag was set, after each
+// execution of the exported command, so that it may be executed again.
+func (e *exporter) reset() {
+	e.counts = make(map[*xflags.Flag]int)
+}
+
+func (e *exporter) export(cmd *xflags.Command) *spfcobra.Command {
+	c := &spfcobra.Command{
+		Use:    use(cmd),
+		Short:  cmd.UsageText(),
+		Long:   cmd.SynopsisText(),
+		Hidden: cmd.Hidden,
+	}
+	if len(cmd.Annotations) > 0 {
+		c.Annotations = make(map[string]string, len(cmd.Annotations))
+		for k, v := range cmd.Annotations {
+			c.Annotations[k] = v
+		}
+	}
+	if cmd.Stdout != nil {
+		c.SetOut(cmd.Stdout)
+	}
+	if cmd.Stderr != nil {
+		c.SetErr(cmd.Stderr)
+	}
+	fs := c.Flags()
+	if len(cmd.Subcommands) > 0 {
+		fs = c.PersistentFlags()
+	}
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			if flag.Positional {
+				continue
+			}
+			e.addFlag(fs, flag)
+		}
+	}
+	if cmd.HasHandler() {
+		c.RunE = func(c *spfcobra.Command, args []string) error {
+			return e.run(cmd, c, args)
+		}
+	}
+	for _, sub := range cmd.Subcommands {
+		c.AddCommand(e.export(sub))
+	}
+	return c
+}
+
+func (e *exporter) addFlag(fs *pflag.FlagSet, flag *xflags.Flag) {
+	name := flag.Name
+	if name == "" {
+		name = flag.ShortName
+	}
+	shorthand := flag.ShortName
+	if len(shorthand) > 1 {
+		// pflag shorthands must be one character; the name is used instead
+		shorthand = ""
+	}
+	f := fs.VarPF(&value{flag: flag, exporter: e}, name, shorthand, flag.UsageText())
+	if isBool(flag.Value) {
+		f.NoOptDefVal = "true"
+	}
+	f.Hidden = flag.Hidden
+	for k, v := range flag.Annotations {
+		if f.Annotations == nil {
+			f.Annotations = make(map[string][]string)
+		}
+		f.Annotations[k] = []string{v}
+	}
+	if flag.MinCount > 0 {
+		// only affects cobra's completions; counts are checked in run
+		if f.Annotations == nil {
+			f.Annotations = make(map[string][]string)
+		}
+		f.Annotations[spfcobra.BashCompOneRequiredFlag] = []string{"true"}
+	}
+}
+
+// run applies positional arguments and environment variables to the flags of
+// cmd, checks all flag counts and calls the handler of cmd.
+func (e *exporter) run(cmd *xflags.Command, c *spfcobra.Command, args []string) error {
+	defer e.reset()
+	var tail []string
+	if cmd.WithTerminator {
+		if n := c.ArgsLenAtDash(); n >= 0 {
+			args, tail = args[:n], args[n:]
+		}
+	}
+	if err := e.setPositionals(cmd, args); err != nil {
+		return err
+	}
+	for p := cmd; p != nil; p = p.Parent {
+		if err := e.setEnvVars(p); err != nil {
+			return err
+		}
+	}
+	for p := cmd; p != nil; p = p.Parent {
+		if err := e.checkNArgs(p); err != nil {
+			return err
+		}
+	}
+	if code := cmd.Handle(c.Context(), tail); code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+func (e *exporter) setPositionals(cmd *xflags.Command, args []string) error {
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			if !flag.Positional {
+				continue
+			}
+			for len(args) > 0 && (flag.MaxCount == 0 || e.counts[flag] < flag.MaxCount) {
+				if err := e.set(flag, args[0]); err != nil {
+					return err
+				}
+				args = args[1:]
+			}
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected positional argument: %s", args[0])
+	}
+	return nil
+}
+
+func (e *exporter) setEnvVars(cmd *xflags.Command) error {
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			if flag.EnvVar == "" || e.counts[flag] > 0 {
+				continue
+			}
+			s, ok := os.LookupEnv(flag.EnvVar)
+			if !ok {
+				continue
+			}
+			if err := e.set(flag, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *exporter) checkNArgs(cmd *xflags.Command) error {
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			n := e.counts[flag]
+			if flag.MinCount > 0 && n < flag.MinCount {
+				return fmt.Errorf("missing argument: %s", flag)
+			}
+			if flag.MaxCount > 0 && n > flag.MaxCount {
+				return fmt.Errorf("argument declared too many times: %s", flag)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *exporter) set(flag *xflags.Flag, s string) error {
+	e.counts[flag]++
+	if err := flag.Set(s); err != nil {
+		return fmt.Errorf("%s: %v", flag, err)
+	}
+	return nil
+}
+
+// use returns the one-line usage message for cmd.
+func use(cmd *xflags.Command) string {
+	var sb strings.Builder
+	sb.WriteString(cmd.Name)
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			if !flag.Positional || flag.Hidden {
+				continue
+			}
+			name := strings.ToUpper(flag.Name)
+			if flag.MaxCount != 1 {
+				name += "..."
+			}
+			if flag.MinCount == 0 {
+				name = "[" + name + "]"
+			}
+			sb.WriteString(" " + name)
+		}
+	}
+	return sb.String()
+}
+
+// value adapts an xflags.Flag to the pflag.Value interface.
+type value struct {
+	flag     *xflags.Flag
+	exporter *exporter
+}
+
+func (v *value) String() string {
+	if s, ok := v.flag.Value.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return ""
+}
+
+func (v *value) Set(s string) error {
+	v.exporter.counts[v.flag]++
+	return v.flag.Set(s)
+}
+
+func (v *value) IsBoolFlag() bool { return isBool(v.flag.Value) }
+
+func (v *value) Type() string {
+	if isBool(v.flag.Value) {
+		return "bool"
+	}
+	return "string"
+}
+
+func isBool(v xflags.Value) bool {
+	if bv, ok := v.(xflags.BoolValue); ok {
+		return bv.IsBoolFlag()
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package cobra
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+func TestExport(t *testing.T) {
+	var verbose bool
+	var n int
+	var names []string
+	var tail []string
+	create := xflags.NewCommand("create", "Make new widgets").
+		Flags(
+			xflags.Int(&n, "", 1, "Number of widgets").ShortName("n").Required(),
+			xflags.Strings(&names, "name", nil, "Widget names").
+				Positional().
+				NArgs(1, 0),
+		).
+		WithTerminator().
+		HandleFunc(func(args []string) int {
+			tail = args
+			return 0
+		})
+	root := xflags.NewCommand("widgets", "").
+		Flags(xflags.Bool(&verbose, "verbose", false, "").ShortName("v")).
+		Subcommands(create)
+
+	c := Export(root)
+	c.SetArgs([]string{"create", "-v", "-n", "3", "foo", "bar", "--", "baz"})
+	if err := c.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !verbose {
+		t.Errorf("expected verbose to be set")
+	}
+	if n != 3 {
+		t.Errorf("expected n: 3, got: %d", n)
+	}
+	if len(names) != 2 || names[0] != "foo" || names[1] != "bar" {
+		t.Errorf("expected names: [foo bar], got: %q", names)
+	}
+	if len(tail) != 1 || tail[0] != "baz" {
+		t.Errorf("expected args: [baz], got: %q", tail)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	var n int
+	var name string
+	cmd := xflags.NewCommand("test", "").
+		Flags(
+			xflags.Int(&n, "", 0, "").ShortName("n").Required(),
+			xflags.String(&name, "name", "", "").Positional(),
+		).
+		HandleFunc(func(args []string) int { return 3 })
+	testCases := [][]string{
+		{},
+		{"-n", "1", "-n", "2"},
+		{"-n", "1", "foo", "bar"},
+		{"-n", "x"},
+	}
+	for _, args := range testCases {
+		c := Export(cmd)
+		c.SetArgs(args)
+		c.SetOut(new(bytes.Buffer))
+		c.SetErr(new(bytes.Buffer))
+		if err := c.Execute(); err == nil {
+			t.Errorf("expected error for args: %q", args)
+		}
+	}
+
+	c := Export(cmd)
+	c.SetArgs([]string{"-n", "1"})
+	var exitErr *ExitError
+	if err := c.Execute(); !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("expected exit status 3, got: %v", err)
+	}
+}
+
+func TestExportRepeatedExecution(t *testing.T) {
+	var n int
+	var verbose bool
+	create := xflags.NewCommand("create", "").
+		Flags(xflags.Int(&n, "", 0, "").ShortName("n").Required()).
+		HandleFunc(func(args []string) int { return 0 })
+	c := Export(xflags.NewCommand("widgets", "").
+		Flags(xflags.Bool(&verbose, "verbose", false, "")).
+		Subcommands(create))
+	c.SetOut(new(bytes.Buffer))
+	c.SetErr(new(bytes.Buffer))
+
+	// flags of commands with subcommands are persistent
+	sub, _, err := c.Find([]string{"create"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PersistentFlags().Lookup("verbose") == nil {
+		t.Errorf("expected --verbose to be persistent")
+	}
+	if sub.PersistentFlags().Lookup("n") != nil || sub.Flags().Lookup("n") == nil {
+		t.Errorf("expected -n to be a local flag")
+	}
+
+	// the counts of flags do not accumulate across executions
+	for _, args := range [][]string{
+		{"create", "-n", "1"},
+		{"create", "-n"}, // flag error
+		{"create", "-n", "2"},
+	} {
+		c.SetArgs(args)
+		c.Execute()
+	}
+	c.SetArgs([]string{"create", "-n", "3"})
+	if err := c.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected n: 3, got: %d", n)
+	}
+}
+
+func TestExportAnnotations(t *testing.T) {
+	var force bool
+	cmd := xflags.NewCommand("delete", "").
+		Annotate("requires-auth", "true").
+		Flags(xflags.Bool(&force, "force", false, "").Annotate("cost", "expensive"))
+	c := Export(cmd)
+	if v := c.Annotations["requires-auth"]; v != "true" {
+		t.Errorf("expected command annotation, got: %q", v)
+	}
+	f := c.Flags().Lookup("force")
+	if v := f.Annotations["cost"]; len(v) != 1 || v[0] != "expensive" {
+		t.Errorf("expected flag annotation, got: %q", v)
+	}
+}
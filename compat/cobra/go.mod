@@ -0,0 +1,11 @@
+module github.com/cavaliergopher/xflags/compat/cobra
+
+go 1.15
+
+require (
+	github.com/cavaliergopher/xflags v0.0.0
+	github.com/spf13/cobra v1.6.1
+	github.com/spf13/pflag v1.0.5
+)
+
+replace github.com/cavaliergopher/xflags => ../..
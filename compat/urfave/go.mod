@@ -0,0 +1,16 @@
+module github.com/cavaliergopher/xflags/compat/urfave
+
+go 1.18
+
+require (
+	github.com/cavaliergopher/xflags v0.0.0
+	github.com/urfave/cli/v2 v2.25.7
+)
+
+require (
+	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
+)
+
+replace github.com/cavaliergopher/xflags => ../..
@@ -0,0 +1,258 @@
+// Package urfave imports urfave/cli applications as xflags commands so that
+// large existing applications may migrate to xflags incrementally.
+//
+// Commands, subcommands, flags, flag categories, and the Before, Action and
+// After hooks of an App and its commands are mapped to their xflags
+// equivalents. Flag categories become flag groups. Command categories have no
+// xflags equivalent and are ignored.
+package urfave
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cavaliergopher/xflags"
+	"github.com/urfave/cli/v2"
+)
+
+// Import returns a Commander that builds an xflags command from app and each
+// of its commands.
+//
+// Hooks are called by the handler of the invoked command in the order: the
+// App's Before, each command's Before from the root, the Action of the invoked
+// command, and then each After hook in reverse order. If an Action returns an
+// error that implements cli.ExitCoder, its exit code is returned from the
+// handler.
+func Import(app *cli.App) xflags.Commander {
+	return &importer{app: app}
+}
+
+type importer struct {
+	app *cli.App
+}
+
+// level describes one command in the path of an invoked command.
+type level struct {
+	cmd    *cli.Command // nil for the App
+	set    *flag.FlagSet
+	args   *[]string
+	before cli.BeforeFunc
+	after  cli.AfterFunc
+}
+
+func (c *importer) Command() (*xflags.Command, error) {
+	root := &level{
+		set:    flag.NewFlagSet(c.app.Name, flag.ContinueOnError),
+		before: c.app.Before,
+		after:  c.app.After,
+	}
+	builder, err := c.command(
+		[]*level{root},
+		c.app.Name,
+		c.app.Usage,
+		c.app.Description,
+		false,
+		c.app.Flags,
+		c.app.Commands,
+		c.app.Action,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return builder.Command()
+}
+
+func (c *importer) command(
+	path []*level,
+	name, usage, description string,
+	hidden bool,
+	flags []cli.Flag,
+	commands []*cli.Command,
+	action cli.ActionFunc,
+) (*xflags.CommandBuilder, error) {
+	lvl := path[len(path)-1]
+	builder := xflags.NewCommand(name, usage).Synopsis(description)
+	if hidden {
+		builder.Hidden()
+	}
+	groups := make(map[string][]xflags.Flagger)
+	categories := make([]string, 0)
+	for _, f := range flags {
+		if err := f.Apply(lvl.set); err != nil {
+			return nil, fmt.Errorf("urfave: %s: %v", name, err)
+		}
+		flagger, err := importFlag(lvl.set, f)
+		if err != nil {
+			return nil, fmt.Errorf("urfave: %s: %v", name, err)
+		}
+		category := ""
+		if cf, ok := f.(cli.CategorizableFlag); ok {
+			category = cf.GetCategory()
+		}
+		if _, ok := groups[category]; !ok && category != "" {
+			categories = append(categories, category)
+		}
+		groups[category] = append(groups[category], flagger)
+	}
+	builder.Flags(groups[""]...)
+	for _, category := range categories {
+		builder.FlagGroup(category, category, groups[category]...)
+	}
+	for _, cmd := range commands {
+		sub := &level{
+			cmd:    cmd,
+			set:    flag.NewFlagSet(cmd.Name, flag.ContinueOnError),
+			before: cmd.Before,
+			after:  cmd.After,
+		}
+		subBuilder, err := c.command(
+			append(path[:len(path):len(path)], sub),
+			cmd.Name,
+			cmd.Usage,
+			cmd.Description,
+			cmd.Hidden,
+			cmd.Flags,
+			cmd.Subcommands,
+			cmd.Action,
+		)
+		if err != nil {
+			return nil, err
+		}
+		builder.Subcommands(subBuilder)
+	}
+	if len(commands) == 0 {
+		// urfave commands accept any number of arguments which are exposed
+		// by cli.Context.Args. The flag has no name, so that it cannot
+		// collide with the flags of the command.
+		lvl.args = new([]string)
+		builder.Flags(
+			xflags.Strings(lvl.args, "", nil, "").Positional().Hidden(),
+		)
+	}
+	if action != nil {
+		builder.HandleFunc(func(args []string) int {
+			return c.run(path, action)
+		})
+	}
+	return builder, nil
+}
+
+// run builds a cli.Context for each command in path and calls all hooks and
+// the action of the invoked command.
+func (c *importer) run(path []*level, action cli.ActionFunc) (exitCode int) {
+	var ctx *cli.Context
+	for _, lvl := range path {
+		args := []string{"--"}
+		if lvl.args != nil {
+			args = append(args, *lvl.args...)
+		}
+		if err := lvl.set.Parse(args); err != nil {
+			return c.handleErr(err)
+		}
+		if ctx == nil {
+			ctx = cli.NewContext(c.app, lvl.set, nil)
+			ctx.Context = context.Background()
+		} else {
+			ctx = cli.NewContext(c.app, lvl.set, ctx)
+		}
+		ctx.Command = lvl.cmd
+		if lvl.before != nil {
+			if err := lvl.before(ctx); err != nil {
+				return c.handleErr(err)
+			}
+		}
+		if lvl.after != nil {
+			after, hookCtx := lvl.after, ctx
+			defer func() {
+				if err := after(hookCtx); err != nil && exitCode == 0 {
+					exitCode = c.handleErr(err)
+				}
+			}()
+		}
+	}
+	return c.handleErr(action(ctx))
+}
+
+func (c *importer) handleErr(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitCoder cli.ExitCoder
+	if errors.As(err, &exitCoder) {
+		if s := exitCoder.Error(); s != "" {
+			fmt.Fprintln(c.stderr(), s)
+		}
+		return exitCoder.ExitCode()
+	}
+	fmt.Fprintf(c.stderr(), "Error: %v\n", err)
+	return 1
+}
+
+func (c *importer) stderr() io.Writer {
+	if c.app.ErrWriter != nil {
+		return c.app.ErrWriter
+	}
+	return os.Stderr
+}
+
+// importFlag returns a Flagger for a urfave flag which has already been applied
+// to set.
+func importFlag(set *flag.FlagSet, f cli.Flag) (xflags.Flagger, error) {
+	var name, shortName string
+	for _, s := range f.Names() {
+		if len(s) == 1 && shortName == "" {
+			shortName = s
+		} else if len(s) > 1 && name == "" {
+			name = s
+		}
+	}
+	if name == "" {
+		name = shortName
+	}
+	stdFlag := set.Lookup(name)
+	if stdFlag == nil {
+		return nil, fmt.Errorf("flag was not applied: %s", name)
+	}
+	usage := ""
+	if df, ok := f.(cli.DocGenerationFlag); ok {
+		usage = df.GetUsage()
+	}
+	builder := xflags.Var(&flagSetValue{set: set, flag: stdFlag}, name, usage)
+	if shortName != "" && shortName != name {
+		builder.ShortName(shortName)
+	}
+	min, max := 0, 1
+	if sf, ok := f.(cli.DocGenerationSliceFlag); ok && sf.IsSliceFlag() {
+		max = 0
+	}
+	if rf, ok := f.(cli.RequiredFlag); ok && rf.IsRequired() {
+		min = 1
+	}
+	builder.NArgs(min, max)
+	if vf, ok := f.(cli.VisibleFlag); ok && !vf.IsVisible() {
+		builder.Hidden()
+	}
+	return builder, nil
+}
+
+// flagSetValue sets values via the FlagSet so that urfave's cli.Context.IsSet
+// reports flags that were specified.
+type flagSetValue struct {
+	set  *flag.FlagSet
+	flag *flag.Flag
+}
+
+func (v *flagSetValue) String() string { return v.flag.Value.String() }
+
+func (v *flagSetValue) Set(s string) error { return v.set.Set(v.flag.Name, s) }
+
+func (v *flagSetValue) IsBoolFlag() bool {
+	if bv, ok := v.flag.Value.(xflags.BoolValue); ok {
+		return bv.IsBoolFlag()
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package urfave
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+	"github.com/urfave/cli/v2"
+)
+
+func TestImport(t *testing.T) {
+	var calls []string
+	var verbose bool
+	var replicas int
+	var names []string
+	var args []string
+	app := &cli.App{
+		Name: "widgets",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "verbose", Aliases: []string{"v"}},
+		},
+		Before: func(ctx *cli.Context) error {
+			calls = append(calls, "app.Before")
+			return nil
+		},
+		After: func(ctx *cli.Context) error {
+			calls = append(calls, "app.After")
+			return nil
+		},
+		Commands: []*cli.Command{
+			{
+				Name: "deploy",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "replicas", Required: true},
+					&cli.StringSliceFlag{Name: "name", Category: "Naming"},
+				},
+				Before: func(ctx *cli.Context) error {
+					calls = append(calls, "deploy.Before")
+					return nil
+				},
+				After: func(ctx *cli.Context) error {
+					calls = append(calls, "deploy.After")
+					return nil
+				},
+				Action: func(ctx *cli.Context) error {
+					calls = append(calls, "deploy.Action")
+					verbose = ctx.Bool("verbose")
+					replicas = ctx.Int("replicas")
+					names = ctx.StringSlice("name")
+					args = ctx.Args().Slice()
+					if !ctx.IsSet("replicas") {
+						t.Errorf("expected replicas to be set")
+					}
+					return nil
+				},
+			},
+		},
+	}
+	cmd, err := Import(app).Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exitCode := cmd.Run([]string{
+		"deploy", "-v", "--replicas=3", "--name", "foo", "--name", "bar", "baz",
+	})
+	if exitCode != 0 {
+		t.Fatalf("expected exit code 0, got: %d", exitCode)
+	}
+	expectCalls := "app.Before deploy.Before deploy.Action deploy.After app.After"
+	if s := strings.Join(calls, " "); s != expectCalls {
+		t.Errorf("expected calls: %s, got: %s", expectCalls, s)
+	}
+	if !verbose {
+		t.Errorf("expected verbose to be set")
+	}
+	if replicas != 3 {
+		t.Errorf("expected replicas: 3, got: %d", replicas)
+	}
+	if strings.Join(names, ",") != "foo,bar" {
+		t.Errorf("expected names: [foo bar], got: %q", names)
+	}
+	if strings.Join(args, ",") != "baz" {
+		t.Errorf("expected args: [baz], got: %q", args)
+	}
+	if cmd.Subcommands[0].FlagGroups[1].Name != "Naming" {
+		t.Errorf("expected flag category to be imported as a flag group")
+	}
+}
+
+func TestImportExitCode(t *testing.T) {
+	stderr := new(bytes.Buffer)
+	app := &cli.App{
+		Name:      "test",
+		ErrWriter: stderr,
+		Flags:     []cli.Flag{&cli.StringFlag{Name: "name", Required: true}},
+		Action: func(ctx *cli.Context) error {
+			return cli.Exit("failed", 3)
+		},
+	}
+	cmd := xflags.NewCommand("root", "").
+		Output(stderr, stderr).
+		Subcommands(Import(app)).
+		Must()
+	if exitCode := cmd.Run([]string{"test"}); exitCode != 1 {
+		t.Errorf("expected exit code 1 for missing flag, got: %d", exitCode)
+	}
+	if exitCode := cmd.Run([]string{"test", "--name=foo"}); exitCode != 3 {
+		t.Errorf("expected exit code 3, got: %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "failed") {
+		t.Errorf("expected error message in stderr, got: %q", stderr.String())
+	}
+}
+
+func TestImportArgsFlag(t *testing.T) {
+	var flagArgs string
+	var args []string
+	app := &cli.App{
+		Name:  "test",
+		Flags: []cli.Flag{&cli.StringFlag{Name: "args"}},
+		Action: func(ctx *cli.Context) error {
+			flagArgs = ctx.String("args")
+			args = ctx.Args().Slice()
+			return nil
+		},
+	}
+	cmd, err := Import(app).Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitCode := cmd.Run([]string{"--args=x", "a", "b"}); exitCode != 0 {
+		t.Fatalf("expected exit code 0, got: %d", exitCode)
+	}
+	if flagArgs != "x" {
+		t.Errorf("expected --args: x, got: %q", flagArgs)
+	}
+	if strings.Join(args, ",") != "a,b" {
+		t.Errorf("expected args: [a b], got: %q", args)
+	}
+}
@@ -0,0 +1,144 @@
+package xflags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// completeCommand is the hidden first argument with which Command.Run prints
+// completion candidates instead of running a command.
+const completeCommand = "__complete"
+
+// A CompletionFunc returns the candidates to complete the partial argument
+// prefix of a flag. cmd is the invoked command with the flags that precede
+// the argument on the command line already set, so that candidates may
+// depend on them, such as the contexts of a configuration file given by an
+// earlier --config flag.
+type CompletionFunc func(cmd *Command, prefix string) []string
+
+// Completion specifies a function that returns the candidates to complete the
+// arguments of this flag, typically from live data such as the names of
+// remote resources. It is most useful for positional arguments:
+//
+//	xflags.String(&name, "context", "", "Context to switch to").
+//		Positional().
+//		Required().
+//		Completion(func(cmd *xflags.Command, prefix string) []string {
+//			return contextNames()
+//		})
+//
+// Without a CompletionFunc, arguments are completed from the choices given
+// to Choices.
+func (c *FlagBuilder) Completion(fn CompletionFunc) *FlagBuilder {
+	c.flag.complete = fn
+	return c
+}
+
+// Complete returns the candidates to complete the last element of args, which
+// are the arguments of the command line up to the cursor, without the program
+// name. The last element is the partial argument being completed and may be
+// empty. Candidates are sorted and each begins with the partial argument.
+//
+// Flags that begin with a dash are completed with the names of the visible
+// flags of the command, flag values with the CompletionFunc or choices of the
+// flag, and other arguments with those of the next positional flag or with
+// the names of the subcommands.
+//
+// Command.Run prints the candidates one per line if its first argument is
+// "__complete", as in "myapp __complete switch pro", so that shell completion
+// scripts may call the program to complete its command lines.
+func (c *Command) Complete(args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	prev, word := args[:len(args)-1], args[len(args)-1]
+
+	// replay the preceding arguments, ignoring errors
+	p := newArgParser(c, prev)
+	var pending *Flag // flag that expects word as its value
+	for {
+		token, ok := p.next()
+		if !ok {
+			break
+		}
+		if len(p.tokens) == 0 && !p.isTerminated && !isPositional(token) {
+			flag := p.lookupFlag(token)
+			if flag != nil && !isBoolValue(flag.Value) {
+				pending = flag
+				break
+			}
+		}
+		_ = p.dispatch(token)
+	}
+	cmd := p.cmd
+
+	var candidates []string
+	switch {
+	case pending != nil:
+		candidates = completeFlag(cmd, pending, word)
+	case !p.isTerminated && strings.HasPrefix(word, "-"):
+		if name, value, ok := strings.Cut(word, "="); ok {
+			if flag := p.lookupFlag(name); flag != nil {
+				for _, s := range completeFlag(cmd, flag, value) {
+					candidates = append(candidates, name+"="+s)
+				}
+			}
+			break
+		}
+		for owner := cmd; owner != nil; owner = owner.Parent {
+			for name, flag := range owner.index().flagsByName {
+				if !flag.Positional && !isHidden(cmd, flag) && p.lookupFlag(name) == flag {
+					candidates = append(candidates, name)
+				}
+			}
+		}
+	case len(p.positionals) > 0:
+		candidates = completeFlag(cmd, p.positionals[0], word)
+	default:
+		for _, sub := range cmd.Subcommands {
+			if !isHiddenCommand(sub) {
+				candidates = append(candidates, sub.Name)
+			}
+		}
+	}
+	return filterCandidates(candidates, word)
+}
+
+// completeFlag returns the candidates for an argument of flag.
+func completeFlag(cmd *Command, flag *Flag, prefix string) []string {
+	if flag.complete != nil {
+		return flag.complete(cmd, prefix)
+	}
+	return flag.choices
+}
+
+// filterCandidates returns the sorted, unique candidates that begin with
+// prefix.
+func filterCandidates(candidates []string, prefix string) []string {
+	a := make([]string, 0, len(candidates))
+	for _, s := range candidates {
+		if strings.HasPrefix(s, prefix) {
+			a = append(a, s)
+		}
+	}
+	sort.Strings(a)
+	n := 0
+	for i, s := range a {
+		if i == 0 || s != a[n-1] {
+			a[n] = s
+			n++
+		}
+	}
+	return a[:n]
+}
+
+// printCompletions prints the candidates to complete args to the standard
+// output of c.
+func (c *Command) printCompletions(args []string) int {
+	stdout, _ := c.output()
+	for _, s := range c.Complete(args) {
+		fmt.Fprintln(stdout, s)
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package xflags
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newCompleteTestCommand() *Command {
+	var config, name, format string
+	var force bool
+	contexts := map[string][]string{
+		"":      {"dev", "prod", "production"},
+		"other": {"staging"},
+	}
+	return NewCommand("app", "").
+		Flags(
+			String(&config, "config", "", "").ShortName("c"),
+			Bool(&force, "force", false, ""),
+			String(nil, "secret-option", "", "").Hidden(),
+		).
+		Subcommands(
+			NewCommand("switch", "").
+				Flags(
+					String(&format, "format", "text", "").Choices("text", "json"),
+					String(&name, "context", "", "").
+						Positional().
+						Required().
+						Completion(func(cmd *Command, prefix string) []string {
+							return contexts[config]
+						}),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+			NewCommand("status", "").HandleFunc(func(args []string) int { return 0 }),
+			NewCommand("debug", "").Hidden().HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		Args   []string
+		Expect []string
+	}{
+		{[]string{""}, []string{"status", "switch"}},
+		{[]string{"s"}, []string{"status", "switch"}},
+		{[]string{"sw"}, []string{"switch"}},
+		{[]string{"--"}, []string{"--config", "--force"}},
+		{[]string{"-"}, []string{"--config", "--force", "-c"}},
+		{[]string{"switch", "--"}, []string{"--config", "--force", "--format"}},
+		{[]string{"switch", "--format", ""}, []string{"json", "text"}},
+		{[]string{"switch", "--format=j"}, []string{"--format=json"}},
+		{[]string{"switch", "pro"}, []string{"prod", "production"}},
+		{[]string{"--force", "switch", ""}, []string{"dev", "prod", "production"}},
+		{[]string{"--config", "other", "switch", ""}, []string{"staging"}},
+		{[]string{"--config", ""}, []string{}},
+		{[]string{"switch", "prod", ""}, []string{}},
+		{nil, []string{"status", "switch"}},
+	}
+	for _, test := range tests {
+		assertStrings(t, test.Expect, newCompleteTestCommand().Complete(test.Args))
+	}
+}
+
+func TestCompleteRun(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := newCompleteTestCommand()
+	cmd.Stdout = &stdout
+	assertInt64(t, 0, int64(cmd.Run([]string{"__complete", "switch", "pro"})))
+	assertString(t, "prod\nproduction\n", stdout.String())
+}
+
+func TestPositionalChoices(t *testing.T) {
+	cmd := NewCommand("app", "").
+		Flags(String(nil, "mode", "", "").Positional().Choices("fast", "slow")).
+		Must()
+	if _, err := cmd.Parse([]string{"fast"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewCommand("app", "").
+		Flags(String(nil, "mode", "", "").Positional().Choices("fast", "slow")).
+		Must().
+		Parse([]string{"medium"})
+	if assertBool(t, true, err != nil) {
+		assertString(t, `xflags: MODE: invalid choice: "medium", expected one of: "fast", "slow"`, err.Error())
+	}
+}
@@ -0,0 +1,408 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Formats of the configuration printed by the --print-config flag and written
+// by the "config init" command of ConfigInit. The text and JSON formats are
+// only supported by --print-config and the TOML format is only supported by
+// "config init".
+const (
+	ConfigFormatText = "text"
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+	ConfigFormatTOML = "toml"
+)
+
+// printConfigOptions is the value of the flag registered by PrintConfig.
+type printConfigOptions struct {
+	format string // empty if the flag was not specified
+	diff   bool
+}
+
+func (c *printConfigOptions) String() string {
+	if c.diff {
+		return c.format + ",diff"
+	}
+	return c.format
+}
+
+func (c *printConfigOptions) clone() Value { v := *c; return &v }
+
+// IsBoolFlag allows the flag to be specified without a value.
+func (c *printConfigOptions) IsBoolFlag() bool { return true }
+
+func (c *printConfigOptions) Set(s string) error {
+	c.format, c.diff = "", false
+	if s == "false" {
+		return nil
+	}
+	c.format = ConfigFormatText
+	for _, opt := range strings.Split(s, ",") {
+		switch opt {
+		case "true":
+		case "diff":
+			c.diff = true
+		case ConfigFormatText, ConfigFormatJSON, ConfigFormatYAML:
+			c.format = opt
+		default:
+			c.format = ""
+			return &choiceError{
+				Arg:     opt,
+				Choices: []string{ConfigFormatText, ConfigFormatJSON, ConfigFormatYAML, "diff"},
+			}
+		}
+	}
+	return nil
+}
+
+// PrintConfig registers the --print-config flag for this command and its
+// subcommands. If it is specified, the effective configuration of the invoked
+// command is printed to stdout instead of calling its handler.
+//
+// The configuration lists the value of every flag of the invoked command and
+// its parents, where the value was read from, as described by Provenance, and
+// whether it differs from the default value of the flag. This helps to debug
+// configuration that is layered from the command line, environment variables
+// and sources. The values of secret flags are masked.
+//
+// The configuration is printed as a table by default, or as JSON or YAML with
+// --print-config=json or --print-config=yaml.
+//
+// With --print-config=diff, only the flags whose value differs from their
+// default are printed, as lines of the form "path=value", or as a JSON or
+// YAML object keyed by flag path with --print-config=diff,json or
+// --print-config=diff,yaml. This captures a minimal configuration which
+// reproduces the invocation. Secret flags are omitted from the diff.
+func (c *CommandBuilder) PrintConfig() *CommandBuilder {
+	opts := &printConfigOptions{}
+	c.cmd.printConfigOptions = opts
+	return c.Flags(
+		Var(opts, "print-config", "Print the effective configuration and exit").builtin(),
+	)
+}
+
+// ConfigEntry describes the effective value of a flag as it is printed by the
+// --print-config flag.
+type ConfigEntry struct {
+	Flag       string `json:"flag"` // path of the flag, as passed to Source.Lookup
+	Value      string `json:"value"`
+	Default    string `json:"default"`
+	Provenance string `json:"provenance"`
+	Source     string `json:"source"` // name of the Source, if any
+	Changed    bool   `json:"changed"`
+
+	secret bool
+}
+
+// printConfigFormat returns the format selected with --print-config, as parsed
+// by parser, or an empty string if it was not specified for c.
+func (c *Command) printConfigFormat(parser *argParser) (format string, diff bool) {
+	for p := c; p != nil; p = p.Parent {
+		if p.printConfigOptions != nil {
+			opts := parser.valueOf(p.printConfigOptions).(*printConfigOptions)
+			return opts.format, opts.diff
+		}
+	}
+	return "", false
+}
+
+// Config returns the effective configuration of a command returned by Parse,
+// in the same form as it is printed by the --print-config flag. Flags of the
+// root command are listed first.
+func (c *Command) Config() []ConfigEntry {
+	if c.parser == nil {
+		return nil
+	}
+	return c.parser.config()
+}
+
+func (c *argParser) config() []ConfigEntry {
+	path := make([]*Command, 0)
+	for p := c.cmd; p != nil; p = p.Parent {
+		path = append([]*Command{p}, path...)
+	}
+	entries := make([]ConfigEntry, 0)
+	for _, cmd := range path {
+		idx := cmd.index()
+		for i, flag := range idx.flags {
+			if _, ok := flag.Value.(*printConfigOptions); ok {
+				continue
+			}
+			if !cmd.featureEnabled(flag.Feature) {
+				continue
+			}
+			o := c.origins[flag]
+			e := ConfigEntry{
+				Flag:       idx.paths[i],
+				Value:      valueString(c.value(flag)),
+				Default:    idx.defaults[i],
+				Provenance: o.provenance.String(),
+			}
+			if o.src != nil {
+				e.Source = o.src.Name()
+			}
+			e.Changed = e.Value != e.Default
+			if flag.Secret {
+				e.secret = true
+				e.Value, e.Default = maskSecret(e.Value), maskSecret(e.Default)
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// maskSecret returns a mask for a non-empty secret value.
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "********"
+}
+
+// writeConfig writes the entries in the given --print-config format to w.
+func writeConfig(w io.Writer, format string, entries []ConfigEntry) error {
+	p := &Printer{Format: FormatTable, W: w}
+	switch format {
+	case ConfigFormatJSON:
+		p.Format = FormatJSON
+	case ConfigFormatYAML:
+		p.Format = FormatYAML
+	}
+	return p.Print(entries)
+}
+
+// printConfig writes the effective configuration of the invoked command to
+// stdout in the given format and returns an exit code.
+func (c *Invocation) printConfig(format string, diff bool) int {
+	stdout, stderr := c.output()
+	entries := c.parser.config()
+	var err error
+	if diff {
+		err = writeConfigDiff(stdout, format, entries)
+	} else {
+		err = writeConfig(stdout, format, entries)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeConfigDiff writes the non-default, non-secret entries in the given
+// --print-config format to w.
+func writeConfigDiff(w io.Writer, format string, entries []ConfigEntry) error {
+	m := &orderedMap{values: make(map[string]interface{})}
+	for _, e := range entries {
+		if !e.Changed || e.secret {
+			continue
+		}
+		m.keys = append(m.keys, e.Flag)
+		m.values[e.Flag] = e.Value
+	}
+	switch format {
+	case ConfigFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m.values)
+	case ConfigFormatYAML:
+		buf := new(bytes.Buffer)
+		writeYAML(buf, m, 0)
+		_, err := buf.WriteTo(w)
+		return err
+	}
+	aw := newAggregatedWriter(w)
+	for _, key := range m.keys {
+		fmt.Fprintf(aw, "%s=%s\n", key, m.values[key])
+	}
+	return aw.Err()
+}
+
+// ConfigInit adds a "config" subcommand with an "init" subcommand that writes
+// a configuration file generated from the flags of this command and its
+// subcommands, so that users may bootstrap a configuration file that matches
+// the command line exactly:
+//
+//	app config init --format toml app.toml
+//
+// The file contains the default value of every flag, keyed by flag name and
+// nested in a section for each subcommand, so that the keys correspond to the
+// flag paths passed to Source.Lookup. Each key is preceded by a comment with
+// the usage and type of the flag. The values of secret flags are commented
+// out. Hidden and positional flags and the flags registered by options such as
+// OutputFlags are not written.
+//
+// The file is written in YAML by default, or in TOML with --format toml. It is
+// written to stdout unless a path is given. An existing file is not
+// overwritten unless --force is specified.
+func (c *CommandBuilder) ConfigInit() *CommandBuilder {
+	return c.Subcommands(newConfigInitCommand())
+}
+
+func newConfigInitCommand() *CommandBuilder {
+	initCmd := NewCommand("init", "Write a configuration file with the default value of each flag").
+		Flags(
+			String(nil, "format", ConfigFormatYAML, "Format of the file").
+				Choices(ConfigFormatYAML, ConfigFormatTOML).
+				ShowDefault().
+				builtin(),
+			Bool(nil, "force", false, "Overwrite an existing file").builtin(),
+			String(nil, "FILE", "", "Path of the file to write instead of stdout").
+				Positional().
+				NArgs(0, 1).
+				builtin(),
+		).
+		HandleE(func(ctx context.Context, args []string) error {
+			inv := InvocationFrom(ctx)
+			format := inv.Get("format").(string)
+			force := inv.Get("force").(bool)
+			path := inv.Get("FILE").(string)
+			root := inv.Target()
+			for root.Parent != nil {
+				root = root.Parent
+			}
+			buf := new(bytes.Buffer)
+			writeConfigFile(buf, format, root)
+			if path == "" {
+				_, err := buf.WriteTo(OutputFrom(ctx).Stdout)
+				return err
+			}
+			flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+			if force {
+				flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			}
+			f, err := os.OpenFile(path, flag, 0o644)
+			if err != nil {
+				return err
+			}
+			if _, err := buf.WriteTo(f); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		})
+	return NewCommand("config", "Manage configuration files").Subcommands(initCmd)
+}
+
+// writeConfigFile writes a configuration file in the given "config init"
+// format to w with the default value of each flag of cmd and its subcommands.
+func writeConfigFile(w *bytes.Buffer, format string, cmd *Command) {
+	fmt.Fprintf(w, "# Configuration file for %s.\n", cmd.Name)
+	writeConfigSection(w, format, cmd, nil)
+}
+
+func writeConfigSection(w *bytes.Buffer, format string, cmd *Command, path []string) {
+	indent := ""
+	if format == ConfigFormatYAML {
+		indent = strings.Repeat("  ", len(path))
+	}
+	if len(path) > 0 {
+		parent := ""
+		if format == ConfigFormatYAML {
+			parent = strings.Repeat("  ", len(path)-1)
+		}
+		w.WriteString("\n")
+		if usage := cmd.UsageText(); usage != "" {
+			w.WriteString(parent + "# " + usage + "\n")
+		}
+		if format == ConfigFormatTOML {
+			w.WriteString("[" + strings.Join(path, ".") + "]\n")
+		} else {
+			w.WriteString(parent + cmd.Name + ":\n")
+		}
+	}
+	for _, flag := range configFileFlags(cmd) {
+		w.WriteString("\n")
+		w.WriteString(indent + "# " + configFileComment(flag) + "\n")
+		sep := ": "
+		if format == ConfigFormatTOML {
+			sep = " = "
+		}
+		line := flag.name() + sep + configFileLiteral(flag.Value)
+		if flag.Secret {
+			line = "# " + line
+		}
+		w.WriteString(indent + line + "\n")
+	}
+	for _, sub := range cmd.Subcommands {
+		if hasConfigFileFlags(sub) {
+			writeConfigSection(w, format, sub, append(path[:len(path):len(path)], sub.Name))
+		}
+	}
+}
+
+// configFileFlags returns the flags of cmd that are written by "config init".
+func configFileFlags(cmd *Command) []*Flag {
+	flags := make([]*Flag, 0)
+	for _, flag := range cmd.index().flags {
+		if flag.Positional || flag.builtin || isHidden(cmd, flag) {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+// hasConfigFileFlags reports whether cmd or any of its visible subcommands has
+// flags that are written by "config init".
+func hasConfigFileFlags(cmd *Command) bool {
+	if isHiddenCommand(cmd) {
+		return false
+	}
+	if len(configFileFlags(cmd)) > 0 {
+		return true
+	}
+	for _, sub := range cmd.Subcommands {
+		if hasConfigFileFlags(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// configFileComment returns the comment that describes flag in a
+// configuration file.
+func configFileComment(flag *Flag) string {
+	details := make([]string, 0, 3)
+	if typ := valueType(flag.Value); typ != "" {
+		details = append(details, typ)
+	}
+	if len(flag.choices) > 0 {
+		details = append(details, "one of: "+strings.Join(flag.choices, ", "))
+	}
+	if flag.EnvVar != "" {
+		details = append(details, "env: "+flag.EnvVar)
+	}
+	s := flag.UsageText()
+	if len(details) > 0 {
+		s = strings.TrimSpace(s + " (" + strings.Join(details, "; ") + ")")
+	}
+	return s
+}
+
+// configFileLiteral returns the current value of v as a YAML or TOML literal.
+func configFileLiteral(v Value) string {
+	switch v := v.(type) {
+	case *stringSliceValue:
+		elems := make([]string, len(*v.p))
+		for i, s := range *v.p {
+			elems[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	switch valueType(v) {
+	case "bool", "float64", "int", "int64", "uint", "uint64":
+		return valueString(v)
+	}
+	return strconv.Quote(valueString(v))
+}
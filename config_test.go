@@ -0,0 +1,215 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newConfigCommand(stdout *bytes.Buffer, called *bool) *Command {
+	return NewCommand("app", "").
+		Output(stdout, new(bytes.Buffer)).
+		PrintConfig().
+		Sources(MapSource("config.json", map[string]string{"deploy.replicas": "3"})).
+		Flags(
+			String(nil, "region", "us-east-1", ""),
+			String(nil, "token", "", "").Env("APP_TOKEN").Secret(),
+		).
+		Subcommands(
+			NewCommand("deploy", "").
+				Flags(
+					Int(nil, "replicas", 1, ""),
+					Bool(nil, "force", false, ""),
+				).
+				HandleFunc(func(args []string) int {
+					*called = true
+					return 0
+				}),
+		).
+		Must()
+}
+
+func TestPrintConfig(t *testing.T) {
+	t.Setenv("APP_TOKEN", "secret")
+	stdout := new(bytes.Buffer)
+	called := false
+	cmd := newConfigCommand(stdout, &called)
+	code := cmd.Run([]string{"--region", "eu-west-1", "deploy", "--print-config"})
+	assertInt64(t, 0, int64(code))
+	assertBool(t, false, called)
+	expect := []string{
+		"FLAG            VALUE       DEFAULT     PROVENANCE     SOURCE        CHANGED",
+		"region          eu-west-1   us-east-1   command line                 true",
+		"token           ********                env                          true",
+		"deploy.replicas 3           1           config         config.json   true",
+		"deploy.force    false       false       default                      false",
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	if len(lines) != len(expect) {
+		t.Fatalf("unexpected output:\n%s", stdout)
+	}
+	for i := range expect {
+		assertString(t, strings.Join(strings.Fields(expect[i]), " "), strings.Join(strings.Fields(lines[i]), " "))
+	}
+
+	stdout.Reset()
+	code = cmd.Run([]string{"deploy", "--print-config=json"})
+	assertInt64(t, 0, int64(code))
+	if !strings.Contains(stdout.String(), `"flag": "deploy.replicas"`) {
+		t.Errorf("unexpected JSON output:\n%s", stdout)
+	}
+
+	stdout.Reset()
+	code = cmd.Run([]string{"deploy", "--print-config=yaml"})
+	assertInt64(t, 0, int64(code))
+	if !strings.Contains(stdout.String(), "- flag: region\n") {
+		t.Errorf("unexpected YAML output:\n%s", stdout)
+	}
+
+	code = newConfigCommand(stdout, &called).Run([]string{"deploy"})
+	assertInt64(t, 0, int64(code))
+	assertBool(t, true, called)
+
+	_, err := cmd.Parse([]string{"--print-config=xml"})
+	assertErrorAs(t, err, new(*ArgumentError))
+}
+
+func TestPrintConfigDiff(t *testing.T) {
+	t.Setenv("APP_TOKEN", "secret")
+	tests := []struct {
+		Arg    string
+		Expect string
+	}{
+		{"--print-config=diff", "region=eu-west-1\ndeploy.replicas=3\n"},
+		{"--print-config=diff,yaml", "region: eu-west-1\ndeploy.replicas: \"3\"\n"},
+		{"--print-config=json,diff", "{\n  \"deploy.replicas\": \"3\",\n  \"region\": \"eu-west-1\"\n}\n"},
+	}
+	for _, test := range tests {
+		stdout := new(bytes.Buffer)
+		called := false
+		cmd := newConfigCommand(stdout, &called)
+		code := cmd.Run([]string{"--region", "eu-west-1", "deploy", test.Arg})
+		assertInt64(t, 0, int64(code))
+		assertString(t, test.Expect, stdout.String())
+	}
+}
+
+func newConfigInitApp(stdout *bytes.Buffer) *Command {
+	return NewCommand("app", "").
+		Output(stdout, new(bytes.Buffer)).
+		OutputFlags().
+		ConfigInit().
+		Flags(
+			String(nil, "region", "us-east-1", "Region to deploy to").Env("APP_REGION"),
+			String(nil, "token", "", "API token").Secret(),
+			String(nil, "debug-addr", "", "").Hidden(),
+		).
+		Subcommands(
+			NewCommand("deploy", "Deploy the application").
+				Flags(
+					Int(nil, "replicas", 1, "Number of replicas"),
+					Strings(nil, "tag", []string{"a", "b"}, "Image tags"),
+					String(nil, "mode", "fast", "").Choices("fast", "safe"),
+					String(nil, "TARGET", "", "").Positional(),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+			NewCommand("version", "").
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+}
+
+func TestConfigInit(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	code := newConfigInitApp(stdout).Run([]string{"config", "init"})
+	assertInt64(t, 0, int64(code))
+	assertString(t, `# Configuration file for app.
+
+# Region to deploy to (string; env: APP_REGION)
+region: "us-east-1"
+
+# API token (string)
+# token: ""
+
+# Deploy the application
+deploy:
+
+  # Number of replicas (int)
+  replicas: 1
+
+  # Image tags (strings)
+  tag: ["a", "b"]
+
+  # (string; one of: fast, safe)
+  mode: "fast"
+`, stdout.String())
+
+	stdout.Reset()
+	code = newConfigInitApp(stdout).Run([]string{"config", "init", "--format", "toml"})
+	assertInt64(t, 0, int64(code))
+	assertString(t, `# Configuration file for app.
+
+# Region to deploy to (string; env: APP_REGION)
+region = "us-east-1"
+
+# API token (string)
+# token = ""
+
+# Deploy the application
+[deploy]
+
+# Number of replicas (int)
+replicas = 1
+
+# Image tags (strings)
+tag = ["a", "b"]
+
+# (string; one of: fast, safe)
+mode = "fast"
+`, stdout.String())
+}
+
+func TestConfigInitFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	stdout := new(bytes.Buffer)
+	code := newConfigInitApp(stdout).Run([]string{"config", "init", path})
+	assertInt64(t, 0, int64(code))
+	assertString(t, "", stdout.String())
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "replicas: 1\n") {
+		t.Errorf("unexpected file contents:\n%s", b)
+	}
+
+	// existing files are only overwritten with --force
+	code = newConfigInitApp(stdout).Run([]string{"config", "init", path})
+	assertInt64(t, 1, int64(code))
+	code = newConfigInitApp(stdout).Run([]string{"config", "init", "--force", path})
+	assertInt64(t, 0, int64(code))
+}
+
+func TestConfigInitExec(t *testing.T) {
+	cmd := newConfigInitApp(new(bytes.Buffer))
+	stdout := new(bytes.Buffer)
+	code := cmd.Exec(context.Background(), []string{"config", "init", "--format=toml"}, nil, stdout, nil)
+	assertInt64(t, 0, int64(code))
+	if !strings.Contains(stdout.String(), "\n[deploy]\n") {
+		t.Errorf("expected TOML, got:\n%s", stdout)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	code = cmd.Exec(context.Background(), []string{"config", "init", path}, nil, nil, nil)
+	assertInt64(t, 0, int64(code))
+	code = cmd.Exec(context.Background(), []string{"config", "init", path}, nil, nil, nil)
+	assertInt64(t, 1, int64(code))
+	code = cmd.Exec(context.Background(), []string{"config", "init", "--force", path}, nil, nil, nil)
+	assertInt64(t, 0, int64(code))
+}
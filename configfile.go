@@ -0,0 +1,970 @@
+package xflags
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigFile returns a Source that reads flag values from the configuration
+// file at path. The format of the file is selected by its extension, which
+// must be ".json", ".toml", ".yaml" or ".yml".
+//
+// The keys of the file are flag names, nested in a mapping or table for each
+// subcommand, so that the configuration of the "replicas" flag of the
+// "deploy" subcommand may be written in YAML as:
+//
+//	deploy:
+//	  replicas: 3
+//
+// Keys may also be written as flag paths, such as "deploy.replicas". Scalar
+// values are passed to the flag as they are written and each element of a list
+// is passed to the flag in turn. Files in the format written by the
+// "config init" command of ConfigInit are always accepted.
+//
+// Only a subset of YAML and TOML is supported: YAML block mappings and
+// sequences with plain or quoted scalars and flow sequences, and TOML tables
+// and key-value pairs with single-line strings and arrays that may span lines.
+//
+// The file is read when the first flag is looked up and again if its
+// modification time or size has changed, so it may be used with Reload and
+// WatchFiles. A file that does not exist provides no values.
+func ConfigFile(path string) Source {
+	return &configFileSource{path: path}
+}
+
+// ConfigFileFS returns a Source that reads flag values from the configuration
+// file at path in fsys, as ConfigFile does, for programs that have no access to
+// the file system of the operating system, such as those compiled to
+// WebAssembly, or that embed their configuration.
+func ConfigFileFS(fsys fs.FS, path string) Source {
+	return &configFileSource{fsys: fsys, path: path}
+}
+
+// configFileSource is the Source returned by ConfigFile and ConfigFileFS.
+type configFileSource struct {
+	fsys fs.FS // nil for the file system of the operating system
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	doc     *configDoc
+}
+
+var _ MultiSource = (*configFileSource)(nil)
+
+func (c *configFileSource) Name() string { return c.path }
+
+func (c *configFileSource) Lookup(flagPath string) (string, bool) {
+	s, ok, err := c.LookupErr(flagPath)
+	if err != nil {
+		return "", false
+	}
+	return s, ok
+}
+
+func (c *configFileSource) LookupErr(flagPath string) (string, bool, error) {
+	values, ok, err := c.LookupValues(flagPath)
+	return strings.Join(values, ","), ok, err
+}
+
+func (c *configFileSource) LookupValues(flagPath string) ([]string, bool, error) {
+	doc, err := c.load()
+	if err != nil {
+		return nil, false, err
+	}
+	values, ok := doc.values[flagPath]
+	return values, ok, nil
+}
+
+// load returns the contents of the file, reading it again if it has changed.
+func (c *configFileSource) load() (*configDoc, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fi, err := c.stat()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &configDoc{}, nil
+		}
+		return nil, err
+	}
+	if c.doc != nil && fi.ModTime().Equal(c.modTime) && fi.Size() == c.size {
+		return c.doc, nil
+	}
+	b, err := c.readFile()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseConfigFile(c.path, b)
+	if err != nil {
+		return nil, err
+	}
+	c.doc, c.modTime, c.size = doc, fi.ModTime(), fi.Size()
+	return doc, nil
+}
+
+func (c *configFileSource) stat() (fs.FileInfo, error) {
+	if c.fsys != nil {
+		return fs.Stat(c.fsys, c.path)
+	}
+	return os.Stat(c.path)
+}
+
+func (c *configFileSource) readFile() ([]byte, error) {
+	if c.fsys != nil {
+		return fs.ReadFile(c.fsys, c.path)
+	}
+	return os.ReadFile(c.path)
+}
+
+// configDoc is the flattened contents of a configuration file.
+type configDoc struct {
+	keys   []string // flag paths in the order they appear in the file
+	values map[string][]string
+}
+
+// parseConfigFile parses the contents of a configuration file in the format
+// given by the extension of path.
+func parseConfigFile(path string, b []byte) (*configDoc, error) {
+	var m *orderedMap
+	var err error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		m, err = parseJSONConfig(b)
+	case ".toml":
+		m, err = parseTOMLConfig(b)
+	case ".yaml", ".yml":
+		m, err = parseYAMLConfig(b)
+	default:
+		return nil, fmt.Errorf("unsupported configuration file format: %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc := &configDoc{values: make(map[string][]string)}
+	if err := doc.flatten("", m); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// flatten adds the values of m to the document, keyed by their path.
+func (c *configDoc) flatten(prefix string, m *orderedMap) error {
+	for _, key := range m.keys {
+		path := prefix + key
+		var values []string
+		switch v := m.values[key].(type) {
+		case nil:
+			continue
+		case *orderedMap:
+			if err := c.flatten(path+".", v); err != nil {
+				return err
+			}
+			continue
+		case []interface{}:
+			values = make([]string, 0, len(v))
+			for _, elem := range v {
+				s, ok := configScalar(elem)
+				if !ok {
+					return fmt.Errorf("%s: unsupported nested value", path)
+				}
+				values = append(values, s)
+			}
+		default:
+			s, _ := configScalar(v)
+			values = []string{s}
+		}
+		if _, ok := c.values[path]; !ok {
+			c.keys = append(c.keys, path)
+		}
+		c.values[path] = values
+	}
+	return nil
+}
+
+// configScalar returns the string form of a scalar value.
+func configScalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	case nil:
+		return "", true
+	}
+	return "", false
+}
+
+func parseJSONConfig(b []byte) (*orderedMap, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(*orderedMap)
+	if !ok {
+		return nil, fmt.Errorf("configuration must be a JSON object")
+	}
+	return m, nil
+}
+
+// configLine is a line of a YAML or TOML configuration file with its comment
+// removed.
+type configLine struct {
+	n      int // line number
+	indent int
+	text   string
+}
+
+// configLines splits a configuration file into lines and removes comments and
+// blank lines.
+func configLines(b []byte) []configLine {
+	lines := make([]configLine, 0)
+	for i, s := range strings.Split(string(b), "\n") {
+		s = strings.TrimRight(stripComment(s), " \t\r")
+		text := strings.TrimLeft(s, " ")
+		if text == "" {
+			continue
+		}
+		lines = append(lines, configLine{n: i + 1, indent: len(s) - len(text), text: text})
+	}
+	return lines
+}
+
+// stripComment removes a comment that starts with "#" outside of quotes at the
+// start of s or after whitespace.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// configLineErr returns an error for a line of a configuration file.
+func configLineErr(line configLine, format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", line.n, fmt.Sprintf(format, a...))
+}
+
+// yamlFrame is a mapping being parsed and the indentation of its keys.
+type yamlFrame struct {
+	indent int
+	m      *orderedMap
+}
+
+func parseYAMLConfig(b []byte) (*orderedMap, error) {
+	root := &orderedMap{values: make(map[string]interface{})}
+	stack := []yamlFrame{{indent: -1, m: root}}
+	var open *orderedMap // mapping of the last key without a value
+	var openKey string
+	openIndent := -1
+	for _, line := range configLines(b) {
+		if line.text == "---" && line.indent == 0 {
+			continue
+		}
+		if strings.HasPrefix(line.text, "\t") {
+			return nil, configLineErr(line, "tabs are not permitted for indentation")
+		}
+		if line.text == "-" || strings.HasPrefix(line.text, "- ") {
+			if open == nil || line.indent < openIndent {
+				return nil, configLineErr(line, "unexpected sequence entry")
+			}
+			v, err := parseYAMLScalar(strings.TrimSpace(line.text[1:]))
+			if err != nil {
+				return nil, configLineErr(line, "%v", err)
+			}
+			if _, ok := v.(string); !ok {
+				return nil, configLineErr(line, "unsupported nested value")
+			}
+			a, _ := open.values[openKey].([]interface{})
+			open.values[openKey] = append(a, v)
+			continue
+		}
+		if open != nil {
+			if _, isSeq := open.values[openKey].([]interface{}); !isSeq && line.indent > openIndent {
+				child := &orderedMap{values: make(map[string]interface{})}
+				open.values[openKey] = child
+				stack = append(stack, yamlFrame{indent: line.indent, m: child})
+			}
+			open = nil
+		}
+		for len(stack) > 1 && stack[len(stack)-1].indent > line.indent {
+			stack = stack[:len(stack)-1]
+		}
+		top := &stack[len(stack)-1]
+		if top.indent < 0 {
+			top.indent = line.indent
+		}
+		if top.indent != line.indent {
+			return nil, configLineErr(line, "bad indentation")
+		}
+		key, rest, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, configLineErr(line, "%v", err)
+		}
+		if _, ok := top.m.values[key]; !ok {
+			top.m.keys = append(top.m.keys, key)
+		}
+		if rest == "" {
+			top.m.values[key] = nil
+			open, openKey, openIndent = top.m, key, line.indent
+			continue
+		}
+		v, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, configLineErr(line, "%v", err)
+		}
+		top.m.values[key] = v
+	}
+	return root, nil
+}
+
+// splitYAMLKey splits a line of a YAML mapping into its key and the text of
+// its value.
+func splitYAMLKey(s string) (key, rest string, err error) {
+	if s[0] == '"' || s[0] == '\'' {
+		end, err := quotedEnd(s)
+		if err != nil {
+			return "", "", err
+		}
+		if key, err = unquoteConfig(s[:end]); err != nil {
+			return "", "", err
+		}
+		s = s[end:]
+		if !strings.HasPrefix(s, ":") {
+			return "", "", fmt.Errorf("expected ':' after key")
+		}
+		return key, strings.TrimSpace(s[1:]), nil
+	}
+	if i := strings.Index(s, ": "); i >= 0 {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+2:]), nil
+	}
+	if strings.HasSuffix(s, ":") {
+		return strings.TrimSpace(s[:len(s)-1]), "", nil
+	}
+	return "", "", fmt.Errorf("expected 'key: value'")
+}
+
+// parseYAMLScalar parses a YAML scalar or flow sequence of scalars.
+func parseYAMLScalar(s string) (interface{}, error) {
+	switch {
+	case s == "":
+		return "", nil
+	case s == "~" || s == "null":
+		return nil, nil
+	case s == "{}":
+		return &orderedMap{values: make(map[string]interface{})}, nil
+	case s[0] == '[':
+		return parseFlowSequence(s, parseYAMLScalar)
+	case s[0] == '"' || s[0] == '\'':
+		end, err := quotedEnd(s)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(s[end:]) != "" {
+			return nil, fmt.Errorf("unexpected text after quoted string: %q", s[end:])
+		}
+		return unquoteConfig(s)
+	case s[0] == '|' || s[0] == '>':
+		return nil, fmt.Errorf("block scalars are not supported")
+	case s[0] == '{' || s[0] == '&' || s[0] == '*' || s[0] == '!':
+		return nil, fmt.Errorf("unsupported value: %q", s)
+	}
+	return s, nil
+}
+
+// parseFlowSequence parses a sequence of comma separated scalars enclosed in
+// square brackets.
+func parseFlowSequence(
+	s string,
+	parseElem func(s string) (interface{}, error),
+) ([]interface{}, error) {
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("unterminated sequence: %q", s)
+	}
+	a := make([]interface{}, 0)
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	for s != "" {
+		end := strings.IndexByte(s, ',')
+		if s[0] == '"' || s[0] == '\'' {
+			n, err := quotedEnd(s)
+			if err != nil {
+				return nil, err
+			}
+			end = strings.IndexByte(s[n:], ',')
+			if end >= 0 {
+				end += n
+			}
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		elem := strings.TrimSpace(s[:end])
+		if elem == "" || elem[0] == '[' || elem[0] == '{' {
+			return nil, fmt.Errorf("unsupported sequence element: %q", elem)
+		}
+		v, err := parseElem(elem)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		if end == len(s) {
+			break
+		}
+		s = strings.TrimSpace(s[end+1:])
+	}
+	return a, nil
+}
+
+// quotedEnd returns the index following the closing quote of the quoted
+// string at the start of s.
+func quotedEnd(s string) (int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++ // escaped single quote
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string: %s", s)
+}
+
+// unquoteConfig returns the contents of a single or double quoted string.
+func unquoteConfig(s string) (string, error) {
+	if s[0] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return strconv.Unquote(s)
+}
+
+func parseTOMLConfig(b []byte) (*orderedMap, error) {
+	root := &orderedMap{values: make(map[string]interface{})}
+	table := root
+	lines := configLines(b)
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		text := strings.TrimSpace(line.text)
+		if strings.HasPrefix(text, "[[") {
+			return nil, configLineErr(line, "arrays of tables are not supported")
+		}
+		if text[0] == '[' {
+			if !strings.HasSuffix(text, "]") {
+				return nil, configLineErr(line, "unterminated table header")
+			}
+			keys, err := splitTOMLKey(text[1 : len(text)-1])
+			if err != nil {
+				return nil, configLineErr(line, "%v", err)
+			}
+			if table, err = tomlTable(root, keys); err != nil {
+				return nil, configLineErr(line, "%v", err)
+			}
+			continue
+		}
+		eq := tomlKeyEnd(text)
+		if eq < 0 {
+			return nil, configLineErr(line, "expected 'key = value'")
+		}
+		keys, err := splitTOMLKey(text[:eq])
+		if err != nil {
+			return nil, configLineErr(line, "%v", err)
+		}
+		rest := strings.TrimSpace(text[eq+1:])
+		// arrays may span several lines
+		for strings.HasPrefix(rest, "[") && !tomlArrayClosed(rest) && i+1 < len(lines) {
+			i++
+			rest += " " + strings.TrimSpace(lines[i].text)
+		}
+		v, err := parseTOMLValue(rest)
+		if err != nil {
+			return nil, configLineErr(line, "%v", err)
+		}
+		m, err := tomlTable(table, keys[:len(keys)-1])
+		if err != nil {
+			return nil, configLineErr(line, "%v", err)
+		}
+		key := keys[len(keys)-1]
+		if _, ok := m.values[key]; ok {
+			return nil, configLineErr(line, "duplicate key: %s", key)
+		}
+		m.keys = append(m.keys, key)
+		m.values[key] = v
+	}
+	return root, nil
+}
+
+// tomlTable returns the table at the given keys of m, creating it if
+// necessary.
+func tomlTable(m *orderedMap, keys []string) (*orderedMap, error) {
+	for _, key := range keys {
+		switch v := m.values[key].(type) {
+		case nil:
+			child := &orderedMap{values: make(map[string]interface{})}
+			m.keys = append(m.keys, key)
+			m.values[key] = child
+			m = child
+		case *orderedMap:
+			m = v
+		default:
+			return nil, fmt.Errorf("key is not a table: %s", key)
+		}
+	}
+	return m, nil
+}
+
+// tomlKeyEnd returns the index of the "=" that follows the key of a TOML
+// key-value pair or -1.
+func tomlKeyEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			n, err := quotedEnd(s[i:])
+			if err != nil {
+				return -1
+			}
+			i += n - 1
+		case '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// splitTOMLKey splits a dotted TOML key into its parts.
+func splitTOMLKey(s string) ([]string, error) {
+	keys := make([]string, 0, 1)
+	s = strings.TrimSpace(s)
+	for {
+		var key string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end, err := quotedEnd(s)
+			if err != nil {
+				return nil, err
+			}
+			if key, err = unquoteConfig(s[:end]); err != nil {
+				return nil, err
+			}
+			s = strings.TrimSpace(s[end:])
+		} else {
+			end := strings.IndexByte(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			key, s = strings.TrimSpace(s[:end]), strings.TrimSpace(s[end:])
+			if key == "" || strings.ContainsAny(key, " \t") {
+				return nil, fmt.Errorf("invalid key: %q", key)
+			}
+		}
+		keys = append(keys, key)
+		if s == "" {
+			return keys, nil
+		}
+		if s[0] != '.' {
+			return nil, fmt.Errorf("invalid key: %q", s)
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+}
+
+// tomlArrayClosed reports whether the brackets of s, outside of strings, are
+// balanced.
+func tomlArrayClosed(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			n, err := quotedEnd(s[i:])
+			if err != nil {
+				return false
+			}
+			i += n - 1
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+	}
+	return depth == 0
+}
+
+// parseTOMLValue parses a TOML string, number, boolean, date or array of
+// scalars.
+func parseTOMLValue(s string) (interface{}, error) {
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("missing value")
+	case strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''"):
+		return nil, fmt.Errorf("multi-line strings are not supported")
+	case s[0] == '[':
+		return parseFlowSequence(s, parseTOMLValue)
+	case s[0] == '{':
+		return nil, fmt.Errorf("inline tables are not supported")
+	case s[0] == '"' || s[0] == '\'':
+		end, err := quotedEnd(s)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(s[end:]) != "" {
+			return nil, fmt.Errorf("unexpected text after string: %q", s[end:])
+		}
+		return unquoteConfig(s)
+	case s == "true" || s == "false":
+		return s, nil
+	case strings.ContainsAny(s[:1], "+-0123456789"), strings.HasSuffix(s, "inf"), strings.HasSuffix(s, "nan"):
+		return strings.ReplaceAll(s, "_", ""), nil // numbers may contain "_"
+	}
+	return nil, fmt.Errorf("invalid value: %q", s)
+}
+
+// StrictConfig specifies that keys of configuration files read by ConfigFile
+// sources of this command and its subcommands which do not correspond to any
+// flag of the command tree are an error. Parse returns a ConfigKeyError that
+// lists the unknown keys with suggested corrections, so that misspelled keys
+// are not silently ignored.
+func (c *CommandBuilder) StrictConfig() *CommandBuilder {
+	c.cmd.StrictConfig = true
+	return c
+}
+
+// ConfigKeyError is returned by Parse if a configuration file of a command
+// configured with StrictConfig has keys that do not correspond to any flag.
+type ConfigKeyError struct {
+	Source string   // name of the source, such as the path of the file
+	Keys   []string // unknown keys, in the order they appear in the file
+
+	// Suggestions are possible corrections for each key in Keys.
+	Suggestions [][]string
+}
+
+func (e *ConfigKeyError) Error() string {
+	w := new(bytes.Buffer)
+	fmt.Fprintf(w, "xflags: %s: unknown configuration key", e.Source)
+	if len(e.Keys) > 1 {
+		w.WriteString("s")
+	}
+	w.WriteString(": ")
+	for i, key := range e.Keys {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		fmt.Fprintf(w, "%q", key)
+		switch suggestions := e.Suggestions[i]; len(suggestions) {
+		case 0:
+		case 1:
+			fmt.Fprintf(w, " (did you mean %q?)", suggestions[0])
+		default:
+			fmt.Fprintf(w, " (did you mean one of %s?)", quoteList(suggestions))
+		}
+	}
+	return w.String()
+}
+
+// checkConfigKeys returns a ConfigKeyError if a configuration file of the
+// current command or its parents has a key that does not correspond to any flag
+// of the command tree and any of these commands is configured with
+// StrictConfig.
+func (c *argParser) checkConfigKeys() error {
+	strict := false
+	for p := c.cmd; p != nil; p = p.Parent {
+		strict = strict || p.StrictConfig
+	}
+	if !strict {
+		return nil
+	}
+	var known map[string]bool
+	var paths []string
+	profiles, aliases, presets := c.cmd.hasProfiles(), c.cmd.hasAliases(), c.cmd.hasPresets()
+	for _, src := range c.sources() {
+		cfg, ok := src.(*configFileSource)
+		if !ok {
+			continue
+		}
+		doc, err := cfg.load()
+		if err != nil {
+			return sourceErr(src, err)
+		}
+		if known == nil {
+			known, paths = treeFlagPaths(c.cmd)
+		}
+		var keyErr *ConfigKeyError
+		for _, key := range doc.keys {
+			if known[key] ||
+				aliases && strings.HasPrefix(key, aliasKey+".") ||
+				presets && strings.HasPrefix(key, presetKey+".") {
+				continue
+			}
+			suggestions := suggest(key, paths)
+			if profile, flagPath, ok := splitProfilePath(key); ok && profiles {
+				if known[flagPath] {
+					continue
+				}
+				suggestions = suggest(flagPath, paths)
+				for i := range suggestions {
+					suggestions[i] = profilePath(profile, suggestions[i])
+				}
+			}
+			if keyErr == nil {
+				keyErr = &ConfigKeyError{Source: src.Name()}
+			}
+			keyErr.Keys = append(keyErr.Keys, key)
+			keyErr.Suggestions = append(keyErr.Suggestions, suggestions)
+		}
+		if keyErr != nil {
+			return keyErr
+		}
+	}
+	return nil
+}
+
+// treeFlagPaths returns the paths of all flags of the command tree of cmd.
+func treeFlagPaths(cmd *Command) (map[string]bool, []string) {
+	for cmd.Parent != nil {
+		cmd = cmd.Parent
+	}
+	known := make(map[string]bool)
+	paths := make([]string, 0)
+	var walk func(cmd *Command)
+	walk = func(cmd *Command) {
+		for _, path := range cmd.index().paths {
+			if !known[path] {
+				known[path] = true
+				paths = append(paths, path)
+			}
+		}
+		for _, sub := range cmd.Subcommands {
+			walk(sub)
+		}
+	}
+	walk(cmd)
+	return known, paths
+}
+
+// ConfigProfiles registers the --profile flag for this command and its
+// subcommands which selects a named profile of the configuration of the
+// command. If envVar is not empty, the profile may also be selected with the
+// named environment variable.
+//
+// The values of a profile are read from each source at the path of the flag
+// prefixed by "profiles.NAME.", and take precedence over the values of the
+// same source that are not in a profile. For example, the following
+// configuration file sets the region to "us-east-1" and the number of
+// replicas of the deploy subcommand to 1, unless --profile staging is
+// specified, which changes the region to "eu-west-1":
+//
+//	region: us-east-1
+//	deploy:
+//	  replicas: 1
+//	profiles:
+//	  staging:
+//	    region: eu-west-1
+//
+// Parse returns an error if the selected profile is not defined by any
+// configuration file read by ConfigFile.
+func (c *CommandBuilder) ConfigProfiles(envVar string) *CommandBuilder {
+	opts := &profileOptions{}
+	c.cmd.profileOptions = opts
+	return c.Flags(
+		Var(opts, "profile", "Name of the configuration profile to use").
+			Env(envVar).
+			builtin(),
+	)
+}
+
+// profileOptions is the value of the flag registered by ConfigProfiles.
+type profileOptions struct {
+	name string
+}
+
+func (c *profileOptions) String() string { return c.name }
+
+func (c *profileOptions) Set(s string) error {
+	c.name = s
+	return nil
+}
+
+func (c *profileOptions) Get() interface{} { return c.name }
+
+func (c *profileOptions) clone() Value { v := *c; return &v }
+
+// profilesKey is the key of the configuration of each profile.
+const profilesKey = "profiles"
+
+// profilePath returns the path of a flag in the named profile.
+func profilePath(profile, flagPath string) string {
+	return profilesKey + "." + profile + "." + flagPath
+}
+
+// splitProfilePath returns the profile and flag path of a key which is
+// prefixed by "profiles.NAME.".
+func splitProfilePath(key string) (profile, flagPath string, ok bool) {
+	if !strings.HasPrefix(key, profilesKey+".") {
+		return "", "", false
+	}
+	key = key[len(profilesKey)+1:]
+	i := strings.Index(key, ".")
+	if i < 0 {
+		return "", "", false
+	}
+	return key[:i], key[i+1:], true
+}
+
+// hasProfiles reports whether c or any of its parents registered the --profile
+// flag.
+func (c *Command) hasProfiles() bool {
+	for p := c; p != nil; p = p.Parent {
+		if p.profileOptions != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveProfile sets the --profile flag from its environment variable or a
+// source if it was not specified on the command line and selects the profile
+// for the lookup of all other flags.
+func (c *argParser) resolveProfile(sources []Source) error {
+	var cmd *Command
+	for p := c.cmd; p != nil && cmd == nil; p = p.Parent {
+		if p.profileOptions != nil {
+			cmd = p
+		}
+	}
+	if cmd == nil {
+		return nil
+	}
+	idx := cmd.index()
+	for i, flag := range idx.flags {
+		if _, ok := flag.Value.(*profileOptions); !ok {
+			continue
+		}
+		if err := c.setUnset(sources, flag, idx.paths[i]); err != nil {
+			return err
+		}
+		opts, _ := c.value(flag).(*profileOptions)
+		if opts == nil || opts.name == "" {
+			return nil
+		}
+		c.profile = opts.name
+		return c.checkProfile(sources, flag)
+	}
+	return nil
+}
+
+// checkProfile returns an error if the selected profile is not defined by any
+// configuration file of sources.
+func (c *argParser) checkProfile(sources []Source, flag *Flag) error {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		cfg, ok := src.(*configFileSource)
+		if !ok {
+			continue
+		}
+		doc, err := cfg.load()
+		if err != nil {
+			return wrapArgErr(sourceErr(src, err), c.cmd, flag, "")
+		}
+		for _, key := range doc.keys {
+			if name, _, ok := splitProfilePath(key); ok && !seen[name] {
+				if name == c.profile {
+					return nil
+				}
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if len(seen) == 0 && !hasConfigFile(sources) {
+		return nil
+	}
+	err := newArgErr(c.cmd, flag, c.profile, "unknown profile: %s", c.profile)
+	err.Suggestions = suggest(c.profile, names)
+	return err
+}
+
+// hasConfigFile reports whether any of sources was created by ConfigFile.
+func hasConfigFile(sources []Source) bool {
+	for _, src := range sources {
+		if _, ok := src.(*configFileSource); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigSearchPaths returns the paths of the configuration file with the
+// given name for the named program, in order of precedence. On Unix-like
+// systems, following the XDG Base Directory Specification, these are:
+//
+//  1. $XDG_CONFIG_HOME/app/name, or ~/.config/app/name if XDG_CONFIG_HOME is
+//     not set
+//  2. DIR/app/name for each DIR in $XDG_CONFIG_DIRS, or /etc/xdg/app/name if
+//     XDG_CONFIG_DIRS is not set
+//  3. /etc/app/name
+//  4. name in the directory of the executable
+//
+// On Windows, these are:
+//
+//  1. %APPDATA%\app\name
+//  2. %ProgramData%\app\name
+//  3. name in the directory of the executable
+//
+// The paths may be passed to ConfigFile to read configuration that is layered
+// from all of the files that exist, with values in user configuration taking
+// precedence over system configuration:
+//
+//	for _, path := range xflags.ConfigSearchPaths("app", "config.yaml") {
+//		cmd.Sources(xflags.ConfigFile(path))
+//	}
+//
+// Use FindConfigFile to read only the file with the highest precedence.
+func ConfigSearchPaths(app, name string) []string {
+	dirs := configDirs()
+	paths := make([]string, 0, len(dirs)+1)
+	for _, dir := range dirs {
+		paths = append(paths, filepath.Join(dir, app, name))
+	}
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), name))
+	}
+	return paths
+}
+
+// FindConfigFile returns the first path returned by ConfigSearchPaths at which
+// a regular file exists, or false if there is no such file.
+func FindConfigFile(app, name string) (string, bool) {
+	for _, path := range ConfigSearchPaths(app, name) {
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			return path, true
+		}
+	}
+	return "", false
+}
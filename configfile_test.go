@@ -0,0 +1,325 @@
+package xflags
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// writeConfigTestFile writes a configuration file to a temporary directory
+// and returns its path.
+func writeConfigTestFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type configFileTestFlags struct {
+	region   string
+	verbose  bool
+	replicas int
+	timeout  time.Duration
+	tags     []string
+}
+
+func newConfigFileCommand(flags *configFileTestFlags, sources ...Source) *CommandBuilder {
+	return NewCommand("app", "").
+		Sources(sources...).
+		Flags(
+			String(&flags.region, "region", "us-east-1", ""),
+			Bool(&flags.verbose, "verbose", false, ""),
+		).
+		Subcommands(
+			NewCommand("deploy", "").
+				Flags(
+					Int(&flags.replicas, "replicas", 1, ""),
+					Duration(&flags.timeout, "timeout", 0, ""),
+					Strings(&flags.tags, "tag", nil, ""),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+		)
+}
+
+func TestConfigFile(t *testing.T) {
+	tests := map[string]string{
+		"app.json": `{
+			"region": "eu-west-1",
+			"verbose": true,
+			"deploy": {"replicas": 3, "timeout": "1m", "tag": ["a", "b"]}
+		}`,
+		"app.yaml": `---
+# comment
+region: eu-west-1 # trailing comment
+verbose: true
+deploy:
+  replicas: 3
+  timeout: "1m"
+  tag:
+    - a
+    - 'b'
+`,
+		"app.yml": `
+region: "eu-west-1"
+verbose: true
+deploy.replicas: 3
+deploy:
+  timeout: 1m
+  tag: [a, "b"]
+`,
+		"app.toml": `
+# comment
+region = "eu-west-1" # trailing comment
+verbose = true
+
+[deploy]
+replicas = 3
+timeout = '1m'
+tag = [
+  "a",
+  "b",
+]
+`,
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			var flags configFileTestFlags
+			src := ConfigFile(writeConfigTestFile(t, name, contents))
+			cmd := newConfigFileCommand(&flags, src).Must()
+			if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+				t.Fatal(err)
+			}
+			assertString(t, "eu-west-1", flags.region)
+			assertBool(t, true, flags.verbose)
+			assertInt64(t, 3, int64(flags.replicas))
+			assertDuration(t, time.Minute, flags.timeout)
+			assertStrings(t, []string{"a", "b"}, flags.tags)
+		})
+	}
+}
+
+func TestConfigFileMissing(t *testing.T) {
+	var flags configFileTestFlags
+	src := ConfigFile(filepath.Join(t.TempDir(), "app.yaml"))
+	cmd := newConfigFileCommand(&flags, src).Must()
+	if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "us-east-1", flags.region)
+}
+
+func TestConfigFileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/app.yaml": {Data: []byte("region: eu-west-1\ndeploy:\n  replicas: 3\n")},
+	}
+	var flags configFileTestFlags
+	cmd := newConfigFileCommand(
+		&flags,
+		ConfigFileFS(fsys, "etc/missing.yaml"),
+		ConfigFileFS(fsys, "etc/app.yaml"),
+	).Must()
+	if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "eu-west-1", flags.region)
+	assertInt64(t, 3, int64(flags.replicas))
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	tests := map[string]string{
+		"app.ini":  "region = eu-west-1",
+		"app.json": `["region"]`,
+		"app.yaml": "region: eu-west-1\n  bad: indent\n",
+		"app.yml":  "deploy:\n  tag:\n    - [a, b]\n",
+		"app.toml": "region = eu-west-1\n",
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			var flags configFileTestFlags
+			src := ConfigFile(writeConfigTestFile(t, name, contents))
+			cmd := newConfigFileCommand(&flags, src).Must()
+			_, err := cmd.Parse([]string{"deploy"})
+			assertErrorAs(t, err, new(*ArgumentError))
+		})
+	}
+}
+
+func TestConfigInitRoundTrip(t *testing.T) {
+	for _, format := range []string{ConfigFormatYAML, ConfigFormatTOML} {
+		t.Run(format, func(t *testing.T) {
+			var flags configFileTestFlags
+			stdout := new(bytes.Buffer)
+			code := newConfigFileCommand(&flags).
+				Output(stdout, new(bytes.Buffer)).
+				ConfigInit().
+				Must().
+				Run([]string{"config", "init", "--format", format})
+			assertInt64(t, 0, int64(code))
+
+			path := writeConfigTestFile(t, "app."+format, stdout.String())
+			cmd := newConfigFileCommand(&flags, ConfigFile(path)).StrictConfig().Must()
+			if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+				t.Fatalf("%v\n%s", err, stdout)
+			}
+			assertString(t, "us-east-1", flags.region)
+			assertInt64(t, 1, int64(flags.replicas))
+		})
+	}
+}
+
+func TestStrictConfig(t *testing.T) {
+	path := writeConfigTestFile(t, "app.yaml", `
+regoin: eu-west-1
+deploy:
+  replicas: 3
+  tags: [a]
+  unknown: true
+`)
+
+	// unknown keys are ignored by default
+	var flags configFileTestFlags
+	cmd := newConfigFileCommand(&flags, ConfigFile(path)).Must()
+	if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 3, int64(flags.replicas))
+
+	cmd = newConfigFileCommand(&flags, ConfigFile(path)).StrictConfig().Must()
+	_, err := cmd.Parse([]string{"deploy"})
+	var keyErr *ConfigKeyError
+	assertErrorAs(t, err, &keyErr)
+	assertStrings(t, []string{"regoin", "deploy.tags", "deploy.unknown"}, keyErr.Keys)
+	assertStrings(t, []string{"region"}, keyErr.Suggestions[0])
+	assertStrings(t, []string{"deploy.tag"}, keyErr.Suggestions[1])
+	assertStrings(t, nil, keyErr.Suggestions[2])
+	assertString(
+		t,
+		`xflags: `+path+`: unknown configuration keys: "regoin" (did you mean "region"?), `+
+			`"deploy.tags" (did you mean "deploy.tag"?), "deploy.unknown"`,
+		err.Error(),
+	)
+}
+
+func TestConfigProfiles(t *testing.T) {
+	path := writeConfigTestFile(t, "app.toml", `
+region = "us-east-1"
+
+[deploy]
+replicas = 1
+tag = ["a"]
+
+[profiles.staging]
+region = "eu-west-1"
+
+[profiles.staging.deploy]
+replicas = 2
+
+[profiles.production.deploy]
+replicas = 5
+`)
+	newCmd := func(flags *configFileTestFlags) *Command {
+		return newConfigFileCommand(flags, ConfigFile(path)).
+			ConfigProfiles("APP_PROFILE").
+			StrictConfig().
+			Must()
+	}
+
+	var flags configFileTestFlags
+	if _, err := newCmd(&flags).Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "us-east-1", flags.region)
+	assertInt64(t, 1, int64(flags.replicas))
+
+	flags = configFileTestFlags{}
+	if _, err := newCmd(&flags).Parse([]string{"--profile", "staging", "deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "eu-west-1", flags.region)
+	assertInt64(t, 2, int64(flags.replicas))
+	assertStrings(t, []string{"a"}, flags.tags)
+
+	// the environment variable selects the default profile
+	t.Setenv("APP_PROFILE", "production")
+	flags = configFileTestFlags{}
+	if _, err := newCmd(&flags).Parse([]string{"deploy", "--replicas", "7"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "us-east-1", flags.region)
+	assertInt64(t, 7, int64(flags.replicas))
+
+	// profiles also apply to invocations
+	inv, err := newCmd(&flags).ParseInvocation([]string{"deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 5, inv.Get("replicas").(int64))
+
+	_, err = newCmd(&flags).Parse([]string{"--profile", "stagign", "deploy"})
+	var argErr *ArgumentError
+	assertErrorAs(t, err, &argErr)
+	assertStrings(t, []string{"staging"}, argErr.Suggestions)
+}
+
+func TestConfigProfilesStrict(t *testing.T) {
+	path := writeConfigTestFile(t, "app.yaml", `
+profiles:
+  staging:
+    deploy:
+      replicsa: 2
+`)
+	var flags configFileTestFlags
+	cmd := newConfigFileCommand(&flags, ConfigFile(path)).
+		ConfigProfiles("").
+		StrictConfig().
+		Must()
+	_, err := cmd.Parse([]string{"deploy"})
+	var keyErr *ConfigKeyError
+	assertErrorAs(t, err, &keyErr)
+	assertStrings(t, []string{"profiles.staging.deploy.replicsa"}, keyErr.Keys)
+	assertStrings(t, []string{"profiles.staging.deploy.replicas"}, keyErr.Suggestions[0])
+}
+
+func TestConfigSearchPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("XDG directories are not used on Windows")
+	}
+	home, system := t.TempDir(), t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("XDG_CONFIG_DIRS", system+string(filepath.ListSeparator)+"relative")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{
+		filepath.Join(home, "app", "config.yaml"),
+		filepath.Join(system, "app", "config.yaml"),
+		"/etc/app/config.yaml",
+		filepath.Join(filepath.Dir(exe), "config.yaml"),
+	}, ConfigSearchPaths("app", "config.yaml"))
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CONFIG_DIRS", "")
+	t.Setenv("HOME", home)
+	paths := ConfigSearchPaths("app", "config.yaml")
+	assertString(t, filepath.Join(home, ".config", "app", "config.yaml"), paths[0])
+	assertString(t, "/etc/xdg/app/config.yaml", paths[1])
+
+	_, ok := FindConfigFile("app", "config.yaml")
+	assertBool(t, false, ok)
+	path := filepath.Join(home, ".config", "app", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	found, ok := FindConfigFile("app", "config.yaml")
+	assertBool(t, true, ok)
+	assertString(t, path, found)
+}
@@ -0,0 +1,140 @@
+package xflags
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// confirmOptions are the prompt and the value of the flag registered by
+// Confirm.
+type confirmOptions struct {
+	prompt func(inv *Invocation) string // nil if handlers prompt themselves
+	yes    *boolValue
+}
+
+// Confirm specifies that users must confirm each invocation of this command
+// by answering a y/N prompt before the handler is called, which is useful for
+// destructive commands. The prompt is formatted with fmt.Sprintf when it is
+// shown. Use ConfirmFunc for prompts that depend on the invocation, such as
+// the number of resources that will be deleted.
+//
+// The --yes (-y) flag is registered to skip the prompt. If stdin is not a
+// terminal and --yes is not specified, the command fails without prompting.
+//
+// If prompt is empty, only the --yes flag is registered and handlers may
+// prompt for confirmation at any time with the Confirm function.
+func (c *CommandBuilder) Confirm(prompt string, a ...interface{}) *CommandBuilder {
+	if prompt == "" {
+		return c.confirm(nil)
+	}
+	return c.confirm(func(inv *Invocation) string {
+		if len(a) == 0 {
+			return prompt
+		}
+		return fmt.Sprintf(prompt, a...)
+	})
+}
+
+// ConfirmFunc is like Confirm but calls fn with the invocation to get the
+// prompt when it is shown:
+//
+//	ConfirmFunc(func(inv *xflags.Invocation) string {
+//		return fmt.Sprintf("This will delete %d resources. Continue?", len(inv.Args()))
+//	})
+func (c *CommandBuilder) ConfirmFunc(fn func(inv *Invocation) string) *CommandBuilder {
+	if fn == nil {
+		return c.error(errorf("%s: nil confirmation prompt", c.cmd.Name))
+	}
+	return c.confirm(fn)
+}
+
+func (c *CommandBuilder) confirm(prompt func(inv *Invocation) string) *CommandBuilder {
+	opts := &confirmOptions{prompt: prompt, yes: newBoolValue(false, nil)}
+	c.cmd.confirmOptions = opts
+	return c.Flags(
+		Var(opts.yes, "yes", "Do not prompt for confirmation").
+			ShortName("y").
+			builtin(),
+	)
+}
+
+// Input sets the source of user input for prompts. By default, os.Stdin is
+// used.
+func (c *CommandBuilder) Input(stdin io.Reader) *CommandBuilder {
+	c.cmd.Stdin = stdin
+	return c
+}
+
+// Confirm prompts the user of the command invocation that ctx was created for
+// to answer y/N and reports whether they answered yes. The prompt is formatted
+// with fmt.Sprintf. Confirm returns true without prompting if --yes was
+// specified for a command configured with CommandBuilder.Confirm. It returns
+// false if stdin is not a terminal.
+func Confirm(ctx context.Context, format string, a ...interface{}) bool {
+	out := OutputFrom(ctx)
+	if out.assumeYes {
+		return true
+	}
+	return prompt(out, fmt.Sprintf(format, a...))
+}
+
+// prompt prints a y/N prompt to stderr and reads the answer from stdin.
+func prompt(out *Output, s string) bool {
+	if f, ok := out.Stdin.(*os.File); ok && !isTerminal(f) {
+		return false
+	}
+	fmt.Fprintf(out.Stderr, "%s [y/N] ", s)
+	line, err := bufio.NewReader(out.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(out.Stderr)
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// assumeYes reports whether --yes was specified for inv, an invocation of c.
+func (c *Command) assumeYes(inv *Invocation) bool {
+	for p := c; p != nil; p = p.Parent {
+		if opts := p.confirmOptions; opts != nil {
+			return bool(*inv.parser.valueOf(opts.yes).(*boolValue))
+		}
+	}
+	return false
+}
+
+// confirm prompts for confirmation of inv, an invocation of c, if c was
+// configured with CommandBuilder.Confirm and reports whether the handler may
+// be called.
+func (c *Command) confirm(inv *Invocation, out *Output) bool {
+	opts := c.confirmOptions
+	if opts == nil || opts.prompt == nil || out.assumeYes {
+		return true
+	}
+	if f, ok := out.Stdin.(*os.File); ok && !isTerminal(f) {
+		fmt.Fprintf(out.Stderr, "Error: %s: confirmation required; specify --yes to continue\n", c.Name)
+		return false
+	}
+	if !prompt(out, opts.prompt(inv)) {
+		fmt.Fprintln(out.Stderr, "Aborted")
+		return false
+	}
+	return true
+}
+
+// input returns stdin, inheriting from parents and defaulting to os.Stdin.
+func (c *Command) input() io.Reader {
+	for p := c; p != nil; p = p.Parent {
+		if p.Stdin != nil {
+			return p.Stdin
+		}
+	}
+	return os.Stdin
+}
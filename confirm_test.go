@@ -0,0 +1,107 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		Args    []string
+		Input   string
+		Called  bool
+		Prompts int
+	}{
+		{nil, "y\n", true, 1},
+		{nil, "YES\n", true, 1},
+		{nil, "n\n", false, 1},
+		{nil, "\n", false, 1},
+		{nil, "", false, 1},
+		{[]string{"-y"}, "", true, 0},
+		{[]string{"--yes"}, "", true, 0},
+	}
+	for _, test := range tests {
+		called := false
+		stderr := new(bytes.Buffer)
+		cmd := NewCommand("delete", "").
+			Input(strings.NewReader(test.Input)).
+			Output(new(bytes.Buffer), stderr).
+			Confirm("This will delete %d resources. Continue?", 3).
+			HandleFunc(func(args []string) int {
+				called = true
+				return 0
+			}).
+			Must()
+		code := cmd.Run(test.Args)
+		assertBool(t, test.Called, called)
+		if !test.Called && code == 0 {
+			t.Errorf("%q: expected non-zero exit code", test.Args)
+		}
+		if n := strings.Count(stderr.String(), "This will delete 3 resources. Continue? [y/N] "); n != test.Prompts {
+			t.Errorf("%q: expected %d prompts, got: %q", test.Args, test.Prompts, stderr)
+		}
+	}
+}
+
+func TestConfirmFunc(t *testing.T) {
+	for _, args := range [][]string{nil, {"--yes"}} {
+		var confirmed bool
+		stderr := new(bytes.Buffer)
+		cmd := NewCommand("delete", "").
+			Input(strings.NewReader("n\n")).
+			Output(new(bytes.Buffer), stderr).
+			Confirm("").
+			HandleContext(func(ctx context.Context, args []string) int {
+				confirmed = Confirm(ctx, "Delete %d?", 5)
+				return 0
+			}).
+			Must()
+		if code := cmd.Run(args); code != 0 {
+			t.Fatalf("unexpected exit code: %d", code)
+		}
+		assertBool(t, len(args) > 0, confirmed)
+		if len(args) == 0 {
+			assertString(t, "Delete 5? [y/N] ", stderr.String())
+		}
+	}
+}
+
+func TestConfirmExec(t *testing.T) {
+	called := false
+	cmd := NewCommand("delete", "").
+		Flags(Strings(nil, "name", nil, "").Positional()).
+		ConfirmFunc(func(inv *Invocation) string {
+			return fmt.Sprintf("This will delete %d resources. Continue?", len(inv.Values("name")))
+		}).
+		HandleFunc(func(args []string) int {
+			called = true
+			return 0
+		}).
+		Must()
+	tests := []struct {
+		Args   []string
+		Input  string
+		Called bool
+	}{
+		{[]string{"a", "b"}, "n\n", false},
+		{[]string{"a", "b"}, "y\n", true},
+		{[]string{"--yes", "a", "b"}, "", true},
+	}
+	for _, test := range tests {
+		called = false
+		var stdout, stderr bytes.Buffer
+		code := cmd.Exec(context.Background(), test.Args, strings.NewReader(test.Input), &stdout, &stderr)
+		assertBool(t, test.Called, called)
+		if !test.Called && code == 0 {
+			t.Errorf("%q: expected non-zero exit code", test.Args)
+		}
+		prompted := strings.Contains(stderr.String(), "This will delete 2 resources. Continue? [y/N] ")
+		assertBool(t, test.Input != "", prompted)
+	}
+	if _, err := NewCommand("delete", "").ConfirmFunc(nil).Command(); err == nil {
+		t.Errorf("expected error for nil prompt function")
+	}
+}
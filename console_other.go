@@ -0,0 +1,9 @@
+//go:build !windows
+
+package xflags
+
+import "io"
+
+// enableVirtualTerminal reports whether w processes ANSI escape sequences,
+// which all terminals other than the consoles of Windows do.
+func enableVirtualTerminal(w io.Writer) bool { return isTerminal(w) }
@@ -0,0 +1,38 @@
+//go:build windows
+
+package xflags
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+// enableVirtualTerminalProcessing is the console mode that interprets ANSI
+// escape sequences, such as those that set colors or clear the screen.
+const enableVirtualTerminalProcessing = 0x0004
+
+// enableVirtualTerminal enables the processing of ANSI escape sequences by the
+// console of w, if w is a console, and reports whether they are processed.
+// Consoles before Windows 10 do not support them.
+func enableVirtualTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	h := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	r, _, _ := procSetConsoleMode.Call(uintptr(h), uintptr(mode|enableVirtualTerminalProcessing))
+	return r != 0
+}
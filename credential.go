@@ -0,0 +1,246 @@
+package xflags
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CredentialSource is a Source that can also store and delete values, such as
+// the credential store of the operating system returned by Keyring.
+//
+// Values are stored at the path of a flag, as described by Source.
+type CredentialSource interface {
+	Source
+
+	// Store stores the value of the flag at the given path, replacing any
+	// existing value.
+	Store(flagPath, value string) error
+
+	// Delete deletes the value of the flag at the given path. It is not an
+	// error if the source has no value for the flag.
+	Delete(flagPath string) error
+}
+
+// authCommandName is the name of the subcommand added by Credentials.
+const authCommandName = "auth"
+
+// credentialOptions is the configuration of Credentials.
+type credentialOptions struct {
+	src        CredentialSource
+	deviceAuth *DeviceAuthConfig // set by DeviceAuth
+}
+
+// Credentials resolves the values of secret flags of this command and its
+// subcommands that were not specified on the command line, in the environment
+// or by another source, from src. See FlagBuilder.Secret.
+//
+// Credentials also adds an "auth" subcommand with the following subcommands:
+//
+//   - "auth login [FLAG...]" prompts for the value of each secret flag, or of
+//     the flags at the given paths, and stores it in src. Values are read
+//     from stdin, one per line, so that they may also be piped. A blank line
+//     skips a flag.
+//   - "auth logout [FLAG...]" deletes the stored values.
+//   - "auth status" lists the path of each secret flag and whether a value is
+//     stored.
+//
+// If DeviceAuth is also used, "auth login" without arguments logs in with the
+// OAuth 2.0 device authorization grant instead and "auth logout" and
+// "auth status" also include the token.
+//
+// Since the "auth" command is a subcommand, the command cannot have
+// positional arguments.
+func (c *CommandBuilder) Credentials(src CredentialSource) *CommandBuilder {
+	if src == nil {
+		return c.error(errorf("%s: nil credential source", c.cmd.Name))
+	}
+	c.cmd.credentialOptions = &credentialOptions{src: src}
+	return c.Subcommands(newAuthCommand())
+}
+
+// lookupCredential returns the value of a secret flag from the credential
+// source of the current command or its nearest parent.
+func (c *argParser) lookupCredential(flag *Flag, path string) ([]string, Source, bool, error) {
+	for p := c.cmd; p != nil; p = p.Parent {
+		if p.credentialOptions == nil {
+			continue
+		}
+		src := p.credentialOptions.src
+		values, ok, err := lookupValues(src, path)
+		if err != nil {
+			return nil, src, false, wrapArgErr(sourceErr(src, err), c.cmd, flag, "")
+		}
+		return values, src, ok, nil
+	}
+	return nil, nil, false, nil
+}
+
+// secretPaths returns the paths of the secret flags of cmd and its
+// subcommands, except the "auth" command.
+func secretPaths(cmd *Command) []string {
+	paths := make([]string, 0)
+	idx := cmd.index()
+	for i, flag := range idx.flags {
+		if flag.Secret {
+			paths = append(paths, idx.paths[i])
+		}
+	}
+	for _, sub := range cmd.Subcommands {
+		if sub.Name == authCommandName && sub.Parent == cmd {
+			continue
+		}
+		paths = append(paths, secretPaths(sub)...)
+	}
+	return paths
+}
+
+func newAuthCommand() *CommandBuilder {
+	// flagArgs returns the FLAG arguments of the "login" or "logout" command, as
+	// parsed for the current invocation.
+	flagArgs := func(ctx context.Context) []string {
+		paths, _ := InvocationFrom(ctx).Get("FLAG").([]string)
+		return paths
+	}
+	// selected returns the paths of the secret flags selected by paths.
+	selected := func(root *Command, paths []string) ([]string, error) {
+		all := secretPaths(root)
+		if len(paths) == 0 {
+			return all, nil
+		}
+		for _, path := range paths {
+			found := false
+			for _, s := range all {
+				found = found || s == path
+			}
+			if !found {
+				return nil, errorf("not a secret flag: %s", path)
+			}
+		}
+		return paths, nil
+	}
+	pathsFlag := func() *FlagBuilder {
+		var paths []string
+		return Strings(&paths, "FLAG", nil, "Path of a secret flag, such as \"deploy.token\"").
+			Positional().
+			NArgs(0, 0).
+			builtin()
+	}
+	// root returns the command that enabled Credentials.
+	root := func(ctx context.Context) *Command {
+		return InvocationFrom(ctx).Target().Parent.Parent
+	}
+	return NewCommand(authCommandName, "Manage stored credentials").
+		Subcommands(
+			NewCommand("login", "Store the values of secret flags").
+				Flags(pathsFlag()).
+				HandleE(func(ctx context.Context, args []string) error {
+					cmd, paths := root(ctx), flagArgs(ctx)
+					if cfg := cmd.credentialOptions.deviceAuth; cfg != nil && len(paths) == 0 {
+						return deviceLogin(ctx, cfg, cmd.credentialOptions.src)
+					}
+					flagPaths, err := selected(cmd, paths)
+					if err != nil {
+						return err
+					}
+					out := OutputFrom(ctx)
+					r := bufio.NewReader(out.Stdin)
+					for _, path := range flagPaths {
+						value, err := readSecret(out, r, path+": ")
+						if err != nil {
+							return err
+						}
+						if value == "" {
+							continue
+						}
+						if err := cmd.credentialOptions.src.Store(path, value); err != nil {
+							return sourceErr(cmd.credentialOptions.src, err)
+						}
+					}
+					return nil
+				}),
+			NewCommand("logout", "Delete the stored values of secret flags").
+				Flags(pathsFlag()).
+				HandleE(func(ctx context.Context, args []string) error {
+					cmd, paths := root(ctx), flagArgs(ctx)
+					flagPaths, err := selected(cmd, paths)
+					if err != nil {
+						return err
+					}
+					if cfg := cmd.credentialOptions.deviceAuth; cfg != nil && len(paths) == 0 {
+						flagPaths = append(flagPaths, cfg.TokenPath)
+					}
+					for _, path := range flagPaths {
+						if err := cmd.credentialOptions.src.Delete(path); err != nil {
+							return sourceErr(cmd.credentialOptions.src, err)
+						}
+					}
+					return nil
+				}),
+			NewCommand("status", "Show which secret flags have a stored value").
+				HandleE(func(ctx context.Context, args []string) error {
+					cmd := root(ctx)
+					src := cmd.credentialOptions.src
+					aw := newAggregatedWriter(OutputFrom(ctx).Stdout)
+					statusPaths := secretPaths(cmd)
+					if cfg := cmd.credentialOptions.deviceAuth; cfg != nil {
+						statusPaths = append(statusPaths, cfg.TokenPath)
+					}
+					for _, path := range statusPaths {
+						_, ok, err := lookup(src, path)
+						if err != nil {
+							return sourceErr(src, err)
+						}
+						status := "not stored"
+						if ok {
+							status = "stored"
+						}
+						fmt.Fprintf(aw, "%s: %s\n", path, status)
+					}
+					return aw.Err()
+				}),
+		)
+}
+
+// readSecret prints a prompt to stderr and reads a line from r, which reads
+// the stdin of out. If stdin is a terminal, input is not echoed while it is
+// read. A blank line or the end of input returns an empty string.
+func readSecret(out *Output, r *bufio.Reader, prompt string) (string, error) {
+	f, ok := out.Stdin.(*os.File)
+	if ok && isTerminal(f) {
+		fmt.Fprint(out.Stderr, prompt)
+		if restore := disableEcho(f); restore != nil {
+			defer func() {
+				restore()
+				fmt.Fprintln(out.Stderr)
+			}()
+		}
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// disableEcho disables echoing of input on the terminal f with stty and
+// returns a function that enables it again, or nil if it cannot be disabled.
+func disableEcho(f *os.File) (restore func()) {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = f
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return nil
+	}
+	return func() { _ = stty("echo") }
+}
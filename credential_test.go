@@ -0,0 +1,125 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// memCredentials is a CredentialSource that stores values in memory.
+type memCredentials map[string]string
+
+func (c memCredentials) Name() string { return "memory" }
+
+func (c memCredentials) Lookup(flagPath string) (string, bool) {
+	s, ok := c[flagPath]
+	return s, ok
+}
+
+func (c memCredentials) Store(flagPath, value string) error {
+	c[flagPath] = value
+	return nil
+}
+
+func (c memCredentials) Delete(flagPath string) error {
+	delete(c, flagPath)
+	return nil
+}
+
+// credentialTestFlags are the values of the flags of newCredentialTestCommand.
+type credentialTestFlags struct {
+	token, user, key string
+}
+
+func newCredentialTestCommand(creds memCredentials, stdin string, stdout *bytes.Buffer) *Command {
+	return newCredentialTestCommandVars(creds, stdin, stdout, &credentialTestFlags{})
+}
+
+func newCredentialTestCommandVars(
+	creds memCredentials,
+	stdin string,
+	stdout *bytes.Buffer,
+	v *credentialTestFlags,
+) *Command {
+	return NewCommand("app", "").
+		Input(strings.NewReader(stdin)).
+		Output(stdout, new(bytes.Buffer)).
+		Credentials(creds).
+		Flags(
+			String(&v.token, "token", "", "").Secret(),
+			String(&v.user, "user", "", ""),
+		).
+		Subcommands(
+			NewCommand("deploy", "").
+				Flags(String(&v.key, "key", "", "").Secret()).
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+}
+
+func TestCredentials(t *testing.T) {
+	creds := memCredentials{"token": "t0k3n", "user": "alice", "deploy.key": "k3y"}
+	v := &credentialTestFlags{}
+	_, err := newCredentialTestCommandVars(creds, "", new(bytes.Buffer), v).Parse([]string{"deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "t0k3n", v.token)
+	assertString(t, "k3y", v.key)
+	assertString(t, "", v.user) // not secret
+
+	// the command line takes precedence
+	v = &credentialTestFlags{}
+	_, err = newCredentialTestCommandVars(creds, "", new(bytes.Buffer), v).
+		Parse([]string{"--token", "other", "deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "other", v.token)
+}
+
+func TestAuthCommand(t *testing.T) {
+	creds := memCredentials{}
+	code := newCredentialTestCommand(creds, "t0k3n\n\n", new(bytes.Buffer)).
+		Run([]string{"auth", "login"})
+	assertInt64(t, 0, int64(code))
+	assertString(t, "t0k3n", creds["token"])
+	if _, ok := creds["deploy.key"]; ok {
+		t.Error("expected blank line to skip deploy.key")
+	}
+
+	code = newCredentialTestCommand(creds, "k3y\n", new(bytes.Buffer)).
+		Run([]string{"auth", "login", "deploy.key"})
+	assertInt64(t, 0, int64(code))
+	assertString(t, "k3y", creds["deploy.key"])
+
+	stdout := new(bytes.Buffer)
+	code = newCredentialTestCommand(creds, "", new(bytes.Buffer)).
+		Run([]string{"auth", "logout", "token"})
+	assertInt64(t, 0, int64(code))
+	code = newCredentialTestCommand(creds, "", stdout).Run([]string{"auth", "status"})
+	assertInt64(t, 0, int64(code))
+	assertString(t, "token: not stored\ndeploy.key: stored\n", stdout.String())
+
+	code = newCredentialTestCommand(creds, "", new(bytes.Buffer)).
+		Run([]string{"auth", "login", "user"})
+	assertInt64(t, 1, int64(code))
+}
+
+func TestAuthCommandExec(t *testing.T) {
+	creds := memCredentials{"token": "t0k3n", "deploy.key": "k3y"}
+	cmd := newCredentialTestCommand(creds, "", new(bytes.Buffer))
+	ctx := context.Background()
+	code := cmd.Exec(ctx, []string{"auth", "logout", "deploy.key"}, nil, nil, nil)
+	assertInt64(t, 0, int64(code))
+	assertString(t, "t0k3n", creds["token"])
+	if _, ok := creds["deploy.key"]; ok {
+		t.Error("expected deploy.key to be deleted")
+	}
+
+	// the arguments of one invocation do not select flags for the next
+	code = cmd.Exec(ctx, []string{"auth", "login"}, strings.NewReader("n3w\n\n"), nil, nil)
+	assertInt64(t, 0, int64(code))
+	assertString(t, "n3w", creds["token"])
+}
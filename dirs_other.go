@@ -0,0 +1,43 @@
+//go:build !windows
+
+package xflags
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// configDirs returns the directories that contain a subdirectory with the
+// configuration files of each program, in order of precedence, following the
+// XDG Base Directory Specification.
+func configDirs() []string {
+	dirs := make([]string, 0, 3)
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		dirs = append(dirs, dir)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+	systemDirs := filepath.SplitList(os.Getenv("XDG_CONFIG_DIRS"))
+	if len(systemDirs) == 0 {
+		systemDirs = []string{"/etc/xdg"}
+	}
+	for _, dir := range systemDirs {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return append(dirs, "/etc")
+}
+
+// stateHome returns the directory that contains a subdirectory with the state
+// of each program, following the XDG Base Directory Specification.
+func stateHome() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state"), nil
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package xflags
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// configDirs returns the directories that contain a subdirectory with the
+// configuration files of each program, in order of precedence: the roaming
+// application data directory of the user and the application data directory
+// that is shared by all users.
+func configDirs() []string {
+	dirs := make([]string, 0, 2)
+	for _, name := range []string{"APPDATA", "ProgramData"} {
+		if dir := os.Getenv(name); filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// stateHome returns the directory that contains a subdirectory with the state
+// of each program: the local application data directory of the user.
+func stateHome() (string, error) {
+	if dir := os.Getenv("LOCALAPPDATA"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	return "", errorf("%%LOCALAPPDATA%% is not defined")
+}
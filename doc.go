@@ -96,5 +96,20 @@ The noted forms are not permitted for boolean flags because of the meaning of th
 	cmd -x *
 
 where * is a Unix shell wildcard, will change if there is a file called 0, false, etc.
+Commands may opt in to "--flag true" and "--flag false" with CommandBuilder.BoolSyntax and
+BoolSeparate. The form used for each boolean flag is reported by Command.BoolForm.
+
+Parsing modes
+
+By default, the parser guarantees that flags are matched by their exact names, never by
+abbreviations, that short flags are never grouped, so "-vx" is the flag -v with the value "x", and
+that flags and positional arguments may be interleaved. Flags of a subcommand may also precede its
+name, as in "app --replicas 3 deploy", and are set when the parser reaches the subcommand. Behaviors that programs opt in to, such as
+BoolSeparate, PrefixShortNames, aliases defined by users and decimal commas that depend on the
+locale of the user, may change how a command line is parsed.
+
+Scripts that must parse identically across versions and machines may be served by commands that
+call CommandBuilder.POSIXStrict, which disables those behaviors and requires options to precede
+operands and to follow the name of the command that declares them.
 */
 package xflags
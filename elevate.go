@@ -0,0 +1,126 @@
+package xflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Command returns the normalized command line of the invocation, starting
+// with the name of the root command, followed by the names of each
+// subcommand and each flag, positional argument and operator in the order it
+// was specified. Flags are written as "--name=value" or "-n=value" if the flag
+// has no name, so that values which begin with a dash are preserved. Values
+// read from environment variables or sources are not included.
+//
+// The returned command line may be passed to the root command to repeat the
+// invocation, or quoted with QuoteArgs to print it.
+func (c *Invocation) Command() []string {
+	return c.command(false)
+}
+
+// command returns the normalized command line of the invocation. If redact is
+// true, the values of secret flags are masked and values that implement
+// redacter, such as DSNs, are redacted.
+func (c *Invocation) command(redact bool) []string {
+	argv := commandPath(c.cmd)
+	if c.parser == nil {
+		return append(argv, c.args...)
+	}
+	argv = argv[:1]
+	for _, entry := range c.parser.trace {
+		if redact && entry.Flag != nil {
+			if entry.Flag.Secret {
+				entry.Value = maskSecret(entry.Value)
+			} else if r, ok := entry.Flag.Value.(redacter); ok {
+				entry.Value = r.redact(entry.Value)
+			}
+		}
+		switch {
+		case entry.Command != nil:
+			argv = append(argv, entry.Command.Name)
+		case entry.Operator != "":
+			argv = append(argv, entry.Operator)
+		case entry.Flag != nil && entry.Flag.Positional:
+			argv = append(argv, entry.Value)
+		case entry.Flag != nil && entry.Flag.Name != "":
+			argv = append(argv, "--"+entry.Flag.Name+"="+entry.Value)
+		case entry.Flag != nil:
+			argv = append(argv, "-"+entry.Flag.ShortName+"="+entry.Value)
+		}
+	}
+	if c.parser.isTerminated {
+		argv = append(argv, terminator)
+		argv = append(argv, c.args...)
+	}
+	return argv
+}
+
+// errNoInvocation is returned by functions that require a context created by
+// Command.Handle.
+var errNoInvocation = errors.New("context has no invocation")
+
+// Elevate runs the current invocation again as a new process with
+// administrative privileges and returns its exit code. Handlers that require
+// privileges may call Elevate if IsElevated returns false:
+//
+//	func install(ctx context.Context, args []string) int {
+//	    if !xflags.IsElevated() {
+//	        return xflags.Elevate(ctx)
+//	    }
+//	    ...
+//	}
+//
+// The command line of the new process is the running executable followed by
+// Invocation.Command. On Windows, the user is prompted by User Account Control
+// and the new process runs in a new console window without the environment of
+// this process. On other platforms, the process is run with sudo, preserving
+// the environment variables of any flags that are set, and shares the
+// standard streams of the invocation.
+//
+// Any error is written to the standard error of the invocation.
+func Elevate(ctx context.Context) int {
+	out := OutputFrom(ctx)
+	inv := InvocationFrom(ctx)
+	if inv == nil {
+		fmt.Fprintf(out.Stderr, "Error: elevate: %v\n", errNoInvocation)
+		return 1
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(out.Stderr, "Error: elevate: %v\n", err)
+		return 1
+	}
+	cmd := elevateCommand(ctx, exe, inv.Command()[1:], inv.envVars())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = out.Stdin, out.Stdout, out.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(out.Stderr, "Error: elevate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// envVars returns the names of the environment variables of all flags of the
+// invocation that are set in the environment.
+func (c *Invocation) envVars() []string {
+	names := make([]string, 0)
+	for p := c.cmd; p != nil; p = p.Parent {
+		for _, group := range p.FlagGroups {
+			for _, flag := range group.Flags {
+				if flag.EnvVar == "" {
+					continue
+				}
+				if _, ok := os.LookupEnv(flag.EnvVar); ok {
+					names = append(names, flag.EnvVar)
+				}
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package xflags
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// IsElevated reports whether the process is running with administrative
+// privileges. On Windows, this means the process is elevated by User Account
+// Control. On other platforms, it means the effective user is root.
+func IsElevated() bool { return os.Geteuid() == 0 }
+
+// elevateCommand returns a command that runs exe with args as root.
+func elevateCommand(ctx context.Context, exe string, args, envVars []string) *exec.Cmd {
+	sudoArgs := make([]string, 0, len(args)+3)
+	if len(envVars) > 0 {
+		sudoArgs = append(sudoArgs, "--preserve-env="+strings.Join(envVars, ","))
+	}
+	sudoArgs = append(sudoArgs, "--", exe)
+	sudoArgs = append(sudoArgs, args...)
+	return exec.CommandContext(ctx, "sudo", sudoArgs...)
+}
@@ -0,0 +1,48 @@
+//go:build windows
+
+package xflags
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// IsElevated reports whether the process is running with administrative
+// privileges. On Windows, this means the process is elevated by User Account
+// Control. On other platforms, it means the effective user is root.
+func IsElevated() bool {
+	// only elevated processes may open a physical drive
+	f, err := os.Open(`\\.\PHYSICALDRIVE0`)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// elevateCommand returns a command that runs exe with args elevated by User
+// Account Control. The elevated process does not inherit the environment of
+// this process, so envVars is ignored.
+func elevateCommand(ctx context.Context, exe string, args, envVars []string) *exec.Cmd {
+	script := "$p = Start-Process -Verb RunAs -Wait -PassThru -FilePath " +
+		powerShellQuote(exe)
+	if len(args) > 0 {
+		script += " -ArgumentList " + powerShellQuote(quoteWindows(args))
+	}
+	script += "; exit $p.ExitCode"
+	return exec.CommandContext(
+		ctx,
+		"powershell.exe",
+		"-NoProfile",
+		"-NonInteractive",
+		"-Command",
+		script,
+	)
+}
+
+// powerShellQuote returns s as a single-quoted PowerShell string.
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
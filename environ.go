@@ -0,0 +1,36 @@
+package xflags
+
+import (
+	"os"
+	"strings"
+)
+
+// Environ sets the environment that the environment variables of flags are read
+// from, as a list of "key=value" strings, in place of the environment of the
+// process, for example to run a command in a sandbox, a test or a WebAssembly
+// host. Subcommands inherit the environment of their parents.
+func (c *CommandBuilder) Environ(env []string) *CommandBuilder {
+	if env == nil {
+		env = []string{}
+	}
+	c.cmd.Env = env
+	return c
+}
+
+// lookupEnv returns the value of the environment variable key from the
+// environment set with Environ, inheriting from parents and defaulting to the
+// environment of the process.
+func (c *Command) lookupEnv(key string) (string, bool) {
+	for p := c; p != nil; p = p.Parent {
+		if p.Env == nil {
+			continue
+		}
+		for i := len(p.Env) - 1; i >= 0; i-- {
+			if k, v, ok := strings.Cut(p.Env[i], "="); ok && k == key {
+				return v, true
+			}
+		}
+		return "", false
+	}
+	return os.LookupEnv(key)
+}
@@ -0,0 +1,24 @@
+package xflags
+
+import "testing"
+
+func TestEnviron(t *testing.T) {
+	t.Setenv("XFLAGS_TEST_REGION", "process")
+	t.Setenv("XFLAGS_TEST_NAME", "process")
+	var region, name string
+	cmd := NewCommand("app", "").
+		Environ([]string{"XFLAGS_TEST_REGION=a", "XFLAGS_TEST_REGION=eu-west-1"}).
+		Flags(
+			String(&region, "region", "us-east-1", "").Env("XFLAGS_TEST_REGION"),
+			String(&name, "name", "default", "").Env("XFLAGS_TEST_NAME"),
+		).
+		Subcommands(
+			NewCommand("deploy", "").HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "eu-west-1", region)
+	assertString(t, "default", name)
+}
@@ -2,7 +2,10 @@ package xflags
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 )
 
 type xflagsErr struct {
@@ -17,13 +20,13 @@ func (e *xflagsErr) Error() string { return "xflags: " + e.String() }
 func (e *xflagsErr) String() string {
 	w := new(bytes.Buffer)
 	if e.Text != "" {
-		fmt.Fprintf(w, e.Text)
+		w.WriteString(e.Text)
 	}
 	if e.Text != "" && e.Err != nil {
 		fmt.Fprintf(w, ": ")
 	}
 	if e.Err != nil {
-		fmt.Fprintf(w, ": %s", errStr(e.Err))
+		fmt.Fprintf(w, "%s", errStr(e.Err))
 	}
 	return w.String()
 }
@@ -32,10 +35,23 @@ func errorf(format string, a ...interface{}) error {
 	return &xflagsErr{Text: fmt.Sprintf(format, a...)}
 }
 
-// HelpError is the error returned if the -h or --help argument is specified
-// but no such flag is explicitly defined.
+// HelpError is the error returned if the -h, --help or --help-all argument is
+// specified but no such flag is explicitly defined.
 type HelpError struct {
-	Cmd *Command // The command that was invoked and produced this error.
+	Cmd   *Command // The command that was invoked and produced this error.
+	Brief bool     // Whether -h requested a brief help message.
+	All   bool     // Whether --help-all or --all after the help flag requested all flags.
+}
+
+// level returns the amount of detail requested for the help message.
+func (err *HelpError) level() helpLevel {
+	switch {
+	case err.All:
+		return helpAll
+	case err.Brief:
+		return helpBrief
+	}
+	return helpFull
 }
 
 func (err *HelpError) Error() string {
@@ -45,11 +61,18 @@ func (err *HelpError) Error() string {
 // ArgumentError indicates that an argument specified on the command line was
 // incorrect.
 type ArgumentError struct {
-	Text string
-	Err  error
-	Cmd  *Command
-	Flag *Flag
-	Arg  string
+	Text  string
+	Err   error
+	Cmd   *Command
+	Flag  *Flag
+	Arg   string
+	Index int    // Index of Token in the command line, or -1.
+	Token string // Command line argument that caused the error.
+
+	// Suggestions are possible corrections for a misspelled argument.
+	Suggestions []string
+
+	args []string // command line that was parsed
 }
 
 func (e *ArgumentError) Unwrap() error { return e.Err }
@@ -62,7 +85,7 @@ func (e *ArgumentError) String() string {
 		fmt.Fprintf(w, "%s: ", e.Flag)
 	}
 	if e.Text != "" {
-		fmt.Fprintf(w, e.Text)
+		w.WriteString(e.Text)
 	}
 	if e.Text != "" && e.Err != nil {
 		fmt.Fprintf(w, ": ")
@@ -70,6 +93,13 @@ func (e *ArgumentError) String() string {
 	if e.Err != nil {
 		fmt.Fprintf(w, "%s", errStr(e.Err))
 	}
+	switch len(e.Suggestions) {
+	case 0:
+	case 1:
+		fmt.Fprintf(w, " (did you mean %q?)", e.Suggestions[0])
+	default:
+		fmt.Fprintf(w, " (did you mean one of %s?)", quoteList(e.Suggestions))
+	}
 	return w.String()
 }
 
@@ -89,12 +119,101 @@ func newArgErr(
 }
 
 func wrapArgErr(err error, cmd *Command, flag *Flag, arg string) *ArgumentError {
-	return &ArgumentError{
-		Err:  err,
-		Cmd:  cmd,
-		Flag: flag,
-		Arg:  arg,
+	e := &ArgumentError{
+		Err:   err,
+		Cmd:   cmd,
+		Flag:  flag,
+		Arg:   arg,
+		Index: -1,
+	}
+	var choiceErr *choiceError
+	if errors.As(err, &choiceErr) {
+		e.Suggestions = suggest(choiceErr.Arg, choiceErr.Choices)
+	}
+	return e
+}
+
+// maxHighlightPad is the column after which the start of the command line
+// is elided by ArgumentError.Highlight.
+const maxHighlightPad = 60
+
+// Highlight returns the command line that caused the error, quoted for a POSIX
+// shell, and a second line that marks the offending argument, or the offending
+// value of a flag, with a caret:
+//
+//	app deploy --replicas=x
+//	                      ^
+//
+// The start of long command lines is elided so that the mark is visible.
+// Highlight returns "" if the error was not caused by a command line argument.
+// Run prints the highlight after argument errors if stderr is a terminal.
+func (e *ArgumentError) Highlight() string {
+	if e.Cmd == nil || e.Index < 0 || e.Index >= len(e.args) {
+		return ""
+	}
+	root := e.Cmd
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	// mark only the value of a flag if it was attached, as in --foo=bar
+	arg := e.args[e.Index]
+	offset, length := 0, len(arg)
+	if e.Flag != nil {
+		scanner := root.Scan(e.args)
+		for scanner.Next() {
+			tok := scanner.Token()
+			if tok.Index == e.Index && tok.Kind == ValueToken && tok.Offset > 0 {
+				offset, length = tok.Offset, tok.Len
+			}
+		}
+	}
+	var line strings.Builder
+	line.WriteString(quotePOSIX([]string{root.Name}))
+	start := 0
+	for i, s := range e.args {
+		line.WriteByte(' ')
+		quoted := quotePOSIX([]string{s})
+		if i == e.Index {
+			start = line.Len() + offset
+			switch {
+			case quoted == s:
+			case strings.Contains(s, "'"):
+				start, length = line.Len(), len(quoted)
+			default:
+				start++ // opening quote
+			}
+		}
+		line.WriteString(quoted)
+	}
+
+	s := line.String()
+	pad := utf8.RuneCountInString(s[:start])
+	width := utf8.RuneCountInString(s[start : start+length])
+	if width == 0 {
+		width = 1
+	}
+	if pad > maxHighlightPad {
+		runes := []rune(s)
+		cut := pad - maxHighlightPad/2
+		s = "..." + string(runes[cut:])
+		pad = pad - cut + 3
+	}
+	return s + "\n" + strings.Repeat(" ", pad) + "^" + strings.Repeat("~", width-1)
+}
+
+// choiceError is returned by the ValidateFunc created by FlagBuilder.Choices.
+type choiceError struct {
+	Arg     string
+	Choices []string
+}
+
+func (e *choiceError) Error() string {
+	if len(suggest(e.Arg, e.Choices)) > 0 {
+		// suggestions are shown by ArgumentError
+		return fmt.Sprintf("invalid choice: %q", e.Arg)
 	}
+	return fmt.Sprintf("invalid choice: %q, expected one of: %s", e.Arg, quoteList(e.Choices))
 }
 
 func errStr(err error) string {
@@ -0,0 +1,21 @@
+package xflags
+
+import "testing"
+
+func TestErrorString(t *testing.T) {
+	tests := []struct {
+		err  *xflagsErr
+		want string
+	}{
+		{&xflagsErr{Text: "broken"}, "broken"},
+		{&xflagsErr{Err: errorf("connection refused")}, "connection refused"},
+		{
+			&xflagsErr{Text: "broken", Err: errorf("connection refused")},
+			"broken: connection refused",
+		},
+	}
+	for _, test := range tests {
+		assertString(t, test.want, test.err.String())
+		assertString(t, "xflags: "+test.want, test.err.Error())
+	}
+}
@@ -30,10 +30,10 @@ var App = NewCommand("helloworld", "Print \"Hello, World!\"").
 		// stored in cmd.NoNewLines.
 		Bool(
 			&flagNoNewLines,
-			"n",
+			"",
 			false,
 			"Do not print the trailing newline character",
-		),
+		).ShortName("n"),
 
 		// String flag to select a desired language. Can be specified with
 		// -l, --language or the HW_LANG environment variable.
@@ -0,0 +1,245 @@
+package xflags
+
+import (
+	"strings"
+)
+
+// Expression operators recognized by commands configured with Expression.
+const (
+	OpenParen  = "("
+	CloseParen = ")"
+	OpNot      = "!"
+	OpNotLong  = "--not"
+	OpAnd      = "--and"
+	OpOr       = "--or"
+)
+
+func isOperator(token string) bool {
+	switch token {
+	case OpenParen, CloseParen, OpNot, OpNotLong, OpAnd, OpOr:
+		return true
+	}
+	return false
+}
+
+type exprOp int
+
+const (
+	exprPredicate exprOp = iota
+	exprNot
+	exprAnd
+	exprOr
+)
+
+// Expr is a boolean expression parsed from the command line of a command
+// configured with Expression, in the style of find(1). Each regular flag
+// specified on the command line is a predicate and flags may be combined with
+// the operators "!" or "--not", "--and" and "--or" and grouped with "(" and
+// ")". Adjacent predicates are joined by an implicit "--and". "--not" binds
+// tighter than "--and", which binds tighter than "--or".
+//
+// For example:
+//
+//	find . ( --name '*.go' --or --size +1M ) ! --empty
+type Expr struct {
+	op    exprOp
+	args  []*Expr
+	flag  *Flag
+	value string
+}
+
+// PredicateFunc evaluates a predicate of an expression. It receives the flag
+// and the value that was specified for the flag on the command line.
+type PredicateFunc func(flag *Flag, value string) (bool, error)
+
+// Eval evaluates the expression from left to right by calling fn for each
+// predicate. Evaluation of "--and" and "--or" is short-circuited so fn is not
+// called for predicates that cannot change the result. An empty expression,
+// which is nil, evaluates to true.
+func (e *Expr) Eval(fn PredicateFunc) (bool, error) {
+	if e == nil {
+		return true, nil
+	}
+	switch e.op {
+	case exprNot:
+		ok, err := e.args[0].Eval(fn)
+		return !ok, err
+	case exprAnd:
+		for _, arg := range e.args {
+			if ok, err := arg.Eval(fn); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case exprOr:
+		for _, arg := range e.args {
+			if ok, err := arg.Eval(fn); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	return fn(e.flag, e.value)
+}
+
+// String returns the expression with all implicit operators and grouping made
+// explicit.
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	switch e.op {
+	case exprNot:
+		return OpNot + " " + e.args[0].String()
+	case exprAnd, exprOr:
+		op := OpAnd
+		if e.op == exprOr {
+			op = OpOr
+		}
+		args := make([]string, len(e.args))
+		for i, arg := range e.args {
+			args[i] = arg.String()
+		}
+		return "( " + strings.Join(args, " "+op+" ") + " )"
+	}
+	return e.flag.String() + "=" + e.value
+}
+
+// Expr returns the expression that was specified on the command line if the
+// command is configured with Expression. Expr is only populated on the command
+// returned by Parse and is nil if no predicates were specified.
+func (c *Command) Expr() *Expr {
+	if c.parser == nil {
+		return nil
+	}
+	return c.parser.expr
+}
+
+// exprParser parses an expression from the trace of an argParser using
+// recursive descent.
+type exprParser struct {
+	cmd   *Command
+	trace []TraceEntry
+}
+
+func parseExpr(cmd *Command, trace []TraceEntry) (*Expr, error) {
+	c := &exprParser{cmd: cmd}
+	for _, entry := range trace {
+		// ignore subcommands and positional arguments
+		if entry.Operator != "" || (entry.Flag != nil && !entry.Flag.Positional) {
+			c.trace = append(c.trace, entry)
+		}
+	}
+	if len(c.trace) == 0 {
+		return nil, nil
+	}
+	e, err := c.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if len(c.trace) > 0 {
+		return nil, c.errorf(c.trace[0], "unexpected operator: %s", c.trace[0].Operator)
+	}
+	return e, nil
+}
+
+func (c *exprParser) errorf(entry TraceEntry, format string, a ...interface{}) error {
+	err := newArgErr(c.cmd, nil, "", format, a...)
+	err.Index = entry.Index
+	if len(entry.Args) > 0 {
+		err.Token = entry.Args[0]
+	}
+	return err
+}
+
+func (c *exprParser) peek() (TraceEntry, bool) {
+	if len(c.trace) == 0 {
+		return TraceEntry{}, false
+	}
+	return c.trace[0], true
+}
+
+func (c *exprParser) next() TraceEntry {
+	entry := c.trace[0]
+	c.trace = c.trace[1:]
+	return entry
+}
+
+func (c *exprParser) parseOr() (*Expr, error) {
+	e, err := c.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	args := []*Expr{e}
+	for {
+		entry, ok := c.peek()
+		if !ok || entry.Operator != OpOr {
+			break
+		}
+		c.next()
+		e, err := c.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, e)
+	}
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	return &Expr{op: exprOr, args: args}, nil
+}
+
+func (c *exprParser) parseAnd() (*Expr, error) {
+	e, err := c.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	args := []*Expr{e}
+	for {
+		entry, ok := c.peek()
+		if !ok || entry.Operator == OpOr || entry.Operator == CloseParen {
+			break
+		}
+		if entry.Operator == OpAnd {
+			c.next()
+		}
+		e, err := c.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, e)
+	}
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	return &Expr{op: exprAnd, args: args}, nil
+}
+
+func (c *exprParser) parseUnary() (*Expr, error) {
+	entry, ok := c.peek()
+	if !ok {
+		return nil, newArgErr(c.cmd, nil, "", "expected expression at end of arguments")
+	}
+	c.next()
+	switch entry.Operator {
+	case "":
+		return &Expr{op: exprPredicate, flag: entry.Flag, value: entry.Value}, nil
+	case OpNot, OpNotLong:
+		e, err := c.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{op: exprNot, args: []*Expr{e}}, nil
+	case OpenParen:
+		e, err := c.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end, ok := c.peek(); !ok || end.Operator != CloseParen {
+			return nil, c.errorf(entry, "unmatched parenthesis")
+		}
+		c.next()
+		return e, nil
+	}
+	return nil, c.errorf(entry, "expected expression before: %s", entry.Operator)
+}
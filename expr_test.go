@@ -0,0 +1,111 @@
+package xflags
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+)
+
+func newFindCommand() *Command {
+	var name, size []string
+	var empty bool
+	var paths []string
+	return NewCommand("find", "").
+		Expression().
+		Flags(
+			Strings(&name, "name", nil, "").NArgs(0, 0),
+			Strings(&size, "size", nil, "").NArgs(0, 0),
+			Bool(&empty, "empty", false, "").NArgs(0, 0),
+			Strings(&paths, "path", nil, "").Positional(),
+		).
+		Must()
+}
+
+func TestExpr(t *testing.T) {
+	tests := []struct {
+		Args   []string
+		Expect string
+	}{
+		{nil, ""},
+		{[]string{"."}, ""},
+		{[]string{".", "--name", "a"}, "--name=a"},
+		{[]string{"--name=a", "--size=1"}, "( --name=a --and --size=1 )"},
+		{[]string{"--name=a", "--and", "--size=1"}, "( --name=a --and --size=1 )"},
+		{
+			[]string{"--name=a", "--or", "--size=1", "--empty"},
+			"( --name=a --or ( --size=1 --and --empty=true ) )",
+		},
+		{
+			[]string{".", "(", "--name=a", "--or", "--size=1", ")", "!", "--empty"},
+			"( ( --name=a --or --size=1 ) --and ! --empty=true )",
+		},
+		{[]string{"--not", "(", "--name=a", ")"}, "! --name=a"},
+	}
+	for _, test := range tests {
+		cmd, err := newFindCommand().Parse(test.Args)
+		if err != nil {
+			t.Errorf("%q: %v", test.Args, err)
+			continue
+		}
+		if s := cmd.Expr().String(); s != test.Expect {
+			t.Errorf("%q: expected %q, got %q", test.Args, test.Expect, s)
+		}
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	tests := []struct {
+		Args  []string
+		Index int
+	}{
+		{[]string{"(", "--name=a"}, 0},
+		{[]string{"--name=a", ")"}, 1},
+		{[]string{"--name=a", "--or"}, -1},
+		{[]string{"--or", "--name=a"}, 0},
+		{[]string{"(", ")"}, 1},
+	}
+	for _, test := range tests {
+		_, err := newFindCommand().Parse(test.Args)
+		var argErr *ArgumentError
+		if !assertErrorAs(t, err, &argErr) {
+			continue
+		}
+		if argErr.Index != test.Index {
+			t.Errorf("%q: expected error at %d, got %d: %v", test.Args, test.Index, argErr.Index, err)
+		}
+	}
+}
+
+func TestExprEval(t *testing.T) {
+	files := map[string]int{"main.go": 10, "big.bin": 2000, "empty.txt": 0}
+	cmd, err := newFindCommand().Parse([]string{
+		".", "(", "--name=*.go", "--or", "--size=+1000", ")", "!", "--empty",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := make([]string, 0)
+	for _, name := range []string{"big.bin", "empty.txt", "main.go"} {
+		ok, err := cmd.Expr().Eval(func(flag *Flag, value string) (bool, error) {
+			switch flag.Name {
+			case "name":
+				return path.Match(value, name)
+			case "size":
+				var n int
+				_, err := fmt.Sscanf(strings.TrimPrefix(value, "+"), "%d", &n)
+				return files[name] > n, err
+			case "empty":
+				return files[name] == 0, nil
+			}
+			return false, fmt.Errorf("unknown predicate: %s", flag)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			matches = append(matches, name)
+		}
+	}
+	assertStrings(t, []string{"big.bin", "main.go"}, matches)
+}
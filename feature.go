@@ -0,0 +1,136 @@
+package xflags
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FeatureGate reports whether experimental features are enabled.
+//
+// Commands and flags may be tied to a named feature with the FeatureFlag
+// methods of their builders. Until the feature is enabled by the FeatureGate
+// of the root command or one of its subcommands, they are hidden from help
+// messages and any attempt to use them fails with a FeatureError.
+//
+// Features that are enabled at build time may be declared in a file with a
+// build constraint:
+//
+//	//go:build beta
+//
+//	package main
+//
+//	func init() { features = xflags.Features("beta-exports") }
+type FeatureGate interface {
+	Enabled(feature string) bool
+}
+
+// FeatureGateFunc is an adapter to allow the use of ordinary functions as a
+// FeatureGate.
+type FeatureGateFunc func(feature string) bool
+
+// Enabled calls f(feature).
+func (f FeatureGateFunc) Enabled(feature string) bool { return f(feature) }
+
+// Features returns a FeatureGate that enables the given features.
+func Features(features ...string) FeatureGate {
+	enabled := make(map[string]bool, len(features))
+	for _, feature := range features {
+		enabled[feature] = true
+	}
+	return FeatureGateFunc(func(feature string) bool { return enabled[feature] })
+}
+
+// EnvFeatureGate returns a FeatureGate that enables each feature named in a
+// comma separated list in the given environment variable. The variable is
+// read each time a feature is checked.
+func EnvFeatureGate(name string) FeatureGate {
+	return FeatureGateFunc(func(feature string) bool {
+		for _, s := range strings.Split(os.Getenv(name), ",") {
+			if strings.TrimSpace(s) == feature {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// AnyFeatureGate returns a FeatureGate that enables a feature if it is enabled
+// by any of the given gates.
+func AnyFeatureGate(gates ...FeatureGate) FeatureGate {
+	return FeatureGateFunc(func(feature string) bool {
+		for _, gate := range gates {
+			if gate.Enabled(feature) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FeatureError is the error returned if a command or flag is used while the
+// feature it is tied to is not enabled.
+type FeatureError struct {
+	Feature string
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("feature %q is not enabled", e.Feature)
+}
+
+// featureEnabled reports whether the given feature is enabled by the
+// FeatureGate of c or its nearest parent. The empty feature is always enabled.
+func (c *Command) featureEnabled(feature string) bool {
+	if feature == "" {
+		return true
+	}
+	for p := c; p != nil; p = p.Parent {
+		if p.FeatureGate != nil {
+			return p.FeatureGate.Enabled(feature)
+		}
+	}
+	return false
+}
+
+// isHidden reports whether flag of cmd should be hidden from help messages.
+func isHidden(cmd *Command, flag *Flag) bool {
+	return flag.Hidden || !cmd.featureEnabled(flag.Feature)
+}
+
+// isHiddenFromHelp reports whether flag of cmd should be hidden from a help
+// message with the given level of detail. Unlike isHidden, it also hides flags
+// by VisibleIf and Common, which only apply to help messages.
+func isHiddenFromHelp(cmd *Command, flag *Flag, level helpLevel) bool {
+	return isHidden(cmd, flag) ||
+		isConditional(cmd, flag, level) ||
+		isUncommon(cmd, flag, level)
+}
+
+// isConditional reports whether flag of cmd is hidden from a help message with
+// the given level of detail by its VisibleIf function.
+func isConditional(cmd *Command, flag *Flag, level helpLevel) bool {
+	return flag.visibleIf != nil && level != helpAll && !flag.visibleIf(cmd)
+}
+
+// isUncommon reports whether flag of cmd is hidden from a brief help message
+// because it is not marked as Common.
+func isUncommon(cmd *Command, flag *Flag, level helpLevel) bool {
+	return level == helpBrief && !flag.common && !flag.Positional && cmd.hasCommon()
+}
+
+// hasCommon reports whether any flag of c is marked as Common.
+func (c *Command) hasCommon() bool {
+	for _, group := range c.FlagGroups {
+		for _, flag := range group.Flags {
+			if flag.common {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHiddenCommand reports whether cmd should be hidden from help messages.
+func isHiddenCommand(cmd *Command) bool {
+	return cmd.Hidden || !cmd.featureEnabled(cmd.Feature)
+}
@@ -0,0 +1,69 @@
+package xflags
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFeatureGate(t *testing.T) {
+	newCmd := func(gate FeatureGate) *Command {
+		var format, target string
+		return NewCommand("app", "").
+			FeatureGate(gate).
+			Flags(
+				String(&format, "format", "", "Export format").
+					FeatureFlag("beta-exports").
+					Required(),
+			).
+			Subcommands(
+				NewCommand("export", "Export widgets").FeatureFlag("beta-exports"),
+				NewCommand("deploy", "Deploy widgets").
+					Flags(String(&target, "target", "", "").Positional()),
+			).
+			Must()
+	}
+
+	for _, gate := range []FeatureGate{nil, Features("other")} {
+		cmd := newCmd(gate)
+		for _, args := range [][]string{{"export"}, {"--format=json", "deploy"}} {
+			_, err := cmd.Parse(args)
+			var featureErr *FeatureError
+			if !errors.As(err, &featureErr) {
+				t.Errorf("%q: expected FeatureError, got: %v", args, err)
+				continue
+			}
+			assertString(t, "beta-exports", featureErr.Feature)
+		}
+
+		// disabled required flags are not enforced
+		if _, err := cmd.Parse([]string{"deploy", "prod"}); err != nil {
+			t.Error(err)
+		}
+
+		w := new(bytes.Buffer)
+		if err := cmd.WriteUsage(w); err != nil {
+			t.Fatal(err)
+		}
+		if s := w.String(); strings.Contains(s, "export") || strings.Contains(s, "--format") {
+			t.Errorf("disabled features shown in help message:\n%s", s)
+		}
+	}
+
+	os.Setenv("XFLAGS_TEST_FEATURES", "alpha, beta-exports")
+	defer os.Unsetenv("XFLAGS_TEST_FEATURES")
+	gate := AnyFeatureGate(Features("other"), EnvFeatureGate("XFLAGS_TEST_FEATURES"))
+	cmd := newCmd(gate)
+	if _, err := cmd.Parse([]string{"--format=json", "export"}); err != nil {
+		t.Error(err)
+	}
+	w := new(bytes.Buffer)
+	if err := cmd.WriteUsage(w); err != nil {
+		t.Fatal(err)
+	}
+	if s := w.String(); !strings.Contains(s, "export") || !strings.Contains(s, "--format") {
+		t.Errorf("enabled features not shown in help message:\n%s", s)
+	}
+}
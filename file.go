@@ -0,0 +1,183 @@
+package xflags
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// stdioPath is the path of a file flag that refers to the standard input or
+// output of a command.
+const stdioPath = "-"
+
+// Reader returns a FlagBuilder that can be used to define a flag with the
+// specified name and usage string whose value is the path of a file to read.
+// The file is opened by Command.Parse and the argument p points to an
+// io.Reader variable in which to store the opened file. If the value is "-",
+// p is set to the standard input of the command instead, which may be
+// configured with CommandBuilder.Input. If the flag is not specified, p is
+// not modified.
+//
+// Errors opening the file are returned by Parse as an ArgumentError. The
+// file is closed after the handler of the command returns.
+func Reader(p *io.Reader, name, usage string) *FlagBuilder {
+	return Var(newFileValue(os.O_RDONLY, 0, func(v interface{}) error {
+		*p = v.(io.Reader)
+		return nil
+	}), name, usage)
+}
+
+// Writer returns a FlagBuilder that can be used to define a flag with the
+// specified name and usage string whose value is the path of a file to write.
+// The file is created or truncated by Command.Parse and the argument p points
+// to an io.Writer variable in which to store the opened file. If the value is
+// "-", p is set to the standard output of the command instead, which may be
+// configured with CommandBuilder.Output. If the flag is not specified, p is
+// not modified.
+//
+// Errors opening the file are returned by Parse as an ArgumentError. The
+// file is closed after the handler of the command returns.
+func Writer(p *io.Writer, name, usage string) *FlagBuilder {
+	return Var(newFileValue(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666, func(v interface{}) error {
+		*p = v.(io.Writer)
+		return nil
+	}), name, usage)
+}
+
+// OpenFile returns a FlagBuilder that can be used to define a flag with the
+// specified name and usage string whose value is the path of a file that is
+// opened by Command.Parse with os.OpenFile and the given flag and permissions.
+// The argument p points to an *os.File variable in which to store the opened
+// file. If the value is "-", p is set to the standard input of the command,
+// or its standard output if flagMode opens the file for writing, which must
+// be an *os.File. If the flag is not specified, p is not modified.
+//
+// Errors opening the file are returned by Parse as an ArgumentError that names
+// the flag, so that handlers need not handle them. The file is closed after
+// the handler of the command returns.
+func OpenFile(p **os.File, name string, flagMode int, perm fs.FileMode, usage string) *FlagBuilder {
+	v := newFileValue(flagMode, perm, nil)
+	v.assign = func(f interface{}) error {
+		file, ok := f.(*os.File)
+		if !ok {
+			if v.writable() {
+				return errorf("standard output is not a file")
+			}
+			return errorf("standard input is not a file")
+		}
+		*p = file
+		return nil
+	}
+	return Var(v, name, usage)
+}
+
+// fileValue is the value of a flag that names a file which is opened by the
+// parser once all flags are set.
+type fileValue struct {
+	path   string
+	flag   int
+	perm   fs.FileMode
+	file   *os.File                  // the opened file, if it is not stdin or stdout
+	assign func(v interface{}) error // stores the opened file or stdin or stdout
+}
+
+func newFileValue(flag int, perm fs.FileMode, assign func(v interface{}) error) *fileValue {
+	return &fileValue{flag: flag, perm: perm, assign: assign}
+}
+
+func (c *fileValue) String() string { return c.path }
+
+func (c *fileValue) Set(s string) error {
+	if s == "" {
+		return errorf("empty path")
+	}
+	c.path = s
+	return nil
+}
+
+func (c *fileValue) Get() interface{} { return c.path }
+
+// writable reports whether the file is opened for writing.
+func (c *fileValue) writable() bool {
+	return c.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+// open opens the file or assigns the standard input or output of cmd if the
+// path is "-".
+func (c *fileValue) open(cmd *Command) error {
+	if c.path == "" {
+		return nil
+	}
+	if c.path == stdioPath {
+		if c.writable() {
+			stdout, _ := cmd.output()
+			return c.assign(stdout)
+		}
+		return c.assign(cmd.input())
+	}
+	f, err := os.OpenFile(c.path, c.flag, c.perm)
+	if err != nil {
+		return err
+	}
+	if err := c.assign(f); err != nil {
+		f.Close()
+		return err
+	}
+	c.file = f
+	return nil
+}
+
+// close closes the opened file, if any.
+func (c *fileValue) close() error {
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// openFiles opens the files named by the file flags of the current command and
+// its parents. If any file cannot be opened, all files are closed.
+//
+// Since opened files are stored in the variables that the flags were defined
+// with, which are shared by all invocations, file flags may not be specified
+// for invocations parsed by Command.ParseInvocation.
+func (c *argParser) openFiles() error {
+	if c.values != nil {
+		for _, v := range c.rawValues {
+			if _, ok := v.flag.Value.(*fileValue); ok {
+				return newArgErr(c.cmd, v.flag, v.value, "files are not opened by ParseInvocation")
+			}
+		}
+		return nil
+	}
+	err := c.walkFlags(func(flag *Flag, path string) error {
+		v, ok := flag.Value.(*fileValue)
+		if !ok {
+			return nil
+		}
+		if err := v.open(c.cmd); err != nil {
+			return wrapArgErr(err, c.cmd, flag, v.path)
+		}
+		return nil
+	})
+	if err != nil {
+		c.closeFiles()
+	}
+	return err
+}
+
+// closeFiles closes all files opened by openFiles and returns the first error.
+func (c *argParser) closeFiles() error {
+	var firstErr error
+	c.walkFlags(func(flag *Flag, path string) error {
+		if v, ok := flag.Value.(*fileValue); ok {
+			if err := v.close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil
+	})
+	return firstErr
+}
@@ -0,0 +1,130 @@
+package xflags
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReaderWriter(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(in, []byte("from file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.txt")
+	tests := []struct {
+		Args   []string
+		Stdout string
+		File   string
+	}{
+		{[]string{in, "--out", out}, "", "from file\n"},
+		{[]string{"-", "--out", out}, "", "from stdin\n"},
+		{[]string{"-", "--out", "-"}, "from stdin\n", ""},
+		{[]string{in, "-o-"}, "from file\n", ""},
+	}
+	for _, test := range tests {
+		os.Remove(out)
+		var r io.Reader
+		var w io.Writer
+		stdout := new(bytes.Buffer)
+		code := NewCommand("cat", "").
+			Input(strings.NewReader("from stdin\n")).
+			Output(stdout, new(bytes.Buffer)).
+			Flags(
+				Writer(&w, "out", "Output file").ShortName("o").Required(),
+				Reader(&r, "FILE", "Input file").Positional().Required(),
+			).
+			HandleFunc(func(args []string) int {
+				if _, err := io.Copy(w, r); err != nil {
+					t.Error(err)
+					return 1
+				}
+				return 0
+			}).
+			Must().
+			Run(test.Args)
+		assertInt64(t, 0, int64(code))
+		assertString(t, test.Stdout, stdout.String())
+		b, _ := os.ReadFile(out)
+		assertString(t, test.File, string(b))
+		if f, ok := r.(*os.File); ok {
+			if _, err := f.Stat(); err == nil {
+				t.Errorf("%v: expected input file to be closed", test.Args)
+			}
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	var r io.Reader
+	_, err := NewCommand("cat", "").
+		Flags(Reader(&r, "in", "")).
+		Must().
+		Parse([]string{"--in", filepath.Join(t.TempDir(), "missing")})
+	var argErr *ArgumentError
+	if assertErrorAs(t, err, &argErr) {
+		assertString(t, "in", argErr.Flag.Name)
+	}
+	if r != nil {
+		t.Errorf("expected reader to be unset")
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	newCmd := func(f **os.File) *Command {
+		return NewCommand("app", "").
+			Input(strings.NewReader("")).
+			Output(new(bytes.Buffer), new(bytes.Buffer)).
+			Flags(
+				OpenFile(f, "log", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600, "Log file"),
+			).
+			HandleFunc(func(args []string) int {
+				if _, err := (*f).WriteString("line\n"); err != nil {
+					t.Error(err)
+					return 1
+				}
+				return 0
+			}).
+			Must()
+	}
+	for i := 0; i < 2; i++ {
+		var f *os.File
+		assertInt64(t, 0, int64(newCmd(&f).Run([]string{"--log", path})))
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "line\nline\n", string(b))
+
+	// the output of the command is not a file
+	var f *os.File
+	_, err = newCmd(&f).Parse([]string{"--log", "-"})
+	var argErr *ArgumentError
+	if assertErrorAs(t, err, &argErr) {
+		assertString(t, "--log: standard output is not a file", argErr.String())
+	}
+
+	_, err = newCmd(&f).Parse([]string{"--log", filepath.Join(path, "nope")})
+	assertErrorAs(t, err, &argErr)
+}
+
+func TestReaderParseInvocation(t *testing.T) {
+	var r io.Reader
+	_, err := NewCommand("cat", "").
+		Flags(Reader(&r, "in", "")).
+		Must().
+		ParseInvocation([]string{"--in", "-"})
+	var argErr *ArgumentError
+	if assertErrorAs(t, err, &argErr) {
+		assertString(t, "in", argErr.Flag.Name)
+	}
+	if r != nil {
+		t.Errorf("expected reader to be unset")
+	}
+}
@@ -1,6 +1,7 @@
 package xflags
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -31,14 +32,46 @@ type Flag struct {
 	Name        string
 	ShortName   string
 	Usage       string
+	UsageFunc   func() string
 	ShowDefault bool
 	Positional  bool
 	MinCount    int
 	MaxCount    int
 	Hidden      bool
+	Unique      bool
+	Sorted      bool
+	Secret      bool
 	EnvVar      string
 	Validate    ValidateFunc
+	OnChange    func(oldValue, newValue string)
+	OnSet       func(value string)
+	Feature     string
+	Annotations map[string]string
 	Value       Value
+
+	choices    []string
+	requiredIf []flagCondition
+	visibleIf  func(cmd *Command) bool
+	complete   CompletionFunc
+	common     bool
+	builtin    bool // registered by a CommandBuilder option, such as OutputFlags
+
+	numberFormat numberFormat
+	glob         GlobMode
+}
+
+// flagCondition is satisfied when the named flag has the given value.
+type flagCondition struct {
+	name  string
+	value string
+}
+
+func (c flagCondition) String() string {
+	name := "--" + c.name
+	if len(c.name) == 1 {
+		name = "-" + c.name
+	}
+	return name + "=" + c.value
 }
 
 // Flag implements the Flagger interface.
@@ -49,11 +82,13 @@ func (c *Flag) Flag() (*Flag, error) {
 	if c.Value == nil {
 		return nil, errorf("%s: value cannot be nil", c.name())
 	}
-	if len(c.ShortName) > 1 {
-		return nil, errorf(
-			"short name must be one character in length: %s",
-			c.ShortName,
-		)
+	if strings.HasPrefix(c.ShortName, "-") || strings.Contains(c.ShortName, "=") {
+		return nil, errorf("%s: invalid short name: %s", c.name(), c.ShortName)
+	}
+	if c.Sorted {
+		if _, ok := c.Value.(sort.Interface); !ok {
+			return nil, errorf("%s: value cannot be sorted", c.name())
+		}
 	}
 	if c.MinCount < 0 ||
 		c.MaxCount < 0 ||
@@ -81,6 +116,15 @@ func (c *Flag) String() string {
 	return "unknown"
 }
 
+// UsageText returns the usage string of the flag, calling UsageFunc if it is
+// set.
+func (c *Flag) UsageText() string {
+	if c.UsageFunc != nil {
+		return c.UsageFunc()
+	}
+	return c.Usage
+}
+
 // name returns the name or shortname of the flag in that order of precedence.
 func (c *Flag) name() string {
 	if c.Name != "" {
@@ -89,14 +133,39 @@ func (c *Flag) name() string {
 	return c.ShortName
 }
 
-// Set sets the value of the command-line flag.
+// Set sets the value of the command-line flag. Numeric arguments are first
+// normalized as specified by FlagBuilder.AllowThousandsSeparators and
+// FlagBuilder.DecimalComma, and glob patterns are expanded as specified by
+// FlagBuilder.Glob.
 func (c *Flag) Set(s string) error {
-	if c.Validate != nil {
-		if err := c.Validate(s); err != nil {
+	return c.set(c.Value, s, c.numberFormat)
+}
+
+// set normalizes s in the given format, validates it and sets it on v, or
+// only validates it if v is nil. If glob patterns are expanded for the flag,
+// each matching path is validated and set instead of s.
+func (c *Flag) set(v Value, s string, format numberFormat) error {
+	s = format.normalize(s)
+	paths := []string{s}
+	if c.glob.expands() {
+		var err error
+		if paths, err = expandGlob(s); err != nil {
 			return err
 		}
 	}
-	return c.Value.Set(s)
+	for _, path := range paths {
+		if c.Validate != nil {
+			if err := c.Validate(path); err != nil {
+				return err
+			}
+		}
+		if v != nil {
+			if err := v.Set(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // FlagGroup is a nominal grouping of flags which affects how the flags are
@@ -143,6 +212,16 @@ func (c *flagGroupBuilder) FlagGroup() (*FlagGroup, error) {
 // All chain methods return a pointer to the same builder.
 type FlagBuilder struct {
 	flag Flag
+	err  error
+}
+
+// UsageFunc specifies a function that returns the usage string of the flag.
+// It is called only when the usage string is needed to print a help message,
+// so that programs do not pay for formatting long or computed usage strings on
+// every invocation. It overrides the usage string given to the FlagBuilder.
+func (c *FlagBuilder) UsageFunc(fn func() string) *FlagBuilder {
+	c.flag.UsageFunc = fn
+	return c
 }
 
 // ShowDefault specifies that the default vlaue of this flag should be show in
@@ -155,6 +234,12 @@ func (c *FlagBuilder) ShowDefault() *FlagBuilder {
 // ShortName specifies an alternative short name for a command line flag. For
 // example, a command named "foo" can be specified on the command line with
 // "--foo" but may also use a short name of "f" to be specified by "-f".
+//
+// Short names may be longer than one character, such as "verbose" to be
+// specified by "-verbose". To declare a flag that has only a short name, pass
+// an empty name to the function that creates the FlagBuilder. Values may be
+// attached directly to multi-character short names, as in "-Xmx512m", if the
+// root command is configured with PrefixShortNames.
 func (c *FlagBuilder) ShortName(name string) *FlagBuilder {
 	c.flag.ShortName = name
 	return c
@@ -185,6 +270,61 @@ func (c *FlagBuilder) Required() *FlagBuilder {
 	return c.NArgs(1, 1)
 }
 
+// RequiredIf specifies that this flag must be specified if the flag with the
+// given name or short name, which is declared by the same command or its
+// parents, has the given value. The value of the other flag may be its default
+// value or be read from any source. If RequiredIf is called more than once,
+// the flag is required if any of the conditions is met. CommandBuilder.Command
+// returns an error if the other flag is not declared. For example, an SSH key
+// may be required only in remote mode:
+//
+//	xflags.String(&key, "ssh-key", "", "Path of the SSH key").
+//		RequiredIf("mode", "remote")
+func (c *FlagBuilder) RequiredIf(name, value string) *FlagBuilder {
+	c.flag.requiredIf = append(c.flag.requiredIf, flagCondition{name: name, value: value})
+	return c
+}
+
+// Unique specifies that repeated identical values for this flag should be
+// ignored. Only the first instance of each value is Set. This is useful for
+// slice flags whose values may be merged from several sources.
+func (c *FlagBuilder) Unique() *FlagBuilder {
+	c.flag.Unique = true
+	return c
+}
+
+// AppendToDefault specifies that the values of a slice flag should be appended
+// to its default value. By default, the first value specified replaces the
+// default value. The flag's Value must be created by Strings or NewValue.
+func (c *FlagBuilder) AppendToDefault() *FlagBuilder {
+	v, ok := c.flag.Value.(*stringSliceValue)
+	if !ok {
+		if c.err == nil {
+			c.err = errorf("%s: value cannot append to default", c.flag.name())
+		}
+		return c
+	}
+	v.appendToDefault = true
+	return c
+}
+
+// Sorted specifies that the values of a slice flag should be sorted after the
+// command line is parsed so that results are deterministic regardless of the
+// order in which values were specified. The flag's Value must implement
+// sort.Interface, as the Strings flag does.
+func (c *FlagBuilder) Sorted() *FlagBuilder {
+	c.flag.Sorted = true
+	return c
+}
+
+// Secret specifies that the value of this flag is sensitive, such as a
+// password or token. The values of secret flags are never shown in help
+// messages or reported to telemetry.
+func (c *FlagBuilder) Secret() *FlagBuilder {
+	c.flag.Secret = true
+	return c
+}
+
 // Hidden hides the command line flag from all help messages but still allows
 // the flag to be specified on the command line.
 func (c *FlagBuilder) Hidden() *FlagBuilder {
@@ -192,6 +332,25 @@ func (c *FlagBuilder) Hidden() *FlagBuilder {
 	return c
 }
 
+// VisibleIf hides the flag from help messages unless fn returns true for the
+// command whose help is written, so that advanced flags do not lengthen the
+// help of a command. For example, fn may report whether an expert-mode
+// environment variable is set. The flag may always be specified on the
+// command line and is always shown by "--help-all" or "--help --all". Help
+// messages note when flags were hidden by VisibleIf.
+func (c *FlagBuilder) VisibleIf(fn func(cmd *Command) bool) *FlagBuilder {
+	c.flag.visibleIf = fn
+	return c
+}
+
+// Common marks the flag as one of the most commonly used flags of its command.
+// If any flag of a command is marked as common, -h shows a brief help message
+// with only the common flags, while --help shows all flags.
+func (c *FlagBuilder) Common() *FlagBuilder {
+	c.flag.common = true
+	return c
+}
+
 // Env allows the value of the flag to be specified with an environment variable
 // if it is not specified on the command line.
 func (c *FlagBuilder) Env(name string) *FlagBuilder {
@@ -204,30 +363,79 @@ func (c *FlagBuilder) Env(name string) *FlagBuilder {
 // same error.
 func (c *FlagBuilder) Validate(f ValidateFunc) *FlagBuilder {
 	c.flag.Validate = f
+	c.flag.choices = nil
+	return c
+}
+
+// OnChange specifies a function that is called each time the value of this
+// flag is changed by Command.Reload. The function receives the previous and
+// new values as they were read from the environment or a Source.
+func (c *FlagBuilder) OnChange(fn func(oldValue, newValue string)) *FlagBuilder {
+	c.flag.OnChange = fn
+	return c
+}
+
+// OnSet specifies a function that is called with each value of this flag
+// after it is successfully set from the command line, an environment variable
+// or a source. This allows side effects, such as changing the log level as
+// soon as a flag is parsed, without replacing the value of the flag with a
+// Func.
+func (c *FlagBuilder) OnSet(fn func(value string)) *FlagBuilder {
+	c.flag.OnSet = fn
+	return c
+}
+
+// FeatureFlag ties the flag to the named feature. The flag is hidden and
+// cannot be used until the feature is enabled by the FeatureGate of the
+// command.
+func (c *FlagBuilder) FeatureFlag(feature string) *FlagBuilder {
+	c.flag.Feature = feature
+	return c
+}
+
+// Annotate attaches metadata to the flag which is not used by xflags but may
+// be read from Flag.Annotations by other systems such as completion scripts,
+// documentation generators or policy engines.
+func (c *FlagBuilder) Annotate(key, value string) *FlagBuilder {
+	if c.flag.Annotations == nil {
+		c.flag.Annotations = make(map[string]string)
+	}
+	c.flag.Annotations[key] = value
 	return c
 }
 
 // Choices is a convenience method that calls Validate and sets a ValidateFunc
 // that enforces that the flag value must be one of the given choices.
 func (c *FlagBuilder) Choices(elems ...string) *FlagBuilder {
-	return c.Validate(
-		func(arg string) error {
-			for _, elem := range elems {
-				if arg == elem {
-					return nil
-				}
+	c.Validate(validateChoices(elems))
+	c.flag.choices = elems
+	return c
+}
+
+// validateChoices returns a ValidateFunc that accepts any of elems.
+func validateChoices(elems []string) ValidateFunc {
+	return func(arg string) error {
+		for _, elem := range elems {
+			if arg == elem {
+				return nil
 			}
-			return errorf(
-				"invalid argument: \"%s\", expected one of: \"%s\"",
-				arg,
-				strings.Join(elems, "\", \""),
-			)
-		},
-	)
+		}
+		return &choiceError{Arg: arg, Choices: elems}
+	}
+}
+
+// builtin marks the flag as registered by a CommandBuilder option so that it is
+// excluded from generated configuration files.
+func (c *FlagBuilder) builtin() *FlagBuilder {
+	c.flag.builtin = true
+	return c
 }
 
 // Flag implements the Flagger interface and produces a new Flag.
 func (c *FlagBuilder) Flag() (*Flag, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
 	flag := c.flag
 	return flag.Flag()
 }
@@ -1,6 +1,7 @@
 package xflags
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -23,6 +24,19 @@ func TestBitField(t *testing.T) {
 		t.Fatal(err)
 	}
 	assertInt64(t, 0x05, int64(v))
+
+	v = 0x07
+	_, err = NewCommand("test", "").
+		Flags(
+			BitField(&v, 0x01, "foo", false, "").Must(),
+			BitField(&v, 0x04, "baz", true, "").Must(),
+		).
+		Must().
+		Parse([]string{"--foo=false", "--baz=false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 0x02, int64(v))
 }
 
 func TestBool(t *testing.T) {
@@ -53,6 +67,36 @@ func TestInt64(t *testing.T) {
 	}
 }
 
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		Arg    string
+		Expect float64
+		OK     bool
+	}{
+		{"75%", 0.75, true},
+		{"0.75", 0.75, true},
+		{"7 %", 0.07, true},
+		{"100%", 1, true},
+		{"0", 0, true},
+		{"150%", 0, false},
+		{"1.5", 0, false},
+		{"-1%", 0, false},
+		{"NaN", 0, false},
+		{"half", 0, false},
+	}
+	for _, test := range tests {
+		var v float64
+		err := parseFlag(Percent(&v, "rate", 0, "").Must(), "--rate="+test.Arg)
+		if assertBool(t, test.OK, err == nil) && test.OK {
+			assertFloat64(t, test.Expect, v)
+		}
+		if err != nil {
+			assertBool(t, true, strings.Contains(err.Error(), test.Arg))
+		}
+	}
+	assertString(t, "7%", newPercentValue(0.07, nil).String())
+}
+
 func TestString(t *testing.T) {
 	var v string
 	if assertFlagParses(t, String(&v, "foo", "", "").Must(), "--foo=bar") {
@@ -76,9 +120,9 @@ func TestFlagChoices(t *testing.T) {
 	flag := String(&v, "foo", "", "").Choices("bar", "baz").Must()
 	assertFlagParses(t, flag, "--foo=bar")
 	assertFlagParses(t, flag, "--foo=baz")
-	assertErrorAs(t, parseFlag(flag, "--foo=qux"), &ArgumentError{})
-	assertErrorAs(t, parseFlag(flag, "--foo=ba"), &ArgumentError{})
-	assertErrorAs(t, parseFlag(flag, "--foo=barr"), &ArgumentError{})
+	assertErrorAs(t, parseFlag(flag, "--foo=qux"), new(*ArgumentError))
+	assertErrorAs(t, parseFlag(flag, "--foo=ba"), new(*ArgumentError))
+	assertErrorAs(t, parseFlag(flag, "--foo=barr"), new(*ArgumentError))
 }
 
 func ExampleFlagBuilder_Validate() {
@@ -107,6 +151,7 @@ func ExampleFlagBuilder_Validate() {
 	// Output:
 	// ping: 127.0.0.1
 	// Argument error: --ip: invalid IP: 256.0.0.1
+	// See 'ping --help'.
 }
 
 func ExampleBitField() {
@@ -120,9 +165,9 @@ func ExampleBitField() {
 
 	cmd := NewCommand("user-allow", "").
 		Flags(
-			BitField(&mode, UserRead, "r", false, "Enable user read"),
-			BitField(&mode, UserWrite, "w", false, "Enable user write"),
-			BitField(&mode, UserExecute, "x", false, "Enable user execute"),
+			BitField(&mode, UserRead, "", false, "Enable user read").ShortName("r"),
+			BitField(&mode, UserWrite, "", false, "Enable user write").ShortName("w"),
+			BitField(&mode, UserExecute, "", false, "Enable user execute").ShortName("x"),
 		).
 		HandleFunc(func(args []string) (exitCode int) {
 			fmt.Printf("File mode: %s\n", os.FileMode(mode))
@@ -160,6 +205,7 @@ func ExampleFunc() {
 	// Output:
 	// ping: 127.0.0.1
 	// Argument error: --ip: invalid IP: 256.0.0.1
+	// See 'ping --help'.
 }
 
 func ExampleStrings() {
@@ -179,3 +225,297 @@ func ExampleStrings() {
 	RunWithArgs(cmd, "--name=foo", "--name=bar")
 	// Output: Created new widgets: foo, bar
 }
+
+func TestUniqueSorted(t *testing.T) {
+	var unique, sorted, both []string
+	cmd := NewCommand("test", "").
+		Flags(
+			Strings(&unique, "unique", nil, "").Unique(),
+			Strings(&sorted, "sorted", nil, "").Sorted(),
+			Strings(&both, "both", nil, "").Unique().Sorted(),
+		).
+		Must()
+	args := make([]string, 0)
+	for _, s := range []string{"b", "c", "a", "c", "b"} {
+		args = append(args, "--unique="+s, "--sorted="+s, "--both="+s)
+	}
+	if _, err := cmd.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"b", "c", "a"}, unique)
+	assertStrings(t, []string{"a", "b", "b", "c", "c"}, sorted)
+	assertStrings(t, []string{"a", "b", "c"}, both)
+
+	var n int
+	if _, err := Int(&n, "", 0, "").ShortName("n").Sorted().Flag(); err == nil {
+		t.Errorf("expected error sorting an int flag")
+	}
+}
+
+func TestStringSliceDefaults(t *testing.T) {
+	defaultValue := []string{"b", "a"}
+	var replace, appended, unset []string
+	cmd := NewCommand("test", "").
+		Flags(
+			Strings(&replace, "replace", defaultValue, "").Sorted(),
+			Strings(&appended, "append", defaultValue, "").AppendToDefault(),
+			Strings(&unset, "unset", defaultValue, "").Sorted(),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"--replace=c", "--append=c"}); err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"c"}, replace)
+	assertStrings(t, []string{"b", "a", "c"}, appended)
+	assertStrings(t, []string{"a", "b"}, unset)
+	assertStrings(t, []string{"b", "a"}, defaultValue)
+
+	var s string
+	if _, err := String(&s, "", "", "").ShortName("s").AppendToDefault().Flag(); err == nil {
+		t.Errorf("expected error for non-slice flag")
+	}
+}
+
+func TestBoolForms(t *testing.T) {
+	tests := []struct {
+		Args   []string
+		Syntax BoolSyntax
+		Expect bool
+		Form   BoolForm
+		Rest   []string
+	}{
+		{[]string{"--foo"}, BoolDefault, true, BoolFormBare, nil},
+		{[]string{"--foo=true"}, BoolDefault, true, BoolFormAttached, nil},
+		{[]string{"--foo=false"}, BoolDefault, false, BoolFormAttached, nil},
+		{[]string{"-f=false"}, BoolDefault, false, BoolFormAttached, nil},
+		{[]string{"--foo", "false"}, BoolDefault, true, BoolFormBare, []string{"false"}},
+		{[]string{"--foo", "false"}, BoolSeparate, false, BoolFormSeparate, nil},
+		{[]string{"--foo", "true"}, BoolSeparate, true, BoolFormSeparate, nil},
+		{[]string{"--foo", "x"}, BoolSeparate, true, BoolFormBare, []string{"x"}},
+		{[]string{"x"}, BoolSeparate, false, BoolFormNone, []string{"x"}},
+	}
+	for _, test := range tests {
+		var foo bool
+		var rest []string
+		cmd, err := NewCommand("test", "").
+			BoolSyntax(test.Syntax).
+			Flags(
+				Bool(&foo, "foo", false, "").ShortName("f"),
+				Strings(&rest, "rest", nil, "").Positional(),
+			).
+			Must().
+			Parse(test.Args)
+		if err != nil {
+			t.Errorf("%v: %v", test.Args, err)
+			continue
+		}
+		assertBool(t, test.Expect, foo)
+		assertStrings(t, test.Rest, rest)
+		if form := cmd.BoolForm("foo"); form != test.Form {
+			t.Errorf("%v: expected form %v, got %v", test.Args, test.Form, form)
+		}
+	}
+
+	var foo bool
+	_, err := NewCommand("test", "").
+		Flags(Bool(&foo, "foo", false, "")).
+		Must().
+		Parse([]string{"--foo=nope"})
+	assertErrorAs(t, err, new(*ArgumentError))
+}
+
+func TestOnSet(t *testing.T) {
+	var level string
+	seen := make([]string, 0)
+	cmd := NewCommand("app", "").
+		Flags(
+			String(&level, "log-level", "info", "").
+				Env("APP_LOG_LEVEL").
+				OnSet(func(value string) {
+					// the flag value is set before OnSet is called
+					assertString(t, value, level)
+					seen = append(seen, value)
+				}),
+			Strings(nil, "tag", nil, "").OnSet(func(value string) {
+				seen = append(seen, "tag:"+value)
+			}),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"--log-level", "debug", "--tag", "a", "--tag", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"debug", "tag:a", "tag:b"}, seen)
+
+	seen = seen[:0]
+	t.Setenv("APP_LOG_LEVEL", "warn")
+	if _, err := cmd.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"warn"}, seen)
+
+	// invalid values do not call OnSet
+	seen = seen[:0]
+	cmd.FlagGroups[0].Flags[0].Validate = func(arg string) error { return errors.New("invalid") }
+	if _, err := cmd.Parse([]string{"--log-level", "x"}); err == nil {
+		t.Fatal("expected error")
+	}
+	assertStrings(t, []string{}, seen)
+}
+
+func TestRequiredIf(t *testing.T) {
+	newCmd := func() *Command {
+		var mode, key string
+		var tags []string
+		return NewCommand("app", "").
+			Flags(
+				String(&mode, "mode", "local", "").Env("APP_MODE"),
+				Strings(&tags, "tag", nil, ""),
+			).
+			Subcommands(
+				NewCommand("deploy", "").
+					Flags(
+						String(&key, "ssh-key", "", "").
+							RequiredIf("mode", "remote").
+							RequiredIf("tag", "ssh"),
+					).
+					HandleFunc(func(args []string) int { return 0 }),
+			).
+			Must()
+	}
+	tests := []struct {
+		Args []string
+		Err  string
+	}{
+		{[]string{"deploy"}, ""},
+		{[]string{"--mode", "remote", "deploy", "--ssh-key", "id_rsa"}, ""},
+		{[]string{"--mode=remote", "deploy"}, "missing argument: --ssh-key (required when --mode=remote)"},
+		{[]string{"--tag", "a", "--tag", "ssh", "deploy"}, "missing argument: --ssh-key (required when --tag=ssh)"},
+	}
+	for _, test := range tests {
+		_, err := newCmd().Parse(test.Args)
+		if test.Err == "" {
+			if err != nil {
+				t.Errorf("%v: %v", test.Args, err)
+			}
+			continue
+		}
+		assertErrorAs(t, err, new(*ArgumentError))
+		assertString(t, "xflags: --ssh-key: "+test.Err, err.Error())
+	}
+
+	// the condition may be met by the environment
+	t.Setenv("APP_MODE", "remote")
+	_, err := newCmd().Parse([]string{"deploy"})
+	assertErrorAs(t, err, new(*ArgumentError))
+
+	// conditions are checked when the command is built
+	_, err = NewCommand("app", "").
+		Subcommands(
+			NewCommand("deploy", "").
+				Flags(String(nil, "ssh-key", "", "").RequiredIf("mdoe", "remote")),
+		).
+		Command()
+	if err == nil {
+		t.Fatal("expected error for undeclared flag")
+	}
+	assertString(t, "xflags: deploy: --ssh-key: RequiredIf refers to an undeclared flag: mdoe", err.Error())
+}
+
+func TestVisibleIf(t *testing.T) {
+	expert := func(cmd *Command) bool { return os.Getenv("APP_EXPERT") != "" }
+	cmd := NewCommand("app", "").
+		Subcommands(
+			NewCommand("deploy", "").
+				Flags(
+					Bool(nil, "verbose", false, "Basic flag"),
+					Int(nil, "shards", 1, "Advanced flag").VisibleIf(expert),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+	usage := func(cmd *Command) string {
+		w := new(strings.Builder)
+		if err := cmd.WriteUsage(w); err != nil {
+			t.Fatal(err)
+		}
+		return w.String()
+	}
+	hint := "Run 'app deploy --help-all' to show all options."
+
+	_, err := cmd.Parse([]string{"deploy", "--help"})
+	var helpErr *HelpError
+	assertErrorAs(t, err, &helpErr)
+	assertBool(t, false, helpErr.All)
+	help := usage(helpErr.Cmd)
+	if strings.Contains(help, "--shards") || !strings.Contains(help, hint) {
+		t.Errorf("expected --shards to be hidden, got:\n%s", help)
+	}
+
+	// hidden flags may still be specified and are suggested
+	if _, err := cmd.Parse([]string{"deploy", "--shards", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmd.Parse([]string{"deploy", "--shard", "3"})
+	var argErr *ArgumentError
+	if assertErrorAs(t, err, &argErr) {
+		assertStrings(t, []string{"--shards"}, argErr.Suggestions)
+	}
+
+	_, err = cmd.Parse([]string{"deploy", "--help", "--all"})
+	assertErrorAs(t, err, &helpErr)
+	assertBool(t, true, helpErr.All)
+	w := new(strings.Builder)
+	if err := helpErr.Cmd.writeUsage(w, helpErr.level()); err != nil {
+		t.Fatal(err)
+	}
+	help = w.String()
+	if !strings.Contains(help, "--shards") || strings.Contains(help, hint) {
+		t.Errorf("expected --shards to be shown, got:\n%s", help)
+	}
+
+	t.Setenv("APP_EXPERT", "1")
+	if help := usage(helpErr.Cmd); !strings.Contains(help, "--shards") {
+		t.Errorf("expected --shards to be shown, got:\n%s", help)
+	}
+}
+
+func TestCommon(t *testing.T) {
+	cmd := NewCommand("app", "").
+		Flags(
+			Bool(nil, "verbose", false, "Common flag").Common(),
+			Int(nil, "shards", 1, "Uncommon flag").Env("APP_SHARDS"),
+			String(nil, "file", "", "Positional argument").Positional(),
+		).
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+	tests := []struct {
+		Args   []string
+		Shards bool
+		Hint   string
+	}{
+		{[]string{"-h"}, false, "Run 'app --help' to show all options."},
+		{[]string{"--help"}, true, ""},
+		{[]string{"--help-all"}, true, ""},
+		{[]string{"-h", "--all"}, true, ""},
+	}
+	for _, test := range tests {
+		_, err := cmd.Parse(test.Args)
+		var helpErr *HelpError
+		assertErrorAs(t, err, &helpErr)
+		w := new(strings.Builder)
+		if err := cmd.writeUsage(w, helpErr.level()); err != nil {
+			t.Fatal(err)
+		}
+		help := w.String()
+		for _, s := range []string{"--verbose", "FILE"} {
+			if !strings.Contains(help, s) {
+				t.Errorf("%v: expected help to contain %q, got:\n%s", test.Args, s, help)
+			}
+		}
+		assertBool(t, test.Shards, strings.Contains(help, "--shards"))
+		assertBool(t, test.Shards, strings.Contains(help, "APP_SHARDS"))
+		if test.Hint != "" && !strings.Contains(help, test.Hint) {
+			t.Errorf("%v: expected help to contain %q, got:\n%s", test.Args, test.Hint, help)
+		}
+	}
+}
@@ -12,38 +12,67 @@ type FormatFunc func(w io.Writer, cmd *Command) error
 
 // Format is the default FormatFunc to print help messages for a commands.
 func Format(w io.Writer, cmd *Command) error {
+	return format(w, cmd, helpFull)
+}
+
+// format prints the help message of cmd with the given level of detail.
+func format(w io.Writer, cmd *Command, level helpLevel) error {
 	aw := newAggregatedWriter(w)
-	if err := printUsage(aw, cmd); err != nil {
+	if err := printUsage(aw, cmd, level); err != nil {
 		return err
 	}
-	if cmd.Usage != "" {
-		fmt.Fprintf(aw, "\n%s\n", cmd.Usage)
+	if usage := cmd.UsageText(); usage != "" {
+		fmt.Fprintf(aw, "\n%s\n", usage)
 	}
-	if err := detailPositionals(aw, cmd); err != nil {
+	if err := detailPositionals(aw, cmd, level); err != nil {
 		return err
 	}
 	for _, group := range cmd.FlagGroups {
-		if err := detailFlagGroup(aw, group); err != nil {
+		if err := detailFlagGroup(aw, cmd, group, level); err != nil {
 			return err
 		}
 	}
 	if err := detailSubcommands(aw, cmd.Subcommands); err != nil {
 		return err
 	}
-	if err := detailEnvVars(aw, cmd); err != nil {
+	name := strings.Join(commandPath(cmd), " ")
+	if level == helpBrief && cmd.hasCommon() {
+		fmt.Fprintf(aw, "\nRun '%s --help' to show all options.\n", name)
+		return aw.Err()
+	}
+	if err := detailEnvVars(aw, cmd, level); err != nil {
+		return err
+	}
+	if err := detailExitCodes(aw, cmd); err != nil {
 		return err
 	}
-	if cmd.Synopsis != "" {
-		fmt.Fprintf(aw, "\n%s\n", cmd.Synopsis)
+	if synopsis := cmd.SynopsisText(); synopsis != "" {
+		fmt.Fprintf(aw, "\n%s\n", synopsis)
+	}
+	if hasConditional(cmd, level) {
+		fmt.Fprintf(aw, "\nRun '%s --help-all' to show all options.\n", name)
 	}
 	return aw.Err()
 }
 
-func getPositionals(cmd *Command) []*Flag {
+// hasConditional reports whether any flag of cmd is hidden from its help
+// message with the given level of detail by VisibleIf.
+func hasConditional(cmd *Command, level helpLevel) bool {
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			if !flag.Hidden && cmd.featureEnabled(flag.Feature) && isConditional(cmd, flag, level) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func getPositionals(cmd *Command, level helpLevel) []*Flag {
 	a := make([]*Flag, 0, 8)
 	for _, group := range cmd.FlagGroups {
 		for _, flag := range group.Flags {
-			if flag.Hidden || !flag.Positional {
+			if isHiddenFromHelp(cmd, flag, level) || !flag.Positional {
 				continue
 			}
 			a = append(a, flag)
@@ -52,34 +81,38 @@ func getPositionals(cmd *Command) []*Flag {
 	return a
 }
 
-func hasRegular(cmd *Command) bool {
+func hasRegular(cmd *Command, level helpLevel) bool {
 	if cmd == nil {
 		return false
 	}
 	for _, group := range cmd.FlagGroups {
 		for _, flag := range group.Flags {
-			if flag.Hidden || flag.Positional {
+			if isHiddenFromHelp(cmd, flag, level) || flag.Positional {
 				continue
 			}
 			return true
 		}
 	}
-	return hasRegular(cmd.Parent)
+	return hasRegular(cmd.Parent, level)
 }
 
-func printUsage(w io.Writer, cmd *Command) error {
+func printUsage(w io.Writer, cmd *Command, level helpLevel) error {
 	fullName := cmd.Name
 	for p := cmd.Parent; p != nil; p = p.Parent {
 		fullName = fmt.Sprintf("%s %s", p.Name, fullName)
 	}
 	fmt.Fprintf(w, "Usage: %s", fullName)
-	if hasRegular(cmd) {
+	if hasRegular(cmd, level) {
 		fmt.Fprintf(w, " [OPTIONS]")
 	}
 	if len(cmd.Subcommands) > 0 {
 		fmt.Fprintf(w, " COMMAND")
 	}
-	for _, flag := range getPositionals(cmd) {
+	if cmd.usageSyntax != "" {
+		fmt.Fprintf(w, " %s\n", cmd.usageSyntax)
+		return nil
+	}
+	for _, flag := range getPositionals(cmd, level) {
 		name := strings.ToUpper(flag.Name)
 		if flag.MinCount == 0 {
 			if flag.MaxCount == 1 {
@@ -99,8 +132,8 @@ func printUsage(w io.Writer, cmd *Command) error {
 	return nil
 }
 
-func detailPositionals(w io.Writer, cmd *Command) error {
-	flags := getPositionals(cmd)
+func detailPositionals(w io.Writer, cmd *Command, level helpLevel) error {
+	flags := getPositionals(cmd, level)
 	if len(flags) == 0 {
 		return nil
 	}
@@ -108,9 +141,9 @@ func detailPositionals(w io.Writer, cmd *Command) error {
 	w = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	for _, flag := range flags {
 		fmt.Fprintf(w, "  %s", strings.ToUpper(flag.Name))
-		if flag.Usage != "" {
-			fmt.Fprintf(w, "\t%s", flag.Usage)
-			if flag.ShowDefault {
+		if usage := flag.UsageText(); usage != "" {
+			fmt.Fprintf(w, "\t%s", usage)
+			if flag.ShowDefault && !flag.Secret {
 				fmt.Fprintf(w, " (default: %s)", flag.Value)
 			}
 		}
@@ -119,10 +152,10 @@ func detailPositionals(w io.Writer, cmd *Command) error {
 	return w.(*tabwriter.Writer).Flush()
 }
 
-func filterRegular(flags []*Flag) []*Flag {
+func filterRegular(cmd *Command, flags []*Flag, level helpLevel) []*Flag {
 	a := make([]*Flag, 0, 8)
 	for _, flag := range flags {
-		if flag.Hidden || flag.Positional {
+		if isHiddenFromHelp(cmd, flag, level) || flag.Positional {
 			continue
 		}
 		a = append(a, flag)
@@ -130,8 +163,8 @@ func filterRegular(flags []*Flag) []*Flag {
 	return a
 }
 
-func detailFlagGroup(w io.Writer, group *FlagGroup) error {
-	flags := filterRegular(group.Flags)
+func detailFlagGroup(w io.Writer, cmd *Command, group *FlagGroup, level helpLevel) error {
+	flags := filterRegular(cmd, group.Flags, level)
 	if len(flags) == 0 {
 		return nil
 	}
@@ -149,23 +182,26 @@ func detailFlagGroup(w io.Writer, group *FlagGroup) error {
 				shortName = fmt.Sprintf("-%s", flag.ShortName)
 			}
 		}
-		fmt.Fprintf(w, "  %s\t%s\t %s", shortName, name, flag.Usage)
-		if flag.ShowDefault {
+		fmt.Fprintf(w, "  %s\t%s\t %s", shortName, name, flag.UsageText())
+		if flag.ShowDefault && !flag.Secret {
 			fmt.Fprintf(w, " (default: %s)", flag.Value)
 		}
+		for _, cond := range flag.requiredIf {
+			fmt.Fprintf(w, " (required when %s)", cond)
+		}
 		fmt.Fprintf(w, "\n")
 	}
 	return w.(*tabwriter.Writer).Flush()
 }
 
-func getEnvVars(a []*Flag, cmd *Command) []*Flag {
+func getEnvVars(a []*Flag, cmd *Command, level helpLevel) []*Flag {
 	if cmd == nil {
 		return a
 	}
-	a = getEnvVars(a, cmd.Parent)
+	a = getEnvVars(a, cmd.Parent, level)
 	for _, group := range cmd.FlagGroups {
 		for _, flag := range group.Flags {
-			if flag.EnvVar == "" || flag.Hidden {
+			if flag.EnvVar == "" || isHiddenFromHelp(cmd, flag, level) {
 				continue
 			}
 			a = append(a, flag)
@@ -174,8 +210,8 @@ func getEnvVars(a []*Flag, cmd *Command) []*Flag {
 	return a
 }
 
-func detailEnvVars(w io.Writer, cmd *Command) error {
-	flags := getEnvVars(nil, cmd)
+func detailEnvVars(w io.Writer, cmd *Command, level helpLevel) error {
+	flags := getEnvVars(nil, cmd, level)
 	if len(flags) == 0 {
 		return nil
 	}
@@ -186,12 +222,25 @@ func detailEnvVars(w io.Writer, cmd *Command) error {
 			w,
 			"  %s\t%s\n",
 			strings.ToUpper(flag.EnvVar),
-			flag.Usage,
+			flag.UsageText(),
 		)
 	}
 	return w.(*tabwriter.Writer).Flush()
 }
 
+func detailExitCodes(w io.Writer, cmd *Command) error {
+	codes, usage := cmd.exitCodes()
+	if len(codes) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "\nExit status:\n")
+	w = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %d\t%s\n", code, usage[code])
+	}
+	return w.(*tabwriter.Writer).Flush()
+}
+
 func detailSubcommands(w io.Writer, subcommands []*Command) error {
 	// TODO: wrap final column to terminal
 	if len(subcommands) == 0 {
@@ -200,10 +249,10 @@ func detailSubcommands(w io.Writer, subcommands []*Command) error {
 	fmt.Fprintf(w, "\nCommands:\n")
 	w = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	for _, cmd := range subcommands {
-		if cmd.Hidden {
+		if isHiddenCommand(cmd) {
 			continue
 		}
-		fmt.Fprintf(w, "  %s\t%s\n", cmd.Name, cmd.Usage)
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.Name, cmd.UsageText())
 	}
 	return w.(*tabwriter.Writer).Flush()
 }
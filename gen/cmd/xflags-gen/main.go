@@ -0,0 +1,77 @@
+// Command xflags-gen generates Go source code declaring xflags commands from
+// a YAML or JSON description of a command line interface.
+//
+//	xflags-gen --package main --output cli_gen.go cli.yaml
+//
+// It may be invoked with go:generate:
+//
+//	//go:generate xflags-gen -o cli_gen.go cli.yaml
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/cavaliergopher/xflags"
+	"github.com/cavaliergopher/xflags/gen"
+)
+
+var (
+	flagPackage string
+	flagOutput  string
+	flagInput   string
+)
+
+var App = xflags.NewCommand("xflags-gen", "Generate xflags commands from a YAML or JSON specification").
+	Flags(
+		xflags.String(&flagPackage, "package", "main", "Package name of the generated code").
+			ShortName("p").
+			ShowDefault(),
+		xflags.String(&flagOutput, "output", "", "Write the generated code to a file instead of stdout").
+			ShortName("o"),
+		xflags.String(&flagInput, "input", "-", "YAML or JSON specification file or - for stdin").
+			Positional(),
+	).
+	HandleFunc(run)
+
+func run(args []string) int {
+	var r io.Reader = os.Stdin
+	if flagInput != "-" {
+		f, err := os.Open(flagInput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	cmd, err := gen.Decode(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", flagInput, err)
+		return 1
+	}
+	w := new(bytes.Buffer)
+	if err := gen.Generate(w, flagPackage, cmd); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if flagOutput == "" {
+		if _, err := w.WriteTo(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if err := ioutil.WriteFile(flagOutput, w.Bytes(), 0666); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(xflags.Run(App))
+}
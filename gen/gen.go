@@ -0,0 +1,235 @@
+// Package gen generates Go source code that declares xflags commands from a
+// declarative description of a command line interface.
+//
+// Describing a command line interface as data allows it to be reviewed
+// independently of its implementation and shared with tools written in other
+// languages. The xflags-gen command is a thin wrapper around this package:
+//
+//	go install github.com/cavaliergopher/xflags/gen/cmd/xflags-gen@latest
+//
+// The package is a separate module so that the xflags module has no
+// dependencies.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var builderFuncs = map[string]string{
+	"bool":     "Bool",
+	"duration": "Duration",
+	"float64":  "Float64",
+	"int":      "Int",
+	"int64":    "Int64",
+	"string":   "String",
+	"strings":  "Strings",
+	"uint":     "Uint",
+	"uint64":   "Uint64",
+}
+
+// Generate writes formatted Go source code to w which declares a variable for
+// every flag and a CommandBuilder for cmd and each of its subcommands. The
+// generated code belongs to the named package.
+func Generate(w io.Writer, pkg string, cmd *Command) error {
+	if err := cmd.validate(""); err != nil {
+		return err
+	}
+	g := &generator{}
+	g.collect(cmd, nil)
+	g.printf("// Code generated by xflags-gen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkg)
+	g.printf("import (\n")
+	if g.usesTime {
+		g.printf("\t\"time\"\n\n")
+	}
+	g.printf("\t\"github.com/cavaliergopher/xflags\"\n")
+	g.printf(")\n\n")
+	if len(g.flags) > 0 {
+		g.printf("var (\n")
+		for _, flag := range g.flags {
+			g.printf("\t%s %s\n", flag.varName, Types[flag.Type])
+		}
+		g.printf(")\n\n")
+	}
+	for _, c := range g.commands {
+		g.command(c)
+	}
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("gen: %v", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type namedCommand struct {
+	*Command
+	varName string
+}
+
+type namedFlag struct {
+	*Flag
+	varName string
+}
+
+type generator struct {
+	buf      bytes.Buffer
+	commands []*namedCommand
+	flags    []*namedFlag
+	flagVars map[*Flag]string
+	usesTime bool
+}
+
+func (g *generator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&g.buf, format, a...)
+}
+
+// collect assigns variable names to each command and flag in depth-first
+// order.
+func (g *generator) collect(cmd *Command, path []string) {
+	if g.flagVars == nil {
+		g.flagVars = make(map[*Flag]string)
+	}
+	if len(path) > 0 {
+		path = append(path[:len(path):len(path)], cmd.Name)
+	} else {
+		// the name of the root command is often os.Args[0] so it is omitted
+		// from derived variable names
+		path = []string{""}
+	}
+	varName := cmd.Var
+	if varName == "" {
+		varName = identifier(path[1:]...) + "Command"
+		if len(path) == 1 {
+			varName = "App"
+		}
+	}
+	g.commands = append(g.commands, &namedCommand{Command: cmd, varName: varName})
+	for _, flag := range cmd.allFlags() {
+		name := flag.Var
+		if name == "" {
+			name = "Flag" + identifier(append(path[1:len(path):len(path)], flag.Name)...)
+		}
+		g.flags = append(g.flags, &namedFlag{Flag: flag, varName: name})
+		g.flagVars[flag] = name
+		if flag.Type == "duration" {
+			g.usesTime = true
+		}
+	}
+	for _, sub := range cmd.Subcommands {
+		g.collect(sub, path)
+	}
+}
+
+func (g *generator) command(cmd *namedCommand) {
+	if cmd.Usage != "" || cmd.Synopsis != "" {
+		g.printf("// %s is the %q command.\n", cmd.varName, cmd.Name)
+	}
+	g.printf("var %s = xflags.NewCommand(%q, %q)", cmd.varName, cmd.Name, cmd.Usage)
+	if cmd.Synopsis != "" {
+		g.printf(".\nSynopsis(%q)", cmd.Synopsis)
+	}
+	if cmd.Hidden {
+		g.printf(".\nHidden()")
+	}
+	if cmd.WithTerminator {
+		g.printf(".\nWithTerminator()")
+	}
+	if len(cmd.Flags) > 0 {
+		g.printf(".\nFlags(\n")
+		for _, flag := range cmd.Flags {
+			g.flag(flag)
+		}
+		g.printf(")")
+	}
+	for _, group := range cmd.FlagGroups {
+		g.printf(".\nFlagGroup(\n%q,\n%q,\n", group.Name, group.Usage)
+		for _, flag := range group.Flags {
+			g.flag(flag)
+		}
+		g.printf(")")
+	}
+	if len(cmd.Subcommands) > 0 {
+		g.printf(".\nSubcommands(\n")
+		for _, sub := range cmd.Subcommands {
+			for _, c := range g.commands {
+				if c.Command == sub {
+					g.printf("%s,\n", c.varName)
+				}
+			}
+		}
+		g.printf(")")
+	}
+	if cmd.Handler != "" {
+		g.printf(".\nHandleFunc(%s)", cmd.Handler)
+	}
+	g.printf("\n\n")
+}
+
+func (g *generator) flag(flag *Flag) {
+	// defaultExpr was checked by validate
+	value, _ := flag.defaultExpr()
+	g.printf(
+		"xflags.%s(&%s, %q, %s, %q)",
+		builderFuncs[flag.Type],
+		g.flagVars[flag],
+		flag.Name,
+		value,
+		flag.Usage,
+	)
+	if flag.ShortName != "" {
+		g.printf(".\nShortName(%q)", flag.ShortName)
+	}
+	if flag.Positional {
+		g.printf(".\nPositional()")
+	}
+	if flag.Required {
+		g.printf(".\nRequired()")
+	}
+	if len(flag.NArgs) == 2 {
+		g.printf(".\nNArgs(%d, %d)", flag.NArgs[0], flag.NArgs[1])
+	}
+	if flag.Hidden {
+		g.printf(".\nHidden()")
+	}
+	if flag.ShowDefault {
+		g.printf(".\nShowDefault()")
+	}
+	if flag.Env != "" {
+		g.printf(".\nEnv(%q)", flag.Env)
+	}
+	if len(flag.Choices) > 0 {
+		choices := make([]string, len(flag.Choices))
+		for i, choice := range flag.Choices {
+			choices[i] = fmt.Sprintf("%q", choice)
+		}
+		g.printf(".\nChoices(%s)", strings.Join(choices, ", "))
+	}
+	g.printf(",\n")
+}
+
+// identifier joins the given names into a single exported Go identifier. For
+// example, "dry-run" becomes "DryRun".
+func identifier(names ...string) string {
+	var sb strings.Builder
+	for _, name := range names {
+		upper := true
+		for _, r := range name {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package gen
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	f, err := os.Open("testdata/widgets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cmd, err := Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if err := Generate(w, "main", cmd); err != nil {
+		t.Fatal(err)
+	}
+	src := w.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	expect := []string{
+		"FlagVerbose    bool\n",
+		"FlagTimeout    time.Duration\n",
+		"FlagCreateName []string\n",
+		`var App = xflags.NewCommand("widgets", "Manage widgets").`,
+		`xflags.Duration(&FlagTimeout, "timeout", 30*time.Second, "Request timeout").`,
+		`Choices("text", "json"),`,
+		`var CreateCommand = xflags.NewCommand("create", "Make new widgets").`,
+		`NArgs(1, 0),`,
+		`HandleFunc(create)`,
+	}
+	for _, s := range expect {
+		if !strings.Contains(src, s) {
+			t.Errorf("expected generated code to contain %q, got:\n%s", s, src)
+		}
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	generate := func(name string) string {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		cmd, err := Decode(f)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		w := new(bytes.Buffer)
+		if err := Generate(w, "main", cmd); err != nil {
+			t.Fatal(err)
+		}
+		return w.String()
+	}
+	expect := generate("testdata/widgets.json")
+	if actual := generate("testdata/widgets.yaml"); actual != expect {
+		t.Errorf("expected the same code as for JSON:\n%s\ngot:\n%s", expect, actual)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	testCases := []string{
+		`{}`,
+		`{"name": "test", "unknown": true}`,
+		`{"name": "test", "flags": [{"name": "foo", "type": "complex128"}]}`,
+		`{"name": "test", "flags": [{"name": "foo", "type": "int", "default": "one"}]}`,
+		`{"name": "test", "flags": [{"name": "foo", "type": "duration", "default": "1y"}]}`,
+		`{"name": "test", "flags": [{"name": "foo", "type": "int", "nargs": [1]}]}`,
+		`{"name": "test", "subcommands": [{"flags": []}]}`,
+		"name: test\nunknown: true\n",
+		"name: test\nflags: {1: 2}\n",
+		"name: [test\n",
+	}
+	for _, s := range testCases {
+		if _, err := Decode(strings.NewReader(s)); err == nil {
+			t.Errorf("expected error decoding: %s", s)
+		}
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	testCases := map[string][]string{
+		"DryRun":        {"dry-run"},
+		"CreateDryRun":  {"create", "dry-run"},
+		"GopherType":    {"gopher_type"},
+		"X":             {"x"},
+		"ListAllItems2": {"list", "all items2"},
+	}
+	for expect, names := range testCases {
+		if actual := identifier(names...); actual != expect {
+			t.Errorf("expected %q, got %q", expect, actual)
+		}
+	}
+}
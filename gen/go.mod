@@ -0,0 +1,10 @@
+module github.com/cavaliergopher/xflags/gen
+
+go 1.18
+
+require (
+	github.com/cavaliergopher/xflags v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/cavaliergopher/xflags => ..
@@ -0,0 +1,255 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Command describes a command and all of its flags and subcommands.
+type Command struct {
+	// Name is the name of the command as it is typed on the command line.
+	Name string `json:"name"`
+
+	// Var is the name of the Go variable that the generated CommandBuilder
+	// is assigned to. If empty, a name is derived from the command path.
+	Var string `json:"var,omitempty"`
+
+	Usage          string       `json:"usage,omitempty"`
+	Synopsis       string       `json:"synopsis,omitempty"`
+	Hidden         bool         `json:"hidden,omitempty"`
+	WithTerminator bool         `json:"withTerminator,omitempty"`
+	Flags          []*Flag      `json:"flags,omitempty"`
+	FlagGroups     []*FlagGroup `json:"flagGroups,omitempty"`
+	Subcommands    []*Command   `json:"subcommands,omitempty"`
+
+	// Handler is the name of a function in the generated package with the
+	// signature func(args []string) int that handles the command.
+	Handler string `json:"handler,omitempty"`
+}
+
+// FlagGroup describes a group of flags that are shown under a common heading
+// in help messages.
+type FlagGroup struct {
+	Name  string  `json:"name"`
+	Usage string  `json:"usage"`
+	Flags []*Flag `json:"flags,omitempty"`
+}
+
+// Flag describes a command line flag.
+type Flag struct {
+	Name      string `json:"name"`
+	ShortName string `json:"shortName,omitempty"`
+
+	// Type is the type of the flag value. It must be one of the types named
+	// in Types.
+	Type string `json:"type"`
+
+	// Var is the name of the Go variable that stores the value of the flag.
+	// If empty, a name is derived from the command path and flag name.
+	Var string `json:"var,omitempty"`
+
+	// Default is the default value of the flag. For "strings" flags it must
+	// be a list of strings. For "duration" flags it must be a string
+	// acceptable to time.ParseDuration.
+	Default json.RawMessage `json:"default,omitempty"`
+
+	Usage       string   `json:"usage,omitempty"`
+	Positional  bool     `json:"positional,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	NArgs       []int    `json:"nargs,omitempty"`
+	Hidden      bool     `json:"hidden,omitempty"`
+	ShowDefault bool     `json:"showDefault,omitempty"`
+	Env         string   `json:"env,omitempty"`
+	Choices     []string `json:"choices,omitempty"`
+}
+
+// Types lists the flag types supported by the generator and the Go type of
+// the variable generated for each.
+var Types = map[string]string{
+	"bool":     "bool",
+	"duration": "time.Duration",
+	"float64":  "float64",
+	"int":      "int",
+	"int64":    "int64",
+	"string":   "string",
+	"strings":  "[]string",
+	"uint":     "uint",
+	"uint64":   "uint64",
+}
+
+// Decode reads a YAML or JSON description of a command from r. The keys of
+// YAML documents are the same as those of JSON documents.
+func Decode(r io.Reader) (*Command, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("gen: %v", err)
+	}
+	// YAML is a superset of JSON, so JSON documents are read as YAML and
+	// converted to JSON to be decoded with the same field names and
+	// validation.
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("gen: %v", err)
+	}
+	if b, err = json.Marshal(doc); err != nil {
+		return nil, fmt.Errorf("gen: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	cmd := &Command{}
+	if err := dec.Decode(cmd); err != nil {
+		return nil, fmt.Errorf("gen: %v", err)
+	}
+	if err := cmd.validate(""); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (c *Command) validate(path string) error {
+	if c.Name == "" {
+		return fmt.Errorf("gen: %s: command name cannot be empty", path)
+	}
+	path = strings.TrimSpace(path + " " + c.Name)
+	for _, flag := range c.allFlags() {
+		if err := flag.validate(path); err != nil {
+			return err
+		}
+	}
+	for _, sub := range c.Subcommands {
+		if err := sub.validate(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Command) allFlags() []*Flag {
+	flags := make([]*Flag, 0, len(c.Flags))
+	flags = append(flags, c.Flags...)
+	for _, group := range c.FlagGroups {
+		flags = append(flags, group.Flags...)
+	}
+	return flags
+}
+
+func (c *Flag) validate(path string) error {
+	if c.Name == "" {
+		return fmt.Errorf("gen: %s: flag name cannot be empty", path)
+	}
+	if _, ok := Types[c.Type]; !ok {
+		return fmt.Errorf("gen: %s: --%s: unsupported type: %q", path, c.Name, c.Type)
+	}
+	if len(c.NArgs) != 0 && len(c.NArgs) != 2 {
+		return fmt.Errorf("gen: %s: --%s: nargs must be [min, max]", path, c.Name)
+	}
+	if _, err := c.defaultExpr(); err != nil {
+		return fmt.Errorf("gen: %s: --%s: invalid default: %v", path, c.Name, err)
+	}
+	return nil
+}
+
+// defaultExpr returns a Go expression for the default value of the flag.
+func (c *Flag) defaultExpr() (string, error) {
+	isSet := len(c.Default) > 0 && string(c.Default) != "null"
+	switch c.Type {
+	case "bool":
+		var v bool
+		if isSet {
+			if err := json.Unmarshal(c.Default, &v); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%v", v), nil
+	case "duration":
+		var s string
+		if isSet {
+			if err := json.Unmarshal(c.Default, &s); err != nil {
+				return "", err
+			}
+		}
+		if s == "" {
+			return "0", nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return "", err
+		}
+		return durationExpr(d), nil
+	case "float64":
+		var v float64
+		if isSet {
+			if err := json.Unmarshal(c.Default, &v); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%v", v), nil
+	case "int", "int64":
+		var v int64
+		if isSet {
+			if err := json.Unmarshal(c.Default, &v); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%d", v), nil
+	case "uint", "uint64":
+		var v uint64
+		if isSet {
+			if err := json.Unmarshal(c.Default, &v); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%d", v), nil
+	case "string":
+		var v string
+		if isSet {
+			if err := json.Unmarshal(c.Default, &v); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%q", v), nil
+	case "strings":
+		var v []string
+		if isSet {
+			if err := json.Unmarshal(c.Default, &v); err != nil {
+				return "", err
+			}
+		}
+		if v == nil {
+			return "nil", nil
+		}
+		elems := make([]string, len(v))
+		for i, s := range v {
+			elems[i] = fmt.Sprintf("%q", s)
+		}
+		return fmt.Sprintf("[]string{%s}", strings.Join(elems, ", ")), nil
+	}
+	return "", fmt.Errorf("unsupported type: %q", c.Type)
+}
+
+// durationExpr returns a Go expression for d using the largest unit that
+// represents it exactly.
+func durationExpr(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, unit := range units {
+		if d%unit.d == 0 {
+			return fmt.Sprintf("%d * %s", d/unit.d, unit.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", int64(d))
+}
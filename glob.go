@@ -0,0 +1,154 @@
+package xflags
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// GlobMode specifies on which platforms FlagBuilder.Glob expands patterns.
+type GlobMode int
+
+const (
+	// GlobAlways expands patterns on every platform.
+	GlobAlways GlobMode = iota + 1
+
+	// GlobWindows expands patterns only on Windows, whose shells pass them
+	// to programs unexpanded. On other platforms, the shell has already
+	// expanded any unquoted patterns, so arguments are passed through
+	// literally and quoted patterns are preserved.
+	GlobWindows
+)
+
+// expands reports whether patterns are expanded on this platform.
+func (c GlobMode) expands() bool {
+	return c == GlobAlways || c == GlobWindows && runtime.GOOS == "windows"
+}
+
+// Glob specifies that arguments of this path flag that contain the
+// metacharacters "*", "?" or "[" are patterns that are expanded into the
+// paths that match them, in lexical order, when the command line is parsed.
+// Patterns use the syntax of path.Match with forward slashes as separators,
+// and a path element of "**" matches zero or more directories, as in
+// "src/**/*.go". As in shells, wildcards do not match a leading "." of a
+// file name unless the pattern does.
+//
+// Each matching path is validated and set individually, so the flag's Value
+// must be a slice, such as one created by Strings. A pattern that matches no
+// paths is an error. The mode specifies on which platforms patterns are
+// expanded; elsewhere, arguments are set literally.
+func (c *FlagBuilder) Glob(mode GlobMode) *FlagBuilder {
+	if _, ok := c.flag.Value.(*stringSliceValue); !ok {
+		if c.err == nil {
+			c.err = errorf("%s: value cannot store glob matches", c.flag.name())
+		}
+		return c
+	}
+	c.flag.glob = mode
+	return c
+}
+
+// isGlob reports whether s contains any glob metacharacters.
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// expandGlob returns the paths that match pattern, or pattern itself if it is
+// not a pattern.
+func expandGlob(pattern string) ([]string, error) {
+	if !isGlob(pattern) {
+		return []string{pattern}, nil
+	}
+	elems := strings.Split(filepath.ToSlash(pattern), "/")
+	for _, elem := range elems {
+		if _, err := path.Match(elem, ""); err != nil {
+			return nil, errorf("invalid glob pattern: %s", pattern)
+		}
+	}
+
+	// walk from the longest prefix of the pattern without metacharacters
+	i := 0
+	for i < len(elems)-1 && !isGlob(elems[i]) {
+		i++
+	}
+	root := filepath.FromSlash(strings.Join(elems[:i], "/"))
+	if root == "" {
+		root = "."
+		if i > 0 {
+			root = string(filepath.Separator) // absolute pattern
+		}
+	}
+	elems = elems[i:]
+	recursive := false
+	for _, elem := range elems {
+		recursive = recursive || elem == "**"
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil // skip unreadable directories
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		relElems := strings.Split(filepath.ToSlash(rel), "/")
+		if matchGlob(elems, relElems) {
+			if root == "." {
+				matches = append(matches, rel)
+			} else {
+				matches = append(matches, p)
+			}
+		}
+		if d.IsDir() && !recursive && len(relElems) >= len(elems) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errorf("%s: %v", pattern, errStr(err))
+	}
+	if len(matches) == 0 {
+		return nil, errorf("no files match pattern: %s", pattern)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchGlob reports whether the path elements name match the pattern
+// elements, where the element "**" matches zero or more path elements.
+func matchGlob(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if i > 0 && strings.HasPrefix(name[i-1], ".") {
+				return false // do not descend into hidden directories
+			}
+			if matchGlob(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if strings.HasPrefix(name[0], ".") && !strings.HasPrefix(pattern[0], ".") {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], name[0])
+	return ok && matchGlob(pattern[1:], name[1:])
+}
@@ -0,0 +1,79 @@
+package xflags
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"a.go",
+		"b.go",
+		"c.txt",
+		".hidden.go",
+		"pkg/d.go",
+		"pkg/sub/e.go",
+		".git/f.go",
+	} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	join := func(names ...string) []string {
+		for i, name := range names {
+			names[i] = filepath.Join(dir, filepath.FromSlash(name))
+		}
+		return names
+	}
+	pattern := func(s string) string { return filepath.ToSlash(dir) + "/" + s }
+
+	tests := []struct {
+		Args   []string
+		Expect []string
+	}{
+		{[]string{pattern("*.go")}, join("a.go", "b.go")},
+		{[]string{pattern("[bc].*")}, join("b.go", "c.txt")},
+		{[]string{pattern(".*.go")}, join(".hidden.go")},
+		{[]string{pattern("*/*.go")}, join("pkg/d.go")},
+		{[]string{pattern("**/*.go")}, join("a.go", "b.go", "pkg/d.go", "pkg/sub/e.go")},
+		{[]string{pattern("pkg/**")}, join("pkg/d.go", "pkg/sub", "pkg/sub/e.go")},
+		{[]string{pattern("c.txt"), pattern("*.go")}, join("c.txt", "a.go", "b.go")},
+		{[]string{"not-a-pattern"}, []string{"not-a-pattern"}},
+	}
+	for _, test := range tests {
+		var paths []string
+		flag := Strings(&paths, "files", nil, "").Glob(GlobAlways).Positional().Must()
+		if assertFlagParses(t, flag, test.Args...) {
+			assertStrings(t, test.Expect, paths)
+		}
+	}
+
+	// patterns that match nothing are errors
+	var paths []string
+	flag := Strings(&paths, "files", nil, "").Glob(GlobAlways).Must()
+	err := parseFlag(flag, "--files", pattern("*.rs"))
+	if assertBool(t, true, err != nil) {
+		assertString(t, "xflags: --files: no files match pattern: "+pattern("*.rs"), err.Error())
+	}
+	err = parseFlag(flag, "--files", pattern("[a"))
+	if assertBool(t, true, err != nil) {
+		assertString(t, "xflags: --files: invalid glob pattern: "+pattern("[a"), err.Error())
+	}
+
+	// patterns are passed through literally on other platforms
+	flag = Strings(&paths, "files", nil, "").Glob(GlobWindows).Must()
+	if assertFlagParses(t, flag, "--files", pattern("*.go")) && runtime.GOOS != "windows" {
+		assertStrings(t, []string{pattern("*.go")}, paths)
+	}
+
+	if _, err := String(nil, "file", "", "").Glob(GlobAlways).Flag(); err == nil {
+		t.Error("expected error for non-slice value")
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/cavaliergopher/xflags
 
-go 1.11
+go 1.18
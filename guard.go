@@ -0,0 +1,171 @@
+package xflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AnnotationRequiresRole is the annotation of commands that require roles,
+// set by RequiresRole. Its value is a comma-separated list of roles.
+const AnnotationRequiresRole = "xflags.requires-role"
+
+// ExitPermissionDenied is the exit code of commands whose invocation was
+// denied by a guard. It is EX_NOPERM of sysexits.h.
+const ExitPermissionDenied = 77
+
+// GuardFunc decides whether an invocation of a command is permitted. It returns
+// an error to deny the invocation.
+type GuardFunc func(ctx context.Context, inv *Invocation) error
+
+// PermissionError is the error of an invocation that was denied by a guard.
+type PermissionError struct {
+	Cmd   *Command // the command that was invoked
+	Roles []string // the required roles that the user does not have, if any
+	Err   error    // the error returned by the guard, if any
+}
+
+func (e *PermissionError) Unwrap() error { return e.Err }
+
+// ExitCode returns ExitPermissionDenied.
+func (e *PermissionError) ExitCode() int { return ExitPermissionDenied }
+
+func (e *PermissionError) Error() string { return "xflags: " + e.String() }
+
+func (e *PermissionError) String() string {
+	s := "permission denied"
+	if len(e.Roles) > 0 {
+		noun := "role"
+		if len(e.Roles) > 1 {
+			noun = "roles"
+		}
+		s += fmt.Sprintf(
+			": '%s' requires %s %s",
+			strings.Join(commandPath(e.Cmd), " "),
+			noun,
+			strings.Join(e.Roles, ", "),
+		)
+	}
+	if e.Err != nil {
+		s += ": " + errStr(e.Err)
+	}
+	return s
+}
+
+// Guard adds a GuardFunc that is called before each invocation of this command
+// and its subcommands by Run, Exec or Handle. If the guard returns an error, the
+// command is not run, the error is printed and the exit code is the code of
+// the error if it implements ExitCoder, or ExitPermissionDenied.
+//
+// Guards are called before any middleware and before the builtin behavior of
+// an invocation, such as printing the configuration for --print-config,
+// calling the function of OnUnknownCommand or printing the usage of a command
+// without a handler. The guards of parent commands are called first. Help
+// requested with -h or --help is not guarded.
+//
+// Guards are usually added to the root command to enforce an access policy,
+// such as the roles required by RequiresRole, across a whole command tree.
+func (c *CommandBuilder) Guard(fn GuardFunc) *CommandBuilder {
+	if fn == nil {
+		return c.error(errorf("%s: nil guard", c.cmd.Name))
+	}
+	c.cmd.guards = append(c.cmd.guards, fn)
+	return c
+}
+
+// guard calls the guards of the invoked command and its parents and returns
+// the error of the first guard that denies the invocation, or nil. The guards
+// are only called once for each Invocation, by Run, Exec or Handle.
+func (c *Invocation) guard(ctx context.Context) error {
+	if c.guarded {
+		return nil
+	}
+	c.guarded = true
+	var guards []GuardFunc
+	for p := c.cmd; p != nil; p = p.Parent {
+		guards = append(append([]GuardFunc(nil), p.guards...), guards...)
+	}
+	if len(guards) == 0 {
+		return nil
+	}
+	ctx = context.WithValue(ctx, invocationKey{}, c)
+	for _, fn := range guards {
+		err := fn(ctx, c)
+		if err == nil {
+			continue
+		}
+		var permErr *PermissionError
+		if !errors.As(err, &permErr) {
+			err = &PermissionError{Cmd: c.cmd, Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// RequiresRole annotates the command with roles that users must have to invoke
+// it or any of its subcommands. The roles are enforced by guards created with
+// RoleGuard. RequiresRole may be called more than once.
+func (c *CommandBuilder) RequiresRole(roles ...string) *CommandBuilder {
+	for _, role := range roles {
+		if role == "" || strings.Contains(role, ",") {
+			return c.error(errorf("%s: invalid role: %q", c.cmd.Name, role))
+		}
+	}
+	if s := c.cmd.Annotations[AnnotationRequiresRole]; s != "" {
+		roles = append(strings.Split(s, ","), roles...)
+	}
+	return c.Annotate(AnnotationRequiresRole, strings.Join(roles, ","))
+}
+
+// RequiredRoles returns the roles required by the invoked command and its
+// parents with RequiresRole.
+func (c *Invocation) RequiredRoles() []string {
+	var roles []string
+	seen := make(map[string]bool)
+	for p := c.cmd; p != nil; p = p.Parent {
+		s := p.Annotations[AnnotationRequiresRole]
+		if s == "" {
+			continue
+		}
+		for _, role := range strings.Split(s, ",") {
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// RoleGuard returns a GuardFunc that denies the invocation of commands that
+// require a role, with RequiresRole, that is not returned by roles for the
+// current user. A command that requires more than one role, including those
+// required by its parents, requires all of them.
+func RoleGuard(roles func(ctx context.Context) ([]string, error)) GuardFunc {
+	return func(ctx context.Context, inv *Invocation) error {
+		required := inv.RequiredRoles()
+		if len(required) == 0 {
+			return nil
+		}
+		granted, err := roles(ctx)
+		if err != nil {
+			return err
+		}
+		has := make(map[string]bool, len(granted))
+		for _, role := range granted {
+			has[role] = true
+		}
+		var missing []string
+		for _, role := range required {
+			if !has[role] {
+				missing = append(missing, role)
+			}
+		}
+		if len(missing) > 0 {
+			return &PermissionError{Cmd: inv.Target(), Roles: missing}
+		}
+		return nil
+	}
+}
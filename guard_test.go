@@ -0,0 +1,112 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	var roles []string
+	stderr := new(bytes.Buffer)
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, stderr).
+		Guard(RoleGuard(func(ctx context.Context) ([]string, error) {
+			if roles == nil {
+				return nil, errors.New("not logged in")
+			}
+			return roles, nil
+		})).
+		Subcommands(
+			NewCommand("status", "").HandleFunc(func(args []string) int { return 0 }),
+			NewCommand("db", "").
+				RequiresRole("operator").
+				Subcommands(
+					NewCommand("drop", "").
+						RequiresRole("admin").
+						HandleFunc(func(args []string) int { return 0 }),
+				),
+		).
+		Must()
+
+	tests := []struct {
+		Args     []string
+		Roles    []string
+		ExitCode int
+		Stderr   string
+	}{
+		{[]string{"status"}, nil, 0, ""},
+		{[]string{"db", "drop"}, []string{"admin", "operator"}, 0, ""},
+		{
+			[]string{"db", "drop"},
+			[]string{"operator"},
+			ExitPermissionDenied,
+			"Error: permission denied: 'app db drop' requires role admin\n",
+		},
+		{
+			[]string{"db", "drop"},
+			[]string{},
+			ExitPermissionDenied,
+			"Error: permission denied: 'app db drop' requires roles admin, operator\n",
+		},
+		{
+			[]string{"db", "drop"},
+			nil,
+			ExitPermissionDenied,
+			"Error: permission denied: not logged in\n",
+		},
+	}
+	for _, test := range tests {
+		roles = test.Roles
+		stderr.Reset()
+		assertInt64(t, int64(test.ExitCode), int64(cmd.Run(test.Args)))
+		assertString(t, test.Stderr, stderr.String())
+	}
+}
+
+func TestGuardBuiltins(t *testing.T) {
+	var unknown bool
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := NewCommand("app", "").
+		Output(stdout, stderr).
+		PrintConfig().
+		Guard(RoleGuard(func(ctx context.Context) ([]string, error) { return nil, nil })).
+		OnUnknownCommand(func(name string, args []string) int {
+			unknown = true
+			return 0
+		}).
+		RequiresRole("admin").
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+
+	const denied = "Error: permission denied: 'app' requires role admin\n"
+	for _, args := range [][]string{
+		{"--print-config"},
+		{"plugin"},
+		{},
+	} {
+		stdout.Reset()
+		stderr.Reset()
+		assertInt64(t, ExitPermissionDenied, int64(cmd.Run(args)))
+		assertString(t, "", stdout.String())
+		assertString(t, denied, stderr.String())
+
+		stderr.Reset()
+		code := cmd.Exec(context.Background(), args, nil, nil, nil)
+		assertInt64(t, ExitPermissionDenied, int64(code))
+		assertString(t, "", stdout.String())
+		assertString(t, denied, stderr.String())
+	}
+	if unknown {
+		t.Error("expected OnUnknownCommand not to be called")
+	}
+
+	// Handle calls the guards
+	inv, err := cmd.ParseInvocation(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, ExitPermissionDenied, int64(inv.Handle(context.Background())))
+}
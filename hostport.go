@@ -0,0 +1,136 @@
+package xflags
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// HostPort returns a FlagBuilder that can be used to define a flag for a
+// network address of the form "host:port" with specified name, default value,
+// default port and usage string. The argument p points to a string or
+// netip.AddrPort variable in which to store the value of the flag.
+//
+// The host may be a name, an IPv4 address or an IPv6 address, which must be
+// enclosed in brackets if a port is given, as in "[::1]:8080". The port may be
+// a number or a service name, such as "https", which is resolved with
+// net.LookupPort. If the port is omitted, defaultPort is used, or the
+// argument is an error if defaultPort is empty.
+//
+// String values are stored in the normalized form returned by
+// net.JoinHostPort with a numeric port, so "example.com:https" is stored as
+// "example.com:443". An empty host, as in ":8080", is stored as is to denote
+// all interfaces. For netip.AddrPort values, the host must be an IP address.
+func HostPort[T string | netip.AddrPort](p *T, name string, value T, defaultPort, usage string) *FlagBuilder {
+	if p == nil {
+		p = new(T)
+	}
+	*p = value
+	return Var(&hostPortValue[T]{p: p, defaultPort: defaultPort}, name, usage)
+}
+
+type hostPortValue[T string | netip.AddrPort] struct {
+	p           *T
+	defaultPort string
+}
+
+func (c *hostPortValue[T]) Get() interface{} { return *c.p }
+
+func (c *hostPortValue[T]) clone() Value {
+	v := *c.p
+	return &hostPortValue[T]{p: &v, defaultPort: c.defaultPort}
+}
+
+func (c *hostPortValue[T]) String() string {
+	switch v := any(*c.p).(type) {
+	case string:
+		return v
+	case netip.AddrPort:
+		if v.IsValid() {
+			return v.String()
+		}
+	}
+	return ""
+}
+
+func (c *hostPortValue[T]) Set(s string) error {
+	host, port, err := splitHostPort(s, c.defaultPort)
+	if err != nil {
+		return err
+	}
+	switch p := any(c.p).(type) {
+	case *string:
+		*p = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	case *netip.AddrPort:
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return errorf("invalid address: %s: host is not an IP address", s)
+		}
+		*p = netip.AddrPortFrom(addr, port)
+	}
+	return nil
+}
+
+// splitHostPort splits s into its host and port, using defaultPort if s has
+// no port, and resolves the port if it is a service name.
+func splitHostPort(s, defaultPort string) (host string, port uint16, err error) {
+	host, portName := s, defaultPort
+	switch {
+	case strings.HasPrefix(s, "["):
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return "", 0, errorf("invalid address: %s: missing ']'", s)
+		}
+		host = s[1:end]
+		if rest := s[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return "", 0, errorf("invalid address: %s: expected ':' after ']'", s)
+			}
+			portName = rest[1:]
+		}
+		if _, err := netip.ParseAddr(host); err != nil || !strings.Contains(host, ":") {
+			return "", 0, errorf("invalid address: %s: %q is not an IPv6 address", s, host)
+		}
+	case strings.Count(s, ":") > 1:
+		// an IPv6 address without brackets cannot have a port
+		if _, err := netip.ParseAddr(s); err != nil {
+			return "", 0, errorf("invalid address: %s: IPv6 addresses with ports must be enclosed in brackets", s)
+		}
+	case strings.Contains(s, ":"):
+		host, portName, _ = strings.Cut(s, ":")
+	}
+	if host != "" && !isHostName(host) {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return "", 0, errorf("invalid address: %s: invalid host: %q", s, host)
+		}
+	}
+	if portName == "" {
+		return "", 0, errorf("invalid address: %s: missing port", s)
+	}
+	n, err := net.LookupPort("tcp", portName)
+	if err != nil || n < 0 || n > 65535 {
+		return "", 0, errorf("invalid address: %s: unknown port: %s", s, portName)
+	}
+	return host, uint16(n), nil
+}
+
+// isHostName reports whether s is syntactically a valid host name: labels of
+// letters, digits, hyphens and underscores separated by periods.
+func isHostName(s string) bool {
+	if len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(s, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			ch := label[i]
+			if !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '-' || ch == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package xflags
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestHostPort(t *testing.T) {
+	tests := []struct {
+		Arg    string
+		Expect string
+	}{
+		{"example.com:8080", "example.com:8080"},
+		{"example.com", "example.com:443"},
+		{"example.com:http", "example.com:80"},
+		{"127.0.0.1", "127.0.0.1:443"},
+		{":8080", ":8080"},
+		{"[::1]:8080", "[::1]:8080"},
+		{"[::1]", "[::1]:443"},
+		{"::1", "[::1]:443"},
+		{"[fe80::1%eth0]:22", "[fe80::1%eth0]:22"},
+	}
+	for _, test := range tests {
+		var v string
+		flag := HostPort(&v, "addr", "", "443", "").Must()
+		if assertFlagParses(t, flag, "--addr="+test.Arg) {
+			assertString(t, test.Expect, v)
+		}
+	}
+
+	errTests := []struct {
+		Arg    string
+		Expect string
+	}{
+		{"fe80::x:8080", "invalid address: fe80::x:8080: IPv6 addresses with ports must be enclosed in brackets"},
+		{"[::1", "invalid address: [::1: missing ']'"},
+		{"[::1]8080", "invalid address: [::1]8080: expected ':' after ']'"},
+		{"[example.com]:80", `invalid address: [example.com]:80: "example.com" is not an IPv6 address`},
+		{"exa mple.com:80", `invalid address: exa mple.com:80: invalid host: "exa mple.com"`},
+		{"example.com:", "invalid address: example.com:: missing port"},
+		{"example.com:99999", "invalid address: example.com:99999: unknown port: 99999"},
+		{"example.com:nope", "invalid address: example.com:nope: unknown port: nope"},
+	}
+	for _, test := range errTests {
+		err := parseFlag(HostPort[string](nil, "addr", "", "", "").Must(), "--addr="+test.Arg)
+		if assertBool(t, true, err != nil) {
+			assertString(t, "xflags: --addr: "+test.Expect, err.Error())
+		}
+	}
+	err := parseFlag(HostPort[string](nil, "addr", "", "", "").Must(), "--addr=example.com")
+	if assertBool(t, true, err != nil) {
+		assertString(t, "xflags: --addr: invalid address: example.com: missing port", err.Error())
+	}
+}
+
+func TestHostPortAddrPort(t *testing.T) {
+	var v netip.AddrPort
+	flag := HostPort(&v, "addr", netip.MustParseAddrPort("127.0.0.1:80"), "80", "").Must()
+	assertString(t, "127.0.0.1:80", valueString(flag.Value))
+	if assertFlagParses(t, flag, "--addr=[::1]") {
+		assertString(t, "[::1]:80", v.String())
+	}
+	err := parseFlag(flag, "--addr=example.com:80")
+	if assertBool(t, true, err != nil) {
+		assertString(t, "xflags: --addr: invalid address: example.com:80: host is not an IP address", err.Error())
+	}
+	assertString(t, "", valueString(HostPort[netip.AddrPort](nil, "addr", netip.AddrPort{}, "", "").Must().Value))
+}
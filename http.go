@@ -0,0 +1,255 @@
+package xflags
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// prefixedName returns the name of a flag registered by a flag bundle, such
+// as TLSFlags, with the given prefix.
+func prefixedName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "-" + name
+}
+
+// httpClientOptions are the values of the flags registered by HTTPClientFlags.
+type httpClientOptions struct {
+	prefix     string
+	timeout    time.Duration
+	proxy      string
+	retries    int
+	headers    []string
+	unixSocket string
+}
+
+// HTTPClientFlags registers the --PREFIX-timeout, --PREFIX-proxy,
+// --PREFIX-retries, --PREFIX-header and --PREFIX-unix-socket flags for this
+// command and its subcommands, or --timeout, --proxy, --retries, --header and
+// --unix-socket if prefix is empty. Once the command line is parsed, an
+// *http.Client is configured from the flags and handlers registered with
+// HandleContext may retrieve it with HTTPClientFrom:
+//
+//   - --timeout limits the time of each request, including reading the
+//     response body. It is 30 seconds by default and zero means no limit.
+//   - --proxy is the URL of a proxy for all requests. By default, the proxy
+//     is read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+//     variables.
+//   - --retries is the number of times that requests with idempotent methods
+//     are retried after a network error or a 502, 503 or 504 response, with
+//     exponential backoff.
+//   - --header adds a header in the form "Name: value" to each request that
+//     does not already set it and may be specified more than once.
+//   - --unix-socket connects to the HTTP server at the path of a Unix socket
+//     instead of the host of the URL of each request.
+//
+// Invalid proxy URLs and headers are returned by Parse as an ArgumentError.
+// Since ListenFlags also registers --PREFIX-unix-socket, a command that both
+// listens and makes HTTP requests must call them with different prefixes.
+func (c *CommandBuilder) HTTPClientFlags(prefix string) *CommandBuilder {
+	for _, opts := range c.cmd.listenOptions {
+		if opts.prefix == prefix {
+			return c.error(unixSocketConflict(c.cmd.Name, prefix))
+		}
+	}
+	opts := &httpClientOptions{prefix: prefix}
+	c.cmd.httpClientOptions = append(c.cmd.httpClientOptions, opts)
+	usage := "HTTP client options"
+	if prefix != "" {
+		usage += " (" + prefix + ")"
+	}
+	return c.FlagGroup(
+		"http-"+prefix,
+		usage,
+		Duration(&opts.timeout, prefixedName(prefix, "timeout"), 30*time.Second, "Timeout of each HTTP request").
+			ShowDefault().
+			builtin(),
+		String(&opts.proxy, prefixedName(prefix, "proxy"), "", "URL of the HTTP proxy").builtin(),
+		Int(&opts.retries, prefixedName(prefix, "retries"), 0, "Number of times to retry failed HTTP requests").
+			builtin(),
+		Strings(&opts.headers, prefixedName(prefix, "header"), nil, "HTTP header to send, as \"Name: value\"").
+			builtin(),
+		String(&opts.unixSocket, prefixedName(prefix, "unix-socket"), "", "Path of a Unix socket to connect to").
+			builtin(),
+	)
+}
+
+// unixSocketConflict returns the error for a call to HTTPClientFlags and
+// ListenFlags with the same prefix, which both register --PREFIX-unix-socket.
+func unixSocketConflict(name, prefix string) error {
+	return errorf(
+		"%s: HTTPClientFlags and ListenFlags cannot both use the prefix %q: both declare --%s",
+		name,
+		prefix,
+		prefixedName(prefix, "unix-socket"),
+	)
+}
+
+type httpClientKey struct{ prefix string }
+
+// HTTPClientFrom returns the *http.Client configured by the flags registered by
+// HTTPClientFlags with the given prefix for the command invocation that ctx
+// was created for. If ctx has no such client, http.DefaultClient is returned.
+func HTTPClientFrom(ctx context.Context, prefix string) *http.Client {
+	if client, ok := ctx.Value(httpClientKey{prefix}).(*http.Client); ok {
+		return client
+	}
+	return http.DefaultClient
+}
+
+// withHTTPClients returns a context derived from ctx that carries the HTTP
+// clients configured for inv, an invocation of c, and its parents.
+func (c *Command) withHTTPClients(ctx context.Context, inv *Invocation) context.Context {
+	if inv.parser == nil {
+		return ctx
+	}
+	for p := c; p != nil; p = p.Parent {
+		for _, opts := range p.httpClientOptions {
+			key := httpClientKey{opts.prefix}
+			if client := inv.parser.httpClients[opts]; client != nil && ctx.Value(key) == nil {
+				ctx = context.WithValue(ctx, key, client)
+			}
+		}
+	}
+	return ctx
+}
+
+// configureHTTPClients configures the HTTP client of each call to
+// HTTPClientFlags by the current command and its parents from the values of
+// their flags.
+func (c *argParser) configureHTTPClients() error {
+	for p := c.cmd; p != nil; p = p.Parent {
+		for _, opts := range p.httpClientOptions {
+			client, err := c.newHTTPClient(p, opts)
+			if err != nil {
+				return err
+			}
+			if c.httpClients == nil {
+				c.httpClients = make(map[*httpClientOptions]*http.Client)
+			}
+			c.httpClients[opts] = client
+		}
+	}
+	return nil
+}
+
+// newHTTPClient returns a client configured by the flags of opts, which were
+// registered by cmd.
+func (c *argParser) newHTTPClient(cmd *Command, opts *httpClientOptions) (*http.Client, error) {
+	flag := func(name string) *Flag { return c.lookupName(prefixedName(opts.prefix, name)) }
+	value := func(name string) interface{} { return c.optionValue(cmd, prefixedName(opts.prefix, name)) }
+	var (
+		timeout    = value("timeout").(time.Duration)
+		proxy      = value("proxy").(string)
+		retries    = int(value("retries").(int64))
+		headers    = value("header").([]string)
+		unixSocket = value("unix-socket").(string)
+	)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, newArgErr(c.cmd, flag("proxy"), proxy, "invalid proxy URL: %s", proxy)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if path := unixSocket; path != "" {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+	}
+	header := make(http.Header)
+	for _, s := range headers {
+		name, value, ok := strings.Cut(s, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, newArgErr(c.cmd, flag("header"), s, "invalid header: %q", s)
+		}
+		header.Add(name, strings.TrimSpace(value))
+	}
+	if retries < 0 {
+		return nil, newArgErr(c.cmd, flag("retries"), "", "must not be negative")
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &httpTransport{
+			next:    transport,
+			header:  header,
+			retries: retries,
+			backoff: ExponentialBackoff(100*time.Millisecond, 5*time.Second),
+		},
+	}, nil
+}
+
+// httpTransport is the http.RoundTripper of the clients configured by
+// HTTPClientFlags which adds headers and retries failed requests.
+type httpTransport struct {
+	next    http.RoundTripper
+	header  http.Header
+	retries int
+	backoff BackoffFunc
+}
+
+func (c *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(c.header) > 0 {
+		req = req.Clone(req.Context())
+		for name, values := range c.header {
+			if _, ok := req.Header[name]; !ok {
+				req.Header[name] = values
+			}
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := c.next.RoundTrip(req)
+		if attempt > c.retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		t := time.NewTimer(c.backoff(attempt + 1))
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		case <-t.C:
+		}
+	}
+}
+
+// retryable reports whether req may be retried after the given response or
+// error.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
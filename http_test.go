@@ -0,0 +1,144 @@
+package xflags
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientFlags(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, r.Header.Get("X-Token")+" "+r.Header.Get("Accept"))
+	}))
+	defer srv.Close()
+
+	var body string
+	var timeout time.Duration
+	code := NewCommand("app", "").
+		HTTPClientFlags("api").
+		HandleContext(func(ctx context.Context, args []string) int {
+			client := HTTPClientFrom(ctx, "api")
+			timeout = client.Timeout
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			req.Header.Set("Accept", "text/plain")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Error(err)
+				return 1
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			body = string(b)
+			return 0
+		}).
+		Must().
+		Run([]string{
+			"--api-retries", "2",
+			"--api-header", "X-Token: secret",
+			"--api-header", "Accept: application/json",
+			"--api-timeout", "5s",
+		})
+	assertInt64(t, 0, int64(code))
+	assertInt64(t, 3, int64(requests))
+	assertString(t, "secret text/plain", body)
+	assertDuration(t, 5*time.Second, timeout)
+
+	// the default client is returned without flags
+	if HTTPClientFrom(context.Background(), "api") != http.DefaultClient {
+		t.Error("expected default client")
+	}
+}
+
+func TestHTTPClientFlagsUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not tested on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "api.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "unix")
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	var body string
+	code := NewCommand("app", "").
+		HTTPClientFlags("").
+		HandleContext(func(ctx context.Context, args []string) int {
+			resp, err := HTTPClientFrom(ctx, "").Get("http://localhost/")
+			if err != nil {
+				t.Error(err)
+				return 1
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			body = string(b)
+			return 0
+		}).
+		Must().
+		Run([]string{"--unix-socket", path})
+	assertInt64(t, 0, int64(code))
+	assertString(t, "unix", body)
+}
+
+func TestHTTPClientFlagsErrors(t *testing.T) {
+	tests := []struct {
+		Args []string
+		Flag string
+	}{
+		{[]string{"--proxy", "localhost"}, "proxy"},
+		{[]string{"--header", "X-Token"}, "header"},
+		{[]string{"--header", ": value"}, "header"},
+		{[]string{"--retries", "-1"}, "retries"},
+	}
+	for _, test := range tests {
+		_, err := NewCommand("app", "").HTTPClientFlags("").Must().Parse(test.Args)
+		var argErr *ArgumentError
+		if assertErrorAs(t, err, &argErr) {
+			assertString(t, test.Flag, argErr.Flag.Name)
+		}
+	}
+}
+
+func TestHTTPClientFlagsExec(t *testing.T) {
+	var timeout time.Duration
+	cmd := NewCommand("app", "").
+		HTTPClientFlags("").
+		HandleContext(func(ctx context.Context, args []string) int {
+			timeout = HTTPClientFrom(ctx, "").Timeout
+			return 0
+		}).
+		Must()
+	if code := cmd.Run([]string{"--timeout", "5s"}); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	assertDuration(t, 5*time.Second, timeout)
+	tests := []struct {
+		Args    []string
+		Timeout time.Duration
+	}{
+		{[]string{"--timeout", "7s"}, 7 * time.Second},
+		{nil, 30 * time.Second},
+	}
+	for _, test := range tests {
+		if code := cmd.Exec(context.Background(), test.Args, nil, nil, nil); code != 0 {
+			t.Fatalf("%q: unexpected exit code: %d", test.Args, code)
+		}
+		assertDuration(t, test.Timeout, timeout)
+	}
+}
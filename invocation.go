@@ -0,0 +1,304 @@
+package xflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// AnnotationDryRun is the annotation of commands that support the --dry-run
+// flag registered by EnableDryRun.
+const AnnotationDryRun = "xflags.dry-run"
+
+// Invocation describes one invocation of a command. Handlers registered with
+// HandleContext and middleware may retrieve the Invocation from their context
+// with InvocationFrom.
+type Invocation struct {
+	cmd     *Command
+	parser  *argParser
+	args    []string
+	dryRun  bool
+	attempt int
+	err     error
+	aborted bool      // the user did not confirm the invocation
+	guarded bool      // the guards of the command have been called
+	stdin   io.Reader // replaces the input of the command if not nil
+	stdout  io.Writer // replaces the output of the command if not nil
+	stderr  io.Writer
+}
+
+// Target returns the command that was invoked.
+func (c *Invocation) Target() *Command { return c.cmd }
+
+// Args returns the arguments passed to the handler.
+func (c *Invocation) Args() []string { return c.args }
+
+// Sections returns the arguments passed to the handler split into sections by
+// any "--" arguments, if the command was configured with WithSections. Unlike
+// Command.Sections, Sections is computed for this Invocation, so it is also
+// available to handlers called by Exec.
+func (c *Invocation) Sections() [][]string {
+	return splitSections(c.args, c.cmd.MaxSections)
+}
+
+// DryRun reports whether --dry-run was specified for a command configured
+// with EnableDryRun. Handlers should not make any changes if DryRun is true.
+func (c *Invocation) DryRun() bool { return c.dryRun }
+
+// Attempt returns the number of the current attempt to call the handler,
+// starting at 1. It is only greater than 1 if the handler is retried by the
+// middleware of CommandBuilder.Retry.
+func (c *Invocation) Attempt() int { return c.attempt }
+
+// Err returns the error returned by the most recent call to a handler
+// registered with HandleE, or nil.
+func (c *Invocation) Err() error { return c.err }
+
+// Get returns the value of the named flag of the invoked command or its
+// parents if the flag's value implements Getter. Get returns nil if the flag
+// does not exist or the Invocation was not created by parsing a command line.
+//
+// For Invocations returned by Command.ParseInvocation, Get returns the value
+// parsed for this Invocation. Flags with custom values are not copied and Get
+// returns nil for them; their raw values are available from Values.
+func (c *Invocation) Get(name string) interface{} {
+	flag := c.lookup(name)
+	if flag == nil {
+		return nil
+	}
+	if g, ok := c.parser.value(flag).(Getter); ok {
+		return g.Get()
+	}
+	return nil
+}
+
+// Values returns the string values of the named flag of the invoked command
+// or its parents in the order they were read from the command line, the
+// environment or sources. Values returns nil if the flag was not specified.
+func (c *Invocation) Values(name string) []string {
+	flag := c.lookup(name)
+	if flag == nil {
+		return nil
+	}
+	var values []string
+	for _, v := range c.parser.rawValues {
+		if v.flag == flag {
+			values = append(values, v.value)
+		}
+	}
+	return values
+}
+
+// Flags returns the value of each flag of the invoked command and its parents
+// that was set from the command line, the environment or a source, keyed by
+// the path of the flag as described by Source. Flags declared as Secret are
+// never included.
+func (c *Invocation) Flags() map[string]string {
+	if c.parser == nil {
+		return map[string]string{}
+	}
+	return c.parser.setFlags()
+}
+
+// lookup returns the named flag or nil if the Invocation was not parsed.
+func (c *Invocation) lookup(name string) *Flag {
+	if c.parser == nil {
+		return nil
+	}
+	return c.parser.lookupName(name)
+}
+
+// Handle calls the handler of the invoked command and any middleware with the
+// arguments of the Invocation. See Command.Handle.
+func (c *Invocation) Handle(ctx context.Context) int {
+	return c.cmd.handle(ctx, c)
+}
+
+type invocationKey struct{}
+
+// InvocationFrom returns the Invocation that ctx was created for or nil if ctx
+// was not created by Command.Handle.
+func InvocationFrom(ctx context.Context) *Invocation {
+	inv, _ := ctx.Value(invocationKey{}).(*Invocation)
+	return inv
+}
+
+// Middleware wraps the handler of a command to add behavior before or after
+// it is called.
+type Middleware func(next ContextHandlerFunc) ContextHandlerFunc
+
+// Use adds middleware that wraps the handler of this command and its
+// subcommands. Middleware of parent commands wraps that of their subcommands
+// and middleware is applied in the order it is added, so the first middleware
+// added to the root command is called first.
+func (c *CommandBuilder) Use(middleware ...Middleware) *CommandBuilder {
+	for _, mw := range middleware {
+		if mw == nil {
+			return c.error(errorf("%s: nil middleware", c.cmd.Name))
+		}
+	}
+	c.cmd.Middleware = append(c.cmd.Middleware, middleware...)
+	return c
+}
+
+// dryRunOptions is the value of the flag registered by EnableDryRun.
+type dryRunOptions struct {
+	dryRun bool
+}
+
+// EnableDryRun registers the --dry-run flag for this command and its
+// subcommands and annotates the command with AnnotationDryRun. Handlers and
+// middleware read the flag from Invocation.DryRun.
+func (c *CommandBuilder) EnableDryRun() *CommandBuilder {
+	opts := &dryRunOptions{}
+	c.cmd.dryRunOptions = opts
+	return c.
+		Annotate(AnnotationDryRun, "true").
+		Flags(
+			Bool(
+				&opts.dryRun,
+				"dry-run",
+				false,
+				"Show what would be done without making any changes",
+			).builtin(),
+		)
+}
+
+// ParseInvocation parses the given command line arguments like Parse, but
+// does not modify the command tree or the values of any flags. Instead, the
+// values of builtin flag types are copied and set for the returned Invocation
+// and may be read with Invocation.Get. Validation and argument counts are
+// checked as normal.
+//
+// ParseInvocation may be called concurrently on the same command tree, which
+// allows a single tree to serve many requests in parallel, such as a chat bot
+// that dispatches CLI-style commands. Handlers called by Invocation.Handle must
+// read flag values from their Invocation rather than from the variables that
+// the flags were defined with. Custom Values, Func flags and OnSet functions
+// are not called; string values may be read with Invocation.Values. File flags
+// created by Reader, Writer and OpenFile may not be specified, since the
+// opened files would be shared by all invocations.
+func (c *Command) ParseInvocation(args []string) (*Invocation, error) {
+	parser := newArgParser(c, args)
+	parser.values = make(map[*Flag]Value)
+	cmd, args, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	inv := &Invocation{cmd: cmd, parser: parser, args: args, attempt: 1}
+	inv.dryRun, _ = inv.Get("dry-run").(bool)
+	return inv, nil
+}
+
+// Exec parses args with ParseInvocation and calls the handler of the invoked
+// command with stdin as its input and its output written to stdout and
+// stderr, which replace those of the command if they are not nil. Usage
+// requested with -h or --help is written to stdout and argument errors are
+// written to stderr. Unknown commands, --print-config and commands without a
+// handler are handled and the invocation is reported to any Reporters, as by
+// Run. Exec returns the exit code of the handler, or 1 if args could not be
+// parsed.
+//
+// Unlike Run, Exec may be called concurrently, as by Batch or by servers that
+// run commands on behalf of clients, and the caveats of ParseInvocation
+// apply.
+func (c *Command) Exec(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	start := time.Now()
+	inv, err := c.ParseInvocation(args)
+	if err == nil {
+		inv.stdin, inv.stdout, inv.stderr = stdin, stdout, stderr
+		return inv.dispatch(ctx, start, func(ctx context.Context, inv *Invocation) int {
+			return inv.Handle(ctx)
+		})
+	}
+	defaultStdout, defaultStderr := c.output()
+	if stdout == nil {
+		stdout = defaultStdout
+	}
+	if stderr == nil {
+		stderr = defaultStderr
+	}
+	exitCode := 1
+	var helpErr *HelpError
+	var argErr *ArgumentError
+	switch {
+	case errors.As(err, &helpErr):
+		helpErr.Cmd.writeUsage(stdout, helpErr.level())
+		exitCode = 0
+	case errors.As(err, &argErr):
+		fmt.Fprintf(stderr, "Argument error: %s\n", argErr.String())
+	default:
+		fmt.Fprintf(stderr, "Error: %v\n", errStr(err))
+	}
+	c.reportErr(ctx, err, start, exitCode)
+	return exitCode
+}
+
+// output returns the stdout and stderr of the invoked command, or those that
+// replace them for this Invocation.
+func (c *Invocation) output() (stdout, stderr io.Writer) {
+	stdout, stderr = c.cmd.output()
+	if c.stdout != nil {
+		stdout = c.stdout
+	}
+	if c.stderr != nil {
+		stderr = c.stderr
+	}
+	return stdout, stderr
+}
+
+// newInvocation returns the Invocation of c with args.
+func (c *Command) newInvocation(args []string) *Invocation {
+	inv := &Invocation{cmd: c, parser: c.parser, args: args, attempt: 1}
+	for p := c; p != nil; p = p.Parent {
+		if opts := p.dryRunOptions; opts != nil {
+			inv.dryRun = opts.dryRun
+			break
+		}
+	}
+	return inv
+}
+
+// handler returns the handler of c wrapped in the middleware of c and its
+// parents.
+func (c *Command) handler() ContextHandlerFunc {
+	h := c.ContextHandler
+	switch {
+	case c.ErrorHandler != nil:
+		fn := c.ErrorHandler
+		h = func(ctx context.Context, args []string) int {
+			err := fn(ctx, args)
+			InvocationFrom(ctx).err = err
+			return exitCode(err)
+		}
+	case h == nil:
+		fn := c.HandlerFunc
+		h = func(ctx context.Context, args []string) int { return fn(args) }
+	}
+	for p := c; p != nil; p = p.Parent {
+		for i := len(p.Middleware) - 1; i >= 0; i-- {
+			h = p.Middleware[i](h)
+		}
+	}
+	return h
+}
+
+// ExitCoder is an optional interface implemented by errors returned from
+// handlers registered with HandleE to specify the exit code of the command.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// exitCode returns the exit code for an error returned by a handler.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitCoder ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+	return 1
+}
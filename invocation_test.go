@@ -0,0 +1,310 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEnableDryRun(t *testing.T) {
+	for _, args := range [][]string{{"delete"}, {"--dry-run", "delete"}, {"delete", "--dry-run"}} {
+		var dryRun bool
+		cmd := NewCommand("app", "").
+			EnableDryRun().
+			Subcommands(
+				NewCommand("delete", "").
+					HandleContext(func(ctx context.Context, args []string) int {
+						dryRun = InvocationFrom(ctx).DryRun()
+						return 0
+					}),
+			).
+			Must()
+		assertString(t, "true", cmd.Annotations[AnnotationDryRun])
+		if code := cmd.Run(args); code != 0 {
+			t.Fatalf("%q: unexpected exit code: %d", args, code)
+		}
+		assertBool(t, len(args) > 1, dryRun)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := make([]string, 0)
+	trace := func(name string) Middleware {
+		return func(next ContextHandlerFunc) ContextHandlerFunc {
+			return func(ctx context.Context, args []string) int {
+				calls = append(calls, name+":"+strings.Join(InvocationFrom(ctx).Args(), ","))
+				return next(ctx, args)
+			}
+		}
+	}
+	cmd := NewCommand("app", "").
+		Use(trace("a"), trace("b")).
+		Subcommands(
+			NewCommand("sub", "").
+				Use(trace("c")).
+				WithTerminator().
+				HandleFunc(func(args []string) int {
+					calls = append(calls, "handler")
+					return 7
+				}),
+		).
+		Must()
+	if code := cmd.Run([]string{"sub", "--", "x", "y"}); code != 7 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	assertStrings(t, []string{"a:x,y", "b:x,y", "c:x,y", "handler"}, calls)
+}
+
+func TestParseInvocation(t *testing.T) {
+	var name string
+	var count int
+	var tags []string
+	cmd := NewCommand("app", "").
+		Flags(
+			String(&name, "name", "default", ""),
+			Int(&count, "count", 1, "").Validate(func(arg string) error {
+				if arg == "0" {
+					return errors.New("count must not be zero")
+				}
+				return nil
+			}),
+			Strings(&tags, "tag", []string{"a"}, "").Sorted(),
+		).
+		Subcommands(
+			NewCommand("sub", "").EnableDryRun(),
+		).
+		Must()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := strconv.Itoa(i + 1)
+			inv, err := cmd.ParseInvocation(
+				[]string{"--name", "foo" + n, "--count", n, "--tag", "z", "--tag", n, "sub", "--dry-run"},
+			)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			assertString(t, "sub", inv.Target().Name)
+			assertString(t, "foo"+n, inv.Get("name").(string))
+			assertInt64(t, int64(i+1), inv.Get("count").(int64))
+			assertStrings(t, []string{n, "z"}, inv.Get("tag").([]string))
+			assertStrings(t, []string{"z", n}, inv.Values("tag"))
+			assertBool(t, true, inv.DryRun())
+		}(i)
+	}
+	wg.Wait()
+
+	// shared flag values are not modified
+	assertString(t, "default", name)
+	assertInt64(t, 1, int64(count))
+	assertStrings(t, []string{"a"}, tags)
+
+	inv, err := cmd.ParseInvocation(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "default", inv.Get("name").(string))
+	assertStrings(t, []string{"a"}, inv.Get("tag").([]string))
+	if inv.Values("name") != nil {
+		t.Errorf("expected no values for unspecified flag")
+	}
+	if inv.Get("nope") != nil {
+		t.Errorf("expected nil value for unknown flag")
+	}
+
+	_, err = cmd.ParseInvocation([]string{"--count", "0"})
+	assertErrorAs(t, err, new(*ArgumentError))
+}
+
+func TestInvocationHandle(t *testing.T) {
+	var name string
+	cmd := NewCommand("app", "").
+		Output(new(bytes.Buffer), new(bytes.Buffer)).
+		Flags(String(&name, "name", "", "")).
+		HandleContext(func(ctx context.Context, args []string) int {
+			s := InvocationFrom(ctx).Get("name").(string)
+			return len(s)
+		}).
+		Must()
+	inv, err := cmd.ParseInvocation([]string{"--name", "four"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 4, int64(inv.Handle(context.Background())))
+	assertString(t, "", name)
+}
+
+func TestCommandExec(t *testing.T) {
+	cmdOut, cmdErr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := NewCommand("app", "").
+		Output(cmdOut, cmdErr).
+		Subcommands(
+			NewCommand("upper", "Convert stdin to upper case").
+				Flags(Bool(nil, "suffix", false, "")).
+				HandleContext(func(ctx context.Context, args []string) int {
+					out := OutputFrom(ctx)
+					b, err := io.ReadAll(out.Stdin)
+					if err != nil {
+						t.Fatal(err)
+					}
+					s := strings.ToUpper(string(b))
+					if InvocationFrom(ctx).Get("suffix").(bool) {
+						s += "!"
+					}
+					fmt.Fprint(out.Stdout, s)
+					fmt.Fprint(out.Stderr, "done")
+					return 0
+				}),
+		).
+		Must()
+
+	tests := []struct {
+		args     []string
+		exitCode int
+		stdout   string
+		stderr   string
+	}{
+		{[]string{"upper", "--suffix"}, 0, "HELLO!", "done"},
+		{[]string{"upper", "--nope"}, 1, "", "Argument error: unrecognized argument: --nope\n"},
+		{[]string{}, 1, "", "Error: missing command\nSee 'app --help'.\n"},
+	}
+	for _, test := range tests {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		exitCode := cmd.Exec(context.Background(), test.args, strings.NewReader("hello"), stdout, stderr)
+		assertInt64(t, int64(test.exitCode), int64(exitCode))
+		assertString(t, test.stdout, stdout.String())
+		assertString(t, test.stderr, stderr.String())
+	}
+
+	// help is written to stdout
+	stdout := new(bytes.Buffer)
+	assertInt64(t, 0, int64(cmd.Exec(context.Background(), []string{"--help"}, nil, stdout, nil)))
+	if !strings.Contains(stdout.String(), "Convert stdin to upper case") {
+		t.Errorf("expected usage, got: %q", stdout.String())
+	}
+
+	// the output of the command is used by default
+	assertInt64(t, 1, int64(cmd.Exec(context.Background(), nil, nil, nil, nil)))
+	assertString(t, "Error: missing command\nSee 'app --help'.\n", cmdErr.String())
+	assertString(t, "", cmdOut.String())
+}
+
+func TestExecBuiltins(t *testing.T) {
+	var events []*Event
+	var unknown []string
+	cmd := NewCommand("app", "").
+		Output(io.Discard, io.Discard).
+		PrintConfig().
+		Instrument(ReporterFunc(func(e *Event) { events = append(events, e) })).
+		OnUnknownCommand(func(name string, args []string) int {
+			unknown = append([]string{name}, args...)
+			return 3
+		}).
+		Flags(String(nil, "region", "", "")).
+		Subcommands(
+			NewCommand("deploy", "").
+				HandleFunc(func(args []string) int { return 0 }),
+			NewCommand("widgets", "").
+				Subcommands(NewCommand("list", "").HandleFunc(func(args []string) int { return 0 })),
+		).
+		Must()
+
+	tests := []struct {
+		args     []string
+		exitCode int
+		stdout   string
+		stderr   string
+		command  []string
+		class    ErrorClass
+	}{
+		{[]string{"deploy"}, 0, "", "", []string{"app", "deploy"}, ErrorNone},
+		{
+			[]string{"--region=eu", "deploy", "--print-config=diff"},
+			0,
+			"region=eu\n",
+			"",
+			[]string{"app", "deploy"},
+			ErrorNone,
+		},
+		{
+			[]string{"widgets"},
+			1,
+			"",
+			"Error: missing command\nSee 'app widgets --help'.\n",
+			[]string{"app", "widgets"},
+			ErrorUsage,
+		},
+		{[]string{"plugin", "--x"}, 3, "", "", []string{"app"}, ErrorExit},
+		{
+			[]string{"deploy", "--nope"},
+			1,
+			"",
+			"Argument error: unrecognized argument: --nope\n",
+			[]string{"app", "deploy"},
+			ErrorArgument,
+		},
+	}
+	for _, test := range tests {
+		events, unknown = nil, nil
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		exitCode := cmd.Exec(context.Background(), test.args, nil, stdout, stderr)
+		assertInt64(t, int64(test.exitCode), int64(exitCode))
+		assertString(t, test.stdout, stdout.String())
+		assertString(t, test.stderr, stderr.String())
+		if len(events) != 1 {
+			t.Errorf("%q: expected 1 event, got %d", test.args, len(events))
+			continue
+		}
+		assertStrings(t, test.command, events[0].Command)
+		assertInt64(t, int64(test.exitCode), int64(events[0].ExitCode))
+		assertString(t, string(test.class), string(events[0].ErrorClass))
+	}
+
+	// unknown commands are passed to OnUnknownCommand
+	cmd.Exec(context.Background(), []string{"plugin", "--x"}, nil, nil, nil)
+	assertStrings(t, []string{"plugin", "--x"}, unknown)
+
+	// --print-config is read per invocation
+	stdout := new(bytes.Buffer)
+	cmd.Exec(context.Background(), []string{"deploy"}, nil, stdout, nil)
+	assertString(t, "", stdout.String())
+}
+
+func TestInvocationSections(t *testing.T) {
+	var sections [][]string
+	cmd := NewCommand("app", "").
+		Subcommands(
+			NewCommand("run", "").
+				WithSections(2).
+				HandleContext(func(ctx context.Context, args []string) int {
+					sections = InvocationFrom(ctx).Sections()
+					return 0
+				}),
+		).
+		Must()
+	ctx := context.Background()
+	args := []string{"run", "--", "./server", "--port", "80", "--", "--extra", "--", "x"}
+	assertInt64(t, 0, int64(cmd.Exec(ctx, args, nil, nil, nil)))
+	if assertInt64(t, 2, int64(len(sections))) {
+		assertStrings(t, []string{"./server", "--port", "80"}, sections[0])
+		assertStrings(t, []string{"--extra", "--", "x"}, sections[1])
+	}
+	if cmd.Subcommands[0].Sections() != nil {
+		t.Errorf("expected Exec not to modify the command")
+	}
+
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"run", "--", "a"}, nil, nil, nil)))
+	if assertInt64(t, 1, int64(len(sections))) {
+		assertStrings(t, []string{"a"}, sections[0])
+	}
+}
@@ -0,0 +1,109 @@
+package xflags
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// JSON returns a FlagBuilder that can be used to define a flag whose value is
+// a JSON document, such as '{"tier":"web"}', with specified name and usage
+// string. The argument p must be a non-nil pointer to a variable into which
+// the document is unmarshaled with encoding/json. The current value of the
+// variable is the default value of the flag.
+//
+// Each time the flag is set, the variable is replaced by the document rather
+// than merged with its previous value. Errors report the position of the
+// invalid part of the document, as in "invalid JSON at column 9: invalid
+// character '}' looking for beginning of value".
+func JSON(p any, name, usage string) *FlagBuilder {
+	rv := reflect.ValueOf(p)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		c := Var(nil, name, usage)
+		c.err = errorf("%s: value must be a non-nil pointer: %T", name, p)
+		return c
+	}
+	return Var(&jsonValue{p: rv}, name, usage)
+}
+
+type jsonValue struct {
+	p   reflect.Value // pointer to the target
+	raw string        // the document that was last set
+}
+
+func (c *jsonValue) String() string {
+	if c.raw != "" {
+		return c.raw
+	}
+	b, err := json.Marshal(c.p.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (c *jsonValue) Get() interface{} { return c.p.Elem().Interface() }
+
+func (c *jsonValue) clone() Value {
+	p := reflect.New(c.p.Type().Elem())
+	p.Elem().Set(c.p.Elem())
+	return &jsonValue{p: p, raw: c.raw}
+}
+
+func (c *jsonValue) Set(s string) error {
+	v := reflect.New(c.p.Type().Elem())
+	dec := json.NewDecoder(strings.NewReader(s))
+	if err := dec.Decode(v.Interface()); err != nil {
+		return jsonErr(s, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		pos := jsonPosition(s, dec.InputOffset())
+		return errorf("invalid JSON at %s: unexpected data after top-level value", pos)
+	}
+	c.p.Elem().Set(v.Elem())
+	c.raw = s
+	return nil
+}
+
+// jsonErr annotates an error decoding the JSON document s with the position
+// of the error in s.
+func jsonErr(s string, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return errorf("invalid JSON at %s: %v", jsonPosition(s, syntaxErr.Offset), syntaxErr)
+	case errors.As(err, &typeErr):
+		msg := fmt.Sprintf("cannot use %s as %s", typeErr.Value, typeErr.Type)
+		if typeErr.Field != "" {
+			msg += " in field " + typeErr.Field
+		}
+		return errorf("invalid JSON at %s: %s", jsonPosition(s, typeErr.Offset), msg)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return errorf("invalid JSON at %s: unexpected end of input", jsonPosition(s, int64(len(s))))
+	}
+	return errorf("invalid JSON: %v", err)
+}
+
+// jsonPosition returns the column, and the line if s has more than one line,
+// of the byte at offset in s. Offsets reported by encoding/json point after
+// the byte that caused the error.
+func jsonPosition(s string, offset int64) string {
+	if offset > int64(len(s)) {
+		offset = int64(len(s))
+	}
+	b := []byte(s[:offset])
+	line := bytes.Count(b, []byte("\n")) + 1
+	col := len(b) - bytes.LastIndexByte(b, '\n') - 1
+	if col == 0 {
+		col = 1
+	}
+	if strings.Contains(s, "\n") {
+		return fmt.Sprintf("line %d, column %d", line, col)
+	}
+	return fmt.Sprintf("column %d", col)
+}
@@ -0,0 +1,46 @@
+package xflags
+
+import "testing"
+
+func TestJSON(t *testing.T) {
+	type selector struct {
+		Tier     string `json:"tier"`
+		Replicas int    `json:"replicas"`
+	}
+	v := selector{Tier: "db"}
+	flag := JSON(&v, "selector", "").Must()
+	assertString(t, `{"tier":"db","replicas":0}`, valueString(flag.Value))
+	if assertFlagParses(t, flag, `--selector={"tier":"web"}`) {
+		assertString(t, "web", v.Tier)
+		assertInt64(t, 0, int64(v.Replicas))
+	}
+
+	// errors report positions
+	tests := []struct {
+		Arg    string
+		Expect string
+	}{
+		{`{"tier":}`, "xflags: --selector: invalid JSON at column 9: invalid character '}' looking for beginning of value"},
+		{`{"tier":"web"`, "xflags: --selector: invalid JSON at column 13: unexpected end of input"},
+		{`{"replicas":"3"}`, "xflags: --selector: invalid JSON at column 15: cannot use string as int in field replicas"},
+		{`{} {}`, "xflags: --selector: invalid JSON at column 4: unexpected data after top-level value"},
+		{"{\n\"tier\": x}", "xflags: --selector: invalid JSON at line 2, column 9: invalid character 'x' looking for beginning of value"},
+	}
+	for _, test := range tests {
+		err := parseFlag(flag, "--selector="+test.Arg)
+		if err == nil {
+			t.Errorf("%s: expected error", test.Arg)
+			continue
+		}
+		assertString(t, test.Expect, err.Error())
+	}
+	assertString(t, "web", v.Tier) // unchanged by errors
+
+	var m map[string]interface{}
+	if assertFlagParses(t, JSON(&m, "labels", "").Must(), `--labels={"app":"x"}`) {
+		assertString(t, "x", m["app"].(string))
+	}
+	if _, err := JSON(m, "labels", "").Flag(); err == nil {
+		t.Error("expected error for non-pointer")
+	}
+}
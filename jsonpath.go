@@ -0,0 +1,243 @@
+package xflags
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a template in the JSONPath syntax used by kubectl. Text outside
+// of braces is printed literally and each expression in braces prints the
+// values it selects, separated by spaces. Supported expressions are:
+//
+//	{.name}           field of the current object
+//	{$.items[0]}      element of an array, from the root object
+//	{.items[*].name}  field of every element of an array
+//	{.labels.*}       every value of an object
+//	{"\n"}            quoted string
+//	{range .items[*]}{.name}{"\n"}{end}
+//
+// Fields that do not exist select no values.
+type jsonPath struct {
+	nodes []jpNode
+}
+
+// jpNode is one of jpText, jpExpr or jpRange.
+type jpNode interface{}
+
+type jpText string
+
+type jpExpr []jpStep
+
+type jpRange struct {
+	expr jpExpr
+	body []jpNode
+}
+
+// jpStep selects a field of an object, if field is not empty, an element of an
+// array, or, if wildcard is set, all values of an object or array.
+type jpStep struct {
+	root     bool
+	field    string
+	index    int
+	wildcard bool
+}
+
+func parseJSONPath(s string) (*jsonPath, error) {
+	stack := [][]jpNode{nil}
+	ranges := make([]jpExpr, 0)
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			i = len(s)
+		}
+		if i > 0 {
+			stack[len(stack)-1] = append(stack[len(stack)-1], jpText(s[:i]))
+			s = s[i:]
+			continue
+		}
+		j := closingBrace(s)
+		if j < 0 {
+			return nil, fmt.Errorf("unclosed expression: %s", s)
+		}
+		expr := strings.TrimSpace(s[1:j])
+		s = s[j+1:]
+		switch {
+		case expr == "end":
+			if len(ranges) == 0 {
+				return nil, fmt.Errorf("unexpected {end}")
+			}
+			body := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			node := &jpRange{expr: ranges[len(ranges)-1], body: body}
+			ranges = ranges[:len(ranges)-1]
+			stack[len(stack)-1] = append(stack[len(stack)-1], node)
+		case strings.HasPrefix(expr, "range "):
+			steps, err := parseJPExpr(strings.TrimSpace(expr[len("range "):]))
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, steps)
+			stack = append(stack, nil)
+		case strings.HasPrefix(expr, `"`):
+			text, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string: %s", expr)
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], jpText(text))
+		default:
+			steps, err := parseJPExpr(expr)
+			if err != nil {
+				return nil, err
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], steps)
+		}
+	}
+	if len(ranges) > 0 {
+		return nil, fmt.Errorf("missing {end}")
+	}
+	return &jsonPath{nodes: stack[0]}, nil
+}
+
+// closingBrace returns the index of the brace that closes the expression at
+// the start of s, ignoring braces in quoted strings.
+func closingBrace(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '}' && !quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+func parseJPExpr(s string) (jpExpr, error) {
+	steps := make(jpExpr, 0)
+	orig := s
+	if strings.HasPrefix(s, "$") {
+		steps = append(steps, jpStep{root: true})
+		s = s[1:]
+	}
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			n := strings.IndexAny(s, ".[")
+			if n < 0 {
+				n = len(s)
+			}
+			switch name := s[:n]; name {
+			case "":
+				if len(s) > 0 || len(steps) > 0 {
+					return nil, fmt.Errorf("invalid expression: %s", orig)
+				}
+			case "*":
+				steps = append(steps, jpStep{wildcard: true})
+			default:
+				steps = append(steps, jpStep{field: name})
+			}
+			s = s[n:]
+		case '[':
+			n := strings.IndexByte(s, ']')
+			if n < 0 {
+				return nil, fmt.Errorf("invalid expression: %s", orig)
+			}
+			sub := s[1:n]
+			s = s[n+1:]
+			if sub == "*" {
+				steps = append(steps, jpStep{wildcard: true})
+				continue
+			}
+			if len(sub) >= 2 && sub[0] == '\'' && sub[len(sub)-1] == '\'' {
+				steps = append(steps, jpStep{field: sub[1 : len(sub)-1]})
+				continue
+			}
+			i, err := strconv.Atoi(sub)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index: %s", sub)
+			}
+			steps = append(steps, jpStep{index: i})
+		default:
+			return nil, fmt.Errorf("invalid expression: %s", orig)
+		}
+	}
+	return steps, nil
+}
+
+// Execute writes the template for data, which must be decoded by toOrdered.
+func (c *jsonPath) Execute(w io.Writer, data interface{}) error {
+	return executeJP(w, c.nodes, data, data)
+}
+
+func executeJP(w io.Writer, nodes []jpNode, root, current interface{}) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case jpText:
+			if _, err := io.WriteString(w, string(node)); err != nil {
+				return err
+			}
+		case jpExpr:
+			values := node.eval(root, current)
+			s := make([]string, len(values))
+			for i, v := range values {
+				s[i] = tableCell(v)
+			}
+			if _, err := io.WriteString(w, strings.Join(s, " ")); err != nil {
+				return err
+			}
+		case *jpRange:
+			for _, v := range node.expr.eval(root, current) {
+				if err := executeJP(w, node.body, root, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c jpExpr) eval(root, current interface{}) []interface{} {
+	values := []interface{}{current}
+	for _, step := range c {
+		next := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			switch {
+			case step.root:
+				next = append(next, root)
+			case step.wildcard:
+				switch v := v.(type) {
+				case *orderedMap:
+					for _, key := range v.keys {
+						next = append(next, v.values[key])
+					}
+				case []interface{}:
+					next = append(next, v...)
+				}
+			case step.field != "":
+				if m, ok := v.(*orderedMap); ok {
+					if elem, ok := m.values[step.field]; ok {
+						next = append(next, elem)
+					}
+				}
+			default:
+				if a, ok := v.([]interface{}); ok {
+					i := step.index
+					if i < 0 {
+						i += len(a)
+					}
+					if i >= 0 && i < len(a) {
+						next = append(next, a[i])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
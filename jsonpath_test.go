@@ -0,0 +1,54 @@
+package xflags
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	data := map[string]interface{}{
+		"kind": "List",
+		"items": []map[string]interface{}{
+			{"name": "foo", "labels": map[string]string{"app": "a"}, "ports": []int{80, 443}},
+			{"name": "bar", "labels": map[string]string{"app": "b"}},
+		},
+	}
+	tests := []struct {
+		Template string
+		Expect   string
+	}{
+		{"{.kind}", "List"},
+		{"kind: {$.kind}!", "kind: List!"},
+		{"{.items[*].name}", "foo bar"},
+		{"{.items[0].ports}", "[80,443]"},
+		{"{.items[-1].name}", "bar"},
+		{"{.items[*].labels.*}", "a b"},
+		{"{.items[0]['labels'].app}", "a"},
+		{"{.missing}{.items[5].name}", ""},
+		{`{range .items[*]}{.name}{"\t"}{.ports[*]}{"\n"}{end}`, "foo\t80 443\nbar\t\n"},
+		{`{range .items[*]}{range .ports[*]}{$.kind}:{.}{"}"}{end}{end}`, "List:80}List:443}"},
+	}
+	doc, err := toOrdered(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		jp, err := parseJSONPath(test.Template)
+		if err != nil {
+			t.Errorf("%s: %v", test.Template, err)
+			continue
+		}
+		w := new(bytes.Buffer)
+		if err := jp.Execute(w, doc); err != nil {
+			t.Errorf("%s: %v", test.Template, err)
+			continue
+		}
+		assertString(t, test.Expect, w.String())
+	}
+
+	for _, s := range []string{"{.kind", "{range .items[*]}", "{end}", "{.items[x]}", "{kind}"} {
+		if _, err := parseJSONPath(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
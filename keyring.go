@@ -0,0 +1,51 @@
+package xflags
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Keyring returns a CredentialSource that stores the values of flags in the
+// credential store of the operating system under the given service name,
+// which is usually the name of the program:
+//
+//   - On macOS, values are generic passwords in the login keychain, managed
+//     with the security command.
+//   - On Windows, values are generic credentials of the Windows Credential
+//     Manager, with the target name "SERVICE:PATH".
+//   - On other systems, values are stored by the Secret Service, such as
+//     GNOME Keyring or KWallet, with the secret-tool command of libsecret.
+//
+// The account or attribute of each value is the path of the flag. If the
+// credential store is not available, for example because secret-tool is not
+// installed, lookups find no values and Store returns an error.
+func Keyring(service string) CredentialSource {
+	return &keyring{service: service}
+}
+
+type keyring struct {
+	service string
+}
+
+func (c *keyring) Name() string { return "keyring" }
+
+func (c *keyring) Lookup(flagPath string) (string, bool) {
+	s, ok, _ := c.LookupErr(flagPath)
+	return s, ok
+}
+
+func (c *keyring) LookupErr(flagPath string) (string, bool, error) {
+	s, ok, err := keyringGet(c.service, flagPath)
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", false, nil // no credential store
+	}
+	return s, ok, err
+}
+
+func (c *keyring) Store(flagPath, value string) error {
+	return keyringSet(c.service, flagPath, value)
+}
+
+func (c *keyring) Delete(flagPath string) error {
+	return keyringDelete(c.service, flagPath)
+}
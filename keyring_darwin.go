@@ -0,0 +1,89 @@
+//go:build darwin
+
+package xflags
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// securityNotFound is the exit code of the security command if no item
+// matches.
+const securityNotFound = 44
+
+func keyringGet(service, account string) (string, bool, error) {
+	out, err := security("find-generic-password", "-s", service, "-a", account, "-w")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == securityNotFound {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return strings.TrimSuffix(out, "\n"), true, nil
+}
+
+func keyringSet(service, account, value string) error {
+	// The password is written to the interactive mode of security on stdin,
+	// since the arguments of a process are visible to other users in ps.
+	return securityInteractive("add-generic-password", "-U", "-s", service, "-a", account, "-w", value)
+}
+
+func keyringDelete(service, account string) error {
+	_, err := security("delete-generic-password", "-s", service, "-a", account)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityNotFound {
+		return nil
+	}
+	return err
+}
+
+// security runs the macOS security command and returns its output.
+func security(args ...string) (string, error) {
+	cmd := exec.Command("security", args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", &xflagsErr{Text: msg, Err: err}
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// securityInteractive runs a command of the macOS security command in its
+// interactive mode, which reads the command line from stdin.
+func securityInteractive(args ...string) error {
+	var line strings.Builder
+	for i, arg := range args {
+		if strings.ContainsAny(arg, "\n\r") {
+			return errorf("keyring values may not contain line breaks")
+		}
+		if i > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(securityQuote(arg))
+	}
+	line.WriteByte('\n')
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(line.String())
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	// errors of commands in interactive mode do not change the exit code
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return &xflagsErr{Text: msg, Err: err}
+	}
+	return err
+}
+
+// securityQuote quotes s as an argument of a command line in the interactive
+// mode of security, which splits arguments at spaces outside of double quotes
+// and unescapes backslashes.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
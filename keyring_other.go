@@ -0,0 +1,68 @@
+//go:build !darwin && !windows
+
+package xflags
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// secretAttribute is the attribute of the items stored by secret-tool that
+// holds the path of the flag.
+const secretAttribute = "xflags-flag"
+
+func keyringGet(service, account string) (string, bool, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", service, secretAttribute, account)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// secret-tool exits with status 1 and no message if no item matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return "", false, nil
+		}
+		return "", false, secretToolErr(stderr, err)
+	}
+	return string(out), true, nil
+}
+
+func keyringSet(service, account, value string) error {
+	cmd := exec.Command(
+		"secret-tool", "store",
+		"--label", service+" "+account,
+		"service", service,
+		secretAttribute, account,
+	)
+	cmd.Stdin = strings.NewReader(value)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return secretToolErr(stderr, err)
+	}
+	return nil
+}
+
+func keyringDelete(service, account string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", service, secretAttribute, account)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return nil // no such item
+		}
+		return secretToolErr(stderr, err)
+	}
+	return nil
+}
+
+// secretToolErr annotates an error running secret-tool with its output.
+func secretToolErr(stderr *bytes.Buffer, err error) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return &xflagsErr{Text: msg, Err: err}
+	}
+	return err
+}
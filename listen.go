@@ -0,0 +1,209 @@
+package xflags
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemdFirstFD is the first file descriptor passed by systemd socket
+// activation.
+const systemdFirstFD = 3
+
+// listenOptions are the values of the flags registered by ListenFlags.
+type listenOptions struct {
+	prefix     string
+	addr       string
+	port       int
+	unixSocket string
+	systemd    bool
+}
+
+// ListenFlags registers the --PREFIX-listen, --PREFIX-port,
+// --PREFIX-unix-socket and --PREFIX-systemd-socket flags for this command and
+// its subcommands, or --listen, --port, --unix-socket and --systemd-socket if
+// prefix is empty. A net.Listener is created from the flags and handlers
+// registered with HandleContext may retrieve it with ListenerFrom:
+//
+//   - --listen listens on a TCP address such as "localhost:8080".
+//   - --port listens on a TCP port of all interfaces.
+//   - --unix-socket listens on the path of a Unix socket.
+//   - --systemd-socket uses a socket passed by systemd socket activation. If
+//     systemd passes more than one socket, the socket named prefix by the
+//     FileDescriptorName option of the socket unit is used, or the first
+//     socket if prefix is empty.
+//
+// Only one of the flags may be specified. If none are specified, no listener
+// is created and ListenerFrom returns nil, so that the handler may listen on a
+// default address. The listener is created when the command is handled, not by
+// Parse, and is closed after the handler returns. Conflicting flags and
+// invalid ports are returned by Parse as an ArgumentError that names the flag,
+// and errors creating the listener are reported like argument errors when the
+// command is handled.
+//
+// Since HTTPClientFlags also registers --PREFIX-unix-socket, a command that
+// both listens and makes HTTP requests must call them with different
+// prefixes, for example ListenFlags("") and HTTPClientFlags("upstream").
+func (c *CommandBuilder) ListenFlags(prefix string) *CommandBuilder {
+	for _, opts := range c.cmd.httpClientOptions {
+		if opts.prefix == prefix {
+			return c.error(unixSocketConflict(c.cmd.Name, prefix))
+		}
+	}
+	opts := &listenOptions{prefix: prefix}
+	c.cmd.listenOptions = append(c.cmd.listenOptions, opts)
+	usage := "Listen options"
+	if prefix != "" {
+		usage += " (" + prefix + ")"
+	}
+	return c.FlagGroup(
+		"listen-"+prefix,
+		usage,
+		String(&opts.addr, prefixedName(prefix, "listen"), "", "TCP address to listen on").builtin(),
+		Int(&opts.port, prefixedName(prefix, "port"), 0, "TCP port to listen on").builtin(),
+		String(&opts.unixSocket, prefixedName(prefix, "unix-socket"), "", "Path of a Unix socket to listen on").
+			builtin(),
+		Bool(&opts.systemd, prefixedName(prefix, "systemd-socket"), false, "Use the socket passed by systemd").
+			builtin(),
+	)
+}
+
+type listenerKey struct{ prefix string }
+
+// ListenerFrom returns the net.Listener created from the flags registered by
+// ListenFlags with the given prefix for the command invocation that ctx was
+// created for, or nil if none of the flags were specified.
+func ListenerFrom(ctx context.Context, prefix string) net.Listener {
+	l, _ := ctx.Value(listenerKey{prefix}).(net.Listener)
+	return l
+}
+
+// checkListenFlags returns an error if more than one of the flags of a call to
+// ListenFlags by the current command or its parents were specified or if the
+// port is invalid.
+func (c *argParser) checkListenFlags() error {
+	for p := c.cmd; p != nil; p = p.Parent {
+		for _, opts := range p.listenOptions {
+			if _, err := c.listenFlag(p, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listenFlag returns the flag of opts, which were registered by cmd, that was
+// specified, or nil.
+func (c *argParser) listenFlag(cmd *Command, opts *listenOptions) (*Flag, error) {
+	specified := make([]*Flag, 0, 1)
+	for _, name := range []string{"listen", "port", "unix-socket", "systemd-socket"} {
+		flag := c.lookupName(prefixedName(opts.prefix, name))
+		if c.flagsSeen[flag.name()] > 0 {
+			specified = append(specified, flag)
+		}
+	}
+	if len(specified) > 1 {
+		return nil, newArgErr(c.cmd, specified[1], "", "cannot be specified with %s", specified[0])
+	}
+	port := c.optionValue(cmd, prefixedName(opts.prefix, "port")).(int64)
+	if port < 0 || port > 65535 {
+		return nil, newArgErr(c.cmd, c.lookupName(prefixedName(opts.prefix, "port")), "", "invalid port: %d", port)
+	}
+	if len(specified) == 0 {
+		return nil, nil
+	}
+	return specified[0], nil
+}
+
+// withListeners returns a context derived from ctx that carries the listeners
+// created from the flags of inv, an invocation of c, and its parents, and a
+// function that closes them. If a listener cannot be created, the listeners
+// that were created are closed and an ArgumentError is returned.
+func (c *Command) withListeners(ctx context.Context, inv *Invocation) (context.Context, func(), error) {
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close() // may already be closed by a server
+		}
+	}
+	if inv.parser == nil {
+		return ctx, closeAll, nil
+	}
+	for p := c; p != nil; p = p.Parent {
+		for _, opts := range p.listenOptions {
+			key := listenerKey{opts.prefix}
+			if ctx.Value(key) != nil {
+				continue
+			}
+			l, err := inv.parser.listen(p, opts)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			if l != nil {
+				listeners = append(listeners, l)
+				ctx = context.WithValue(ctx, key, l)
+			}
+		}
+	}
+	return ctx, closeAll, nil
+}
+
+// listen creates a listener from the flags of opts, which were registered by
+// cmd, or returns nil if none of the flags were specified.
+func (c *argParser) listen(cmd *Command, opts *listenOptions) (net.Listener, error) {
+	flag, err := c.listenFlag(cmd, opts)
+	if err != nil {
+		return nil, err
+	}
+	value := func(name string) interface{} { return c.optionValue(cmd, prefixedName(opts.prefix, name)) }
+	var l net.Listener
+	switch {
+	case value("listen").(string) != "":
+		l, err = net.Listen("tcp", value("listen").(string))
+	case value("port").(int64) != 0:
+		l, err = net.Listen("tcp", ":"+strconv.FormatInt(value("port").(int64), 10))
+	case value("unix-socket").(string) != "":
+		l, err = net.Listen("unix", value("unix-socket").(string))
+	case value("systemd-socket").(bool):
+		l, err = systemdListener(opts.prefix)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, wrapArgErr(err, c.cmd, flag, "")
+	}
+	return l, nil
+}
+
+// systemdListener returns a listener for the socket passed by systemd socket
+// activation with the given name, or the first socket if name is empty or
+// systemd passed only one socket.
+func systemdListener(name string) (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, errorf("no sockets passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errorf("no sockets passed by systemd")
+	}
+	i := -1
+	if names := os.Getenv("LISTEN_FDNAMES"); names != "" && name != "" {
+		for j, s := range strings.Split(names, ":") {
+			if s == name && j < n {
+				i = j
+			}
+		}
+	}
+	if i < 0 {
+		if n > 1 && name != "" {
+			return nil, errorf("no socket named %q passed by systemd", name)
+		}
+		i = 0
+	}
+	f := os.NewFile(uintptr(systemdFirstFD+i), "systemd-socket")
+	defer f.Close()
+	return net.FileListener(f)
+}
@@ -0,0 +1,138 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestListenFlags(t *testing.T) {
+	var l net.Listener
+	code := NewCommand("app", "").
+		ListenFlags("").
+		HandleContext(func(ctx context.Context, args []string) int {
+			l = ListenerFrom(ctx, "")
+			return 0
+		}).
+		Must().
+		Run([]string{"--listen", "127.0.0.1:0"})
+	assertInt64(t, 0, int64(code))
+	if l == nil {
+		t.Fatal("expected listener")
+	}
+	if _, err := l.Accept(); err == nil {
+		t.Error("expected listener to be closed")
+	}
+
+	// no listener is created without flags
+	code = NewCommand("app", "").
+		ListenFlags("admin").
+		HandleContext(func(ctx context.Context, args []string) int {
+			l = ListenerFrom(ctx, "admin")
+			return 0
+		}).
+		Must().
+		Run(nil)
+	assertInt64(t, 0, int64(code))
+	assertBool(t, true, l == nil)
+}
+
+func TestListenFlagsUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not tested on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "app.sock")
+	var network string
+	code := NewCommand("app", "").
+		ListenFlags("").
+		HandleContext(func(ctx context.Context, args []string) int {
+			network = ListenerFrom(ctx, "").Addr().Network()
+			return 0
+		}).
+		Must().
+		Run([]string{"--unix-socket", path})
+	assertInt64(t, 0, int64(code))
+	assertString(t, "unix", network)
+}
+
+func TestListenFlagsErrors(t *testing.T) {
+	os.Unsetenv("LISTEN_PID")
+	tests := []struct {
+		Args []string
+		Flag string
+	}{
+		{[]string{"--listen", "127.0.0.1:0", "--port", "8080"}, "port"},
+		{[]string{"--port", "70000"}, "port"},
+	}
+	for _, test := range tests {
+		_, err := NewCommand("app", "").ListenFlags("").Must().Parse(test.Args)
+		var argErr *ArgumentError
+		if assertErrorAs(t, err, &argErr) {
+			assertString(t, test.Flag, argErr.Flag.Name)
+		}
+	}
+
+	// listeners are created when the command is handled
+	for _, args := range [][]string{{"--listen", "invalid"}, {"--systemd-socket"}} {
+		called := false
+		stderr := new(bytes.Buffer)
+		code := NewCommand("app", "").
+			Output(nil, stderr).
+			ListenFlags("").
+			HandleContext(func(ctx context.Context, args []string) int {
+				called = true
+				return 0
+			}).
+			Must().
+			Run(args)
+		assertInt64(t, 1, int64(code))
+		assertBool(t, false, called)
+		if !strings.HasPrefix(stderr.String(), "Argument error: "+args[0]) {
+			t.Errorf("%q: unexpected error: %q", args, stderr.String())
+		}
+	}
+}
+
+func TestListenFlagsExec(t *testing.T) {
+	var l net.Listener
+	cmd := NewCommand("app", "").
+		ListenFlags("").
+		HandleContext(func(ctx context.Context, args []string) int {
+			l = ListenerFrom(ctx, "")
+			return 0
+		}).
+		Must()
+
+	// Parse does not listen
+	if _, err := cmd.Parse([]string{"--listen", "127.0.0.1:0"}); err != nil {
+		t.Fatal(err)
+	}
+	assertBool(t, true, l == nil)
+
+	code := cmd.Exec(context.Background(), []string{"--listen", "127.0.0.1:0"}, nil, nil, nil)
+	assertInt64(t, 0, int64(code))
+	if l == nil {
+		t.Fatal("expected listener")
+	}
+	if _, err := l.Accept(); err == nil {
+		t.Error("expected listener to be closed")
+	}
+}
+
+func TestListenFlagsHTTPClientFlags(t *testing.T) {
+	_, err := NewCommand("app", "").ListenFlags("").HTTPClientFlags("").Command()
+	assertString(t, `xflags: app: HTTPClientFlags and ListenFlags cannot both use the prefix "": both declare --unix-socket`, err.Error())
+	_, err = NewCommand("app", "").HTTPClientFlags("api").ListenFlags("api").Command()
+	assertString(t, `xflags: app: HTTPClientFlags and ListenFlags cannot both use the prefix "api": both declare --api-unix-socket`, err.Error())
+
+	// different prefixes are compatible
+	_, err = NewCommand("app", "").ListenFlags("").HTTPClientFlags("upstream").Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package xflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errLocked is returned by lockFile if the lock is held by another process.
+var errLocked = errors.New("lock is held by another process")
+
+// SingleInstance specifies that only one invocation of this command or its
+// subcommands may run at a time. Before the handler is called, an exclusive
+// lock is acquired on the file at lockPath, which is created if necessary. If
+// the lock is held by another invocation, the command fails immediately.
+//
+// This is useful for maintenance commands invoked by cron that must not
+// overlap. On Linux, macOS and the BSDs, the lock is released by the
+// operating system if the process exits unexpectedly. On other platforms, the
+// lock file must be removed manually after a crash.
+func (c *CommandBuilder) SingleInstance(lockPath string) *CommandBuilder {
+	if lockPath == "" {
+		return c.error(errorf("%s: empty lock path", c.cmd.Name))
+	}
+	return c.Use(func(next ContextHandlerFunc) ContextHandlerFunc {
+		return func(ctx context.Context, args []string) int {
+			unlock, err := lockFile(lockPath)
+			if err != nil {
+				out := OutputFrom(ctx)
+				if errors.Is(err, errLocked) {
+					fmt.Fprintf(
+						out.Stderr,
+						"Error: another invocation is already running (lock: %s)\n",
+						lockPath,
+					)
+				} else {
+					fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+				}
+				return 1
+			}
+			defer unlock()
+			return next(ctx, args)
+		}
+	})
+}
+
+// waitLockFile acquires the lock on the file at path like lockFile, retrying
+// while it is held by another process or invocation for at most timeout.
+func waitLockFile(path string, timeout time.Duration) (unlock func(), err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		unlock, err := lockFile(path)
+		if !errors.Is(err, errLocked) || time.Now().After(deadline) {
+			return unlock, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
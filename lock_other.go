@@ -0,0 +1,24 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package xflags
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// lockFile acquires a lock by exclusively creating the file at path. The file
+// is removed when the lock is released.
+func lockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, errLocked
+		}
+		return nil, err
+	}
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+	f.Close()
+	return func() { os.Remove(path) }, nil
+}
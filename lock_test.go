@@ -0,0 +1,41 @@
+package xflags
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSingleInstance(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	stderr := new(bytes.Buffer)
+	calls := 0
+	cmd := NewCommand("app", "").
+		Output(new(bytes.Buffer), stderr).
+		SingleInstance(lockPath).
+		HandleFunc(func(args []string) int {
+			calls++
+			return 0
+		}).
+		Must()
+
+	unlock, err := lockFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := cmd.Run(nil); code != 1 {
+		t.Errorf("expected exit code 1, got: %d", code)
+	}
+	if !strings.Contains(stderr.String(), "already running") {
+		t.Errorf("unexpected error message: %q", stderr)
+	}
+	unlock()
+
+	for i := 0; i < 2; i++ {
+		if code := cmd.Run(nil); code != 0 {
+			t.Errorf("unexpected exit code: %d", code)
+		}
+	}
+	assertInt64(t, 2, int64(calls))
+}
@@ -0,0 +1,16 @@
+package xflags
+
+import "net"
+
+func init() {
+	RegisterValueFunc(net.ParseMAC, net.HardwareAddr.String)
+}
+
+// MAC returns a FlagBuilder that can be used to define a hardware address flag
+// with specified name, default value, and usage string. The argument p points
+// to a net.HardwareAddr variable in which to store the value of the flag. The
+// flag accepts the notations of net.ParseMAC, such as "00:00:5e:00:53:01",
+// "00-00-5e-00-53-01" or "0000.5e00.5301".
+func MAC(p *net.HardwareAddr, name string, value net.HardwareAddr, usage string) *FlagBuilder {
+	return Of(p, name, value, usage)
+}
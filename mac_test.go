@@ -0,0 +1,29 @@
+package xflags
+
+import (
+	"net"
+	"testing"
+)
+
+func TestMAC(t *testing.T) {
+	for _, arg := range []string{
+		"00:00:5e:00:53:01",
+		"00-00-5E-00-53-01",
+		"0000.5e00.5301",
+	} {
+		var v net.HardwareAddr
+		flag := MAC(&v, "mac", nil, "").Must()
+		if assertFlagParses(t, flag, "--mac="+arg) {
+			assertString(t, "00:00:5e:00:53:01", v.String())
+			assertString(t, "00:00:5e:00:53:01", valueString(flag.Value))
+		}
+	}
+
+	def, _ := net.ParseMAC("02:00:5e:10:00:00")
+	flag := MAC(nil, "mac", def, "").Must()
+	assertString(t, "02:00:5e:10:00:00", valueString(flag.Value))
+	err := parseFlag(flag, "--mac=00:00:5e")
+	if assertBool(t, true, err != nil) {
+		assertString(t, "xflags: --mac: address 00:00:5e: invalid MAC address", err.Error())
+	}
+}
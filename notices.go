@@ -0,0 +1,65 @@
+package xflags
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// Notices adds a "licenses" subcommand that prints the contents of each file in
+// fsys, which typically embeds the licenses and notices of third-party
+// software distributed with the program:
+//
+//	//go:embed third_party
+//	var notices embed.FS
+//
+//	var App = xflags.NewCommand("app", "").
+//		Notices(notices)
+//
+// Files are printed in lexical order, each preceded by its path. Since the
+// notices are a subcommand, the command cannot have positional arguments.
+func (c *CommandBuilder) Notices(fsys fs.FS) *CommandBuilder {
+	if fsys == nil {
+		return c.error(errorf("%s: nil notices", c.cmd.Name))
+	}
+	return c.Subcommands(newNoticesCommand(fsys))
+}
+
+func newNoticesCommand(fsys fs.FS) *Command {
+	cmd := &Command{
+		Name:  "licenses",
+		Usage: "Show licenses and notices of third-party software",
+	}
+	cmd.HandlerFunc = func(args []string) int {
+		stdout, stderr := cmd.output()
+		if err := writeNotices(stdout, fsys); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	return cmd
+}
+
+// writeNotices writes the path and contents of each file in fsys to w.
+func writeNotices(w io.Writer, fsys fs.FS) error {
+	first := true
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+		fmt.Fprintf(w, "%s:\n\n%s", path, b)
+		if len(b) > 0 && b[len(b)-1] != '\n' {
+			fmt.Fprintln(w)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,24 @@
+package xflags
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNotices(t *testing.T) {
+	fsys := fstest.MapFS{
+		"b/LICENSE": {Data: []byte("MIT License")},
+		"a/NOTICE":  {Data: []byte("Copyright Acme\n")},
+	}
+	stdout := new(bytes.Buffer)
+	cmd := NewCommand("app", "").
+		Output(stdout, stdout).
+		Notices(fsys).
+		Must()
+	if code := cmd.Run([]string{"licenses"}); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	expect := "a/NOTICE:\n\nCopyright Acme\n\nb/LICENSE:\n\nMIT License\n"
+	assertString(t, expect, stdout.String())
+}
@@ -0,0 +1,239 @@
+package xflags
+
+import (
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// numberFormat describes the separators accepted in the numeric arguments of
+// a flag.
+type numberFormat struct {
+	thousands    bool // accept thousands separators
+	decimalComma bool // accept a decimal comma if the locale uses one
+}
+
+// AllowThousandsSeparators specifies that numeric arguments of the flag may
+// group digits in thousands with commas, as in "1,234,567.89", so that
+// numbers pasted from spreadsheets are accepted. If DecimalComma is also
+// specified and the locale of the user uses a decimal comma, digits are
+// grouped with periods instead, as in "1.234.567,89". Separators must group
+// exactly three digits; otherwise the argument is passed to the flag
+// unchanged.
+func (c *FlagBuilder) AllowThousandsSeparators() *FlagBuilder {
+	c.flag.numberFormat.thousands = true
+	return c
+}
+
+// DecimalComma specifies that numeric arguments of the flag use a comma as
+// the decimal separator, as in "1234,56", if the locale of the user does. The
+// locale is read from the LC_ALL, LC_NUMERIC and LANG environment variables,
+// in that order. Otherwise, arguments use a period.
+func (c *FlagBuilder) DecimalComma() *FlagBuilder {
+	c.flag.numberFormat.decimalComma = true
+	return c
+}
+
+// normalize returns s with the separators accepted by the format replaced by
+// the notation of Go numeric literals.
+func (c numberFormat) normalize(s string) string {
+	if !c.thousands && !c.decimalComma {
+		return s
+	}
+	decimal, group := ".", ","
+	if c.decimalComma && localeDecimalComma() {
+		decimal, group = ",", "."
+	}
+	intPart, fracPart, hasFrac := s, "", false
+	if i := strings.LastIndex(s, decimal); i >= 0 {
+		intPart, fracPart, hasFrac = s[:i], s[i+1:], true
+	}
+	if c.thousands && strings.Contains(intPart, group) {
+		ungrouped, ok := ungroupDigits(intPart, group)
+		if !ok {
+			return s
+		}
+		intPart = ungrouped
+	}
+	if !hasFrac {
+		return intPart
+	}
+	return intPart + "." + fracPart
+}
+
+// ungroupDigits removes the thousands separators of an optionally signed
+// integer and reports whether each separator grouped exactly three digits.
+func ungroupDigits(s, sep string) (string, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	groups := strings.Split(s, sep)
+	for i, group := range groups {
+		if !isDigits(group) || len(group) > 3 || i > 0 && len(group) != 3 {
+			return "", false
+		}
+	}
+	return sign + strings.Join(groups, ""), true
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// decimalCommaLanguages are the languages whose locales use a decimal comma.
+var decimalCommaLanguages = map[string]bool{
+	"af": true, "az": true, "be": true, "bg": true, "bs": true, "ca": true,
+	"cs": true, "da": true, "de": true, "el": true, "es": true, "et": true,
+	"eu": true, "fi": true, "fr": true, "gl": true, "hr": true, "hu": true,
+	"id": true, "is": true, "it": true, "ka": true, "kk": true, "lt": true,
+	"lv": true, "mk": true, "nb": true, "nl": true, "nn": true, "no": true,
+	"pl": true, "pt": true, "ro": true, "ru": true, "sk": true, "sl": true,
+	"sq": true, "sr": true, "sv": true, "tr": true, "uk": true, "vi": true,
+}
+
+// localeDecimalComma reports whether the locale of the user, as read from
+// the environment, uses a decimal comma.
+func localeDecimalComma() bool {
+	var locale string
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if locale = os.Getenv(name); locale != "" {
+			break
+		}
+	}
+	// strip the codeset and modifier, as in "de_DE.UTF-8@euro"
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	language, territory, _ := strings.Cut(strings.ReplaceAll(locale, "-", "_"), "_")
+	if territory == "CH" || territory == "LI" {
+		return false // Swiss locales use a decimal point
+	}
+	return decimalCommaLanguages[strings.ToLower(language)]
+}
+
+// maxExponent is the largest exponent accepted in the scientific notation of
+// an integer, which exceeds the number of digits of any 64-bit integer.
+const maxExponent = 40
+
+// parseInt parses s as a signed integer of the given bit size. It accepts the
+// notation of Go integer literals, with the 0b, 0o and 0x prefixes and
+// underscores between digits, as in "0x_ff" or "1_000_000", and integers in
+// scientific notation, such as "1e6" or "2.5e3". Unlike Go literals, a
+// leading zero does not denote an octal number, so "010" is ten.
+func parseInt(s string, bitSize int) (int64, error) {
+	// decimal integers are parsed without math/big
+	if n, err := strconv.ParseInt(s, 10, bitSize); err == nil {
+		return n, nil
+	} else if !isSyntaxError(err) {
+		return 0, err
+	}
+	n, err := parseInteger("ParseInt", s)
+	if err != nil {
+		return 0, err
+	}
+	max := new(big.Int).Lsh(big.NewInt(1), uint(bitSize-1))
+	min := new(big.Int).Neg(max)
+	max.Sub(max, big.NewInt(1))
+	if n.Cmp(min) < 0 || n.Cmp(max) > 0 {
+		return 0, &strconv.NumError{Func: "ParseInt", Num: s, Err: strconv.ErrRange}
+	}
+	return n.Int64(), nil
+}
+
+// parseUint parses s as an unsigned integer of the given bit size in the
+// notation accepted by parseInt.
+func parseUint(s string, bitSize int) (uint64, error) {
+	if n, err := strconv.ParseUint(s, 10, bitSize); err == nil {
+		return n, nil
+	} else if !isSyntaxError(err) {
+		return 0, err
+	}
+	n, err := parseInteger("ParseUint", s)
+	if err != nil {
+		return 0, err
+	}
+	if n.Sign() < 0 {
+		return 0, &strconv.NumError{Func: "ParseUint", Num: s, Err: strconv.ErrSyntax}
+	}
+	if n.BitLen() > bitSize {
+		return 0, &strconv.NumError{Func: "ParseUint", Num: s, Err: strconv.ErrRange}
+	}
+	return n.Uint64(), nil
+}
+
+// isSyntaxError reports whether err is a strconv.NumError for a string that is
+// not a decimal integer, which parseInteger may still accept.
+func isSyntaxError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrSyntax
+}
+
+// parseInteger parses s in the notation accepted by parseInt without limiting
+// its size. fn is the name of the function reported by errors.
+func parseInteger(fn, s string) (*big.Int, error) {
+	syntaxErr := &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrSyntax}
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 || digits == "" || strings.Contains(digits, "/") {
+		return nil, syntaxErr
+	}
+	literal, base := s, 0
+	if len(digits) > 1 && digits[0] == '0' && (digits[1] == '_' || isDigits(digits[1:2])) {
+		literal, base = strings.ReplaceAll(s, "_", ""), 10 // not octal
+	}
+	if n, ok := new(big.Int).SetString(literal, base); ok {
+		return n, nil
+	}
+	// scientific notation
+	mantissa, exp, ok := cutAny(strings.ReplaceAll(s, "_", ""), "eE")
+	if !ok || strings.ContainsAny(mantissa, "xX") {
+		return nil, syntaxErr
+	}
+	e, err := strconv.Atoi(strings.TrimPrefix(exp, "+"))
+	if err != nil || e < -maxExponent {
+		return nil, syntaxErr
+	}
+	r, ok := new(big.Rat).SetString(mantissa)
+	if !ok {
+		return nil, syntaxErr
+	}
+	if r.Sign() != 0 && e > maxExponent {
+		return nil, &strconv.NumError{Func: fn, Num: s, Err: strconv.ErrRange}
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(e))), nil))
+	if e < 0 {
+		r.Quo(r, scale)
+	} else {
+		r.Mul(r, scale)
+	}
+	if !r.IsInt() {
+		return nil, syntaxErr
+	}
+	return r.Num(), nil
+}
+
+// cutAny slices s around the first instance of any of the bytes in chars.
+func cutAny(s, chars string) (before, after string, found bool) {
+	if i := strings.IndexAny(s, chars); i >= 0 {
+		return s[:i], s[i+1:], true
+	}
+	return s, "", false
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
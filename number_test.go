@@ -0,0 +1,169 @@
+package xflags
+
+import "testing"
+
+func TestAllowThousandsSeparators(t *testing.T) {
+	t.Setenv("LC_ALL", "en_US.UTF-8")
+	tests := []struct {
+		Arg    string
+		Expect float64
+		OK     bool
+	}{
+		{"1234.5", 1234.5, true},
+		{"1,234.5", 1234.5, true},
+		{"-1,234,567", -1234567, true},
+		{"1,23", 0, false},
+		{"1,2345", 0, false},
+		{",123", 0, false},
+	}
+	for _, test := range tests {
+		var v float64
+		flag := Float64(&v, "threshold", 0, "").AllowThousandsSeparators().Must()
+		err := flag.Set(test.Arg)
+		if assertBool(t, test.OK, err == nil) && test.OK {
+			assertFloat64(t, test.Expect, v)
+		}
+	}
+
+	var n int
+	flag := Int(&n, "count", 0, "").AllowThousandsSeparators().Must()
+	if err := flag.Set("10,000"); err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 10000, int64(n))
+}
+
+func TestDecimalComma(t *testing.T) {
+	tests := []struct {
+		Locale string
+		Arg    string
+		Expect float64
+		OK     bool
+	}{
+		{"de_DE.UTF-8", "1.234,56", 1234.56, true},
+		{"de_DE.UTF-8", "0,5", 0.5, true},
+		{"de_DE.UTF-8", "1,234.56", 0, false},
+		{"de_CH.UTF-8", "1,234.56", 1234.56, true},
+		{"en_US.UTF-8", "1,234.56", 1234.56, true},
+		{"en_US.UTF-8", "0,5", 0, false},
+		{"C", "2.5", 2.5, true},
+	}
+	for _, test := range tests {
+		t.Setenv("LC_ALL", test.Locale)
+		var v float64
+		flag := Float64(&v, "threshold", 0, "").
+			AllowThousandsSeparators().
+			DecimalComma().
+			Must()
+		err := flag.Set(test.Arg)
+		if assertBool(t, test.OK, err == nil) && test.OK {
+			assertFloat64(t, test.Expect, v)
+		}
+	}
+
+	// arguments are normalized when parsing invocations
+	t.Setenv("LC_ALL", "fr_FR")
+	var v float64
+	cmd := NewCommand("app", "").
+		Flags(Float64(&v, "threshold", 0, "").DecimalComma()).
+		Must()
+	inv, err := cmd.ParseInvocation([]string{"--threshold", "0,25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat64(t, 0, v)
+	assertFloat64(t, 0.25, inv.Get("threshold").(float64))
+}
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		Arg    string
+		Expect int64
+		OK     bool
+	}{
+		{"42", 42, true},
+		{"-42", -42, true},
+		{"+42", 42, true},
+		{"010", 10, true},
+		{"-0", 0, true},
+		{"9223372036854775807", 9223372036854775807, true},
+		{"-9223372036854775808", -9223372036854775808, true},
+		{"9223372036854775808", 0, false},
+		{"1_000_000", 1000000, true},
+		{"0x_ff", 255, true},
+		{"0o755", 493, true},
+		{"0b1010", 10, true},
+		{"-0x10", -16, true},
+		{"1e6", 1000000, true},
+		{"2.5e3", 2500, true},
+		{"1E+2", 100, true},
+		{"9.223372036854775807e18", 9223372036854775807, true},
+		{"9.223372036854775808e18", 0, false},
+		{"1e100000000", 0, false},
+		{"1.5", 0, false},
+		{"1.5e0", 0, false},
+		{"1e-2", 0, false},
+		{"1/2e3", 0, false},
+		{"1__0", 0, false},
+		{"--1", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		v, err := parseInt(test.Arg, 64)
+		if assertBool(t, test.OK, err == nil) {
+			assertInt64(t, test.Expect, v)
+		} else {
+			t.Logf("%q: %v", test.Arg, err)
+		}
+	}
+}
+
+func TestParseUint(t *testing.T) {
+	var n uint64
+	flag := Uint64(&n, "size", 0, "").Must()
+	if err := flag.Set("0xffff_ffff_ffff_ffff"); err != nil {
+		t.Fatal(err)
+	}
+	assertUint64(t, 1<<64-1, n)
+	for _, test := range []struct {
+		Arg    string
+		Expect uint64
+	}{
+		{"18446744073709551615", 1<<64 - 1},
+		{"+42", 42},
+		{"-0", 0},
+		{"010", 10},
+		{"1_000", 1000},
+	} {
+		if err := flag.Set(test.Arg); err != nil {
+			t.Errorf("%q: %v", test.Arg, err)
+			continue
+		}
+		assertUint64(t, test.Expect, n)
+	}
+	for _, arg := range []string{"-1", "18446744073709551616", "0x1_0000_0000_0000_0000", "2e19"} {
+		if err := flag.Set(arg); err == nil {
+			t.Errorf("%q: expected error", arg)
+		}
+	}
+
+	var i int
+	flag = Int(&i, "count", 0, "").Must()
+	if err := flag.Set("1e3"); err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 1000, int64(i))
+}
+
+func BenchmarkParseInt(b *testing.B) {
+	for _, arg := range []string{"1234567", "1_234_567", "1.234567e6"} {
+		b.Run(arg, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := parseInt(arg, 64); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package xflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// defaultTokenPath is the path at which DeviceAuth stores the token if
+// DeviceAuthConfig.TokenPath is empty.
+const defaultTokenPath = "auth.token"
+
+// DeviceAuthConfig configures the OAuth 2.0 device authorization grant of
+// CommandBuilder.DeviceAuth, as described by RFC 8628.
+type DeviceAuthConfig struct {
+	ClientID      string
+	DeviceAuthURL string // URL of the device authorization endpoint.
+	TokenURL      string // URL of the token endpoint.
+	Scopes        []string
+
+	// TokenPath is the path at which the token is stored by the credential
+	// source. The default is "auth.token".
+	TokenPath string
+
+	// OpenBrowser specifies that the verification URL is opened in the web
+	// browser of the user, in addition to being printed.
+	OpenBrowser bool
+
+	// HTTPClient is used for requests to the endpoints. The default is
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// OAuthToken is an OAuth 2.0 token obtained by DeviceAuth.
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the token has an access token that has not expired.
+func (t *OAuthToken) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+// SetAuthHeader sets the Authorization header of req to the access token.
+func (t *OAuthToken) SetAuthHeader(req *http.Request) {
+	typ := t.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+	req.Header.Set("Authorization", typ+" "+t.AccessToken)
+}
+
+type oauthTokenKey struct{}
+
+// OAuthTokenFrom returns the token obtained by DeviceAuth for the command
+// invocation that ctx was created for, or nil if the user has not logged in.
+func OAuthTokenFrom(ctx context.Context) *OAuthToken {
+	token, _ := ctx.Value(oauthTokenKey{}).(*OAuthToken)
+	return token
+}
+
+// DeviceAuth implements logging in to an API with the OAuth 2.0 device
+// authorization grant for this command and its subcommands. It must follow
+// CommandBuilder.Credentials, whose credential source stores the token.
+//
+// "auth login", without arguments, requests a device code, prints the URL
+// that the user must visit and the code they must enter, and polls the token
+// endpoint until the user has authorized the device. The token is stored at
+// cfg.TokenPath. "auth logout" deletes it and "auth status" reports whether
+// it is stored.
+//
+// DeviceAuth also adds middleware that loads the stored token before the
+// handler of each command is called. If the token has expired and has a
+// refresh token, it is refreshed and stored again. Handlers retrieve the token
+// with OAuthTokenFrom. Errors loading or refreshing the token are printed to
+// stderr and the handler is called without a token, so that it may report that
+// the user must log in.
+func (c *CommandBuilder) DeviceAuth(cfg DeviceAuthConfig) *CommandBuilder {
+	if c.cmd.credentialOptions == nil {
+		return c.error(errorf("%s: DeviceAuth requires Credentials", c.cmd.Name))
+	}
+	if cfg.ClientID == "" || cfg.DeviceAuthURL == "" || cfg.TokenURL == "" {
+		return c.error(errorf("%s: DeviceAuth requires a client ID and endpoint URLs", c.cmd.Name))
+	}
+	if cfg.TokenPath == "" {
+		cfg.TokenPath = defaultTokenPath
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	c.cmd.credentialOptions.deviceAuth = &cfg
+	src := c.cmd.credentialOptions.src
+	return c.Use(func(next ContextHandlerFunc) ContextHandlerFunc {
+		return func(ctx context.Context, args []string) int {
+			if inv := InvocationFrom(ctx); inv != nil && isAuthCommand(inv.Target()) {
+				return next(ctx, args)
+			}
+			token, err := loadToken(ctx, &cfg, src)
+			if err != nil {
+				fmt.Fprintf(OutputFrom(ctx).Stderr, "Warning: %v\n", errStr(err))
+			}
+			if token != nil {
+				ctx = context.WithValue(ctx, oauthTokenKey{}, token)
+			}
+			return next(ctx, args)
+		}
+	})
+}
+
+// isAuthCommand reports whether cmd is the "auth" command added by
+// Credentials or one of its subcommands.
+func isAuthCommand(cmd *Command) bool {
+	for p := cmd; p != nil && p.Parent != nil; p = p.Parent {
+		if p.Name == authCommandName && p.Parent.credentialOptions != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// loadToken returns the token stored in src, refreshing it if it has expired,
+// or nil if no token is stored.
+func loadToken(ctx context.Context, cfg *DeviceAuthConfig, src CredentialSource) (*OAuthToken, error) {
+	s, ok, err := lookup(src, cfg.TokenPath)
+	if err != nil {
+		return nil, sourceErr(src, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	token := &OAuthToken{}
+	if err := json.Unmarshal([]byte(s), token); err != nil {
+		return nil, &xflagsErr{Text: "invalid stored token", Err: err}
+	}
+	if token.Valid() || token.RefreshToken == "" {
+		return token, nil
+	}
+	refreshed, err := requestToken(ctx, cfg, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	})
+	if err != nil {
+		return nil, &xflagsErr{Text: "refreshing token", Err: err}
+	}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, storeToken(cfg, src, refreshed)
+}
+
+// storeToken stores token in src.
+func storeToken(cfg *DeviceAuthConfig, src CredentialSource, token *OAuthToken) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := src.Store(cfg.TokenPath, string(b)); err != nil {
+		return sourceErr(src, err)
+	}
+	return nil
+}
+
+// deviceAuthResponse is the response of the device authorization endpoint.
+type deviceAuthResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// tokenResponse is the response of the token endpoint.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// tokenError is an error response of the token endpoint.
+type tokenError struct {
+	Code        string
+	Description string
+}
+
+func (e *tokenError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+// deviceLogin obtains a token with the device authorization grant and stores
+// it in src.
+func deviceLogin(ctx context.Context, cfg *DeviceAuthConfig, src CredentialSource) error {
+	form := url.Values{"client_id": {cfg.ClientID}}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	var auth deviceAuthResponse
+	if err := postForm(ctx, cfg, cfg.DeviceAuthURL, form, &auth); err != nil {
+		return &xflagsErr{Text: "device authorization", Err: err}
+	}
+	if auth.DeviceCode == "" || auth.VerificationURI == "" {
+		return errorf("device authorization: invalid response")
+	}
+	stderr := OutputFrom(ctx).Stderr
+	fmt.Fprintf(stderr, "Open %s and enter the code: %s\n", auth.VerificationURI, auth.UserCode)
+	if cfg.OpenBrowser {
+		u := auth.VerificationURIComplete
+		if u == "" {
+			u = auth.VerificationURI
+		}
+		_ = openBrowser(u)
+	}
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	for {
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errorf("device authorization: %v", ctx.Err())
+		case <-t.C:
+		}
+		token, err := requestToken(ctx, cfg, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {auth.DeviceCode},
+		})
+		if tokenErr, ok := err.(*tokenError); ok {
+			switch tokenErr.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			}
+		}
+		if err != nil {
+			return &xflagsErr{Text: "device authorization", Err: err}
+		}
+		if err := storeToken(cfg, src, token); err != nil {
+			return err
+		}
+		fmt.Fprintln(stderr, "Logged in.")
+		return nil
+	}
+}
+
+// requestToken requests a token from the token endpoint with the given grant.
+func requestToken(ctx context.Context, cfg *DeviceAuthConfig, form url.Values) (*OAuthToken, error) {
+	form.Set("client_id", cfg.ClientID)
+	var resp tokenResponse
+	if err := postForm(ctx, cfg, cfg.TokenURL, form, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, &tokenError{Code: resp.Error, Description: resp.ErrorDescription}
+	}
+	if resp.AccessToken == "" {
+		return nil, errorf("no access token in response")
+	}
+	token := &OAuthToken{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		RefreshToken: resp.RefreshToken,
+	}
+	if resp.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// postForm posts a form to an OAuth endpoint and decodes the JSON response
+// into v. Error responses of the token endpoint are decoded into v as well.
+func postForm(ctx context.Context, cfg *DeviceAuthConfig, endpoint string, form url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return errorf("%s", resp.Status)
+		}
+		return err
+	}
+	return nil
+}
+
+// openBrowser opens u in the web browser of the user.
+func openBrowser(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
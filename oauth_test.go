@@ -0,0 +1,128 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newOAuthTestServer(t *testing.T) *httptest.Server {
+	polls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		assertString(t, "app", r.Form.Get("client_id"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/device":
+			assertString(t, "read write", r.Form.Get("scope"))
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"device_code":      "dev",
+				"user_code":        "ABCD-EFGH",
+				"verification_uri": "https://example.com/device",
+				"interval":         1,
+			})
+		case "/token":
+			switch r.Form.Get("grant_type") {
+			case "urn:ietf:params:oauth:grant-type:device_code":
+				assertString(t, "dev", r.Form.Get("device_code"))
+				if polls++; polls < 2 {
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token":  "access",
+					"token_type":    "bearer",
+					"refresh_token": "refresh",
+					"expires_in":    3600,
+				})
+			case "refresh_token":
+				assertString(t, "refresh", r.Form.Get("refresh_token"))
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token": "refreshed",
+					"expires_in":   3600,
+				})
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func newOAuthTestCommand(srv *httptest.Server, creds memCredentials, stderr *bytes.Buffer, token **OAuthToken) *Command {
+	return NewCommand("app", "").
+		Output(new(bytes.Buffer), stderr).
+		Credentials(creds).
+		DeviceAuth(DeviceAuthConfig{
+			ClientID:      "app",
+			DeviceAuthURL: srv.URL + "/device",
+			TokenURL:      srv.URL + "/token",
+			Scopes:        []string{"read", "write"},
+		}).
+		Subcommands(
+			NewCommand("get", "").HandleContext(func(ctx context.Context, args []string) int {
+				*token = OAuthTokenFrom(ctx)
+				return 0
+			}),
+		).
+		Must()
+}
+
+func TestDeviceAuth(t *testing.T) {
+	srv := newOAuthTestServer(t)
+	defer srv.Close()
+
+	creds := memCredentials{}
+	stderr := new(bytes.Buffer)
+	var token *OAuthToken
+	code := newOAuthTestCommand(srv, creds, stderr, &token).Run([]string{"auth", "login"})
+	assertInt64(t, 0, int64(code))
+	if !strings.Contains(stderr.String(), "https://example.com/device") ||
+		!strings.Contains(stderr.String(), "ABCD-EFGH") {
+		t.Errorf("expected verification URL and code, got: %s", stderr.String())
+	}
+
+	code = newOAuthTestCommand(srv, creds, stderr, &token).Run([]string{"get"})
+	assertInt64(t, 0, int64(code))
+	if assertBool(t, true, token.Valid()) {
+		assertString(t, "access", token.AccessToken)
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		token.SetAuthHeader(req)
+		assertString(t, "Bearer access", req.Header.Get("Authorization"))
+	}
+
+	// expired tokens are refreshed and stored
+	token.Expiry = time.Now().Add(-time.Minute)
+	b, _ := json.Marshal(token)
+	creds[defaultTokenPath] = string(b)
+	code = newOAuthTestCommand(srv, creds, stderr, &token).Run([]string{"get"})
+	assertInt64(t, 0, int64(code))
+	assertString(t, "refreshed", token.AccessToken)
+	assertString(t, "refresh", token.RefreshToken)
+	assertBool(t, true, strings.Contains(creds[defaultTokenPath], "refreshed"))
+
+	code = newOAuthTestCommand(srv, creds, stderr, &token).Run([]string{"auth", "logout"})
+	assertInt64(t, 0, int64(code))
+	code = newOAuthTestCommand(srv, creds, stderr, &token).Run([]string{"get"})
+	assertInt64(t, 0, int64(code))
+	assertBool(t, true, token == nil)
+}
+
+func TestDeviceAuthRequiresCredentials(t *testing.T) {
+	_, err := NewCommand("app", "").
+		DeviceAuth(DeviceAuthConfig{ClientID: "app", DeviceAuthURL: "x", TokenURL: "y"}).
+		Command()
+	if err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,18 @@
+module github.com/cavaliergopher/xflags/otel
+
+go 1.20
+
+require (
+	github.com/cavaliergopher/xflags v0.0.0
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/sdk v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+)
+
+replace github.com/cavaliergopher/xflags => ..
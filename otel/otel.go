@@ -0,0 +1,121 @@
+// Package otel records xflags command invocations as OpenTelemetry spans so
+// that distributed traces include command line tools invoked by CI systems
+// and scripts.
+//
+//	cmd := xflags.NewCommand("app", "").
+//		Use(otel.Middleware(nil))
+//
+// Each invocation is recorded as a span named after the path of the invoked
+// command, such as "app widgets create", with an attribute for each flag that
+// was set. Flags declared as Secret are never recorded. The status of the
+// span is set from the exit code of the command. Handlers that read the span
+// from their context, with trace.SpanFromContext, may add their own events and
+// attributes to it and start child spans.
+//
+// If the context of the command has no span and the TRACEPARENT environment
+// variable is set, as it is by CI systems that support W3C Trace Context
+// propagation, the span is recorded as its child.
+package otel
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cavaliergopher/xflags"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/cavaliergopher/xflags/otel"
+
+// Attribute keys recorded on each span.
+const (
+	CommandKey    = attribute.Key("xflags.command")
+	ExitCodeKey   = attribute.Key("xflags.exit_code")
+	ErrorClassKey = attribute.Key("xflags.error_class")
+
+	// FlagKeyPrefix prefixes the path of each flag, such as
+	// "xflags.flag.widgets.name".
+	FlagKeyPrefix = "xflags.flag."
+)
+
+// Middleware returns xflags.Middleware that records each invocation of a
+// command as a span with a tracer from tp. If tp is nil, the global
+// TracerProvider is used. The span is started before the handler is called and
+// is the current span of the context passed to the handler, so that spans
+// started by the handler are its children.
+//
+// Command lines that cannot be parsed and commands without handlers are not
+// recorded, as no handler is called for them.
+func Middleware(tp trace.TracerProvider) xflags.Middleware {
+	return func(next xflags.ContextHandlerFunc) xflags.ContextHandlerFunc {
+		return func(ctx context.Context, args []string) int {
+			tp := tp
+			if tp == nil {
+				tp = otel.GetTracerProvider()
+			}
+			inv := xflags.InvocationFrom(ctx)
+			name := strings.Join(commandPath(inv.Target()), " ")
+			attrs := []attribute.KeyValue{CommandKey.String(name)}
+			flags := inv.Flags()
+			paths := make([]string, 0, len(flags))
+			for path := range flags {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				attrs = append(attrs, attribute.String(FlagKeyPrefix+path, flags[path]))
+			}
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				ctx = parentContext(ctx)
+			}
+			ctx, span := tp.Tracer(instrumentationName).Start(
+				ctx,
+				name,
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			exitCode := next(ctx, args)
+			span.SetAttributes(ExitCodeKey.Int(exitCode))
+			if err := inv.Err(); err != nil {
+				span.RecordError(err)
+			}
+			if exitCode != 0 {
+				class := string(xflags.ErrorExit)
+				span.SetAttributes(ErrorClassKey.String(class))
+				span.SetStatus(codes.Error, class)
+			} else {
+				span.SetStatus(codes.Ok, "")
+			}
+			return exitCode
+		}
+	}
+}
+
+// commandPath returns the names of cmd and its parents from the root command.
+func commandPath(cmd *xflags.Command) []string {
+	path := make([]string, 0)
+	for p := cmd; p != nil; p = p.Parent {
+		path = append([]string{p.Name}, path...)
+	}
+	return path
+}
+
+// parentContext returns ctx with any remote span context that is propagated by
+// the TRACEPARENT and TRACESTATE environment variables.
+func parentContext(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	if s := os.Getenv("TRACEPARENT"); s != "" {
+		carrier.Set("traceparent", s)
+	}
+	if s := os.Getenv("TRACESTATE"); s != "" {
+		carrier.Set("tracestate", s)
+	}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
@@ -0,0 +1,85 @@
+package otel
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	var name, token string
+	var handlerSpan trace.SpanContext
+	cmd := xflags.NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		Use(Middleware(tp)).
+		Flags(xflags.String(&token, "token", "", "").Secret()).
+		Subcommands(
+			xflags.NewCommand("create", "").
+				Flags(xflags.String(&name, "name", "", "")).
+				HandleContext(func(ctx context.Context, args []string) int {
+					_, child := trace.SpanFromContext(ctx).TracerProvider().Tracer("test").Start(ctx, "child")
+					child.End()
+					handlerSpan = trace.SpanContextFromContext(ctx)
+					if name == "" {
+						return 2
+					}
+					return 0
+				}),
+		).
+		Must()
+
+	os.Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	defer os.Unsetenv("TRACEPARENT")
+	if code := cmd.Run([]string{"--token=secret", "create", "--name=foo"}); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	name = ""
+	if code := cmd.Run([]string{"create"}); code != 2 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+	child, span := spans[0], spans[1]
+	if child.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("expected handler span to be a child of the command span")
+	}
+	if handlerSpan.SpanID() != spans[3].SpanContext().SpanID() {
+		t.Errorf("expected handler context to contain the command span")
+	}
+	if span.Name() != "app create" {
+		t.Errorf("unexpected span name: %s", span.Name())
+	}
+	if s := span.Parent().TraceID().String(); s != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected parent trace ID: %s", s)
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs[FlagKeyPrefix+"create.name"]; v.AsString() != "foo" {
+		t.Errorf("expected name attribute, got: %q", v.Emit())
+	}
+	if _, ok := attrs[FlagKeyPrefix+"token"]; ok {
+		t.Errorf("secret flag was recorded")
+	}
+	if span.Status().Code != codes.Ok {
+		t.Errorf("expected status ok, got: %v", span.Status())
+	}
+	if status := spans[3].Status(); status.Code != codes.Error || status.Description != "exit" {
+		t.Errorf("expected status error, got: %v", status)
+	}
+}
@@ -0,0 +1,198 @@
+package xflags
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// defaultWidth is the terminal width assumed if it cannot be determined.
+const defaultWidth = 80
+
+// Output describes where and how a command should write its output. Handlers
+// registered with HandleContext may retrieve the Output of their invocation
+// with OutputFrom.
+type Output struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// IsTTY is true if Stdout is a terminal.
+	IsTTY bool
+
+	// ANSI is true if Stdout is a terminal that processes ANSI escape
+	// sequences, such as those that set colors. It is only false for
+	// terminals on Windows consoles that do not support them, which are
+	// configured to process them when possible.
+	ANSI bool
+
+	// Width is the width of the terminal in columns, read from the COLUMNS
+	// environment variable, or 80.
+	Width int
+
+	// Height is the height of the terminal in lines, read from the LINES
+	// environment variable, or 24.
+	Height int
+
+	// Quiet, Verbose and NoProgress are set by the flags registered with
+	// OutputFlags. Quiet and Verbose are also set by the flags registered
+	// with StandardVerbosityFlags.
+	Quiet      bool
+	Verbose    bool
+	NoProgress bool
+
+	// Verbosity is the level returned by VerbosityFrom.
+	Verbosity int
+
+	usePager  bool
+	assumeYes bool
+}
+
+// Info returns a Writer for informational messages which discards all
+// output if Quiet is set.
+func (o *Output) Info() io.Writer {
+	if o.Quiet {
+		return ioutil.Discard
+	}
+	return o.Stdout
+}
+
+// Debug returns a Writer for detailed messages which discards all output
+// unless Verbose is set.
+func (o *Output) Debug() io.Writer {
+	if !o.Verbose || o.Quiet {
+		return ioutil.Discard
+	}
+	return o.Stderr
+}
+
+// Progress returns a Writer for progress bars, spinners and other output that
+// is only meaningful to an interactive user. All output is discarded if Stdout
+// is not a terminal or Quiet or NoProgress are set.
+func (o *Output) Progress() io.Writer {
+	if !o.IsTTY || o.Quiet || o.NoProgress {
+		return ioutil.Discard
+	}
+	return o.Stderr
+}
+
+type outputKey struct{}
+
+// OutputFrom returns the Output of the command invocation that ctx was created
+// for. If ctx has no Output, an Output for os.Stdout and os.Stderr is
+// returned.
+func OutputFrom(ctx context.Context) *Output {
+	if o, ok := ctx.Value(outputKey{}).(*Output); ok {
+		return o
+	}
+	return newOutput(os.Stdout, os.Stderr)
+}
+
+func newOutput(stdout, stderr io.Writer) *Output {
+	o := &Output{
+		Stdin:  os.Stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+		IsTTY:  isTerminal(stdout),
+		Width:  defaultWidth,
+		Height: terminalHeight(),
+	}
+	if o.IsTTY {
+		o.ANSI = enableVirtualTerminal(stdout)
+	}
+	if isTerminal(stderr) {
+		enableVirtualTerminal(stderr)
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		o.Width = n
+	}
+	return o
+}
+
+// isTerminal reports whether w is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// StdinIsPipe reports whether the standard input of the invoked command is a
+// pipe or a redirected file rather than a terminal, so that handlers may read
+// their input from it. If the input of the command was set by
+// CommandBuilder.Input to a reader that is not a file, as in tests,
+// StdinIsPipe returns true.
+func (c *Invocation) StdinIsPipe() bool {
+	f, ok := c.cmd.input().(*os.File)
+	return !ok || !isTerminal(f)
+}
+
+// StdoutIsTTY reports whether the standard output of the invoked command is a
+// terminal, so that handlers may, for example, disable colors if the output is
+// redirected. If the output of the command was set by CommandBuilder.Output to
+// a writer that is not a file, as in tests, StdoutIsTTY returns false.
+func (c *Invocation) StdoutIsTTY() bool {
+	stdout, _ := c.cmd.output()
+	return isTerminal(stdout)
+}
+
+// outputOptions are the values of the flags registered by OutputFlags.
+type outputOptions struct {
+	quiet      bool
+	verbose    bool
+	noProgress bool
+}
+
+// OutputFlags registers the --quiet (-q), --verbose and --no-progress flags
+// for this command and its subcommands. Their values are available to
+// handlers from the Output returned by OutputFrom.
+func (c *CommandBuilder) OutputFlags() *CommandBuilder {
+	opts := &outputOptions{}
+	c.cmd.outputOptions = opts
+	return c.FlagGroup(
+		"output",
+		"Output options",
+		Bool(&opts.quiet, "quiet", false, "Suppress informational output").
+			ShortName("q").
+			builtin(),
+		Bool(&opts.verbose, "verbose", false, "Print detailed output").builtin(),
+		Bool(&opts.noProgress, "no-progress", false, "Do not show progress").builtin(),
+	)
+}
+
+// newOutput returns the Output for an invocation of c with the flag values
+// parsed by parser, which may be nil.
+func (c *Command) newOutput(parser *argParser) *Output {
+	stdout, stderr := c.output()
+	o := newOutput(stdout, stderr)
+	o.Stdin = c.input()
+	for p := c; p != nil; p = p.Parent {
+		if p.outputOptions != nil {
+			o.Quiet = parser.optionValue(p, "quiet").(bool)
+			o.Verbose = parser.optionValue(p, "verbose").(bool)
+			o.NoProgress = parser.optionValue(p, "no-progress").(bool)
+			break
+		}
+	}
+	if p := c.verbosityCommand(); p != nil {
+		o.Verbosity = verbosityLevel(
+			parser.optionValue(p, "quiet").(bool),
+			parser.optionValue(p, "verbose").(int64),
+		)
+		o.Quiet, o.Verbose = o.Verbosity < 0, o.Verbosity > 0
+	}
+	for p := c; p != nil; p = p.Parent {
+		if p.pagerOptions != nil {
+			o.usePager = !parser.optionValue(p, "no-pager").(bool)
+			break
+		}
+	}
+	return o
+}
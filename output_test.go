@@ -0,0 +1,101 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestOutputFlags(t *testing.T) {
+	os.Setenv("COLUMNS", "120")
+	defer os.Unsetenv("COLUMNS")
+	tests := []struct {
+		Args   []string
+		Stdout string
+		Stderr string
+	}{
+		{[]string{"sub"}, "info\n", ""},
+		{[]string{"--verbose", "sub"}, "info\n", "debug\n"},
+		{[]string{"-q", "--verbose", "sub"}, "", ""},
+		{[]string{"--no-progress", "sub"}, "info\n", ""},
+	}
+	for _, test := range tests {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		var out *Output
+		cmd := NewCommand("app", "").
+			Output(stdout, stderr).
+			OutputFlags().
+			Subcommands(
+				NewCommand("sub", "").
+					HandleContext(func(ctx context.Context, args []string) int {
+						out = OutputFrom(ctx)
+						fmt.Fprintln(out.Info(), "info")
+						fmt.Fprintln(out.Debug(), "debug")
+						fmt.Fprintln(out.Progress(), "progress")
+						return 0
+					}),
+			).
+			Must()
+		if code := cmd.Run(test.Args); code != 0 {
+			t.Fatalf("%q: unexpected exit code: %d", test.Args, code)
+		}
+		assertString(t, test.Stdout, stdout.String())
+		assertString(t, test.Stderr, stderr.String())
+		assertBool(t, false, out.IsTTY)
+		assertBool(t, false, out.ANSI)
+		assertInt64(t, 120, int64(out.Width))
+	}
+}
+
+func TestOutputFrom(t *testing.T) {
+	out := OutputFrom(context.Background())
+	if out.Stdout != os.Stdout || out.Stderr != os.Stderr {
+		t.Errorf("expected default output to use os.Stdout and os.Stderr")
+	}
+}
+
+func TestStdinIsPipe(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, stdin := range []io.Reader{new(bytes.Buffer), f} {
+		var pipe, tty bool
+		code := NewCommand("app", "").
+			Input(stdin).
+			Output(new(bytes.Buffer), new(bytes.Buffer)).
+			HandleContext(func(ctx context.Context, args []string) int {
+				inv := InvocationFrom(ctx)
+				pipe, tty = inv.StdinIsPipe(), inv.StdoutIsTTY()
+				return 0
+			}).
+			Must().
+			Run(nil)
+		assertInt64(t, 0, int64(code))
+		assertBool(t, true, pipe)
+		assertBool(t, false, tty)
+	}
+}
+
+func TestOutputFlagsExec(t *testing.T) {
+	var out *Output
+	cmd := NewCommand("app", "").
+		OutputFlags().
+		HandleContext(func(ctx context.Context, args []string) int {
+			out = OutputFrom(ctx)
+			return 0
+		}).
+		Must()
+	if code := cmd.Run([]string{"--verbose"}); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	assertBool(t, true, out.Verbose)
+	code := cmd.Exec(context.Background(), []string{"-q"}, nil, new(bytes.Buffer), nil)
+	assertInt64(t, 0, int64(code))
+	assertBool(t, true, out.Quiet)
+	assertBool(t, false, out.Verbose)
+}
@@ -0,0 +1,139 @@
+package xflags
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// defaultHeight is the terminal height assumed if it cannot be determined.
+const defaultHeight = 24
+
+// pagerOptions are the values of the flag registered by Pager.
+type pagerOptions struct {
+	noPager bool
+}
+
+// Pager specifies that help messages and output written to Output.Pager by
+// this command and its subcommands should be shown in a pager, like git, if
+// stdout is a terminal and the output is longer than the height of the
+// terminal. The pager is read from the PAGER environment variable or is
+// "less". If the pager is not found, output is written directly to stdout.
+// The --no-pager flag is registered to disable the pager.
+func (c *CommandBuilder) Pager() *CommandBuilder {
+	opts := &pagerOptions{}
+	c.cmd.pagerOptions = opts
+	return c.Flags(
+		Bool(&opts.noPager, "no-pager", false, "Do not pipe output into a pager").builtin(),
+	)
+}
+
+// Pager returns a WriteCloser that writes to Stdout through a pager if the
+// command was configured with Pager, Stdout is a terminal and the pager is
+// not disabled. Output is buffered until it exceeds the height of the terminal
+// so short output is written directly to Stdout. Callers must call Close to
+// flush all output and wait for the pager to exit.
+func (o *Output) Pager() io.WriteCloser {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	if !o.usePager || !o.IsTTY || pager == "cat" {
+		return nopWriteCloser{o.Stdout}
+	}
+	return &pagerWriter{w: o.Stdout, height: o.Height, pager: pager}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// pagerWriter buffers output until it exceeds height lines and then starts
+// the pager.
+type pagerWriter struct {
+	w      io.Writer
+	height int
+	pager  string
+	buf    bytes.Buffer
+	lines  int
+	pipe   io.WriteCloser
+	cmd    *exec.Cmd
+}
+
+func (c *pagerWriter) Write(p []byte) (int, error) {
+	if c.pipe != nil {
+		return c.pipe.Write(p)
+	}
+	c.buf.Write(p)
+	c.lines += bytes.Count(p, []byte("\n"))
+	if c.lines < c.height {
+		return len(p), nil
+	}
+	if err := c.start(); err != nil {
+		// fall back to writing directly to stdout
+		c.pipe = nopWriteCloser{c.w}
+	}
+	if _, err := c.buf.WriteTo(c.pipe); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// start starts the pager. The pager command line is split into arguments like
+// a POSIX shell command line and run without a shell, so that pagers work on
+// systems without sh, such as Windows. An error is returned if the pager is
+// not found, in which case output is written directly to stdout.
+func (c *pagerWriter) start() error {
+	args := SplitCommandLine(c.pager)
+	if len(args) == 0 {
+		return errors.New("empty pager command")
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdout = c.w
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	c.pipe, c.cmd = pipe, cmd
+	return nil
+}
+
+func (c *pagerWriter) Close() error {
+	if c.pipe == nil {
+		_, err := c.buf.WriteTo(c.w)
+		return err
+	}
+	if err := c.pipe.Close(); err != nil {
+		return err
+	}
+	if c.cmd != nil {
+		return c.cmd.Wait()
+	}
+	return nil
+}
+
+// terminalHeight returns the height of the terminal from the LINES
+// environment variable or defaultHeight.
+func terminalHeight() int {
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		return n
+	}
+	return defaultHeight
+}
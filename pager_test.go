@@ -0,0 +1,66 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+)
+
+func TestPagerWriter(t *testing.T) {
+	if _, err := exec.LookPath("sed"); err != nil {
+		t.Skip("sed not found")
+	}
+	tests := []struct {
+		Lines  int
+		Expect string
+	}{
+		{2, "1\n2\n"},
+		{4, "> 1\n> 2\n> 3\n> 4\n"},
+	}
+	for _, test := range tests {
+		w := new(bytes.Buffer)
+		pw := &pagerWriter{w: w, height: 3, pager: "sed 's/^/> /'"}
+		for i := 1; i <= test.Lines; i++ {
+			fmt.Fprintf(pw, "%d\n", i)
+		}
+		if err := pw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		assertString(t, test.Expect, w.String())
+	}
+}
+
+func TestPagerNotFound(t *testing.T) {
+	w := new(bytes.Buffer)
+	pw := &pagerWriter{w: w, height: 1, pager: "xflags-no-such-pager -R"}
+	fmt.Fprintf(pw, "1\n2\n")
+	fmt.Fprintf(pw, "3\n")
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "1\n2\n3\n", w.String())
+}
+
+func TestPagerDisabled(t *testing.T) {
+	var w interface{}
+	stdout := new(bytes.Buffer)
+	cmd := NewCommand("app", "").
+		Output(stdout, stdout).
+		Pager().
+		HandleContext(func(ctx context.Context, args []string) int {
+			w = OutputFrom(ctx).Pager()
+			return 0
+		}).
+		Must()
+	for _, args := range [][]string{nil, {"--no-pager"}} {
+		if code := cmd.Run(args); code != 0 {
+			t.Fatalf("unexpected exit code: %d", code)
+		}
+		// stdout is not a terminal
+		if _, ok := w.(nopWriteCloser); !ok {
+			t.Errorf("%q: expected pager to be disabled, got %T", args, w)
+		}
+	}
+}
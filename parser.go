@@ -1,32 +1,182 @@
 package xflags
 
 import (
-	"os"
+	"crypto/tls"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
 )
 
-// TODO: fuzz tests?
-
 // argument to terminate parsing of all remaining arguments
 const terminator = "--"
 
 type argParser struct {
-	tokens            []string
-	args              []string
-	cmd               *Command
-	isTerminated      bool
-	flagsByName       map[string]*Flag
-	subcommandsByName map[string]*Command
-	flagsSeen         map[string]int
-	positionals       []*Flag
-}
-
-func newArgParser(cmd *Command, tokens []string) *argParser {
-	tokens = normalize(tokens, cmd.WithTerminator)
+	rawArgs        []string
+	tokens         []string
+	indexes        []int // index in rawArgs of each token
+	index          int   // index in rawArgs of the last consumed token
+	args           []string
+	cmd            *Command
+	isTerminated   bool
+	flagsSeen      map[string]int
+	resolved       map[*Flag]string
+	valuesSeen     map[*Flag]map[string]bool
+	boolForms      map[*Flag]BoolForm
+	origins        map[*Flag]origin
+	trace          []TraceEntry
+	expr           *Expr
+	positionals    []*Flag
+	values         map[*Flag]Value // copies of flag values if not nil
+	rawValues      []rawValue
+	profile        string        // configuration profile selected with --profile
+	aliasChain     []string      // names of the aliases that have been expanded
+	unknownCommand string        // unknown subcommand handled by OnUnknownCommand
+	strict         bool          // parse in the POSIXStrict mode of the root command
+	prefix         bool          // PrefixShortNames of the root command is in effect
+	operands       bool          // all remaining arguments are operands in strict mode
+	pending        []pendingFlag // flags that precede the subcommand that declares them
+	mu             sync.Mutex    // guards reload
+	tlsConfigs     map[*tlsOptions]*tls.Config
+	httpClients    map[*httpClientOptions]*http.Client
+}
+
+// rawValue is a string value that was set for a flag.
+type rawValue struct {
+	flag  *Flag
+	value string
+}
+
+// pendingFlag is a flag that was specified before the name of the subcommand
+// that declares it. It is set when the parser descends into the subcommand.
+type pendingFlag struct {
+	token string
+	value string
+	form  BoolForm
+	index int // index in rawArgs of the flag
+	trace int // index of the trace entry of the flag
+}
+
+// commandIndex contains the lookup tables of a command that are used by the
+// parser. It is built the first time a command is parsed so that parsing
+// allocates nothing for each flag that is not specified.
+type commandIndex struct {
+	flags       []*Flag
+	paths       []string         // source path of each flag
+	defaults    []string         // default value of each flag
+	initial     map[*Flag]Value  // copies of the values before any parsing
+	flagsByName map[string]*Flag // keyed by "--name" and "-s"
+	subcommands map[string]*Command
+	positionals []*Flag
+	shortNames  []string // short names longer than one character in scope
+
+	treeOnce  sync.Once
+	treeFlags map[string][]*Command // commands of the tree that declare each flag
+}
+
+// index returns the commandIndex of c, building it if necessary. A command must
+// not be modified after it is first parsed.
+func (c *Command) index() *commandIndex {
+	if idx, ok := c.idx.Load().(*commandIndex); ok {
+		return idx
+	}
+	idx := &commandIndex{
+		flagsByName: make(map[string]*Flag),
+		initial:     make(map[*Flag]Value),
+		subcommands: make(map[string]*Command, len(c.Subcommands)),
+		shortNames:  longShortNames(c),
+	}
+	for _, group := range c.FlagGroups {
+		for _, flag := range group.Flags {
+			idx.flags = append(idx.flags, flag)
+			idx.paths = append(idx.paths, flagPath(c, flag))
+			idx.defaults = append(idx.defaults, valueString(flag.Value))
+			if cl, ok := flag.Value.(cloner); ok {
+				idx.initial[flag] = cl.clone()
+			}
+			if flag.Name != "" {
+				idx.flagsByName["--"+flag.Name] = flag
+			}
+			if flag.ShortName != "" {
+				idx.flagsByName["-"+flag.ShortName] = flag
+			}
+			if flag.Positional {
+				idx.positionals = append(idx.positionals, flag)
+			}
+		}
+	}
+	for _, cmd := range c.Subcommands {
+		idx.subcommands[cmd.Name] = cmd
+	}
+	c.idx.Store(idx)
+	return idx
+}
+
+// flagOwners returns the quoted paths of the other commands in the tree of c
+// that declare the visible flag token, such as "--replicas", so that users
+// who specify a flag at the wrong level can be told where it belongs.
+func (c *Command) flagOwners(token string) []string {
+	var owners []string
+	for _, cmd := range c.treeFlags(token) {
+		if !isHidden(cmd, cmd.index().flagsByName[token]) {
+			owners = append(owners, "'"+strings.Join(commandPath(cmd), " ")+"'")
+		}
+	}
+	return owners
+}
+
+// treeFlags returns the visible commands in the tree of c that declare the
+// flag token. The tree is indexed once, at its root, the first time it is
+// needed.
+func (c *Command) treeFlags(token string) []*Command {
+	root := c
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	idx := root.index()
+	idx.treeOnce.Do(func() {
+		idx.treeFlags = make(map[string][]*Command)
+		var walk func(cmd *Command)
+		walk = func(cmd *Command) {
+			for name := range cmd.index().flagsByName {
+				idx.treeFlags[name] = append(idx.treeFlags[name], cmd)
+			}
+			for _, sub := range cmd.Subcommands {
+				if !isHiddenCommand(sub) {
+					walk(sub)
+				}
+			}
+		}
+		walk(root)
+	})
+	return idx.treeFlags[token]
+}
+
+func newArgParser(cmd *Command, args []string) *argParser {
+	prefix := cmd.PrefixShortNames && !cmd.POSIXStrict
+	tokens, indexes := normalizeIndexed(
+		args,
+		cmd.WithTerminator,
+		cmd.index().shortNames,
+		prefix,
+	)
+	// each token sets at most one flag, so the tables that grow with the
+	// number of flags that are set are sized once
+	n := len(tokens)
 	c := &argParser{
-		tokens:            tokens,
-		flagsByName:       make(map[string]*Flag),
-		flagsSeen:         make(map[string]int),
-		subcommandsByName: make(map[string]*Command),
+		rawArgs:    args,
+		tokens:     tokens,
+		indexes:    indexes,
+		index:      -1,
+		flagsSeen:  make(map[string]int, n),
+		resolved:   make(map[*Flag]string),
+		valuesSeen: make(map[*Flag]map[string]bool),
+		boolForms:  make(map[*Flag]BoolForm),
+		origins:    make(map[*Flag]origin, n),
+		trace:      make([]TraceEntry, 0, n),
+		rawValues:  make([]rawValue, 0, n),
+		strict:     cmd.POSIXStrict,
+		prefix:     prefix,
 	}
 	c.setCommand(cmd)
 	return c
@@ -34,73 +184,249 @@ func newArgParser(cmd *Command, tokens []string) *argParser {
 
 // setCommand descends the parser into a new subcommand.
 func (c *argParser) setCommand(cmd *Command) {
-	// accumulate flags
+	if c.cmd != nil && len(cmd.index().shortNames) > len(c.cmd.index().shortNames) {
+		c.splitShortNames(cmd.index().shortNames)
+	}
 	c.cmd = cmd
-	c.positionals = make([]*Flag, 0)
-	for _, group := range cmd.FlagGroups {
-		for _, flag := range group.Flags {
-			if flag.Name != "" {
-				c.flagsByName["--"+flag.Name] = flag
-			}
-			if flag.ShortName != "" {
-				c.flagsByName["-"+flag.ShortName] = flag
-			}
-			if flag.Positional {
-				c.positionals = append(c.positionals, flag)
+	c.positionals = cmd.index().positionals
+}
+
+// splitShortNames splits the remaining single-dash arguments again with the
+// short names that are in scope of a subcommand, which may declare short names
+// longer than one character that its parents do not. Arguments from aliases
+// and arguments after the terminator are not split again.
+func (c *argParser) splitShortNames(shortNames []string) {
+	tokens := make([]string, 0, len(c.tokens))
+	indexes := make([]int, 0, len(c.indexes))
+	for i := 0; i < len(c.tokens); i++ {
+		token, index := c.tokens[i], c.indexes[i]
+		arg := c.rawArgs[index]
+		if isSingleDash(token) &&
+			arg != token &&
+			strings.HasPrefix(arg, token) &&
+			i+1 < len(c.tokens) &&
+			c.indexes[i+1] == index {
+			split, _ := normalizeIndexed([]string{arg}, false, shortNames, c.prefix)
+			for _, s := range split {
+				tokens = append(tokens, s)
+				indexes = append(indexes, index)
 			}
+			i++
+			continue
+		}
+		tokens = append(tokens, token)
+		indexes = append(indexes, index)
+	}
+	c.tokens, c.indexes = tokens, indexes
+}
+
+// lookupFlag returns the flag declared as token by the current command or the
+// nearest of its parents, or nil.
+func (c *argParser) lookupFlag(token string) *Flag {
+	for p := c.cmd; p != nil; p = p.Parent {
+		if flag := p.index().flagsByName[token]; flag != nil {
+			return flag
 		}
 	}
+	return nil
+}
+
+// lookupName returns the flag with the given name or short name that is
+// declared by the current command or the nearest of its parents, or nil.
+func (c *argParser) lookupName(name string) *Flag {
+	flag := c.lookupFlag("--" + name)
+	if flag == nil {
+		flag = c.lookupFlag("-" + name)
+	}
+	return flag
+}
 
-	// reset subcommands
-	c.subcommandsByName = make(map[string]*Command)
-	for _, cmd := range cmd.Subcommands {
-		c.subcommandsByName[cmd.Name] = cmd
+// walkFlags calls fn with each flag of the current command and its parents
+// and the source path of the flag. If fn returns an error, walkFlags stops and
+// returns the error.
+func (c *argParser) walkFlags(fn func(flag *Flag, path string) error) error {
+	for p := c.cmd; p != nil; p = p.Parent {
+		idx := p.index()
+		for i, flag := range idx.flags {
+			if err := fn(flag, idx.paths[i]); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
 func (c *argParser) Parse() (cmd *Command, args []string, err error) {
+	defer func() {
+		if argErr, ok := err.(*ArgumentError); ok && argErr.Index >= 0 {
+			argErr.args = c.rawArgs
+		}
+	}()
 	for {
 		arg, ok := c.next()
 		if !ok {
 			break
 		}
+		start, n := c.index, len(c.trace)
 		if err = c.dispatch(arg); err != nil {
+			if argErr, ok := err.(*ArgumentError); ok && argErr.Index < 0 {
+				argErr.Index = c.index
+				argErr.Token = c.rawArgs[c.index]
+			}
 			return
 		}
+		c.traceArgs(n, start)
+	}
+	if len(c.pending) > 0 {
+		p := c.pending[0]
+		argErr := c.misplacedFlagErr(p.token)
+		argErr.Index, argErr.Token = p.index, c.rawArgs[p.index]
+		return nil, nil, argErr
+	}
+	if err = c.checkConfigKeys(); err != nil {
+		return
 	}
-	if err = c.parseEnvVars(); err != nil {
+	if err = c.parseUnset(); err != nil {
 		return
 	}
+	c.sortValues()
 	if err = c.checkNArgs(); err != nil {
 		return
 	}
+	if err = c.checkUsageSyntax(); err != nil {
+		return
+	}
+	if err = c.checkVerbosity(); err != nil {
+		return
+	}
+	if c.cmd.Expression {
+		if c.expr, err = parseExpr(c.cmd, c.trace); err != nil {
+			return
+		}
+	}
+	if err = c.configureTLS(); err != nil {
+		return
+	}
+	if err = c.configureHTTPClients(); err != nil {
+		return
+	}
+	if err = c.checkListenFlags(); err != nil {
+		return
+	}
+	if err = c.openFiles(); err != nil {
+		return
+	}
 	return c.cmd, c.args, nil
 }
 
-func (c *argParser) parseEnvVars() error {
-	for _, flag := range c.flagsByName {
-		if flag.EnvVar == "" {
-			continue
-		}
-		n := c.flagsSeen[flag.name()]
-		if n > 0 {
-			continue
-		}
-		s, ok := os.LookupEnv(flag.EnvVar)
-		if !ok {
-			continue
-		}
+// parseUnset sets the value of each flag that was not specified on the command
+// line from a preset, its environment variable or a source.
+func (c *argParser) parseUnset() error {
+	sources := c.sources()
+	if err := c.resolveProfile(sources); err != nil {
+		return err
+	}
+	if err := c.applyPresets(sources); err != nil {
+		return err
+	}
+	return c.walkFlags(func(flag *Flag, path string) error {
+		return c.setUnset(sources, flag, path)
+	})
+}
+
+// setUnset sets the value of flag from its environment variable or a source if
+// it was not specified on the command line.
+func (c *argParser) setUnset(sources []Source, flag *Flag, path string) error {
+	if c.flagsSeen[flag.name()] > 0 || !c.cmd.featureEnabled(flag.Feature) {
+		return nil
+	}
+	values, src, ok, err := c.lookup(sources, flag, path)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	for _, s := range values {
 		c.observe(flag)
-		if err := c.setFlag(flag, s); err != nil {
+		if err := c.setFlagFrom(provenanceOf(src), src, flag, s); err != nil {
 			return err
 		}
 	}
+	c.resolved[flag] = strings.Join(values, ",")
 	return nil
 }
 
+// lookup returns the values of a flag from its environment variable, the
+// first source that provides it or, if the flag is secret, the credential
+// source set by CommandBuilder.Credentials. If a configuration profile is
+// selected, the value of the flag in the profile takes precedence over its
+// value in the same source. The returned Source is nil if the value was read
+// from the environment.
+func (c *argParser) lookup(
+	sources []Source,
+	flag *Flag,
+	path string,
+) (values []string, src Source, ok bool, err error) {
+	if flag.EnvVar != "" {
+		if value, ok := c.cmd.lookupEnv(flag.EnvVar); ok {
+			return []string{value}, nil, true, nil
+		}
+	}
+	for _, src = range sources {
+		values, ok, err = lookupFlag(src, flag)
+		switch {
+		case err != nil || ok:
+		case c.profile != "":
+			values, ok, err = lookupValues(src, profilePath(c.profile, path))
+			if err == nil && !ok {
+				values, ok, err = lookupValues(src, path)
+			}
+		default:
+			values, ok, err = lookupValues(src, path)
+		}
+		if err != nil {
+			err = wrapArgErr(sourceErr(src, err), c.cmd, flag, "")
+			return
+		}
+		if ok {
+			return
+		}
+	}
+	if flag.Secret {
+		return c.lookupCredential(flag, path)
+	}
+	return nil, nil, false, nil
+}
+
+// sources returns the sources of the current command and its parents in order
+// of precedence.
+func (c *argParser) sources() []Source {
+	sources := make([]Source, 0)
+	for p := c.cmd; p != nil; p = p.Parent {
+		sources = append(sources, p.Sources...)
+	}
+	return sources
+}
+
+// sortValues sorts the values of all flags that were declared as Sorted.
+func (c *argParser) sortValues() {
+	c.walkFlags(func(flag *Flag, path string) error {
+		if flag.Sorted {
+			if v, ok := c.value(flag).(sort.Interface); ok {
+				sort.Sort(v)
+			}
+		}
+		return nil
+	})
+}
+
 func (c *argParser) checkNArgs() error {
 	for _, group := range c.cmd.FlagGroups {
 		for _, flag := range group.Flags {
+			if !c.cmd.featureEnabled(flag.Feature) {
+				continue
+			}
 			n := c.flagsSeen[flag.name()]
 			if flag.MinCount > 0 && n < flag.MinCount {
 				return newArgErr(c.cmd, flag, "", "missing argument: %s", flag)
@@ -108,11 +434,60 @@ func (c *argParser) checkNArgs() error {
 			if flag.MaxCount > 0 && n > flag.MaxCount {
 				return newArgErr(c.cmd, flag, "", "argument declared too many times: %s", flag)
 			}
+			if n == 0 && len(flag.requiredIf) > 0 {
+				cond, err := c.requiredBy(flag)
+				if err != nil {
+					return err
+				}
+				if cond != nil {
+					return newArgErr(
+						c.cmd,
+						flag,
+						"",
+						"missing argument: %s (required when %s)",
+						flag,
+						cond,
+					)
+				}
+			}
 		}
 	}
 	return nil
 }
 
+// requiredBy returns the first condition of flag.RequiredIf that is met, or
+// nil.
+func (c *argParser) requiredBy(flag *Flag) (*flagCondition, error) {
+	for i, cond := range flag.requiredIf {
+		other := c.lookupName(cond.name)
+		if other == nil {
+			return nil, errorf("%s: RequiredIf refers to an undeclared flag: %s", flag, cond.name)
+		}
+		v := c.value(other)
+		if v == nil {
+			v = other.Value
+		}
+		if valueString(v) == cond.value {
+			return &flag.requiredIf[i], nil
+		}
+		if g, ok := v.(Getter); ok {
+			if values, ok := g.Get().([]string); ok {
+				for _, s := range values {
+					if s == cond.value {
+						return &flag.requiredIf[i], nil
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// release closes the files opened by the parser and returns the first error.
+func (c *argParser) release() error {
+	return c.closeFiles()
+}
+
 func (c *argParser) peek() (token string, ok bool) {
 	if len(c.tokens) == 0 {
 		return
@@ -126,6 +501,8 @@ func (c *argParser) next() (token string, ok bool) {
 	token, ok = c.peek()
 	if ok {
 		c.tokens = c.tokens[1:]
+		c.index = c.indexes[0]
+		c.indexes = c.indexes[1:]
 	}
 	return
 }
@@ -141,14 +518,28 @@ func (c *argParser) dispatch(token string) error {
 			c.args = make([]string, 0, 1)
 		}
 		c.args = append(c.args, token)
+		c.trace = append(c.trace, TraceEntry{})
 		return nil
 	}
 	if token == terminator && c.cmd.WithTerminator {
 		c.isTerminated = true
+		c.trace = append(c.trace, TraceEntry{})
 		return nil
 	}
-	if token == "-h" || token == "--help" {
-		return &HelpError{Cmd: c.cmd}
+	if c.operands {
+		return c.dispatchPositional(token)
+	}
+	if token == "-h" || token == "--help" || token == "--help-all" {
+		next, _ := c.peek()
+		return &HelpError{
+			Cmd:   c.cmd,
+			Brief: token == "-h" && next != "--all",
+			All:   token == "--help-all" || next == "--all",
+		}
+	}
+	if c.cmd.Expression && isOperator(token) {
+		c.trace = append(c.trace, TraceEntry{Operator: token})
+		return nil
 	}
 	if isPositional(token) {
 		return c.dispatchPositional(token)
@@ -160,53 +551,374 @@ func (c *argParser) dispatchPositional(token string) error {
 	// handle positional flag
 	if len(c.positionals) > 0 {
 		flag := c.positionals[0]
+		if !c.cmd.featureEnabled(flag.Feature) {
+			return wrapArgErr(&FeatureError{Feature: flag.Feature}, c.cmd, flag, token)
+		}
 		n := c.observe(flag)
 		if flag.MaxCount > 0 && n == flag.MaxCount {
 			// all done with this positional flag
 			c.positionals = c.positionals[1:]
 		}
+		c.operands = c.strict
 		return c.setFlag(flag, token)
 	}
 
 	// handle subcommand
 	if len(c.cmd.Subcommands) == 0 {
+		if c.cmd.onUnknownCommand != nil {
+			c.stopAtUnknownCommand(token)
+			return nil
+		}
 		return newArgErr(c.cmd, nil, token, "unexpected positional argument: %s", token)
 	}
-	cmd, ok := c.subcommandsByName[token]
+	cmd, ok := c.cmd.index().subcommands[token]
 	if !ok {
-		return newArgErr(c.cmd, nil, token, "unrecognized command: %s", token)
+		if expanded, err := c.expandAlias(token); expanded || err != nil {
+			return err
+		}
+		if c.cmd.unknownCommandHandler() != nil {
+			c.stopAtUnknownCommand(token)
+			return nil
+		}
+		err := newArgErr(c.cmd, nil, token, "unrecognized command: %s", token)
+		names := make([]string, 0, len(c.cmd.Subcommands))
+		for _, cmd := range c.cmd.Subcommands {
+			if !isHiddenCommand(cmd) {
+				names = append(names, cmd.Name)
+			}
+		}
+		err.Suggestions = suggest(token, names)
+		return err
+	}
+	if !cmd.featureEnabled(cmd.Feature) {
+		err := wrapArgErr(&FeatureError{Feature: cmd.Feature}, c.cmd, nil, token)
+		err.Text = token
+		return err
 	}
 	c.setCommand(cmd)
-	return nil
+	c.trace = append(c.trace, TraceEntry{Command: cmd})
+	return c.setPending()
+}
+
+// stopAtUnknownCommand stops parsing at the unknown subcommand token and
+// saves the remaining arguments for the OnUnknownCommand function.
+func (c *argParser) stopAtUnknownCommand(token string) {
+	c.unknownCommand = token
+	c.args = append([]string{}, c.rawArgs[c.index+1:]...)
+	c.tokens, c.indexes = nil, nil
+	c.trace = append(c.trace, TraceEntry{})
 }
 
 func (c *argParser) dispatchRegular(token string) error {
 	// regular flag
-	flag := c.flagsByName[token]
+	flag := c.lookupFlag(token)
 	if flag == nil {
-		return newArgErr(c.cmd, nil, token, "unrecognized argument: %s", token)
+		if flags := c.subcommandFlags(token); len(flags) > 0 {
+			return c.deferFlag(token, flags)
+		}
+		if owners := c.cmd.flagOwners(token); len(owners) > 0 {
+			return c.misplacedFlagErr(token)
+		}
+		err := newArgErr(c.cmd, nil, token, "unrecognized argument: %s", token)
+		names := make([]string, 0)
+		for p := c.cmd; p != nil; p = p.Parent {
+			for name, flag := range p.index().flagsByName {
+				if !isHidden(c.cmd, flag) {
+					names = append(names, name)
+				}
+			}
+		}
+		err.Suggestions = suggest(token, names)
+		return err
+	}
+	if !c.cmd.featureEnabled(flag.Feature) {
+		return wrapArgErr(&FeatureError{Feature: flag.Feature}, c.cmd, flag, "")
 	}
 	c.observe(flag)
 	if isBoolValue(flag.Value) {
-		return c.setFlag(flag, "true")
+		return c.dispatchBool(flag)
 	}
 
-	// read the next arg as a value
+	// read the next arg as a value, which may begin with a dash if it was
+	// attached to the flag as --flag=value
 	value, ok := c.peek()
-	if !ok || !isPositional(value) {
+	if !ok || (!isPositional(value) && c.indexes[0] != c.index) {
 		return newArgErr(c.cmd, flag, token, "no value specified for flag: %s", token)
 	}
 	c.next() // consume the value
 	return c.setFlag(flag, value)
 }
 
+// misplacedFlagErr returns an error for the flag token that is not declared
+// by the current command or its parents, naming the commands that declare it.
+func (c *argParser) misplacedFlagErr(token string) *ArgumentError {
+	return newArgErr(
+		c.cmd,
+		nil,
+		token,
+		"%s is a flag of %s, not '%s'",
+		token,
+		strings.Join(c.cmd.flagOwners(token), " and "),
+		strings.Join(commandPath(c.cmd), " "),
+	)
+}
+
+// subcommandFlags returns the visible flags declared as token by the
+// descendants of the current command. Flags are not deferred to subcommands in strict mode.
+func (c *argParser) subcommandFlags(token string) []*Flag {
+	if c.strict {
+		return nil
+	}
+	var flags []*Flag
+	for _, cmd := range c.cmd.treeFlags(token) {
+		for p := cmd.Parent; p != nil; p = p.Parent {
+			if p == c.cmd {
+				if flag := cmd.index().flagsByName[token]; !isHidden(cmd, flag) {
+					flags = append(flags, flag)
+				}
+				break
+			}
+		}
+	}
+	return flags
+}
+
+// deferFlag reads the value of the flag token that is declared by one or more
+// subcommands of the current command, as flags, and sets it when the parser
+// descends into the subcommand that declares it, so that flags may precede
+// the name of their subcommand, as in "app --replicas 3 deploy".
+func (c *argParser) deferFlag(token string, flags []*Flag) error {
+	isBool := isBoolValue(flags[0].Value)
+	for _, flag := range flags[1:] {
+		if isBoolValue(flag.Value) != isBool {
+			return newArgErr(
+				c.cmd,
+				nil,
+				token,
+				"%s must be specified after the subcommand that declares it",
+				token,
+			)
+		}
+	}
+	p := pendingFlag{token: token, index: c.index, trace: len(c.trace)}
+	value, ok := c.peek()
+	switch {
+	case isBool && ok && c.indexes[0] == c.index:
+		c.next()
+		p.value, p.form = value, BoolFormAttached
+	case isBool && ok && c.boolSyntax() == BoolSeparate && (value == "true" || value == "false"):
+		c.next()
+		p.value, p.form = value, BoolFormSeparate
+	case isBool:
+		p.value, p.form = "true", BoolFormBare
+	case !ok || (!isPositional(value) && c.indexes[0] != c.index):
+		return newArgErr(c.cmd, nil, token, "no value specified for flag: %s", token)
+	default:
+		c.next()
+		p.value = value
+	}
+	c.pending = append(c.pending, p)
+	c.trace = append(c.trace, TraceEntry{Value: p.value})
+	return nil
+}
+
+// setPending sets each pending flag that is declared by the current command.
+func (c *argParser) setPending() error {
+	pending := c.pending[:0]
+	for _, p := range c.pending {
+		flag := c.cmd.index().flagsByName[p.token]
+		if flag == nil {
+			pending = append(pending, p)
+			continue
+		}
+		if err := c.setPendingFlag(flag, p); err != nil {
+			if argErr, ok := err.(*ArgumentError); ok {
+				argErr.Index, argErr.Token = p.index, c.rawArgs[p.index]
+			}
+			return err
+		}
+	}
+	c.pending = pending
+	return nil
+}
+
+func (c *argParser) setPendingFlag(flag *Flag, p pendingFlag) error {
+	if !c.cmd.featureEnabled(flag.Feature) {
+		return wrapArgErr(&FeatureError{Feature: flag.Feature}, c.cmd, flag, "")
+	}
+	c.observe(flag)
+	if isBoolValue(flag.Value) {
+		c.boolForms[flag] = p.form
+	}
+	c.trace[p.trace].Flag = flag
+	return c.setFlagFrom(ProvenanceCommandLine, nil, flag, p.value)
+}
+
+// dispatchBool sets a boolean flag from any value that follows it in a form
+// permitted by the BoolSyntax of the current command.
+func (c *argParser) dispatchBool(flag *Flag) error {
+	value, ok := c.peek()
+	switch {
+	case ok && c.indexes[0] == c.index:
+		// the value was attached to the flag as --flag=value
+		c.next()
+		c.boolForms[flag] = BoolFormAttached
+	case ok && c.boolSyntax() == BoolSeparate && !c.strict && (value == "true" || value == "false"):
+		c.next()
+		c.boolForms[flag] = BoolFormSeparate
+	default:
+		value = "true"
+		c.boolForms[flag] = BoolFormBare
+	}
+	return c.setFlag(flag, value)
+}
+
+// boolSyntax returns the BoolSyntax of the current command, inherited from its
+// parents.
+func (c *argParser) boolSyntax() BoolSyntax {
+	for p := c.cmd; p != nil; p = p.Parent {
+		if p.BoolSyntax != BoolDefault {
+			return p.BoolSyntax
+		}
+	}
+	return BoolAttached
+}
+
+// boolForm returns the form in which the named flag was last specified.
+func (c *argParser) boolForm(name string) BoolForm {
+	return c.boolForms[c.lookupName(name)]
+}
+
+// setFlag sets the value of a flag that was read from the command line.
 func (c *argParser) setFlag(flag *Flag, value string) error {
-	if err := flag.Set(value); err != nil {
+	c.trace = append(c.trace, TraceEntry{Flag: flag, Value: value})
+	return c.setFlagFrom(ProvenanceCommandLine, nil, flag, value)
+}
+
+// traceArgs sets the raw arguments of all trace entries from n that were
+// dispatched from the argument at index start.
+func (c *argParser) traceArgs(n, start int) {
+	for i := n; i < len(c.trace); i++ {
+		c.trace[i].Index = start
+		c.trace[i].Args = c.rawArgs[start : c.index+1]
+	}
+}
+
+// setFlagFrom sets the value of a flag that was read from src, which is nil if
+// the value was not read from a source.
+func (c *argParser) setFlagFrom(p Provenance, src Source, flag *Flag, value string) error {
+	if flag.Unique {
+		if c.valuesSeen[flag][value] {
+			return nil
+		}
+		if c.valuesSeen[flag] == nil {
+			c.valuesSeen[flag] = make(map[string]bool)
+		}
+		c.valuesSeen[flag][value] = true
+	}
+	if err := c.set(flag, value); err != nil {
+		if src != nil {
+			err = sourceErr(src, err)
+		}
 		return wrapArgErr(err, c.cmd, flag, value)
 	}
+	c.origins[flag] = origin{provenance: p, src: src}
+	if flag.OnSet != nil && c.values == nil {
+		flag.OnSet(value)
+	}
 	return nil
 }
 
+// value returns the value of flag that is modified by the parser. If the
+// parser is parsing into an Invocation, the value is a copy of the flag value
+// as it was before any command line was parsed, so that it does not depend on
+// earlier invocations, or nil if the value cannot be copied.
+func (c *argParser) value(flag *Flag) Value {
+	if c.values == nil {
+		return flag.Value
+	}
+	if v, ok := c.values[flag]; ok {
+		return v
+	}
+	var v Value
+	src := flag.Value
+	for p := c.cmd; p != nil; p = p.Parent {
+		if initial, ok := p.index().initial[flag]; ok {
+			src = initial
+			break
+		}
+	}
+	if cl, ok := src.(cloner); ok {
+		v = cl.clone()
+	}
+	c.values[flag] = v
+	return v
+}
+
+// optionValue returns the value of the builtin flag with the given name that
+// is declared by cmd, as it was parsed by c, which may be nil. Builtin options
+// read their flags with optionValue so that each invocation parsed by
+// Command.ParseInvocation has its own options.
+func (c *argParser) optionValue(cmd *Command, name string) interface{} {
+	flag := cmd.index().flagsByName["--"+name]
+	v := flag.Value
+	if c != nil {
+		v = c.value(flag)
+	}
+	return v.(Getter).Get()
+}
+
+// valueOf returns the Value of the flag whose Value is v, which is a copy of v
+// if the parser was created by Command.ParseInvocation. Builtin options use it
+// to read the values that were parsed for an invocation. valueOf returns v if
+// c is nil.
+func (c *argParser) valueOf(v Value) Value {
+	if c == nil || c.values == nil {
+		return v
+	}
+	for p := c.cmd; p != nil; p = p.Parent {
+		for _, flag := range p.index().flags {
+			if flag.Value == v {
+				return c.value(flag)
+			}
+		}
+	}
+	return v
+}
+
+// set validates and sets the value of flag that is modified by the parser.
+func (c *argParser) set(flag *Flag, s string) error {
+	c.rawValues = append(c.rawValues, rawValue{flag: flag, value: s})
+	format := flag.numberFormat
+	if c.strict {
+		format.decimalComma = false // independent of the locale
+	}
+	return flag.set(c.value(flag), s, format)
+}
+
+// splitSections splits args into at most n sections separated by the
+// terminator. If n is less than 2, args are returned as a single section.
+func splitSections(args []string, n int) [][]string {
+	if args == nil {
+		return nil
+	}
+	if n < 2 {
+		return [][]string{args}
+	}
+	sections := make([][]string, 0, 1)
+	start := 0
+	for i, arg := range args {
+		if len(sections) == n-1 {
+			break
+		}
+		if arg == terminator {
+			sections = append(sections, args[start:i])
+			start = i + 1
+		}
+	}
+	return append(sections, args[start:])
+}
+
 func isSingleDash(arg string) bool {
 	if len(arg) < 2 {
 		return false
@@ -228,15 +940,47 @@ func isPositional(arg string) bool {
 // normalize splits any arguments that declare both a key and a value (E.g.
 // --key=value, or -kV) into two distinct arguments.
 func normalize(args []string, withTerminator bool) []string {
-	out := make([]string, 0, len(args))
+	out, _ := normalizeIndexed(args, withTerminator, nil, false)
+	return out
+}
+
+// normalizeIndexed is the same as normalize but also returns the index in args
+// of each normalized argument.
+//
+// Single-dash arguments that begin with any of the given short names, which
+// are longer than one character and sorted longest first, are split after the
+// short name if it is followed by "=", or by any value if prefix is true.
+func normalizeIndexed(
+	args []string,
+	withTerminator bool,
+	shortNames []string,
+	prefix bool,
+) (out []string, indexes []int) {
+	out = make([]string, 0, len(args))
+	indexes = make([]int, 0, len(args))
 	for i, arg := range args {
 		if withTerminator && arg == terminator {
 			out = append(out, args[i:]...)
-			return out
+			for j := i; j < len(args); j++ {
+				indexes = append(indexes, j)
+			}
+			return
 		}
 		if isSingleDash(arg) {
-			out = append(out, arg[:2])
-			arg = arg[2:]
+			n := 1
+			for _, name := range shortNames {
+				if !strings.HasPrefix(arg[1:], name) {
+					continue
+				}
+				tail := arg[1+len(name):]
+				if tail == "" || tail[0] == '=' || prefix {
+					n = len(name)
+					break
+				}
+			}
+			out = append(out, arg[:1+n])
+			indexes = append(indexes, i)
+			arg = arg[1+n:]
 			if len(arg) > 0 {
 				if arg[0] == '=' {
 					arg = arg[1:]
@@ -245,15 +989,36 @@ func normalize(args []string, withTerminator bool) []string {
 				continue
 			}
 		} else if isDoubleDash(arg) {
-			for i := 3; i < len(arg); i++ {
-				if arg[i] == '=' {
-					out = append(out, arg[:i])
-					arg = arg[i+1:]
+			for j := 3; j < len(arg); j++ {
+				if arg[j] == '=' {
+					out = append(out, arg[:j])
+					indexes = append(indexes, i)
+					arg = arg[j+1:]
 					break
 				}
 			}
 		}
 		out = append(out, arg)
+		indexes = append(indexes, i)
 	}
-	return out
+	return
+}
+
+// longShortNames returns all short names longer than one character that are
+// declared by cmd or any of its parents, sorted longest first.
+func longShortNames(cmd *Command) []string {
+	names := make([]string, 0)
+	for p := cmd; p != nil; p = p.Parent {
+		for _, group := range p.FlagGroups {
+			for _, flag := range group.Flags {
+				if len(flag.ShortName) > 1 {
+					names = append(names, flag.ShortName)
+				}
+			}
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	return names
 }
@@ -1,7 +1,15 @@
 package xflags
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNormalize(t *testing.T) {
@@ -66,3 +74,676 @@ func TestTerminator(t *testing.T) {
 	assertBool(t, true, bar)
 	assertStrings(t, tailArgs, cmd.Args())
 }
+
+func TestSections(t *testing.T) {
+	var cpu int
+	cmd := NewCommand("run", "").
+		Flags(Int(&cpu, "cpu", 1, "")).
+		WithSections(2).
+		Must()
+	args := []string{"--cpu", "2", "--", "./server", "--port", "80", "--", "--extra", "--", "x"}
+	if _, err := cmd.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 2, int64(cpu))
+	assertStrings(t, args[3:], cmd.Args())
+	if assertInt64(t, 2, int64(len(cmd.Sections()))) {
+		assertStrings(t, []string{"./server", "--port", "80"}, cmd.Section(0))
+		assertStrings(t, []string{"--extra", "--", "x"}, cmd.Section(1))
+	}
+	if cmd.Section(2) != nil {
+		t.Errorf("expected nil section")
+	}
+
+	testCases := []struct {
+		args   []string
+		n      int
+		expect [][]string
+	}{
+		{nil, 3, nil},
+		{[]string{}, 3, [][]string{{}}},
+		{[]string{"a", "--", "b"}, 1, [][]string{{"a", "--", "b"}}},
+		{[]string{"a", "--", "b"}, 0, [][]string{{"a", "--", "b"}}},
+		{[]string{"a", "--", "b"}, -1, [][]string{{"a", "--", "b"}}},
+		{[]string{"a", "--", "b"}, 3, [][]string{{"a"}, {"b"}}},
+		{[]string{"--", "--"}, 3, [][]string{{}, {}, {}}},
+	}
+	for _, testCase := range testCases {
+		actual := splitSections(testCase.args, testCase.n)
+		if !assertInt64(t, int64(len(testCase.expect)), int64(len(actual))) {
+			continue
+		}
+		for i := range actual {
+			assertStrings(t, testCase.expect[i], actual[i])
+		}
+	}
+	if _, err := NewCommand("test", "").WithSections(0).Command(); err == nil {
+		t.Errorf("expected error for zero sections")
+	}
+}
+
+func TestLongShortNames(t *testing.T) {
+	var heap, stack string
+	var verbose, x bool
+	newCmd := func() *CommandBuilder {
+		return NewCommand("java", "").
+			Flags(
+				String(&heap, "", "", "").ShortName("Xmx"),
+				String(&stack, "", "", "").ShortName("Xss"),
+				Bool(&verbose, "", false, "").ShortName("verbose"),
+				Bool(&x, "", false, "").ShortName("X"),
+			)
+	}
+	tests := []struct {
+		Args   []string
+		Prefix bool
+		Heap   string
+		Stack  string
+	}{
+		{[]string{"-Xmx", "512m"}, false, "512m", ""},
+		{[]string{"-Xmx=512m", "-Xss=1m"}, false, "512m", "1m"},
+		{[]string{"-Xmx512m", "-Xss1m"}, true, "512m", "1m"},
+		{[]string{"-Xmx=512m"}, true, "512m", ""},
+	}
+	for _, test := range tests {
+		heap, stack, verbose, x = "", "", false, false
+		cmd := newCmd()
+		if test.Prefix {
+			cmd.PrefixShortNames()
+		}
+		if _, err := cmd.Must().Parse(append(test.Args, "-verbose", "-X")); err != nil {
+			t.Errorf("%q: %v", test.Args, err)
+			continue
+		}
+		assertString(t, test.Heap, heap)
+		assertString(t, test.Stack, stack)
+		assertBool(t, true, verbose)
+		assertBool(t, true, x)
+	}
+
+	// without PrefixShortNames, -Xmx512m is parsed as -X=mx512m
+	_, err := newCmd().Must().Parse([]string{"-Xmx512m"})
+	assertErrorAs(t, err, new(*ArgumentError))
+
+	if _, err := String(&heap, "", "", "").ShortName("-Xmx").Flag(); err == nil {
+		t.Errorf("expected error for short name with leading dash")
+	}
+}
+
+func TestLongShortNamesScope(t *testing.T) {
+	var heap, x string
+	cmd := NewCommand("app", "").
+		PrefixShortNames().
+		Subcommands(
+			NewCommand("java", "").
+				Flags(String(&heap, "", "", "").ShortName("Xmx")),
+			NewCommand("other", "").
+				Flags(String(&x, "", "", "").ShortName("X")),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"java", "-Xmx512m"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "512m", heap)
+
+	// -Xmx is not declared by other or its parents
+	if _, err := cmd.Parse([]string{"other", "-Xmx512m"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "mx512m", x)
+}
+
+// newWideCommand returns a command with n string flags and the arguments to
+// set each of them.
+func newWideCommand(n int) (*Command, []string) {
+	flags := make([]Flagger, n)
+	args := make([]string, 0, n*2)
+	for i := 0; i < n; i++ {
+		name := "flag-" + strconv.Itoa(i)
+		flags[i] = String(nil, name, "", "Usage of "+name)
+		args = append(args, "--"+name, "value")
+	}
+	return NewCommand("wide", "").Flags(flags...).Must(), args
+}
+
+// newDeepCommand returns a chain of depth subcommands, each with width
+// sibling subcommands and ten flags, and the arguments to invoke the deepest
+// subcommand with a flag of each command.
+func newDeepCommand(depth, width int) (*Command, []string) {
+	args := make([]string, 0, depth*3)
+	var build func(level int) *CommandBuilder
+	build = func(level int) *CommandBuilder {
+		name := "cmd-" + strconv.Itoa(level)
+		flags := make([]Flagger, 10)
+		for i := range flags {
+			flags[i] = Int(nil, name+"-"+strconv.Itoa(i), 0, "")
+		}
+		cmd := NewCommand(name, "").Flags(flags...)
+		args = append(args, "--"+name+"-0", "1")
+		if level == depth {
+			return cmd.HandleFunc(func(args []string) int { return 0 })
+		}
+		for i := 1; i < width; i++ {
+			cmd.Subcommands(NewCommand("sibling-"+strconv.Itoa(i), ""))
+		}
+		args = append(args, "cmd-"+strconv.Itoa(level+1))
+		return cmd.Subcommands(build(level + 1))
+	}
+	return build(0).Must(), args
+}
+
+func TestParseWide(t *testing.T) {
+	cmd, args := newWideCommand(1000)
+	if _, err := cmd.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := cmd.ParseInvocation(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "value", inv.Get("flag-999").(string))
+}
+
+func TestParseDeep(t *testing.T) {
+	cmd, args := newDeepCommand(50, 10)
+	target, err := cmd.Parse(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "cmd-50", target.Name)
+}
+
+func TestParseFlagBeforeSubcommand(t *testing.T) {
+	var replicas int64
+	var force, debug bool
+	newCommand := func() *Command {
+		replicas, force, debug = 1, false, false
+		return NewCommand("app", "").
+			Flags(Bool(&debug, "debug", false, "")).
+			Subcommands(
+				NewCommand("deploy", "").
+					Flags(
+						Int64(&replicas, "replicas", 1, "").ShortName("r"),
+						Bool(&force, "force", false, ""),
+						Bool(nil, "wait", false, ""),
+					).
+					HandleFunc(func(args []string) int { return 0 }),
+				NewCommand("scale", "").
+					Flags(String(nil, "wait", "", "")).
+					HandleFunc(func(args []string) int { return 0 }),
+				NewCommand("logs", "").HandleFunc(func(args []string) int { return 0 }),
+			).
+			Must()
+	}
+
+	cmd, err := newCommand().Parse([]string{"-r", "3", "--debug", "--force", "deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "deploy", cmd.Name)
+	assertInt64(t, 3, replicas)
+	assertBool(t, true, force)
+	assertBool(t, true, debug)
+
+	// the trace is in the order of the arguments
+	cmd, err = newCommand().Parse([]string{"--replicas=2", "deploy", "--force"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 2, replicas)
+	trace := cmd.ParseTrace()
+	if assertInt64(t, 3, int64(len(trace))) {
+		assertString(t, "replicas", trace[0].Flag.Name)
+		assertStrings(t, []string{"--replicas=2"}, trace[0].Args)
+		assertString(t, "deploy", trace[1].Command.Name)
+		assertString(t, "force", trace[2].Flag.Name)
+	}
+
+	// errors report the index of the flag
+	tests := []struct {
+		Args   []string
+		Index  int
+		Expect string
+	}{
+		{
+			Args:   []string{"-r", "x", "deploy"},
+			Index:  0,
+			Expect: `--replicas: strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+		{
+			Args:   []string{"--debug", "-r", "3", "logs"},
+			Index:  1,
+			Expect: "-r is a flag of 'app deploy', not 'app logs'",
+		},
+		{
+			Args:   []string{"--wait", "deploy"},
+			Index:  0,
+			Expect: "--wait must be specified after the subcommand that declares it",
+		},
+		{
+			Args:   []string{"deploy", "-r"},
+			Index:  1,
+			Expect: "--replicas: no value specified for flag: -r",
+		},
+	}
+	for _, test := range tests {
+		_, err := newCommand().Parse(test.Args)
+		var argErr *ArgumentError
+		if assertErrorAs(t, err, &argErr) {
+			assertInt64(t, int64(test.Index), int64(argErr.Index))
+			assertString(t, test.Args[test.Index], argErr.Token)
+			assertString(t, test.Expect, argErr.String())
+		}
+	}
+}
+
+func BenchmarkParseWide(b *testing.B) {
+	cmd, args := newWideCommand(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmd.Parse(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseWideFewArgs(b *testing.B) {
+	cmd, args := newWideCommand(1000)
+	args = args[:4]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmd.Parse(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseDeep(b *testing.B) {
+	cmd, args := newDeepCommand(50, 10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmd.Parse(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseInvocation(b *testing.B) {
+	cmd, args := newWideCommand(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmd.ParseInvocation(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPOSIXStrict(t *testing.T) {
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	newCommand := func(strict bool) *Command {
+		var files []string
+		b := NewCommand("app", "").
+			BoolSyntax(BoolSeparate).
+			PrefixShortNames().
+			Flags(
+				Bool(nil, "force", false, "").ShortName("f"),
+				String(nil, "heap", "", "").ShortName("Xmx"),
+				Float64(nil, "ratio", 0, "").DecimalComma(),
+				Strings(&files, "file", nil, "").Positional(),
+			).
+			HandleFunc(func(args []string) int { return 0 })
+		if strict {
+			b.POSIXStrict()
+		}
+		return b.Must()
+	}
+
+	tests := []struct {
+		Args   []string
+		Expect string // flags that differ, as name=value pairs
+		Strict string // flags that differ in strict mode, or an error
+	}{
+		{
+			Args:   []string{"--force", "false", "a"},
+			Expect: "file=[a]",
+			Strict: "file=[false a] force=true",
+		},
+		{
+			Args:   []string{"-Xmx512m"},
+			Expect: "heap=512m",
+			Strict: `error: unrecognized argument: -X`,
+		},
+		{
+			Args:   []string{"-Xmx=512m"},
+			Expect: "heap=512m",
+			Strict: "heap=512m",
+		},
+		{
+			Args:   []string{"a", "--force", "b"},
+			Expect: "file=[a b] force=true",
+			Strict: "file=[a --force b]",
+		},
+		{
+			Args:   []string{"--ratio=0,5"},
+			Expect: "ratio=5e-01",
+			Strict: `error: --ratio: strconv.ParseFloat: parsing "0,5": invalid syntax`,
+		},
+	}
+	for _, test := range tests {
+		for _, strict := range []bool{false, true} {
+			expect := test.Expect
+			if strict {
+				expect = test.Strict
+			}
+			cmd := newCommand(strict)
+			_, err := cmd.Parse(test.Args)
+			var got string
+			if err != nil {
+				got = "error: " + err.(*ArgumentError).String()
+			} else {
+				var pairs []string
+				for _, flag := range cmd.FlagGroups[0].Flags {
+					if s := valueString(flag.Value); s != "false" && s != "" && s != "0e+00" && s != "[]" {
+						pairs = append(pairs, flag.Name+"="+s)
+					}
+				}
+				sort.Strings(pairs)
+				got = strings.Join(pairs, " ")
+			}
+			if got != expect {
+				t.Errorf("%v (strict: %v): expected %q, got %q", test.Args, strict, expect, got)
+			}
+		}
+	}
+
+	// aliases are not expanded
+	src := MapSource("config", map[string]string{"alias.co": "checkout"})
+	cmd := NewCommand("app", "").
+		POSIXStrict().
+		Aliases("").
+		Sources(src).
+		Subcommands(NewCommand("checkout", "").HandleFunc(func(args []string) int { return 0 })).
+		Must()
+	_, err := cmd.Parse([]string{"co"})
+	if assertBool(t, true, err != nil) {
+		assertString(t, "xflags: unrecognized command: co", err.Error())
+	}
+}
+
+// fuzzSeeds is the number of inputs in the seed corpus of each fuzz test,
+// which are checked by go test without -fuzz.
+const fuzzSeeds = 200
+
+// fuzzInput draws the choices of a generated test case from the bytes of a
+// fuzz input, so that the fuzzer mutates the generated flags and arguments.
+// Once the input is consumed, every choice is zero.
+type fuzzInput struct {
+	data []byte
+}
+
+// Intn returns a choice in [0, n) read from as many bytes as n needs.
+func (c *fuzzInput) Intn(n int) int {
+	var v uint64
+	for m := n - 1; m > 0 && len(c.data) > 0; m >>= 8 {
+		v = v<<8 | uint64(c.data[0])
+		c.data = c.data[1:]
+	}
+	return int(v % uint64(n))
+}
+
+// Shuffle permutes n elements with swap, like rand.Shuffle.
+func (c *fuzzInput) Shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, c.Intn(i+1))
+	}
+}
+
+// addFuzzSeeds adds fuzzSeeds deterministic inputs of 256 bytes to the seed
+// corpus of f.
+func addFuzzSeeds(f *testing.F) {
+	for i := 0; i < fuzzSeeds; i++ {
+		data := make([]byte, 0, 256)
+		for j := 0; len(data) < cap(data); j++ {
+			sum := sha256.Sum256([]byte(fmt.Sprintf("%d/%d", i, j)))
+			data = append(data, sum[:]...)
+		}
+		f.Add(data)
+	}
+}
+
+// randomFlag describes a flag of a randomly generated command.
+type randomFlag struct {
+	kind  string // "bool", "string", "int", "duration" or "strings"
+	name  string
+	short string
+	def   interface{} // default value
+	value interface{} // value that is specified on the command line
+}
+
+// randomString returns a short string that may contain characters that are
+// significant to the parser.
+func randomString(r *fuzzInput) string {
+	const alphabet = "ab-=, \t\"'\\é日"
+	runes := []rune(alphabet)
+	var sb strings.Builder
+	for i := r.Intn(6); i > 0; i-- {
+		sb.WriteRune(runes[r.Intn(len(runes))])
+	}
+	return sb.String()
+}
+
+// randomValue returns a random value of a flag of the given kind.
+func randomValue(r *fuzzInput, kind string) interface{} {
+	switch kind {
+	case "bool":
+		return r.Intn(2) == 0
+	case "string":
+		return randomString(r)
+	case "int":
+		return int64(r.Intn(2001) - 1000)
+	case "duration":
+		return time.Duration(r.Intn(100000)) * time.Millisecond
+	}
+	values := make([]string, 1+r.Intn(3))
+	for i := range values {
+		values[i] = randomString(r)
+	}
+	return values
+}
+
+// randomFlags returns the flags of a random command. The names of flags are
+// unique and some flags have a short name.
+func randomFlags(r *fuzzInput) []randomFlag {
+	kinds := []string{"bool", "string", "int", "duration", "strings"}
+	flags := make([]randomFlag, r.Intn(8))
+	for i := range flags {
+		kind := kinds[r.Intn(len(kinds))]
+		flags[i] = randomFlag{
+			kind:  kind,
+			name:  fmt.Sprintf("%s-%d", kind, i),
+			def:   randomValue(r, kind),
+			value: randomValue(r, kind),
+		}
+		if r.Intn(3) == 0 {
+			flags[i].short = string(rune('a' + i))
+		}
+	}
+	return flags
+}
+
+// newRandomCommand returns a command with the given flags.
+func newRandomCommand(flags []randomFlag) *Command {
+	builders := make([]Flagger, len(flags))
+	for i, f := range flags {
+		var b *FlagBuilder
+		switch f.kind {
+		case "bool":
+			b = Bool(nil, f.name, f.def.(bool), "")
+		case "string":
+			b = String(nil, f.name, f.def.(string), "")
+		case "int":
+			b = Int64(nil, f.name, f.def.(int64), "")
+		case "duration":
+			b = Duration(nil, f.name, f.def.(time.Duration), "")
+		case "strings":
+			b = Strings(nil, f.name, f.def.([]string), "")
+		}
+		if f.short != "" {
+			b = b.ShortName(f.short)
+		}
+		builders[i] = b
+	}
+	return NewCommand("app", "").
+		Output(io.Discard, io.Discard).
+		Flags(builders...).
+		WithTerminator().
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+}
+
+// parseRandom parses args with a new command with the given flags and returns
+// the value of each flag by name, the positional arguments and the error.
+func parseRandom(flags []randomFlag, args []string) (values map[string]interface{}, positional []string, err error) {
+	cmd := newRandomCommand(flags)
+	if _, err := cmd.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	values = make(map[string]interface{})
+	for _, group := range cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			values[flag.Name] = flag.Value.(Getter).Get()
+		}
+	}
+	return values, cmd.Args(), nil
+}
+
+// args returns the arguments that specify value for f, in a random form.
+func (f randomFlag) args(r *fuzzInput, value interface{}) []string {
+	name := "--" + f.name
+	if f.short != "" && r.Intn(2) == 0 {
+		name = "-" + f.short
+	}
+	if f.kind == "bool" {
+		if value.(bool) && r.Intn(2) == 0 {
+			return []string{name}
+		}
+		return []string{fmt.Sprintf("%s=%v", name, value)}
+	}
+	var values []string
+	switch v := value.(type) {
+	case []string:
+		values = v
+	case time.Duration:
+		values = []string{v.String()}
+	default:
+		values = []string{fmt.Sprint(v)}
+	}
+	args := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		if r.Intn(2) == 0 && v != "" && !strings.HasPrefix(v, "-") {
+			args = append(args, name, v)
+		} else {
+			args = append(args, name+"="+v)
+		}
+	}
+	return args
+}
+
+func FuzzParseDefaults(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzInput{data: data}
+		flags := randomFlags(r)
+
+		// without arguments, every flag has its default value
+		values, _, err := parseRandom(flags, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range flags {
+			if !reflect.DeepEqual(values[f.name], f.def) {
+				t.Errorf("%s: expected default %#v, got %#v", f.name, f.def, values[f.name])
+			}
+		}
+
+		// specifying the default of every flag does not change its value
+		var args []string
+		for _, f := range flags {
+			args = append(args, f.args(r, f.def)...)
+		}
+		specified, _, err := parseRandom(flags, args)
+		if err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		if !reflect.DeepEqual(values, specified) {
+			t.Errorf("%q: expected %#v, got %#v", args, values, specified)
+		}
+	})
+}
+
+func FuzzParsePermutations(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzInput{data: data}
+		flags := randomFlags(r)
+		var groups [][]string
+		expect := make(map[string]interface{})
+		for _, f := range flags {
+			expect[f.name] = f.def
+			if r.Intn(4) > 0 {
+				groups = append(groups, f.args(r, f.value))
+				expect[f.name] = f.value
+			}
+		}
+
+		// the order of flags does not change the result
+		for j := 0; j < 4; j++ {
+			r.Shuffle(len(groups), func(a, b int) { groups[a], groups[b] = groups[b], groups[a] })
+			var args []string
+			for _, g := range groups {
+				args = append(args, g...)
+			}
+			args = append(args, "--", "-x", "--y=z")
+			values, positional, err := parseRandom(flags, args)
+			if err != nil {
+				t.Fatalf("%q: %v", args, err)
+			}
+			if !reflect.DeepEqual(expect, values) {
+				t.Errorf("%q: expected %#v, got %#v", args, expect, values)
+			}
+			assertStrings(t, []string{"-x", "--y=z"}, positional)
+		}
+	})
+}
+
+func FuzzParseArbitraryArgs(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzInput{data: data}
+		flags := randomFlags(r)
+		pieces := []string{"", "-", "--", "=", "-a", "--a", "-ab", "true", "false", "1", "1s", "é", " "}
+		for _, f := range flags {
+			pieces = append(pieces, "--"+f.name, "-"+f.short)
+		}
+		args := make([]string, r.Intn(8))
+		for j := range args {
+			for k := 1 + r.Intn(3); k > 0; k-- {
+				args[j] += pieces[r.Intn(len(pieces))]
+			}
+		}
+
+		// parsing never panics and is deterministic
+		var results [2]string
+		for j := range results {
+			values, positional, err := parseRandom(flags, args)
+			results[j] = fmt.Sprintf("%#v %#v %v", values, positional, err)
+		}
+		if results[0] != results[1] {
+			t.Errorf("%q: nondeterministic result:\n%s\n%s", args, results[0], results[1])
+		}
+	})
+}
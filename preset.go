@@ -0,0 +1,273 @@
+package xflags
+
+import (
+	"sort"
+	"strings"
+)
+
+// presetKey is the key of the configuration of each preset.
+const presetKey = "preset"
+
+// Presets registers the --preset flag for this command and its subcommands
+// which applies a named bundle of flags, so that common combinations of flags
+// do not require wrapper scripts. Each preset is a command line of flags that
+// is split like a POSIX shell command line by SplitCommandLine. For example,
+// given the preset "prod" defined as "--region us-east-1 --replicas 3", the
+// command line "app deploy --preset prod" sets --region to "us-east-1" and
+// --replicas to 3.
+//
+// Presets are applied before all other flags: flags specified on the command
+// line take precedence over the flags of a preset, which take precedence over
+// environment variables and sources. If --preset is specified more than once,
+// the presets are applied in order and a flag set by a later preset replaces
+// the value set by an earlier preset. Flags of a preset that are not declared
+// by the invoked command or its parents are ignored, so that a preset may be
+// shared by several subcommands, but flags that are not declared by any
+// command of the tree are an error.
+//
+// Presets are defined by the given map, which may be nil, and by the sources
+// of the command, such as configuration files, at the path "preset.NAME":
+//
+//	preset:
+//	  prod: --region us-east-1 --replicas 3
+//
+// A preset defined by a source takes precedence over a preset of the same name
+// in the map.
+func (c *CommandBuilder) Presets(presets map[string]string) *CommandBuilder {
+	opts := &presetOptions{presets: presets}
+	c.cmd.presetOptions = opts
+	return c.Flags(
+		Var(opts, "preset", "Name of a preset of flags to apply").
+			NArgs(0, 0).
+			builtin(),
+	)
+}
+
+// presetOptions is the value of the flag registered by Presets.
+type presetOptions struct {
+	presets map[string]string // presets defined in code
+	names   []string          // presets selected with --preset
+}
+
+func (c *presetOptions) String() string { return strings.Join(c.names, ",") }
+
+func (c *presetOptions) Set(s string) error {
+	c.names = append(c.names, s)
+	return nil
+}
+
+func (c *presetOptions) Get() interface{} { return append([]string(nil), c.names...) }
+
+func (c *presetOptions) clone() Value {
+	v := *c
+	v.names = append([]string(nil), c.names...)
+	return &v
+}
+
+// hasPresets reports whether c or any of its parents registered the --preset
+// flag.
+func (c *Command) hasPresets() bool {
+	for p := c; p != nil; p = p.Parent {
+		if p.presetOptions != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPresets sets the flags of each preset selected with --preset that were
+// not specified on the command line.
+func (c *argParser) applyPresets(sources []Source) error {
+	var cmd *Command
+	for p := c.cmd; p != nil && cmd == nil; p = p.Parent {
+		if p.presetOptions != nil {
+			cmd = p
+		}
+	}
+	if cmd == nil {
+		return nil
+	}
+	var presetFlag *Flag
+	for _, flag := range cmd.index().flags {
+		if _, ok := flag.Value.(*presetOptions); ok {
+			presetFlag = flag
+		}
+	}
+	opts, _ := c.value(presetFlag).(*presetOptions)
+	if opts == nil || len(opts.names) == 0 {
+		return nil
+	}
+	values := make(map[*Flag][]string)
+	flags := make([]*Flag, 0)
+	for _, name := range opts.names {
+		s, ok, err := lookupPreset(sources, cmd.presetOptions.presets, name)
+		if err != nil {
+			return wrapArgErr(err, c.cmd, presetFlag, name)
+		}
+		if !ok {
+			err := newArgErr(c.cmd, presetFlag, name, "unknown preset: %s", name)
+			names, _ := presetNames(sources, cmd.presetOptions.presets)
+			err.Suggestions = suggest(name, names)
+			return err
+		}
+		preset, order, err := c.parsePreset(presetFlag, name, s)
+		if err != nil {
+			return err
+		}
+		for _, flag := range order {
+			if _, ok := values[flag]; !ok {
+				flags = append(flags, flag)
+			}
+			values[flag] = preset[flag]
+		}
+	}
+	for _, flag := range flags {
+		if c.flagsSeen[flag.name()] > 0 || !c.cmd.featureEnabled(flag.Feature) {
+			continue
+		}
+		for _, s := range values[flag] {
+			c.observe(flag)
+			if err := c.setFlagFrom(ProvenancePreset, nil, flag, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parsePreset returns the values of each flag of the named preset that is
+// declared by the current command or its parents, and the flags in the order
+// they were first specified.
+func (c *argParser) parsePreset(
+	presetFlag *Flag,
+	name, s string,
+) (map[*Flag][]string, []*Flag, error) {
+	root := c.cmd
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	tokens, indexes := normalizeIndexed(
+		SplitCommandLine(s),
+		false,
+		root.index().shortNames,
+		root.PrefixShortNames,
+	)
+	values := make(map[*Flag][]string)
+	flags := make([]*Flag, 0)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if isPositional(token) {
+			return nil, nil, newArgErr(
+				c.cmd,
+				presetFlag,
+				name,
+				"unexpected positional argument in preset %s: %s",
+				name,
+				token,
+			)
+		}
+		flag := c.lookupFlag(token)
+		declared := flag != nil
+		if !declared {
+			flag = lookupTreeFlag(root, token)
+		}
+		if flag == nil {
+			return nil, nil, newArgErr(
+				c.cmd,
+				presetFlag,
+				name,
+				"unrecognized argument in preset %s: %s",
+				name,
+				token,
+			)
+		}
+		attached := i+1 < len(tokens) && indexes[i+1] == indexes[i]
+		value := "true"
+		switch {
+		case attached:
+			i++
+			value = tokens[i]
+		case isBoolValue(flag.Value):
+		case i+1 < len(tokens) && isPositional(tokens[i+1]):
+			i++
+			value = tokens[i]
+		default:
+			return nil, nil, newArgErr(
+				c.cmd,
+				presetFlag,
+				name,
+				"no value specified for flag in preset %s: %s",
+				name,
+				token,
+			)
+		}
+		if !declared {
+			continue
+		}
+		if _, ok := values[flag]; !ok {
+			flags = append(flags, flag)
+		}
+		values[flag] = append(values[flag], value)
+	}
+	return values, flags, nil
+}
+
+// lookupTreeFlag returns the first flag declared as token by cmd or any of its
+// subcommands, or nil.
+func lookupTreeFlag(cmd *Command, token string) *Flag {
+	if flag := cmd.index().flagsByName[token]; flag != nil {
+		return flag
+	}
+	for _, sub := range cmd.Subcommands {
+		if flag := lookupTreeFlag(sub, token); flag != nil {
+			return flag
+		}
+	}
+	return nil
+}
+
+// lookupPreset returns the flags of the named preset from the first source
+// that defines it, or from presets.
+func lookupPreset(sources []Source, presets map[string]string, name string) (string, bool, error) {
+	for _, src := range sources {
+		s, ok, err := lookup(src, presetKey+"."+name)
+		if err != nil {
+			return "", false, sourceErr(src, err)
+		}
+		if ok {
+			return s, true, nil
+		}
+	}
+	s, ok := presets[name]
+	return s, ok, nil
+}
+
+// presetNames returns the sorted names of the presets defined by presets and
+// the configuration files of sources.
+func presetNames(sources []Source, presets map[string]string) ([]string, error) {
+	seen := make(map[string]bool)
+	for name := range presets {
+		seen[name] = true
+	}
+	for _, src := range sources {
+		cfg, ok := src.(*configFileSource)
+		if !ok {
+			continue
+		}
+		doc, err := cfg.load()
+		if err != nil {
+			return nil, sourceErr(src, err)
+		}
+		for _, key := range doc.keys {
+			if name := strings.TrimPrefix(key, presetKey+"."); name != key {
+				seen[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,93 @@
+package xflags
+
+import (
+	"testing"
+)
+
+func newPresetCommand(flags *configFileTestFlags, sources ...Source) *Command {
+	return newConfigFileCommand(flags, sources...).
+		Presets(map[string]string{
+			"prod":    "--region us-east-1 --replicas=3",
+			"eu":      "--region=eu-west-1 --verbose",
+			"canary":  "--replicas 1 --tag canary --tag 'smoke test'",
+			"bad":     "--regoin us-east-1",
+			"missing": "--region",
+		}).
+		StrictConfig().
+		Must()
+}
+
+func TestPresets(t *testing.T) {
+	var flags configFileTestFlags
+	_, err := newPresetCommand(&flags).Parse([]string{"--preset", "canary", "deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 1, int64(flags.replicas))
+	assertStrings(t, []string{"canary", "smoke test"}, flags.tags)
+
+	// command line flags take precedence and later presets replace earlier ones
+	flags = configFileTestFlags{}
+	_, err = newPresetCommand(&flags).Parse([]string{
+		"--preset", "canary",
+		"--preset=eu",
+		"deploy",
+		"--replicas", "5",
+		"--preset", "prod",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "us-east-1", flags.region)
+	assertBool(t, true, flags.verbose)
+	assertInt64(t, 5, int64(flags.replicas))
+
+	// flags of other subcommands are ignored
+	flags = configFileTestFlags{}
+	if _, err := newPresetCommand(&flags).Parse([]string{"--preset", "canary"}); err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, nil, flags.tags)
+}
+
+func TestPresetsConfig(t *testing.T) {
+	path := writeConfigTestFile(t, "app.toml", `
+[preset]
+prod = "--region eu-central-1"
+staging = "--replicas 2"
+`)
+	var flags configFileTestFlags
+	cmd := newPresetCommand(&flags, ConfigFile(path))
+	if _, err := cmd.Parse([]string{"--preset", "prod", "deploy", "--preset", "staging"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "eu-central-1", flags.region)
+	assertInt64(t, 2, int64(flags.replicas))
+
+	inv, err := newPresetCommand(&flags, ConfigFile(path)).ParseInvocation([]string{
+		"deploy",
+		"--preset", "prod",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "eu-central-1", inv.Get("region").(string))
+	p, _ := inv.Provenance("region")
+	assertString(t, "preset", p.String())
+}
+
+func TestPresetsErrors(t *testing.T) {
+	tests := map[string]string{
+		"stagign": `unknown preset: stagign`,
+		"bad":     `unrecognized argument in preset bad: --regoin`,
+		"missing": `no value specified for flag in preset missing: --region`,
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			var flags configFileTestFlags
+			_, err := newPresetCommand(&flags).Parse([]string{"--preset", name, "deploy"})
+			assertErrorAs(t, err, new(*ArgumentError))
+			assertString(t, "xflags: --preset: "+want, err.Error())
+		})
+	}
+}
@@ -0,0 +1,441 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Output formats supported by Printer.
+const (
+	FormatJSON       = "json"
+	FormatYAML       = "yaml"
+	FormatTable      = "table"
+	FormatGoTemplate = "go-template" // specified as go-template=TEMPLATE
+	FormatTemplate   = "template"    // specified as template=FILE
+	FormatJSONPath   = "jsonpath"    // specified as jsonpath=TEMPLATE
+)
+
+// formatHasArg reports whether the named format requires an argument.
+func formatHasArg(format string) bool {
+	switch format {
+	case FormatGoTemplate, FormatTemplate, FormatJSONPath:
+		return true
+	}
+	return false
+}
+
+// Printer renders the results of a command in the format selected by the
+// --output flag registered with Formats. Handlers registered with
+// HandleContext may retrieve the Printer of their invocation with
+// PrinterFrom.
+type Printer struct {
+	// Format is the name of the output format, such as "json".
+	Format string
+
+	// Arg is the argument of the output format, such as the template of the
+	// "go-template" format or the template file of the "template" format.
+	Arg string
+
+	W io.Writer
+}
+
+// Print renders v to the Printer's Writer. Values are first encoded as JSON
+// so that JSON struct tags name the fields of every format except
+// "go-template" and "template", which are executed with v.
+//
+// The "jsonpath" format accepts the JSONPath template syntax of kubectl, such
+// as jsonpath='{.items[*].name}' or
+// jsonpath='{range .items[*]}{.name}{"\n"}{end}'.
+//
+// The "table" format prints one row for each element of a slice or array, or
+// a single row for any other value, with a column for each field of the first
+// row. Nested values are printed as JSON.
+func (c *Printer) Print(v interface{}) error {
+	switch c.Format {
+	case FormatJSON:
+		enc := json.NewEncoder(c.W)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatGoTemplate:
+		tmpl, err := template.New("output").Parse(c.Arg)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(c.W, v)
+	case FormatTemplate:
+		tmpl, err := template.ParseFiles(c.Arg)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(c.W, v)
+	}
+	doc, err := toOrdered(v)
+	if err != nil {
+		return err
+	}
+	switch c.Format {
+	case FormatYAML:
+		w := new(bytes.Buffer)
+		writeYAML(w, doc, 0)
+		_, err := w.WriteTo(c.W)
+		return err
+	case FormatTable:
+		return writeTable(c.W, doc)
+	case FormatJSONPath:
+		jp, err := parseJSONPath(c.Arg)
+		if err != nil {
+			return err
+		}
+		return jp.Execute(c.W, doc)
+	}
+	return errorf("unsupported output format: %s", c.Format)
+}
+
+type printerKey struct{}
+
+// PrinterFrom returns the Printer of the command invocation that ctx was
+// created for. If the invoked command has no --output flag, a Printer for the
+// "json" format that writes to the Stdout of the OutputFrom ctx is returned.
+func PrinterFrom(ctx context.Context) *Printer {
+	if p, ok := ctx.Value(printerKey{}).(*Printer); ok {
+		return p
+	}
+	return &Printer{Format: FormatJSON, W: OutputFrom(ctx).Stdout}
+}
+
+// formatOptions is the value of the flag registered by Formats.
+type formatOptions struct {
+	formats []string
+	format  string
+	arg     string
+}
+
+func (c *formatOptions) String() string {
+	if c.arg != "" {
+		return c.format + "=" + c.arg
+	}
+	return c.format
+}
+
+func (c *formatOptions) Set(s string) error {
+	format, arg := s, ""
+	if i := strings.Index(s, "="); i >= 0 {
+		format, arg = s[:i], s[i+1:]
+	}
+	if !c.accepts(format) {
+		return &choiceError{Arg: format, Choices: c.formats}
+	}
+	if format == FormatJSONPath && arg != "" {
+		if _, err := parseJSONPath(arg); err != nil {
+			return err
+		}
+	}
+	if formatHasArg(format) != (arg != "") {
+		if arg == "" {
+			return fmt.Errorf("%s format requires an argument: %s=...", format, format)
+		}
+		return fmt.Errorf("%s format does not accept an argument", format)
+	}
+	c.format, c.arg = format, arg
+	return nil
+}
+
+// clone returns a copy of c with the default format, so that the format of an
+// invocation does not depend on the formats selected by earlier invocations.
+func (c *formatOptions) clone() Value {
+	return &formatOptions{formats: c.formats, format: c.formats[0]}
+}
+
+func (c *formatOptions) accepts(format string) bool {
+	for _, s := range c.formats {
+		if s == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Formats registers the --output (-o) flag for this command and its
+// subcommands which selects the format of the Printer returned by
+// PrinterFrom. The first format is the default. If no formats are given,
+// "table", "json", "yaml", "go-template", "template" and "jsonpath" are
+// supported.
+func (c *CommandBuilder) Formats(formats ...string) *CommandBuilder {
+	if len(formats) == 0 {
+		formats = []string{
+			FormatTable,
+			FormatJSON,
+			FormatYAML,
+			FormatGoTemplate,
+			FormatTemplate,
+			FormatJSONPath,
+		}
+	}
+	for _, format := range formats {
+		switch format {
+		case FormatJSON, FormatYAML, FormatTable:
+		case FormatGoTemplate, FormatTemplate, FormatJSONPath:
+		default:
+			return c.error(errorf("%s: unsupported output format: %s", c.cmd.Name, format))
+		}
+	}
+	opts := &formatOptions{formats: formats, format: formats[0]}
+	if formatHasArg(opts.format) {
+		return c.error(errorf("%s: default output format requires an argument", c.cmd.Name))
+	}
+	c.cmd.formatOptions = opts
+	return c.Flags(
+		Var(opts, "output", "").
+			UsageFunc(func() string {
+				return "Output format: " + strings.Join(formats, ", ")
+			}).
+			ShortName("o").
+			ShowDefault().
+			builtin(),
+	)
+}
+
+// newPrinter returns the Printer for inv, an invocation of c, or nil if c and
+// its parents have no --output flag.
+func (c *Command) newPrinter(inv *Invocation, out *Output) *Printer {
+	for p := c; p != nil; p = p.Parent {
+		if p.formatOptions != nil {
+			opts := inv.parser.valueOf(p.formatOptions).(*formatOptions)
+			return &Printer{Format: opts.format, Arg: opts.arg, W: out.Stdout}
+		}
+	}
+	return nil
+}
+
+// orderedMap is a JSON object which retains the order of its keys.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// toOrdered encodes v as JSON and decodes it into orderedMaps, slices and
+// scalar values.
+func toOrdered(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return decodeOrdered(dec)
+}
+
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		m := &orderedMap{values: make(map[string]interface{})}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.keys = append(m.keys, key.(string))
+			m.values[key.(string)] = value
+		}
+		_, err := dec.Token()
+		return m, err
+	case json.Delim('['):
+		a := make([]interface{}, 0)
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err := dec.Token()
+		return a, err
+	}
+	return tok, nil
+}
+
+func writeYAML(w *bytes.Buffer, v interface{}, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case *orderedMap:
+		if len(v.keys) == 0 {
+			w.WriteString(prefix + "{}\n")
+			return
+		}
+		for _, key := range v.keys {
+			w.WriteString(prefix + yamlScalar(key) + ":")
+			writeYAMLValue(w, v.values[key], indent+1)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			w.WriteString(prefix + "[]\n")
+			return
+		}
+		for _, elem := range v {
+			if isYAMLCollection(elem) {
+				// write the first line of the element after the indicator
+				elemBuf := new(bytes.Buffer)
+				writeYAML(elemBuf, elem, indent+1)
+				w.WriteString(prefix + "- ")
+				w.Write(elemBuf.Bytes()[len(prefix)+2:])
+				continue
+			}
+			w.WriteString(prefix + "-")
+			writeYAMLValue(w, elem, indent+1)
+		}
+	default:
+		w.WriteString(prefix + yamlScalar(v) + "\n")
+	}
+}
+
+// isYAMLCollection reports whether v is a non-empty mapping or sequence.
+func isYAMLCollection(v interface{}) bool {
+	switch v := v.(type) {
+	case *orderedMap:
+		return len(v.keys) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+// writeYAMLValue writes v after a mapping key or sequence indicator.
+func writeYAMLValue(w *bytes.Buffer, v interface{}, indent int) {
+	switch x := v.(type) {
+	case *orderedMap:
+		if len(x.keys) > 0 {
+			w.WriteString("\n")
+			writeYAML(w, v, indent)
+			return
+		}
+		w.WriteString(" {}\n")
+	case []interface{}:
+		if len(x) > 0 {
+			w.WriteString("\n")
+			writeYAML(w, v, indent)
+			return
+		}
+		w.WriteString(" []\n")
+	default:
+		w.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if yamlNeedsQuotes(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	return strings.Contains(s, ": ") ||
+		strings.Contains(s, " #") ||
+		strings.ContainsAny(s, "\n\t\r")
+}
+
+func writeTable(w io.Writer, v interface{}) error {
+	rows, ok := v.([]interface{})
+	if !ok {
+		rows = []interface{}{v}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	var columns []string
+	if m, ok := rows[0].(*orderedMap); ok {
+		columns = m.keys
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	if len(columns) > 0 {
+		headers := make([]string, len(columns))
+		for i, column := range columns {
+			headers[i] = strings.ToUpper(column)
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	}
+	for _, row := range rows {
+		m, ok := row.(*orderedMap)
+		if !ok || len(columns) == 0 {
+			fmt.Fprintln(tw, tableCell(row))
+			continue
+		}
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = tableCell(m.values[column])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func tableCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case *orderedMap, []interface{}:
+		b, _ := json.Marshal(fromOrdered(v))
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// fromOrdered converts orderedMaps to maps for encoding. Keys are sorted by
+// encoding/json.
+func fromOrdered(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *orderedMap:
+		m := make(map[string]interface{}, len(v.keys))
+		for _, key := range v.keys {
+			m[key] = fromOrdered(v.values[key])
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, elem := range v {
+			a[i] = fromOrdered(elem)
+		}
+		return a
+	}
+	return v
+}
@@ -0,0 +1,104 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+type testWidget struct {
+	Name   string            `json:"name"`
+	Count  int               `json:"count"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Tags   []string          `json:"tags"`
+}
+
+func TestPrinter(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "widgets.tmpl")
+	if err := ioutil.WriteFile(tmpl, []byte("{{range .}}{{.Name}}\n{{end}}"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	widgets := []testWidget{
+		{Name: "foo", Count: 1, Labels: map[string]string{"a": "b"}, Tags: []string{"x", "y"}},
+		{Name: "true", Count: 20},
+	}
+	tests := []struct {
+		Args   []string
+		Expect string
+	}{
+		{
+			nil,
+			"NAME   COUNT   LABELS      TAGS\n" +
+				"foo    1       {\"a\":\"b\"}   [\"x\",\"y\"]\n" +
+				"true   20                  \n",
+		},
+		{
+			[]string{"-o", "json"},
+			"[\n  {\n    \"name\": \"foo\",\n    \"count\": 1,\n    \"labels\": {\n" +
+				"      \"a\": \"b\"\n    },\n    \"tags\": [\n      \"x\",\n      \"y\"\n" +
+				"    ]\n  },\n  {\n    \"name\": \"true\",\n    \"count\": 20,\n" +
+				"    \"tags\": null\n  }\n]\n",
+		},
+		{
+			[]string{"--output=yaml"},
+			"- name: foo\n  count: 1\n  labels:\n    a: b\n  tags:\n    - x\n    - y\n" +
+				"- name: \"true\"\n  count: 20\n  tags: null\n",
+		},
+		{
+			[]string{"-o", "go-template={{range .}}{{.Name}}={{.Count}};{{end}}"},
+			"foo=1;true=20;",
+		},
+		{[]string{"-o", "jsonpath={[*].name}"}, "foo true"},
+		{[]string{"-o", "template=" + tmpl}, "foo\ntrue\n"},
+	}
+	for _, test := range tests {
+		stdout := new(bytes.Buffer)
+		cmd := NewCommand("app", "").
+			Output(stdout, stdout).
+			Formats().
+			HandleContext(func(ctx context.Context, args []string) int {
+				if err := PrinterFrom(ctx).Print(widgets); err != nil {
+					t.Error(err)
+					return 1
+				}
+				return 0
+			}).
+			Must()
+		if code := cmd.Run(test.Args); code != 0 {
+			t.Errorf("%q: unexpected exit code: %d", test.Args, code)
+			continue
+		}
+		assertString(t, test.Expect, stdout.String())
+
+		// the format of each invocation is parsed separately
+		for _, args := range [][]string{test.Args, nil} {
+			var out bytes.Buffer
+			if code := cmd.Exec(context.Background(), args, nil, &out, &out); code != 0 {
+				t.Errorf("%q: unexpected exit code: %d", args, code)
+				continue
+			}
+			expect := tests[0].Expect
+			if args != nil {
+				expect = test.Expect
+			}
+			assertString(t, expect, out.String())
+		}
+	}
+}
+
+func TestFormatsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-o", "xml"},
+		{"-o", "go-template"},
+		{"-o", "json=x"},
+		{"-o", "jsonpath={.x"},
+	} {
+		_, err := NewCommand("app", "").Formats().Must().Parse(args)
+		assertErrorAs(t, err, new(*ArgumentError))
+	}
+	if _, err := NewCommand("app", "").Formats("json", "toml").Command(); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,71 @@
+package xflags
+
+// Provenance describes where the value of a flag was read from.
+type Provenance int
+
+const (
+	ProvenanceDefault     Provenance = iota // the default value of the flag
+	ProvenanceCommandLine                   // the command line
+	ProvenanceEnv                           // the environment variable of the flag
+	ProvenanceConfig                        // a Source, such as a configuration file
+	ProvenancePrompt                        // the user at a prompt, see Invocation.SetPrompted
+	ProvenancePreset                        // a preset selected with --preset, see CommandBuilder.Presets
+)
+
+func (p Provenance) String() string {
+	switch p {
+	case ProvenanceCommandLine:
+		return "command line"
+	case ProvenanceEnv:
+		return "env"
+	case ProvenanceConfig:
+		return "config"
+	case ProvenancePrompt:
+		return "prompt"
+	case ProvenancePreset:
+		return "preset"
+	}
+	return "default"
+}
+
+// origin records where the value of a flag was read from.
+type origin struct {
+	provenance Provenance
+	src        Source // the source of a ProvenanceConfig value
+}
+
+// provenanceOf returns the Provenance of a value read by the parser from src,
+// which is nil for environment variables.
+func provenanceOf(src Source) Provenance {
+	if src == nil {
+		return ProvenanceEnv
+	}
+	return ProvenanceConfig
+}
+
+// Provenance returns where the value of the named flag was last read from.
+// Handlers may use it to warn, for example, when a secret was specified on
+// the command line instead of in the environment. Provenance returns
+// ProvenanceDefault if the flag was not set or does not exist.
+//
+// If the flag was set by a Source, the source is also returned.
+func (c *Invocation) Provenance(name string) (Provenance, Source) {
+	flag := c.lookup(name)
+	if flag == nil {
+		return ProvenanceDefault, nil
+	}
+	o := c.parser.origins[flag]
+	return o.provenance, o.src
+}
+
+// SetPrompted sets the value of the named flag to a value that was entered by
+// the user at a prompt, so that its Provenance is ProvenancePrompt. The flag is
+// validated and its OnSet function is called as if it was parsed.
+func (c *Invocation) SetPrompted(name, value string) error {
+	flag := c.lookup(name)
+	if flag == nil {
+		return errorf("no such flag: %s", name)
+	}
+	c.parser.observe(flag)
+	return c.parser.setFlagFrom(ProvenancePrompt, nil, flag, value)
+}
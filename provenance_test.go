@@ -0,0 +1,56 @@
+package xflags
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	type result struct {
+		p   Provenance
+		src Source
+	}
+	results := make(map[string]result)
+	config := MapSource("config.json", map[string]string{"region": "us-east-1", "token": "ignored"})
+	cmd := NewCommand("app", "").
+		Sources(config).
+		Flags(
+			String(nil, "name", "", ""),
+			String(nil, "token", "", "").Env("APP_TOKEN").Secret(),
+			String(nil, "region", "", ""),
+			String(nil, "zone", "a", ""),
+			String(nil, "password", "", ""),
+		).
+		HandleContext(func(ctx context.Context, args []string) int {
+			inv := InvocationFrom(ctx)
+			if err := inv.SetPrompted("password", "hunter2"); err != nil {
+				t.Error(err)
+			}
+			if err := inv.SetPrompted("nope", ""); err == nil {
+				t.Error("expected error setting unknown flag")
+			}
+			assertString(t, "hunter2", inv.Get("password").(string))
+			for _, name := range []string{"name", "token", "region", "zone", "password", "nope"} {
+				p, src := inv.Provenance(name)
+				results[name] = result{p, src}
+			}
+			return 0
+		}).
+		Must()
+	t.Setenv("APP_TOKEN", "secret")
+	assertInt64(t, 0, int64(cmd.Run([]string{"--name", "foo"})))
+	expect := map[string]result{
+		"name":     {ProvenanceCommandLine, nil},
+		"token":    {ProvenanceEnv, nil},
+		"region":   {ProvenanceConfig, config},
+		"zone":     {ProvenanceDefault, nil},
+		"password": {ProvenancePrompt, nil},
+		"nope":     {ProvenanceDefault, nil},
+	}
+	for name, r := range expect {
+		if results[name] != r {
+			t.Errorf("%s: expected %v, got %v", name, r, results[name])
+		}
+	}
+	assertString(t, "command line", ProvenanceCommandLine.String())
+}
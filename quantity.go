@@ -0,0 +1,183 @@
+package xflags
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Units maps the unit suffixes accepted by Quantity flags to their multiple of
+// the base unit. The empty suffix denotes the base unit; if it is not in the
+// table, every value must have a unit.
+type Units map[string]float64
+
+var (
+	// CPUUnits are the units of Kubernetes CPU quantities, such as "500m" for
+	// half a CPU.
+	CPUUnits = Units{"": 1, "m": 1e-3}
+
+	// ByteUnits are the units of Kubernetes memory and storage quantities
+	// in bytes, with binary suffixes, such as "512Mi", and decimal suffixes,
+	// such as "1G".
+	ByteUnits = Units{
+		"":   1,
+		"k":  1e3,
+		"M":  1e6,
+		"G":  1e9,
+		"T":  1e12,
+		"P":  1e15,
+		"E":  1e18,
+		"Ki": 1 << 10,
+		"Mi": 1 << 20,
+		"Gi": 1 << 30,
+		"Ti": 1 << 40,
+		"Pi": 1 << 50,
+		"Ei": 1 << 60,
+	}
+)
+
+// Number is the set of types that Quantity flags may store.
+type Number interface {
+	~int | ~int64 | ~uint | ~uint64 | ~float64
+}
+
+// Quantity returns a FlagBuilder that can be used to define a flag for a
+// quantity with a unit, such as "500m" or "1.5Gi", with specified name,
+// default value, unit table and usage string. The argument p points to a
+// variable in which to store the value of the flag in the base unit of units.
+// For example, with ByteUnits, "1.5Ki" is stored as 1536.
+//
+// If T is an integer type, the value in the base unit must be an integer.
+// Values, including the default value in help messages, are formatted in the
+// largest unit in which they are integers, so that 1536 is formatted as
+// "1536" and 1048576 as "1Mi".
+func Quantity[T Number](p *T, name string, value T, units Units, usage string) *FlagBuilder {
+	if p == nil {
+		p = new(T)
+	}
+	*p = value
+	return Var(&quantityValue[T]{p: p, units: units}, name, usage)
+}
+
+type quantityValue[T Number] struct {
+	p     *T
+	units Units
+}
+
+func (c *quantityValue[T]) Get() interface{} { return *c.p }
+
+func (c *quantityValue[T]) clone() Value {
+	v := *c.p
+	return &quantityValue[T]{p: &v, units: c.units}
+}
+
+func (c *quantityValue[T]) String() string { return c.units.format(float64(*c.p)) }
+
+func (c *quantityValue[T]) Set(s string) error {
+	f, err := c.units.parse(s)
+	if err != nil {
+		return err
+	}
+	v := T(f)
+	if float64(v) != f {
+		if f != math.Trunc(f) {
+			return errorf("invalid quantity: %s is not an integer in the base unit", s)
+		}
+		return errorf("quantity out of range: %s", s)
+	}
+	*c.p = v
+	return nil
+}
+
+// suffixes returns the unit suffixes of c, longest first.
+func (c Units) suffixes() []string {
+	suffixes := make([]string, 0, len(c))
+	for suffix := range c {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Slice(suffixes, func(i, j int) bool {
+		if len(suffixes[i]) != len(suffixes[j]) {
+			return len(suffixes[i]) > len(suffixes[j])
+		}
+		return suffixes[i] < suffixes[j]
+	})
+	return suffixes
+}
+
+// parse returns the value of the quantity s in the base unit.
+func (c Units) parse(s string) (float64, error) {
+	for _, suffix := range c.suffixes() {
+		number := strings.TrimSuffix(s, suffix)
+		if number == s && suffix != "" || number == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			continue
+		}
+		v := roundQuantity(f * c[suffix])
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return 0, errorf("invalid quantity: %s", s)
+		}
+		return v, nil
+	}
+	return 0, errorf("invalid quantity: %s (units: %s)", s, strings.Join(c.names(), ", "))
+}
+
+// format formats v in the largest unit of c in which it is an integer, or in
+// the base unit.
+func (c Units) format(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	best, bestMultiple := "", 0.0
+	for _, suffix := range c.suffixes() {
+		multiple := c[suffix]
+		q := roundQuantity(v / multiple)
+		if q == math.Trunc(q) && multiple > bestMultiple {
+			best, bestMultiple = suffix, multiple
+		}
+	}
+	if bestMultiple == 0 {
+		if _, ok := c[""]; !ok {
+			// no base unit; use the smallest unit
+			for _, suffix := range c.suffixes() {
+				if bestMultiple == 0 || c[suffix] < bestMultiple {
+					best, bestMultiple = suffix, c[suffix]
+				}
+			}
+		} else {
+			bestMultiple = 1
+		}
+	}
+	return strconv.FormatFloat(roundQuantity(v/bestMultiple), 'f', -1, 64) + best
+}
+
+// names returns the non-empty unit suffixes of c in ascending order of their
+// multiples.
+func (c Units) names() []string {
+	names := make([]string, 0, len(c))
+	for suffix := range c {
+		if suffix != "" {
+			names = append(names, suffix)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if c[names[i]] != c[names[j]] {
+			return c[names[i]] < c[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// roundQuantity rounds v to the nearest integer if it differs from it only by
+// the errors of binary floating point in products and quotients of decimal
+// multiples, such as 0.5 / 0.001.
+func roundQuantity(v float64) float64 {
+	if r := math.Round(v); r != 0 && math.Abs(v-r) <= 1e-9*math.Abs(r) {
+		return r
+	}
+	return v
+}
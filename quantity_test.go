@@ -0,0 +1,41 @@
+package xflags
+
+import "testing"
+
+func TestQuantity(t *testing.T) {
+	tests := []struct {
+		Arg    string
+		Expect float64
+		Format string
+		OK     bool
+	}{
+		{"500m", 0.5, "500m", true},
+		{"2", 2, "2", true},
+		{"1.5", 1.5, "1500m", true},
+		{"0.0015", 0.0015, "0.0015", true},
+		{"1m", 0.001, "1m", true},
+		{"10k", 0, "", false},
+		{"m", 0, "", false},
+	}
+	for _, test := range tests {
+		var v float64
+		err := parseFlag(Quantity(&v, "cpu", 0, CPUUnits, "").Must(), "--cpu", test.Arg)
+		if assertBool(t, test.OK, err == nil) && test.OK {
+			assertFloat64(t, test.Expect, v)
+			assertString(t, test.Format, CPUUnits.format(v))
+		}
+	}
+
+	var n int64
+	flag := Quantity(&n, "memory", 256<<20, ByteUnits, "").Must()
+	assertString(t, "256Mi", valueString(flag.Value))
+	if assertFlagParses(t, flag, "--memory=1.5Gi") {
+		assertInt64(t, 1536<<20, n)
+		assertString(t, "1536Mi", ByteUnits.format(float64(n)))
+	}
+	for _, arg := range []string{"--memory=8Ei", "--memory=1.5"} {
+		if err := parseFlag(flag, arg); err == nil {
+			t.Errorf("%s: expected error", arg)
+		}
+	}
+}
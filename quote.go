@@ -0,0 +1,63 @@
+package xflags
+
+import "strings"
+
+// quotePOSIX returns args joined as a command line that a POSIX shell, or
+// SplitCommandLine, splits into args.
+func quotePOSIX(args []string) string {
+	var sb strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if arg != "" && strings.Trim(arg, posixSafeChars) == "" {
+			sb.WriteString(arg)
+			continue
+		}
+		sb.WriteByte('\'')
+		sb.WriteString(strings.ReplaceAll(arg, "'", `'\''`))
+		sb.WriteByte('\'')
+	}
+	return sb.String()
+}
+
+// posixSafeChars are the characters that need no quoting in a POSIX shell.
+const posixSafeChars = "abcdefghijklmnopqrstuvwxyz" +
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+	"0123456789" +
+	"@%+=:,./_-"
+
+// quoteWindows returns args joined as a command line that is split into args
+// by CommandLineToArgvW and the Microsoft C runtime.
+func quoteWindows(args []string) string {
+	var sb strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if arg != "" && !strings.ContainsAny(arg, " \t\n\v\"") {
+			sb.WriteString(arg)
+			continue
+		}
+		sb.WriteByte('"')
+		backslashes := 0
+		for j := 0; j < len(arg); j++ {
+			switch arg[j] {
+			case '\\':
+				backslashes++
+				continue
+			case '"':
+				// backslashes preceding a quote are escaped, as is the quote
+				sb.WriteString(strings.Repeat(`\`, backslashes*2+1))
+			default:
+				sb.WriteString(strings.Repeat(`\`, backslashes))
+			}
+			backslashes = 0
+			sb.WriteByte(arg[j])
+		}
+		// backslashes preceding the closing quote are escaped
+		sb.WriteString(strings.Repeat(`\`, backslashes*2))
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package xflags
+
+// QuoteArgs returns args joined as a command line that may be copied into a
+// shell to run the same command with the same arguments, such as in a
+// "re-run with: ..." message. Arguments are quoted for a POSIX shell when
+// necessary and the result may also be split by SplitCommandLine.
+func QuoteArgs(args []string) string { return quotePOSIX(args) }
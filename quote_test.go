@@ -0,0 +1,51 @@
+package xflags
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuotePOSIX(t *testing.T) {
+	tests := []struct {
+		Args   []string
+		Expect string
+	}{
+		{nil, ""},
+		{[]string{"deploy", "--replicas=3", "./path/to_file.go"}, "deploy --replicas=3 ./path/to_file.go"},
+		{[]string{""}, "''"},
+		{[]string{"hello world"}, "'hello world'"},
+		{[]string{"it's"}, `'it'\''s'`},
+		{[]string{"$HOME", "*.go", "a\nb"}, "'$HOME' '*.go' 'a\nb'"},
+	}
+	for _, test := range tests {
+		assertString(t, test.Expect, quotePOSIX(test.Args))
+	}
+}
+
+func TestQuoteWindows(t *testing.T) {
+	tests := []struct {
+		Args   []string
+		Expect string
+	}{
+		{nil, ""},
+		{[]string{`C:\Program`, "/flag", "--name=x"}, `C:\Program /flag --name=x`},
+		{[]string{""}, `""`},
+		{[]string{`C:\Program Files\`}, `"C:\Program Files\\"`},
+		{[]string{`say "hi"`}, `"say \"hi\""`},
+		{[]string{`a\"b`}, `"a\\\"b"`},
+		{[]string{`a\\b c`}, `"a\\b c"`},
+	}
+	for _, test := range tests {
+		assertString(t, test.Expect, quoteWindows(test.Args))
+	}
+}
+
+func FuzzQuotePOSIX(f *testing.F) {
+	for _, s := range []string{"deploy\x00--name\x00hello world", "it's\x00", "\\\x00\"'"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		args := strings.Split(s, "\x00")
+		assertStrings(t, args, SplitCommandLine(quotePOSIX(args)))
+	})
+}
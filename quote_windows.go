@@ -0,0 +1,11 @@
+//go:build windows
+
+package xflags
+
+// QuoteArgs returns args joined as a command line that may be used to run the
+// same command with the same arguments, such as in a "re-run with: ..."
+// message. Arguments are quoted by the rules of CommandLineToArgvW and the
+// Microsoft C runtime, which are used by most Windows programs to split their
+// command line. Characters that are special to cmd.exe, such as "%" and "^",
+// are not escaped.
+func QuoteArgs(args []string) string { return quoteWindows(args) }
@@ -0,0 +1,145 @@
+package xflags
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[reflect.Type]func(p interface{}) Value)
+)
+
+// RegisterValueFunc registers functions to parse and format flag values of
+// type T so that flags may be created for T without implementing the Value
+// interface. Registering a type that is already registered replaces the
+// previous registration.
+//
+//	xflags.RegisterValueFunc(semver.NewVersion, func(v *semver.Version) string {
+//		return v.String()
+//	})
+//
+// If format is nil, values are formatted with fmt.Sprint. RegisterValueFunc is
+// typically called from an init function.
+func RegisterValueFunc[T any](parse func(s string) (T, error), format func(v T) string) {
+	if parse == nil {
+		panic("xflags: nil parse function")
+	}
+	if format == nil {
+		format = func(v T) string { return fmt.Sprint(v) }
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[t] = func(p interface{}) Value {
+		return &registeredValue[T]{p: p.(*T), parse: parse, format: format}
+	}
+}
+
+// NewValue returns a Value that stores its value in the variable that p
+// points to. p must be a non-nil pointer to one of the types supported by the
+// flag builders of this package, a type registered with RegisterValueFunc or
+// a type that implements encoding.TextUnmarshaler.
+func NewValue(p interface{}) (Value, error) {
+	switch p := p.(type) {
+	case *bool:
+		return (*boolValue)(p), nil
+	case *time.Duration:
+		return (*durationValue)(p), nil
+	case *float64:
+		return (*float64Value)(p), nil
+	case *int:
+		return (*intValue)(p), nil
+	case *int64:
+		return (*int64Value)(p), nil
+	case *string:
+		return (*stringValue)(p), nil
+	case *[]string:
+		return newStringSliceValue(*p, p), nil
+	case *uint:
+		return (*uintValue)(p), nil
+	case *uint64:
+		return (*uint64Value)(p), nil
+	}
+	rv := reflect.ValueOf(p)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errorf("value must be a non-nil pointer: %T", p)
+	}
+	registryMu.RLock()
+	fn, ok := registry[rv.Type().Elem()]
+	registryMu.RUnlock()
+	if ok {
+		return fn(p), nil
+	}
+	if u, ok := p.(encoding.TextUnmarshaler); ok {
+		return &textValue{p: u}, nil
+	}
+	return nil, errorf("no value function registered for type: %s", rv.Type().Elem())
+}
+
+// Of returns a FlagBuilder that can be used to define a flag of any type
+// supported by NewValue with specified name, default value, and usage string.
+// The argument p points to a variable of type T in which to store the value of
+// the flag. If p is nil, a new variable is allocated.
+//
+//	var addr net.IP
+//	xflags.Of(&addr, "addr", net.IPv4(127, 0, 0, 1), "Address to listen on")
+func Of[T any](p *T, name string, value T, usage string) *FlagBuilder {
+	if p == nil {
+		p = new(T)
+	}
+	*p = value
+	v, err := NewValue(p)
+	if err != nil {
+		c := Var(nil, name, usage)
+		c.err = errorf("%s: %v", name, errStr(err))
+		return c
+	}
+	c := Var(v, name, usage)
+	if _, ok := v.(*stringSliceValue); ok {
+		c.NArgs(0, 0)
+	}
+	return c
+}
+
+// textValue is a Value for types that implement encoding.TextUnmarshaler.
+type textValue struct {
+	p encoding.TextUnmarshaler
+}
+
+func (p *textValue) String() string {
+	v := reflect.ValueOf(p.p).Elem().Interface()
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+func (p *textValue) Get() interface{} { return reflect.ValueOf(p.p).Elem().Interface() }
+
+func (p *textValue) Set(s string) error { return p.p.UnmarshalText([]byte(s)) }
+
+// registeredValue is a Value for a type registered with RegisterValueFunc.
+type registeredValue[T any] struct {
+	p      *T
+	parse  func(string) (T, error)
+	format func(T) string
+}
+
+func (p *registeredValue[T]) String() string { return p.format(*p.p) }
+
+func (p *registeredValue[T]) Get() interface{} { return *p.p }
+
+func (p *registeredValue[T]) Set(s string) error {
+	v, err := p.parse(s)
+	if err != nil {
+		return err
+	}
+	*p.p = v
+	return nil
+}
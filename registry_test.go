@@ -0,0 +1,127 @@
+package xflags
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testPoint struct{ X, Y int }
+
+func parseTestPoint(s string) (testPoint, error) {
+	var p testPoint
+	_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+	return p, err
+}
+
+func TestRegisterValueFunc(t *testing.T) {
+	RegisterValueFunc(parseTestPoint, func(p testPoint) string {
+		return fmt.Sprintf("%d,%d", p.X, p.Y)
+	})
+	RegisterValueFunc(url.Parse, nil)
+
+	var p testPoint
+	var u *url.URL
+	pointValue, err := NewValue(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urlValue, err := NewValue(&u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := NewCommand("test", "").
+		Flags(
+			Var(pointValue, "point", ""),
+			Var(urlValue, "url", ""),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"--point=1,2", "--url=https://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 1, int64(p.X))
+	assertInt64(t, 2, int64(p.Y))
+	assertString(t, "1,2", pointValue.(fmt.Stringer).String())
+	assertString(t, "example.com", u.Host)
+	assertString(t, "https://example.com/", urlValue.(fmt.Stringer).String())
+
+	var argErr *ArgumentError
+	assertErrorAs(t, parseFlag(Var(pointValue, "point", "").Must(), "--point=x"), &argErr)
+}
+
+func TestNewValue(t *testing.T) {
+	var b bool
+	var s []string
+	var c complex128
+	for _, p := range []interface{}{&b, &s} {
+		if _, err := NewValue(p); err != nil {
+			t.Errorf("unexpected error for %T: %v", p, err)
+		}
+	}
+	for _, p := range []interface{}{nil, c, &c, (*testPoint)(nil)} {
+		if _, err := NewValue(p); err == nil {
+			t.Errorf("expected error for %T", p)
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	var n int
+	var names []string
+	var ip net.IP
+	var p testPoint
+	var start time.Time
+	cmd := NewCommand("test", "").
+		Flags(
+			Of(&n, "", 1, "").ShortName("n"),
+			Of(&names, "name", nil, ""),
+			Of(&ip, "ip", net.IPv4(127, 0, 0, 1), "").ShowDefault(),
+			Of(&p, "point", testPoint{}, ""),
+			Of(&start, "start", time.Time{}, ""),
+		).
+		Must()
+	assertInt64(t, 1, int64(n))
+	assertString(t, "127.0.0.1", ip.String())
+	_, err := cmd.Parse([]string{
+		"-n=2",
+		"--name=foo",
+		"--name=bar",
+		"--ip=::1",
+		"--point=3,4",
+		"--start=2022-01-26T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 2, int64(n))
+	assertStrings(t, []string{"foo", "bar"}, names)
+	assertString(t, "::1", ip.String())
+	assertInt64(t, 4, int64(p.Y))
+	assertInt64(t, 2022, int64(start.Year()))
+
+	flag := Of[net.IP](nil, "ip", net.IPv6loopback, "").Must()
+	assertString(t, "::1", flag.Value.(fmt.Stringer).String())
+
+	if _, err := Of[complex128](nil, "c", 0, "").Flag(); err == nil {
+		t.Errorf("expected error for unsupported type")
+	}
+}
+
+func ExampleOf() {
+	var ip net.IP
+
+	cmd := NewCommand("ping", "").
+		Flags(
+			// net.IP implements encoding.TextUnmarshaler
+			Of(&ip, "ip", net.IPv6zero, "IP address to ping"),
+		).
+		HandleFunc(func(args []string) (exitCode int) {
+			fmt.Printf("ping: %s\n", ip)
+			return
+		})
+
+	RunWithArgs(cmd, "--ip=ff02:0000:0000:0000:0000:0000:0000:0001")
+	// Output: ping: ff02::1
+}
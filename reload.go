@@ -0,0 +1,141 @@
+package xflags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// Reload reads the environment variable and sources of each flag of this
+// command and its parents again, allowing long-running handlers to adjust
+// their configuration without restarting. Flags specified on the command
+// line or by a preset are never changed.
+//
+// If the value of a flag has changed since it was last read, the flag is Set
+// to the new value and its OnChange function is called. Builtin slice flags
+// are replaced by the new values rather than appended to. Flags whose value has
+// been removed from the environment or their source retain their current
+// value.
+//
+// Reload may only be called on a command returned by Parse. It is safe to call
+// Reload concurrently but handlers must synchronize their own access to flag
+// values.
+func (c *Command) Reload() error {
+	if c.parser == nil {
+		return errorf("%s: command has not been parsed", c.Name)
+	}
+	return c.parser.reload()
+}
+
+func (c *argParser) reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sources := c.sources()
+	return c.walkFlags(func(flag *Flag, path string) error {
+		oldValue, resolved := c.resolved[flag]
+		if !resolved {
+			if c.flagsSeen[flag.name()] > 0 {
+				return nil // specified on the command line
+			}
+			if s, ok := flag.Value.(fmt.Stringer); ok {
+				oldValue = s.String()
+			}
+		}
+		values, src, ok, err := c.lookup(sources, flag, path)
+		if err != nil {
+			return err
+		}
+		newValue := strings.Join(values, ",")
+		if !ok || (resolved && newValue == oldValue) {
+			return nil
+		}
+		if v, ok := flag.Value.(resetter); ok {
+			v.reset()
+		}
+		for _, s := range values {
+			if err := c.setFlagFrom(provenanceOf(src), src, flag, s); err != nil {
+				return err
+			}
+		}
+		c.resolved[flag] = newValue
+		if flag.OnChange != nil {
+			flag.OnChange(oldValue, newValue)
+		}
+		return nil
+	})
+}
+
+// WatchSignals calls cmd.Reload each time the process receives one of the
+// given signals, or SIGHUP if none are given, until ctx is done. Any errors
+// are written to the configured stderr of cmd. When compiled to WebAssembly,
+// WatchSignals only waits for ctx to be done if no signals are given.
+func WatchSignals(ctx context.Context, cmd *Command, sig ...os.Signal) {
+	if len(sig) == 0 {
+		sig = reloadSignals
+	}
+	if len(sig) == 0 {
+		<-ctx.Done()
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			cmd.reload()
+		}
+	}
+}
+
+// WatchFiles calls cmd.Reload each time the modification time or size of any
+// of the given files changes, until ctx is done. Files are checked at the
+// given interval. Any errors are written to the configured stderr of cmd.
+//
+// WatchFiles is intended for use with file-based sources, such as mounted
+// Kubernetes ConfigMaps.
+func WatchFiles(ctx context.Context, cmd *Command, interval time.Duration, paths ...string) {
+	stat := func() map[string]string {
+		m := make(map[string]string, len(paths))
+		for _, path := range paths {
+			if fi, err := os.Stat(path); err == nil {
+				m[path] = fmt.Sprintf("%d:%d", fi.ModTime().UnixNano(), fi.Size())
+			}
+		}
+		return m
+	}
+	last := stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := stat()
+			changed := len(current) != len(last)
+			for path, s := range current {
+				if last[path] != s {
+					changed = true
+				}
+			}
+			last = current
+			if changed {
+				cmd.reload()
+			}
+		}
+	}
+}
+
+// reload calls Reload and writes any error to stderr.
+func (c *Command) reload() {
+	if err := c.Reload(); err != nil {
+		_, stderr := c.output()
+		fmt.Fprintf(stderr, "Error: %s\n", errStr(err))
+	}
+}
@@ -0,0 +1,132 @@
+package xflags
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	var foo, bar, baz string
+	var changes []string
+	onChange := func(oldValue, newValue string) {
+		changes = append(changes, oldValue+"->"+newValue)
+	}
+	values := map[string]string{"foo": "one", "bar": "one"}
+	os.Setenv("XFLAGS_TEST_BAZ", "one")
+	defer os.Unsetenv("XFLAGS_TEST_BAZ")
+	cmd, err := NewCommand("test", "").
+		Flags(
+			String(&foo, "foo", "", "").OnChange(onChange),
+			String(&bar, "bar", "", "").OnChange(onChange),
+			String(&baz, "baz", "", "").Env("XFLAGS_TEST_BAZ").OnChange(onChange),
+		).
+		Sources(MapSource("test", values)).
+		Must().
+		Parse([]string{"--bar=cli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "one", foo)
+	assertString(t, "cli", bar)
+	assertString(t, "one", baz)
+
+	values["foo"] = "two"
+	values["bar"] = "two"
+	os.Setenv("XFLAGS_TEST_BAZ", "two")
+	if err := cmd.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "two", foo)
+	assertString(t, "cli", bar)
+	assertString(t, "two", baz)
+	if assertInt64(t, 2, int64(len(changes))) {
+		for _, s := range changes {
+			assertString(t, "one->two", s)
+		}
+	}
+
+	// no changes
+	changes = nil
+	if err := cmd.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 0, int64(len(changes)))
+
+	if err := NewCommand("test", "").Must().Reload(); err == nil {
+		t.Errorf("expected error reloading a command that was not parsed")
+	}
+}
+
+func TestReloadSlice(t *testing.T) {
+	var foo []string
+	values := map[string]string{"foo": "one"}
+	cmd, err := NewCommand("test", "").
+		Flags(Strings(&foo, "foo", []string{"default"}, "")).
+		Sources(MapSource("test", values)).
+		Must().
+		Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"one"}, foo)
+
+	values["foo"] = "two"
+	if err := cmd.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"two"}, foo)
+}
+
+type fileSource string
+
+func (c fileSource) Name() string { return string(c) }
+
+func (c fileSource) Lookup(flagPath string) (string, bool) {
+	b, err := ioutil.ReadFile(filepath.Join(string(c), flagPath))
+	return string(b), err == nil
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(path, []byte("one"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var foo string
+	changed := make(chan string, 1)
+	cmd, err := NewCommand("test", "").
+		Flags(
+			String(&foo, "foo", "", "").OnChange(func(oldValue, newValue string) {
+				changed <- newValue
+			}),
+		).
+		Sources(fileSource(dir)).
+		Must().
+		Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "one", foo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchFiles(ctx, cmd, time.Millisecond, path)
+	time.Sleep(10 * time.Millisecond)
+	if err := ioutil.WriteFile(path, []byte("three"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case s := <-changed:
+		assertString(t, "three", s)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for file change")
+	}
+}
@@ -0,0 +1,281 @@
+// Package remote runs the commands of a program on behalf of clients over
+// HTTP, so that automation can run internal tools without shelling out or
+// installing them.
+//
+// The package is experimental and its protocol may change.
+//
+// A server serves a command tree with Handler, or with the "remote" command
+// returned by Command:
+//
+//	var App = xflags.NewCommand("app", "").
+//		Subcommands(
+//			remote.Expose(deployCommand),
+//			adminCommand,
+//			remote.Command(),
+//		)
+//
+//	$ app remote --remote-listen=localhost:8080
+//
+// Clients POST a Request as JSON and receive a Response as JSON:
+//
+//	$ curl -d '{"command": ["deploy"], "flags": {"env": "prod"}}' localhost:8080
+//	{"stdout": "deployed to prod\n", "stderr": "", "exit_code": 0}
+//
+// Only commands that are exposed with Expose, or whose parent is exposed, may
+// be run; requests for other commands are answered with 403 Forbidden. The
+// "remote" command itself is never exposed.
+//
+// Each request is parsed with xflags.Command.Exec, so handlers must read their
+// flags from their xflags.Invocation rather than from the variables that the
+// flags were defined with, and requests may run concurrently. Servers should
+// be protected by the same means as any other administrative endpoint, as
+// they run the exposed commands on behalf of any client.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+// Annotation is the annotation of commands that may be run by clients, set by
+// Expose and Conceal.
+const Annotation = "remote.exposed"
+
+// maxRequestSize is the maximum size of the body of a request.
+const maxRequestSize = 10 << 20
+
+// listenPrefix is the prefix of the flags registered by Command.
+const listenPrefix = "remote"
+
+// shutdownTimeout is how long requests may take to complete after the remote
+// command is interrupted.
+const shutdownTimeout = 30 * time.Second
+
+// Expose allows clients to run the command and its subcommands.
+func Expose(cmd *xflags.CommandBuilder) *xflags.CommandBuilder {
+	return cmd.Annotate(Annotation, "true")
+}
+
+// Conceal prevents clients from running the command and its subcommands, even
+// if a parent command is exposed.
+func Conceal(cmd *xflags.CommandBuilder) *xflags.CommandBuilder {
+	return cmd.Annotate(Annotation, "false")
+}
+
+// Exposed reports whether clients may run cmd: the nearest of cmd and its
+// parents that is annotated with Expose or Conceal decides.
+func Exposed(cmd *xflags.Command) bool {
+	for p := cmd; p != nil; p = p.Parent {
+		if v, ok := p.Annotations[Annotation]; ok {
+			return v == "true"
+		}
+	}
+	return false
+}
+
+// Request is a request to run a command.
+type Request struct {
+	// Command is the path of the command to run, relative to the root of the
+	// command tree, such as ["db", "migrate"].
+	Command []string `json:"command"`
+
+	// Flags are the flags of the command by name, without dashes. They are
+	// passed as "--name=value".
+	Flags map[string]string `json:"flags,omitempty"`
+
+	// Args are passed after the flags, as on a command line. They may include
+	// flags that are specified more than once.
+	Args []string `json:"args,omitempty"`
+
+	// Stdin is the input of the command.
+	Stdin string `json:"stdin,omitempty"`
+}
+
+// CommandLine returns the arguments that r is parsed from.
+func (r *Request) CommandLine() ([]string, error) {
+	args := make([]string, 0, len(r.Command)+len(r.Flags)+len(r.Args))
+	for _, name := range r.Command {
+		if name == "" || strings.HasPrefix(name, "-") {
+			return nil, fmt.Errorf("invalid command: %q", name)
+		}
+		args = append(args, name)
+	}
+	names := make([]string, 0, len(r.Flags))
+	for name := range r.Flags {
+		if name == "" || strings.HasPrefix(name, "-") || strings.Contains(name, "=") {
+			return nil, fmt.Errorf("invalid flag name: %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--"+name+"="+r.Flags[name])
+	}
+	return append(args, r.Args...), nil
+}
+
+// Response is the result of running a command.
+type Response struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Handler returns an http.Handler that runs the exposed commands of the tree
+// of cmd for each POST request and responds with their output and exit code.
+// Requests that are not valid are answered with 400 Bad Request and requests
+// for commands that are not exposed with 403 Forbidden; the status of
+// requests that ran a command is 200 OK, whatever its exit code.
+func Handler(cmd *xflags.Command) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req Request
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		args, err := req.CommandLine()
+		if err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if target := target(cmd, args); !Exposed(target) {
+			msg := fmt.Sprintf("'%s' is not available remotely", commandPath(target))
+			http.Error(w, msg, http.StatusForbidden)
+			return
+		}
+		var stdout, stderr bytes.Buffer
+		exitCode := cmd.Exec(r.Context(), args, strings.NewReader(req.Stdin), &stdout, &stderr)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&Response{
+			Stdout:   stdout.String(),
+			Stderr:   stderr.String(),
+			ExitCode: exitCode,
+		})
+	})
+}
+
+// target returns the command of the tree of cmd that args invoke, or the
+// command that reported an error for them.
+func target(cmd *xflags.Command, args []string) *xflags.Command {
+	inv, err := cmd.ParseInvocation(args)
+	var helpErr *xflags.HelpError
+	var argErr *xflags.ArgumentError
+	switch {
+	case err == nil:
+		return inv.Target()
+	case errors.As(err, &helpErr):
+		return helpErr.Cmd
+	case errors.As(err, &argErr) && argErr.Cmd != nil:
+		return argErr.Cmd
+	}
+	return cmd
+}
+
+// commandPath returns the names of cmd and its parents, separated by spaces.
+func commandPath(cmd *xflags.Command) string {
+	var path []string
+	for p := cmd; p != nil; p = p.Parent {
+		path = append([]string{p.Name}, path...)
+	}
+	return strings.Join(path, " ")
+}
+
+// Client runs commands on a server.
+type Client struct {
+	// URL is the URL of the server.
+	URL string
+
+	// HTTPClient sends the requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// Run runs the command of req on the server. An error is returned if the
+// command could not be run, but not if it exited with a non-zero code.
+func (c *Client) Run(ctx context.Context, req *Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return nil, fmt.Errorf("remote: %s: %s", httpResp.Status, strings.TrimSpace(string(b)))
+	}
+	var resp Response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("remote: invalid response: %v", err)
+	}
+	return &resp, nil
+}
+
+// Command returns a "remote" command that serves the command tree that it is
+// added to with Handler, on the address given with the --remote-listen,
+// --remote-port, --remote-unix-socket or --remote-systemd-socket flag, until
+// it is interrupted. The command is concealed, so that clients cannot start
+// further servers.
+func Command() *xflags.CommandBuilder {
+	return Conceal(xflags.NewCommand("remote", "Serve the commands of this program over HTTP")).
+		ListenFlags(listenPrefix).
+		HandleContext(func(ctx context.Context, args []string) int {
+			out := xflags.OutputFrom(ctx)
+			l := xflags.ListenerFrom(ctx, listenPrefix)
+			if l == nil {
+				fmt.Fprintf(out.Stderr, "Error: --%s-listen is required\n", listenPrefix)
+				return 1
+			}
+			root := xflags.InvocationFrom(ctx).Target()
+			for root.Parent != nil {
+				root = root.Parent
+			}
+			srv := &http.Server{Handler: Handler(root)}
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+			errc := make(chan error, 1)
+			go func() { errc <- srv.Serve(l) }()
+			select {
+			case err := <-errc:
+				fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+				return 1
+			case <-ctx.Done():
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		})
+}
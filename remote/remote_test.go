@@ -0,0 +1,132 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	cmd := xflags.NewCommand("app", "").
+		Output(io.Discard, io.Discard).
+		Subcommands(
+			Expose(xflags.NewCommand("greet", "").
+				Flags(
+					xflags.String(nil, "greeting", "Hello", ""),
+					xflags.Strings(nil, "name", nil, ""),
+				).
+				HandleContext(func(ctx context.Context, args []string) int {
+					inv := xflags.InvocationFrom(ctx)
+					out := xflags.OutputFrom(ctx)
+					stdin, _ := io.ReadAll(out.Stdin)
+					names := inv.Get("name").([]string)
+					fmt.Fprintf(out.Stdout, "%s, %s%s\n", inv.Get("greeting"), strings.Join(names, " and "), stdin)
+					if len(names) == 0 {
+						fmt.Fprintf(out.Stderr, "no names\n")
+						return 2
+					}
+					return 0
+				})),
+			xflags.NewCommand("admin", "").
+				HandleFunc(func(args []string) int { return 0 }),
+			Command(),
+		).
+		Must()
+	srv := httptest.NewServer(Handler(cmd))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRun(t *testing.T) {
+	srv := newServer(t)
+	client := &Client{URL: srv.URL}
+	tests := []struct {
+		req  Request
+		resp Response
+	}{
+		{
+			Request{
+				Command: []string{"greet"},
+				Flags:   map[string]string{"greeting": "Hi"},
+				Args:    []string{"--name=Ann", "--name", "Bob"},
+				Stdin:   "!",
+			},
+			Response{Stdout: "Hi, Ann and Bob!\n"},
+		},
+		{
+			Request{Command: []string{"greet"}},
+			Response{Stdout: "Hello, \n", Stderr: "no names\n", ExitCode: 2},
+		},
+		{
+			Request{Command: []string{"greet"}, Flags: map[string]string{"zzz": "1"}},
+			Response{Stderr: "Argument error: unrecognized argument: --zzz\n", ExitCode: 1},
+		},
+	}
+	for _, test := range tests {
+		resp, err := client.Run(context.Background(), &test.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *resp != test.resp {
+			t.Errorf("%v: expected %+v, got %+v", test.req, test.resp, *resp)
+		}
+	}
+}
+
+func TestHandlerInvalidRequest(t *testing.T) {
+	srv := newServer(t)
+	client := &Client{URL: srv.URL}
+	for _, req := range []Request{
+		{Command: []string{"--greet"}},
+		{Command: []string{"greet"}, Flags: map[string]string{"a=b": "c"}},
+	} {
+		_, err := client.Run(context.Background(), &req)
+		if err == nil || !strings.Contains(err.Error(), "400 Bad Request: invalid request") {
+			t.Errorf("%v: expected bad request, got: %v", req, err)
+		}
+	}
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerNotExposed(t *testing.T) {
+	srv := newServer(t)
+	client := &Client{URL: srv.URL}
+	for _, req := range []Request{
+		{Command: []string{"admin"}},
+		{Command: []string{"admin"}, Args: []string{"--help"}},
+		{Command: []string{"remote"}, Flags: map[string]string{"remote-port": "8080"}},
+		{Command: []string{"nope"}},
+		{},
+	} {
+		_, err := client.Run(context.Background(), &req)
+		if err == nil || !strings.Contains(err.Error(), "403 Forbidden") {
+			t.Errorf("%v: expected forbidden, got: %v", req, err)
+		}
+	}
+}
+
+func TestCommandConcealed(t *testing.T) {
+	cmd := Expose(xflags.NewCommand("app", "")).
+		Subcommands(Command()).
+		Must()
+	if !Exposed(cmd) {
+		t.Error("expected app to be exposed")
+	}
+	if Exposed(cmd.Subcommands[0]) {
+		t.Error("expected app remote to be concealed")
+	}
+}
@@ -0,0 +1,80 @@
+package xflags
+
+import (
+	"context"
+	"time"
+)
+
+// BackoffFunc returns the duration to wait before the given retry attempt.
+// The first retry is attempt 2.
+type BackoffFunc func(attempt int) time.Duration
+
+// ConstantBackoff returns a BackoffFunc that always waits for d.
+func ConstantBackoff(d time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration { return d }
+}
+
+// ExponentialBackoff returns a BackoffFunc that waits for base before the
+// first retry and doubles the wait for each subsequent retry, up to max. If
+// max is zero, the wait is not capped.
+func ExponentialBackoff(base, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 2; i < attempt; i++ {
+			d *= 2
+			if max > 0 && d >= max {
+				return max
+			}
+		}
+		if max > 0 && d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// Retry specifies that the handler of this command or its subcommands is
+// called up to attempts times if it returns an error. Only handlers registered
+// with HandleE are retried. The handler is retried if retryIf is nil or
+// returns true for the returned error. Before each retry, Retry waits for the
+// duration returned by backoff, if not nil, or until the context is cancelled.
+//
+// The number of the current attempt is available to the handler from
+// Invocation.Attempt. This is useful for commands that call flaky remote
+// services, such as in CI pipelines.
+func (c *CommandBuilder) Retry(
+	attempts int,
+	backoff BackoffFunc,
+	retryIf func(err error) bool,
+) *CommandBuilder {
+	if attempts < 1 {
+		return c.error(errorf("%s: retry attempts must be at least 1", c.cmd.Name))
+	}
+	return c.Use(func(next ContextHandlerFunc) ContextHandlerFunc {
+		return func(ctx context.Context, args []string) int {
+			inv := InvocationFrom(ctx)
+			for {
+				inv.err = nil
+				exitCode := next(ctx, args)
+				err := inv.err
+				if err == nil || inv.attempt >= attempts {
+					return exitCode
+				}
+				if retryIf != nil && !retryIf(err) {
+					return exitCode
+				}
+				inv.attempt++
+				if backoff == nil {
+					continue
+				}
+				t := time.NewTimer(backoff(inv.attempt))
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return exitCode
+				case <-t.C:
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testExitError int
+
+func (e testExitError) Error() string { return "exit error" }
+func (e testExitError) ExitCode() int { return int(e) }
+
+func TestRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	errFatal := testExitError(3)
+	tests := []struct {
+		Errors       []error
+		ExpectCalls  int
+		ExpectCode   int
+		ExpectStderr string
+	}{
+		{[]error{nil}, 1, 0, ""},
+		{[]error{errFlaky, nil}, 2, 0, ""},
+		{[]error{errFlaky, errFlaky, errFlaky}, 3, 1, "Error: flaky\n"},
+		{[]error{errFlaky, errFatal}, 2, 3, "Error: exit error\n"},
+	}
+	for _, test := range tests {
+		stderr := new(bytes.Buffer)
+		attempts := make([]int, 0)
+		cmd := NewCommand("app", "").
+			Output(new(bytes.Buffer), stderr).
+			Retry(3, ConstantBackoff(time.Millisecond), func(err error) bool {
+				return err == errFlaky
+			}).
+			HandleE(func(ctx context.Context, args []string) error {
+				n := InvocationFrom(ctx).Attempt()
+				attempts = append(attempts, n)
+				return test.Errors[n-1]
+			}).
+			Must()
+		assertInt64(t, int64(test.ExpectCode), int64(cmd.Run(nil)))
+		assertInt64(t, int64(test.ExpectCalls), int64(len(attempts)))
+		for i, n := range attempts {
+			assertInt64(t, int64(i+1), int64(n))
+		}
+		assertString(t, test.ExpectStderr, stderr.String())
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	expect := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i, d := range expect {
+		if actual := backoff(i + 2); actual != d {
+			t.Errorf("attempt %d: expected %v, got %v", i+2, d, actual)
+		}
+	}
+}
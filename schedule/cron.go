@@ -0,0 +1,213 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch is how far ahead Next searches for a time that matches a
+// schedule, which covers the leap years of "0 0 29 2 *".
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	spec   string
+	every  time.Duration // interval of "@every" schedules
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDay bool // either the day of month or the day of week begins with "*"
+}
+
+// field describes a field of a cron expression.
+type field struct {
+	name     string
+	min, max int
+	names    []string // names of the values from min, if any
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{
+		name:  "month",
+		min:   1,
+		max:   12,
+		names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"},
+	}
+	dowField = field{
+		name:  "day of week",
+		min:   0,
+		max:   7, // 0 and 7 are Sunday
+		names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"},
+	}
+)
+
+// descriptors are the predefined schedules that may be used in place of an
+// expression.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression with the five fields minute, hour, day of
+// month, month and day of week, such as "*/5 * * * *". Each field is "*", a
+// value, a range such as "1-5", or a comma-separated list of them, and values
+// and ranges may be followed by a step, such as "*/15" or "0-30/10". Months
+// and days of week may be named by their first three letters, such as "jan"
+// or "mon". If both the day of month and the day of week are restricted, a day
+// matches if either of them matches, as in Vixie cron.
+//
+// The descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight and
+// @hourly, and "@every DURATION", such as "@every 90s", are also accepted.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if s, ok := descriptors[strings.ToLower(spec)]; ok {
+		sched, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		sched.spec = spec
+		return sched, nil
+	}
+	if d, ok := cutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("invalid schedule: %q: interval must be a duration of at least 1s", spec)
+		}
+		return &Schedule{spec: spec, every: every}, nil
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule: %q: expected 5 fields, got %d", spec, len(fields))
+	}
+	sched := &Schedule{spec: spec}
+	for i, p := range []struct {
+		field field
+		bits  *uint64
+	}{
+		{minuteField, &sched.minute},
+		{hourField, &sched.hour},
+		{domField, &sched.dom},
+		{monthField, &sched.month},
+		{dowField, &sched.dow},
+	} {
+		bits, err := p.field.parse(fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule: %q: %v", spec, err)
+		}
+		*p.bits = bits
+	}
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1 // Sunday
+	}
+	sched.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+	return sched, nil
+}
+
+// String returns the expression that the schedule was parsed from.
+func (c *Schedule) String() string { return c.spec }
+
+// Next returns the first time after t that matches the schedule, in the
+// location of t, or the zero time if there is none, as for "0 0 30 2 *".
+func (c *Schedule) Next(t time.Time) time.Time {
+	if c.every > 0 {
+		return t.Truncate(time.Second).Add(c.every)
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(maxSearch)
+	for t.Before(end) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Schedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parse returns the bit set of the values of the field that are matched by s.
+func (c field) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %s: %q", c.name, part)
+			}
+			step = n
+		}
+		lo, hi := c.min, c.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = c.value(loStr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = c.value(hiStr); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = c.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range in %s: %q", c.name, part)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or name of the field.
+func (c field) value(s string) (int, error) {
+	for i, name := range c.names {
+		if strings.EqualFold(s, name) {
+			return c.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < c.min || n > c.max {
+		return 0, fmt.Errorf("invalid %s: %q", c.name, s)
+	}
+	return n, nil
+}
+
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(s), prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	start := time.Date(2024, 1, 31, 23, 58, 30, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		Spec   string
+		Expect []string
+	}{
+		{"*/5 * * * *", []string{"2024-02-01 00:00", "2024-02-01 00:05", "2024-02-01 00:10"}},
+		{"30 9 * * mon-fri", []string{"2024-02-01 09:30", "2024-02-02 09:30", "2024-02-05 09:30"}},
+		{"0 0 29 2 *", []string{"2024-02-29 00:00", "2028-02-29 00:00"}},
+		{"0 12 1 * sun", []string{"2024-02-01 12:00", "2024-02-04 12:00", "2024-02-11 12:00"}},
+		{"0 0 * * 7", []string{"2024-02-04 00:00"}},
+		{"15,45 */12 * jan,feb *", []string{"2024-02-01 00:15", "2024-02-01 00:45", "2024-02-01 12:15"}},
+		{"@hourly", []string{"2024-02-01 00:00", "2024-02-01 01:00"}},
+		{"@every 90s", []string{"2024-02-01 00:00", "2024-02-01 00:01"}},
+		{"0 0 30 2 *", []string{"0001-01-01 00:00"}},
+	}
+	for _, test := range tests {
+		s, err := Parse(test.Spec)
+		if err != nil {
+			t.Errorf("%s: %v", test.Spec, err)
+			continue
+		}
+		next := start
+		for _, expect := range test.Expect {
+			next = s.Next(next)
+			if got := next.Format("2006-01-02 15:04"); got != expect {
+				t.Errorf("%s: expected %s, got %s", test.Spec, expect, got)
+				break
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"* * * *":       `invalid schedule: "* * * *": expected 5 fields, got 4`,
+		"60 * * * *":    `invalid schedule: "60 * * * *": invalid minute: "60"`,
+		"* * * foo *":   `invalid schedule: "* * * foo *": invalid month: "foo"`,
+		"*/0 * * * *":   `invalid schedule: "*/0 * * * *": invalid step in minute: "*/0"`,
+		"5-1 * * * *":   `invalid schedule: "5-1 * * * *": invalid range in minute: "5-1"`,
+		"@every 500ms":  `invalid schedule: "@every 500ms": interval must be a duration of at least 1s`,
+		"@fortnightly ": `invalid schedule: "@fortnightly": expected 5 fields, got 1`,
+	}
+	for spec, expect := range tests {
+		_, err := Parse(spec)
+		if err == nil || err.Error() != expect {
+			t.Errorf("%q: expected error %q, got %v", spec, expect, err)
+		}
+	}
+}
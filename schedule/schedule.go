@@ -0,0 +1,171 @@
+// Package schedule provides a "cron" subcommand that invokes another command
+// of the same program on a schedule, in-process, so that utility and sidecar
+// containers need no cron daemon:
+//
+//	var App = xflags.NewCommand("app", "").
+//		Subcommands(
+//			syncCommand,
+//			(&schedule.Runner{}).Command(),
+//		)
+//
+//	$ app cron "*/5 * * * *" -- app sync --fast
+//
+// The command line after "--" starts with the name of the program, which is
+// ignored, and is parsed by the root command on each run with
+// xflags.Command.Exec, so that each run starts from the defaults of the flags
+// rather than the values of the previous run. Scheduled commands must read
+// their flags from their xflags.Invocation. Runs never overlap:
+// if a run is still running when the next run is due, the next run is skipped.
+// Each run may be delayed by a random jitter so that many replicas of a
+// program do not run at once.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+// maxMissed is the maximum number of skipped runs counted after a long run.
+const maxMissed = 1000
+
+// Runner runs a function on a Schedule.
+type Runner struct {
+	// Jitter is the maximum random delay of each run. It is the default of the
+	// --jitter flag of the cron command.
+	Jitter time.Duration
+
+	// MaxRuns stops the Runner after the given number of runs if it is
+	// greater than zero. It is the default of the --max-runs flag of the
+	// cron command.
+	MaxRuns int
+
+	// Stderr receives a message for each run that exits with a non-zero code
+	// and each run that is skipped. If nil, os.Stderr is used.
+	Stderr io.Writer
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Run calls fn each time the schedule s is due until ctx is done or MaxRuns
+// runs were completed. Run returns nil when ctx is done and an error if s is
+// never due.
+func (c *Runner) Run(ctx context.Context, s *Schedule, fn func(ctx context.Context) int) error {
+	now, sleep := c.now, c.sleep
+	if now == nil {
+		now = time.Now
+	}
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	stderr := c.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	var prev time.Time // the time that the previous run was due
+	for runs := 0; c.MaxRuns <= 0 || runs < c.MaxRuns; runs++ {
+		t := now()
+		next := s.Next(t)
+		if n := s.Next(prev); !prev.IsZero() && !n.Before(t) {
+			// not delayed by the jitter or the previous run
+			next = n
+		}
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q is never due", s)
+		}
+		delay := next.Sub(t)
+		if c.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(c.Jitter)))
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil
+		}
+		prev = next
+		if code := fn(ctx); code != 0 {
+			fmt.Fprintf(stderr, "Error: run at %s exited with code %d\n", next.Format(time.RFC3339), code)
+		}
+		if missed := missedRuns(s, next, now()); missed > 0 {
+			fmt.Fprintf(stderr, "Warning: skipped %d run(s) while the run at %s was running\n", missed, next.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// missedRuns returns the number of times that s was due after start and up to
+// end.
+func missedRuns(s *Schedule, start, end time.Time) int {
+	n := 0
+	for t := s.Next(start); !t.IsZero() && !t.After(end) && n < maxMissed; t = s.Next(t) {
+		n++
+	}
+	return n
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Command returns a "cron" command that takes a schedule, as accepted by
+// Parse, and a command line after "--", which starts with the name of the
+// program, and runs the command line with the Exec method of the root command
+// on the schedule until it is interrupted.
+func (c *Runner) Command() *xflags.CommandBuilder {
+	return xflags.NewCommand("cron", "Run a command on a schedule").
+		WithTerminator().
+		Flags(
+			xflags.Duration(nil, "jitter", c.Jitter, "Delay each run by a random duration of up to this duration"),
+			xflags.Int(nil, "max-runs", c.MaxRuns, "Stop after this number of runs"),
+			xflags.String(nil, "schedule", "", "Cron expression, such as \"*/5 * * * *\" or \"@every 1m\"").
+				Positional().
+				Required(),
+		).
+		HandleContext(func(ctx context.Context, args []string) int {
+			// each invocation runs with its own copy of c and the values of
+			// its flags, so that invocations by Exec do not share them
+			inv := xflags.InvocationFrom(ctx)
+			out := xflags.OutputFrom(ctx)
+			r := *c
+			r.Jitter = inv.Get("jitter").(time.Duration)
+			r.MaxRuns = int(inv.Get("max-runs").(int64))
+			if r.Stderr == nil {
+				r.Stderr = out.Stderr
+			}
+			s, err := Parse(inv.Get("schedule").(string))
+			if err != nil {
+				fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			if len(args) < 2 {
+				fmt.Fprintf(out.Stderr, "Error: no command specified after --\n")
+				return 1
+			}
+			root := inv.Target()
+			for root.Parent != nil {
+				root = root.Parent
+			}
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+			err = r.Run(ctx, s, func(ctx context.Context) int {
+				return root.Exec(ctx, args[1:], nil, nil, nil)
+			})
+			if err != nil {
+				fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		})
+}
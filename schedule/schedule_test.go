@@ -0,0 +1,165 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+// fakeClock is a clock for Runner that advances when it sleeps.
+type fakeClock struct {
+	t      time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.t = c.t.Add(d)
+	return nil
+}
+
+func TestRunner(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)}
+	stderr := new(bytes.Buffer)
+	r := &Runner{MaxRuns: 3, Stderr: stderr, now: clock.now, sleep: clock.sleep}
+	s, err := Parse("* * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs []time.Time
+	err = r.Run(context.Background(), s, func(ctx context.Context) int {
+		runs = append(runs, clock.t)
+		if len(runs) == 2 {
+			clock.t = clock.t.Add(150 * time.Second) // overlaps the next two runs
+			return 2
+		}
+		return 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"00:01:00", "00:02:00", "00:05:00"}
+	if len(runs) != len(expect) {
+		t.Fatalf("expected %d runs, got %d", len(expect), len(runs))
+	}
+	for i, run := range runs {
+		if got := run.Format("15:04:05"); got != expect[i] {
+			t.Errorf("run %d: expected %s, got %s", i, expect[i], got)
+		}
+	}
+	expectStderr := "Error: run at 2024-01-01T00:02:00Z exited with code 2\n" +
+		"Warning: skipped 2 run(s) while the run at 2024-01-01T00:02:00Z was running\n"
+	if stderr.String() != expectStderr {
+		t.Errorf("expected stderr %q, got %q", expectStderr, stderr.String())
+	}
+
+	// jitter delays each run by less than Jitter without shifting the schedule
+	start := clock.t
+	r = &Runner{Jitter: time.Second, MaxRuns: 10, now: clock.now, sleep: clock.sleep}
+	s, _ = Parse("@every 1m")
+	runs = nil
+	r.Run(context.Background(), s, func(ctx context.Context) int {
+		runs = append(runs, clock.t)
+		return 0
+	})
+	for i, run := range runs {
+		due := start.Add(time.Duration(i+1) * time.Minute)
+		if d := run.Sub(due); d < 0 || d >= time.Second {
+			t.Errorf("run %d: expected a delay in [0, 1s), got %v", i, d)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var fast []bool
+	var tags [][]string
+	r := &Runner{now: clock.now, sleep: clock.sleep}
+	cmd := xflags.NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		Subcommands(
+			xflags.NewCommand("sync", "").
+				Flags(
+					xflags.Bool(nil, "fast", false, ""),
+					xflags.Strings(nil, "tag", nil, ""),
+				).
+				HandleContext(func(ctx context.Context, args []string) int {
+					inv := xflags.InvocationFrom(ctx)
+					fast = append(fast, inv.Get("fast").(bool))
+					tags = append(tags, inv.Get("tag").([]string))
+					return 0
+				}),
+			r.Command(),
+		).
+		Must()
+	code := cmd.Run([]string{"cron", "--max-runs=2", "*/5 * * * *", "--", "app", "sync", "--fast", "--tag", "a"})
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if len(fast) != 2 || !fast[0] || !fast[1] {
+		t.Errorf("expected 2 fast runs, got %v", fast)
+	}
+	// values of slice flags do not accumulate across runs
+	for i, tags := range tags {
+		if len(tags) != 1 || tags[0] != "a" {
+			t.Errorf("run %d: expected tags [a], got %q", i, tags)
+		}
+	}
+	if got := clock.t.Format("15:04"); got != "00:10" {
+		t.Errorf("expected the last run at 00:10, got %s", got)
+	}
+}
+
+func TestCommandExec(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var runs int
+	r := &Runner{now: clock.now, sleep: clock.sleep}
+	cmd := xflags.NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		Subcommands(
+			xflags.NewCommand("sync", "").
+				HandleFunc(func(args []string) int {
+					runs++
+					return 1
+				}),
+			r.Command(),
+		).
+		Must()
+	stderr := new(bytes.Buffer)
+	code := cmd.Exec(
+		context.Background(),
+		[]string{"cron", "--max-runs=2", "@every 1m", "--", "app", "sync"},
+		nil,
+		nil,
+		stderr,
+	)
+	if code != 0 || runs != 2 {
+		t.Fatalf("expected 2 runs and exit code 0, got %d runs and exit code %d", runs, code)
+	}
+	if !strings.Contains(stderr.String(), "exited with code 1") {
+		t.Errorf("expected errors on the stderr of the invocation, got: %q", stderr)
+	}
+	if r.Stderr != nil {
+		t.Error("expected the stderr of the Runner to be unchanged")
+	}
+
+	// the flags of one invocation do not carry over to the next
+	runs = 0
+	code = cmd.Exec(
+		context.Background(),
+		[]string{"cron", "--max-runs=1", "@every 1m", "--", "app", "sync"},
+		nil,
+		nil,
+		nil,
+	)
+	if code != 0 || runs != 1 {
+		t.Errorf("expected 1 run and exit code 0, got %d runs and exit code %d", runs, code)
+	}
+}
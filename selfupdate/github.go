@@ -0,0 +1,123 @@
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// GitHubResolver is a Resolver that finds the latest release of a repository
+// on GitHub.
+type GitHubResolver struct {
+	Owner string
+	Repo  string
+
+	// AssetName returns the name of the release asset for a platform and
+	// version. If nil, assets are named "<repo>_<goos>_<goarch>" with an
+	// ".exe" suffix on Windows.
+	AssetName func(goos, goarch, version string) string
+
+	// Checksums is the name of the release asset that lists the SHA-256
+	// checksum of each asset. If empty, "checksums.txt" is used.
+	Checksums string
+
+	// SignatureSuffix, if not empty, is appended to the name of the asset to
+	// find the release asset that contains its signature, such as ".sig".
+	SignatureSuffix string
+
+	// Client is used for all requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL is the URL of the GitHub API. If empty, https://api.github.com
+	// is used.
+	BaseURL string
+}
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// Latest implements Resolver.
+func (c *GitHubResolver) Latest(ctx context.Context) (*Release, error) {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/latest", baseURL, c.Owner, c.Repo)
+	data, err := download(ctx, c.client(), url)
+	if err != nil {
+		return nil, err
+	}
+	var gr githubRelease
+	if err := json.Unmarshal(data, &gr); err != nil {
+		return nil, fmt.Errorf("%s: %v", url, err)
+	}
+	assets := make(map[string]string, len(gr.Assets))
+	for _, asset := range gr.Assets {
+		assets[asset.Name] = asset.URL
+	}
+	name := c.assetName(gr.TagName)
+	rel := &Release{Version: gr.TagName, URL: assets[name]}
+	if rel.URL == "" {
+		return nil, fmt.Errorf("release %s has no asset named %s", gr.TagName, name)
+	}
+	checksums := c.Checksums
+	if checksums == "" {
+		checksums = "checksums.txt"
+	}
+	if url := assets[checksums]; url != "" {
+		data, err := download(ctx, c.client(), url)
+		if err != nil {
+			return nil, err
+		}
+		rel.Checksum = findChecksum(data, name)
+	}
+	if c.SignatureSuffix != "" {
+		if url := assets[name+c.SignatureSuffix]; url != "" {
+			if rel.Signature, err = download(ctx, c.client(), url); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rel, nil
+}
+
+func (c *GitHubResolver) assetName(version string) string {
+	if c.AssetName != nil {
+		return c.AssetName(runtime.GOOS, runtime.GOARCH, version)
+	}
+	name := fmt.Sprintf("%s_%s_%s", c.Repo, runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func (c *GitHubResolver) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+// findChecksum returns the checksum of the named file from the output of
+// sha256sum.
+func findChecksum(data []byte, name string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0]
+		}
+	}
+	return ""
+}
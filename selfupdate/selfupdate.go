@@ -0,0 +1,233 @@
+// Package selfupdate provides a "self update" subcommand that replaces the
+// running binary with the latest release of a program.
+//
+// Releases are found by a Resolver. GitHubResolver finds releases published
+// on GitHub with a checksums file in the format produced by sha256sum and
+// tools such as GoReleaser. Each release asset must be an uncompressed
+// executable.
+//
+//	updater := &selfupdate.Updater{
+//		Current:  version,
+//		Resolver: &selfupdate.GitHubResolver{Owner: "acme", Repo: "widgets"},
+//	}
+//	var App = xflags.NewCommand("widgets", "").
+//		Subcommands(updater.Command())
+//
+// Downloaded binaries are always verified against the SHA-256 checksum of the
+// release and, if a Verifier is given, its signature before the running binary
+// is atomically replaced.
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+// Release describes a release of a program for the running platform.
+type Release struct {
+	Version   string
+	URL       string // download URL of the executable
+	Checksum  string // hex encoded SHA-256 checksum of the executable
+	Signature []byte // optional signature of the executable
+}
+
+// Resolver finds the latest release of a program for the running platform.
+type Resolver interface {
+	Latest(ctx context.Context) (*Release, error)
+}
+
+// Verifier verifies the signature of a downloaded executable.
+type Verifier interface {
+	Verify(rel *Release, data []byte) error
+}
+
+// Ed25519Verifier returns a Verifier that verifies Ed25519 signatures of
+// executables with the given public key.
+func Ed25519Verifier(publicKey ed25519.PublicKey) Verifier {
+	return ed25519Verifier(publicKey)
+}
+
+type ed25519Verifier ed25519.PublicKey
+
+func (v ed25519Verifier) Verify(rel *Release, data []byte) error {
+	if len(rel.Signature) == 0 {
+		return errors.New("release is not signed")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(v), data, rel.Signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// Updater replaces the running binary with the latest release.
+type Updater struct {
+	// Current is the version of the running binary.
+	Current string
+
+	Resolver Resolver
+
+	// Verifier, if not nil, verifies the signature of each release.
+	Verifier Verifier
+
+	// Client is used to download releases. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Executable is the path of the binary to replace. If empty, the path of
+	// the running binary is used.
+	Executable string
+}
+
+// Check returns the latest release and true if it differs from the current
+// version.
+func (c *Updater) Check(ctx context.Context) (*Release, bool, error) {
+	rel, err := c.Resolver.Latest(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return rel, rel.Version != c.Current, nil
+}
+
+// Install downloads and verifies rel and atomically replaces the executable.
+func (c *Updater) Install(ctx context.Context, rel *Release) error {
+	data, err := download(ctx, c.client(), rel.URL)
+	if err != nil {
+		return err
+	}
+	if rel.Checksum == "" {
+		return errors.New("release has no checksum")
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != strings.ToLower(rel.Checksum) {
+		return fmt.Errorf("checksum mismatch for %s", rel.URL)
+	}
+	if c.Verifier != nil {
+		if err := c.Verifier.Verify(rel, data); err != nil {
+			return err
+		}
+	}
+	path, err := c.executable()
+	if err != nil {
+		return err
+	}
+	return replace(path, data)
+}
+
+func (c *Updater) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+func (c *Updater) executable() (string, error) {
+	path := c.Executable
+	if path == "" {
+		var err error
+		if path, err = os.Executable(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// Command returns a CommandBuilder for a "self" command with an "update"
+// subcommand that updates the running binary.
+func (c *Updater) Command() *xflags.CommandBuilder {
+	update := xflags.NewCommand("update", "Update to the latest version").
+		Flags(
+			xflags.Bool(nil, "check", false, "Only check for a newer version"),
+			xflags.Bool(nil, "force", false, "Reinstall the current version"),
+		).
+		HandleContext(func(ctx context.Context, args []string) int {
+			inv := xflags.InvocationFrom(ctx)
+			check, _ := inv.Get("check").(bool)
+			force, _ := inv.Get("force").(bool)
+			out := xflags.OutputFrom(ctx)
+			rel, ok, err := c.Check(ctx)
+			if err != nil {
+				fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			if !ok && !force {
+				fmt.Fprintf(out.Stdout, "Already up to date (%s)\n", c.Current)
+				return 0
+			}
+			if check {
+				fmt.Fprintf(out.Stdout, "A new version is available: %s\n", rel.Version)
+				return 0
+			}
+			if err := c.Install(ctx, rel); err != nil {
+				fmt.Fprintf(out.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			fmt.Fprintf(out.Stdout, "Updated to %s\n", rel.Version)
+			return 0
+		})
+	return xflags.NewCommand("self", "Manage this program").Subcommands(update)
+}
+
+func download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// replace atomically replaces the file at path with data by writing a
+// temporary file in the same directory and renaming it.
+func replace(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	dir, name := filepath.Split(path)
+	f, err := ioutil.TempFile(dir, "."+name+".new")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := io.Copy(f, bytes.NewReader(data)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, info.Mode()); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// running executables cannot be replaced on Windows but may be moved
+		old := filepath.Join(dir, "."+name+".old")
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,149 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+func newTestServer(t *testing.T, binary []byte, checksum string, sig []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	mux.HandleFunc("/repos/acme/widgets/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tag_name": "v1.1.0",
+			"assets": []map[string]string{
+				{"name": "widgets", "browser_download_url": srv.URL + "/widgets"},
+				{"name": "widgets.sig", "browser_download_url": srv.URL + "/widgets.sig"},
+				{"name": "checksums.txt", "browser_download_url": srv.URL + "/checksums.txt"},
+			},
+		})
+	})
+	mux.HandleFunc("/widgets", func(w http.ResponseWriter, r *http.Request) { w.Write(binary) })
+	mux.HandleFunc("/widgets.sig", func(w http.ResponseWriter, r *http.Request) { w.Write(sig) })
+	mux.HandleFunc("/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "0000  widgets_other\n%s  widgets\n", checksum)
+	})
+	return srv
+}
+
+func newTestUpdater(t *testing.T, srv *httptest.Server, verifier Verifier) *Updater {
+	exe := filepath.Join(t.TempDir(), "widgets")
+	if err := ioutil.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &Updater{
+		Current: "v1.0.0",
+		Resolver: &GitHubResolver{
+			Owner:           "acme",
+			Repo:            "widgets",
+			AssetName:       func(goos, goarch, version string) string { return "widgets" },
+			SignatureSuffix: ".sig",
+			BaseURL:         srv.URL,
+		},
+		Verifier:   verifier,
+		Executable: exe,
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	binary := []byte("new")
+	sum := sha256.Sum256(binary)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, binary, hex.EncodeToString(sum[:]), ed25519.Sign(priv, binary))
+	defer srv.Close()
+
+	updater := newTestUpdater(t, srv, Ed25519Verifier(pub))
+	cmd := updater.Command().Must()
+	var stdout, stderr bytes.Buffer
+	if code := cmd.Exec(context.Background(), []string{"update", "--check"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	if s := stdout.String(); s != "A new version is available: v1.1.0\n" {
+		t.Errorf("unexpected output: %q", s)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stdout.Reset()
+	if code := cmd.Exec(ctx, []string{"update"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit code 1 with a canceled context, got: %d", code)
+	}
+	if !strings.Contains(stderr.String(), "context canceled") {
+		t.Errorf("unexpected error: %q", stderr.String())
+	}
+	if code := xflags.RunWithArgs(updater.Command(), "update"); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	data, err := ioutil.ReadFile(updater.Executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("executable was not replaced: %q", data)
+	}
+	info, err := os.Stat(updater.Executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("unexpected mode: %v", info.Mode())
+	}
+
+	updater.Current = "v1.1.0"
+	if _, ok, err := updater.Check(context.Background()); err != nil || ok {
+		t.Errorf("expected no update, got: %v, %v", ok, err)
+	}
+}
+
+func TestUpdateVerification(t *testing.T) {
+	binary := []byte("new")
+	sum := sha256.Sum256(binary)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Checksum string
+		Verifier Verifier
+	}{
+		{"", nil},
+		{hex.EncodeToString(make([]byte, 32)), nil},
+		{hex.EncodeToString(sum[:]), Ed25519Verifier(pub)},
+	}
+	for _, test := range tests {
+		srv := newTestServer(t, binary, test.Checksum, []byte("bad signature"))
+		updater := newTestUpdater(t, srv, test.Verifier)
+		ctx := context.Background()
+		rel, _, err := updater.Check(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updater.Install(ctx, rel); err == nil {
+			t.Errorf("expected verification error for checksum %q", test.Checksum)
+		}
+		data, err := ioutil.ReadFile(updater.Executable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "old" {
+			t.Errorf("executable was replaced: %q", data)
+		}
+		srv.Close()
+	}
+}
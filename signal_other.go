@@ -0,0 +1,19 @@
+//go:build !wasm && !windows
+
+package xflags
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// reloadSignals are the signals that WatchSignals watches if none are given.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// notifyInterrupt returns a copy of ctx that is done when the program is
+// interrupted.
+func notifyInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
@@ -0,0 +1,16 @@
+package xflags
+
+import (
+	"context"
+	"os"
+)
+
+// reloadSignals is empty as WebAssembly programs, such as those that run in a
+// browser, receive no signals.
+var reloadSignals []os.Signal
+
+// notifyInterrupt returns a copy of ctx. WebAssembly programs are stopped by
+// canceling ctx rather than by signals.
+func notifyInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package xflags
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// reloadSignals is empty as Windows has no SIGHUP.
+var reloadSignals []os.Signal
+
+// notifyInterrupt returns a copy of ctx that is done when the program is
+// interrupted. The runtime delivers the CTRL_C_EVENT and CTRL_BREAK_EVENT
+// console events, as when ^C is pressed, as os.Interrupt and the
+// CTRL_CLOSE_EVENT, CTRL_LOGOFF_EVENT and CTRL_SHUTDOWN_EVENT events, as when
+// the console window is closed, as SIGTERM.
+func notifyInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+}
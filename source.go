@@ -0,0 +1,124 @@
+package xflags
+
+// Source is an interface that describes any backend which may provide values
+// for flags that were not specified on the command line, such as a
+// configuration file, a remote key-value store or a secrets manager.
+//
+// Values are resolved in the following order of precedence:
+//
+//  1. Arguments specified on the command line
+//  2. The environment variable of the flag, if specified with
+//     FlagBuilder.Env
+//  3. Sources of the invoked command, in the order they were added
+//  4. Sources of each parent command, from the nearest parent to the root
+//  5. The default value of the flag
+type Source interface {
+	// Name returns a short description of the source which is used in error
+	// messages.
+	Name() string
+
+	// Lookup returns the value of the flag at the given path and true, or an
+	// empty string and false if the source has no value for the flag.
+	//
+	// The path of a flag is its name, prefixed by the name of each
+	// subcommand that leads to the command which declares the flag, joined
+	// by ".". The name of the root command is omitted. For example, the path
+	// of the "replicas" flag of the "deploy" subcommand is
+	// "deploy.replicas".
+	Lookup(flagPath string) (value string, ok bool)
+}
+
+// FallibleSource is an optional interface implemented by sources whose
+// lookups may fail, such as sources backed by a remote service. If a source
+// implements FallibleSource, LookupErr is called instead of Lookup and any
+// error is returned by the parser as an ArgumentError for the flag.
+type FallibleSource interface {
+	Source
+	LookupErr(flagPath string) (value string, ok bool, err error)
+}
+
+// MultiSource is an optional interface implemented by sources which may
+// provide several values for one flag, such as a list in a configuration file.
+// If a source implements MultiSource, LookupValues is called instead of Lookup
+// and LookupErr and the flag is Set once for each value.
+type MultiSource interface {
+	Source
+	LookupValues(flagPath string) (values []string, ok bool, err error)
+}
+
+// FlagSource is an optional interface implemented by sources which provide
+// values for flags that are tagged with an annotation, such as the ID of a
+// secret, rather than for flag paths. If a source implements FlagSource,
+// LookupFlag is called before the source is looked up by the path of the flag,
+// which only happens if LookupFlag returns false.
+type FlagSource interface {
+	Source
+	LookupFlag(flag *Flag) (value string, ok bool, err error)
+}
+
+// lookupFlag calls LookupFlag if src is a FlagSource.
+func lookupFlag(src Source, flag *Flag) ([]string, bool, error) {
+	fs, ok := src.(FlagSource)
+	if !ok {
+		return nil, false, nil
+	}
+	s, ok, err := fs.LookupFlag(flag)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	return []string{s}, true, nil
+}
+
+// lookup calls LookupErr if src is a FallibleSource, otherwise Lookup.
+func lookup(src Source, flagPath string) (string, bool, error) {
+	if fs, ok := src.(FallibleSource); ok {
+		return fs.LookupErr(flagPath)
+	}
+	s, ok := src.Lookup(flagPath)
+	return s, ok, nil
+}
+
+// lookupValues calls LookupValues if src is a MultiSource, otherwise lookup.
+func lookupValues(src Source, flagPath string) ([]string, bool, error) {
+	if ms, ok := src.(MultiSource); ok {
+		return ms.LookupValues(flagPath)
+	}
+	s, ok, err := lookup(src, flagPath)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	return []string{s}, true, nil
+}
+
+// MapSource returns a Source that looks up flag values in the given map which
+// is keyed by flag path.
+func MapSource(name string, m map[string]string) Source {
+	return &mapSource{name: name, m: m}
+}
+
+type mapSource struct {
+	name string
+	m    map[string]string
+}
+
+func (c *mapSource) Name() string { return c.name }
+
+func (c *mapSource) Lookup(flagPath string) (string, bool) {
+	s, ok := c.m[flagPath]
+	return s, ok
+}
+
+// flagPath returns the path of a flag declared by cmd as it is passed to
+// Source.Lookup.
+func flagPath(cmd *Command, flag *Flag) string {
+	path := flag.name()
+	for p := cmd; p != nil && p.Parent != nil; p = p.Parent {
+		path = p.Name + "." + path
+	}
+	return path
+}
+
+// sourceErr annotates an error setting a flag with the source of the value.
+func sourceErr(src Source, err error) error {
+	return &xflagsErr{Text: src.Name(), Err: err}
+}
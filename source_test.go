@@ -0,0 +1,117 @@
+package xflags
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSources(t *testing.T) {
+	var foo, bar, baz, qux, quux string
+	os.Setenv("XFLAGS_TEST_BAR", "env")
+	defer os.Unsetenv("XFLAGS_TEST_BAR")
+	parentSrc := MapSource("parent", map[string]string{
+		"foo":     "parent",
+		"bar":     "parent",
+		"sub.baz": "parent",
+		"sub.qux": "parent",
+	})
+	childSrc := MapSource("child", map[string]string{
+		"foo":     "child",
+		"sub.baz": "child",
+	})
+	cmd := NewCommand("test", "").
+		Flags(
+			String(&foo, "foo", "default", ""),
+			String(&bar, "bar", "default", "").Env("XFLAGS_TEST_BAR"),
+		).
+		Sources(parentSrc).
+		Subcommands(
+			NewCommand("sub", "").
+				Flags(
+					String(&baz, "baz", "default", ""),
+					String(&qux, "qux", "default", ""),
+					String(&quux, "quux", "default", ""),
+				).
+				Sources(childSrc),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"sub", "--foo=cli"}); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "cli", foo)
+	assertString(t, "env", bar)
+	assertString(t, "child", baz)
+	assertString(t, "parent", qux)
+	assertString(t, "default", quux)
+}
+
+func TestSourceErrors(t *testing.T) {
+	var n int
+	cmd := NewCommand("test", "").
+		Flags(Int(&n, "", 0, "").ShortName("n")).
+		Sources(MapSource("test source", map[string]string{"n": "one"})).
+		Must()
+	_, err := cmd.Parse(nil)
+	var argErr *ArgumentError
+	if assertErrorAs(t, err, &argErr) {
+		assertString(t, "-n", argErr.Flag.String())
+		assertString(t, "one", argErr.Arg)
+		assertString(
+			t,
+			`-n: test source: strconv.ParseInt: parsing "one": invalid syntax`,
+			argErr.String(),
+		)
+	}
+	if _, err := NewCommand("test", "").Sources(nil).Command(); err == nil {
+		t.Errorf("expected error for nil source")
+	}
+}
+
+type errSource struct{}
+
+func (errSource) Name() string { return "broken" }
+
+func (errSource) Lookup(string) (string, bool) { return "", false }
+
+func (errSource) LookupErr(string) (string, bool, error) {
+	return "", false, errorf("connection refused")
+}
+
+func TestFallibleSource(t *testing.T) {
+	var s string
+	cmd := NewCommand("test", "").
+		Flags(String(&s, "foo", "", "")).
+		Sources(errSource{}).
+		Must()
+	_, err := cmd.Parse(nil)
+	var argErr *ArgumentError
+	if assertErrorAs(t, err, &argErr) {
+		assertString(t, "--foo: broken: connection refused", argErr.String())
+	}
+}
+
+type annotationSource struct{ *mapSource }
+
+func (c annotationSource) LookupFlag(flag *Flag) (string, bool, error) {
+	s, ok := flag.Annotations["test.value"]
+	return s, ok, nil
+}
+
+func TestFlagSource(t *testing.T) {
+	var foo, bar string
+	cmd := NewCommand("test", "").
+		Flags(
+			String(&foo, "foo", "", "").Annotate("test.value", "annotated"),
+			String(&bar, "bar", "", ""),
+		).
+		Sources(annotationSource{&mapSource{
+			name: "test",
+			m:    map[string]string{"foo": "by path", "bar": "by path"},
+		}}).
+		Must()
+	if _, err := cmd.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "annotated", foo)
+	assertString(t, "by path", bar)
+}
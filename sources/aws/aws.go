@@ -0,0 +1,252 @@
+// Package aws provides an xflags.Source that resolves flag values from AWS
+// Secrets Manager and AWS Systems Manager Parameter Store so that credentials
+// need not be stored in environment files or specified on the command line.
+//
+//	src := aws.NewSource(secretsmanager.NewFromConfig(cfg), ssm.NewFromConfig(cfg))
+//
+//	var App = xflags.NewCommand("myapp", "").
+//		Sources(src).
+//		Flags(
+//			aws.FromSecret(
+//				xflags.String(&dbPassword, "db-password", "", "Database password"),
+//				"arn:aws:secretsmanager:us-east-1:123456789012:secret:db",
+//			),
+//		)
+//
+// Flags may also be mapped to secrets by their path with Source.FromSecret,
+// for example to read the flags of a program that cannot be changed:
+//
+//	src.FromSecret("deploy.api-key", "/myapp/prod/api-key")
+//
+// Values are fetched at parse time, only for flags that were not specified on
+// the command line or by an environment variable, and are cached for the
+// lifetime of the Source.
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sdkaws "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/cavaliergopher/xflags"
+)
+
+// DefaultTimeout is the default time limit for fetching a single value.
+const DefaultTimeout = 10 * time.Second
+
+// Annotation is the annotation of flags whose value is read from a secret, set
+// by FromSecret.
+const Annotation = "aws.secret"
+
+// FromSecret tags flag so that its value is read from the secret identified by
+// id by any Source of the command that declares it or of its parents, and
+// declares the flag Secret so that its value is never shown in help messages
+// or reported to telemetry. See Source.FromSecret for the format of id.
+func FromSecret(flag *xflags.FlagBuilder, id string) *xflags.FlagBuilder {
+	return flag.Annotate(Annotation, id).Secret()
+}
+
+// SecretsManagerClient is the subset of *secretsmanager.Client used by Source.
+type SecretsManagerClient interface {
+	GetSecretValue(
+		ctx context.Context,
+		params *secretsmanager.GetSecretValueInput,
+		optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// SSMClient is the subset of *ssm.Client used by Source.
+type SSMClient interface {
+	GetParameter(
+		ctx context.Context,
+		params *ssm.GetParameterInput,
+		optFns ...func(*ssm.Options),
+	) (*ssm.GetParameterOutput, error)
+}
+
+// Source is an xflags.Source that resolves flag values from AWS Secrets Manager
+// secrets and SSM parameters.
+type Source struct {
+	// Timeout is the time limit for fetching a single value. If zero,
+	// DefaultTimeout is used.
+	Timeout time.Duration
+
+	secretsManager SecretsManagerClient
+	ssm            SSMClient
+
+	mu      sync.Mutex
+	ids     map[string]string // flag path to secret ID
+	cache   map[string]string // secret ID to value
+	fetches map[string]*fetch // secret ID to fetch in progress
+}
+
+// fetch is a fetch of a secret that other lookups of the same secret wait for.
+type fetch struct {
+	done chan struct{} // closed when value and err are set
+	s    string
+	err  error
+}
+
+var (
+	_ xflags.FallibleSource = (*Source)(nil)
+	_ xflags.FlagSource     = (*Source)(nil)
+)
+
+// NewSource returns a Source that fetches secrets using the given clients.
+// Either client may be nil if no values are fetched from that service.
+func NewSource(secretsManager SecretsManagerClient, ssm SSMClient) *Source {
+	return &Source{
+		secretsManager: secretsManager,
+		ssm:            ssm,
+		ids:            make(map[string]string),
+		cache:          make(map[string]string),
+		fetches:        make(map[string]*fetch),
+	}
+}
+
+// FromSecret specifies that the value of the flag at flagPath is read from the
+// secret identified by id. See xflags.Source for a description of flag paths.
+//
+// If id is an SSM parameter ARN or begins with "/", it is read from SSM
+// Parameter Store with decryption. Otherwise id is the ARN or name of a
+// Secrets Manager secret.
+func (c *Source) FromSecret(flagPath, id string) *Source {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[flagPath] = id
+	return c
+}
+
+// Name implements xflags.Source.
+func (c *Source) Name() string { return "aws" }
+
+// Lookup implements xflags.Source. Errors fetching a secret are ignored. The
+// xflags parser calls LookupErr instead.
+func (c *Source) Lookup(flagPath string) (string, bool) {
+	s, ok, err := c.LookupErr(flagPath)
+	if err != nil {
+		return "", false
+	}
+	return s, ok
+}
+
+// LookupErr implements xflags.FallibleSource.
+func (c *Source) LookupErr(flagPath string) (string, bool, error) {
+	c.mu.Lock()
+	id, ok := c.ids[flagPath]
+	c.mu.Unlock()
+	if !ok {
+		return "", false, nil
+	}
+	return c.get(id)
+}
+
+// LookupFlag implements xflags.FlagSource for flags tagged with FromSecret.
+func (c *Source) LookupFlag(flag *xflags.Flag) (string, bool, error) {
+	id, ok := flag.Annotations[Annotation]
+	if !ok {
+		return "", false, nil
+	}
+	return c.get(id)
+}
+
+// get returns the value of the secret identified by id from the cache, or
+// fetches it. c.mu is not held while the secret is fetched; concurrent lookups
+// of the same secret wait for a single fetch instead.
+func (c *Source) get(id string) (string, bool, error) {
+	c.mu.Lock()
+	if s, ok := c.cache[id]; ok {
+		c.mu.Unlock()
+		return s, true, nil
+	}
+	f, ok := c.fetches[id]
+	if ok {
+		c.mu.Unlock()
+		<-f.done
+	} else {
+		f = &fetch{done: make(chan struct{})}
+		c.fetches[id] = f
+		c.mu.Unlock()
+		f.s, f.err = c.fetch(id)
+		c.mu.Lock()
+		delete(c.fetches, id)
+		if f.err == nil {
+			c.cache[id] = f.s
+		}
+		c.mu.Unlock()
+		close(f.done)
+	}
+	if f.err != nil {
+		return "", false, f.err
+	}
+	return f.s, true, nil
+}
+
+// fetch reads the value of the secret identified by id from AWS.
+func (c *Source) fetch(id string) (string, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if isParameter(id) {
+		return c.getParameter(ctx, id)
+	}
+	return c.getSecretValue(ctx, id)
+}
+
+func (c *Source) getSecretValue(ctx context.Context, id string) (string, error) {
+	if c.secretsManager == nil {
+		return "", fmt.Errorf("no Secrets Manager client for secret: %s", id)
+	}
+	out, err := c.secretsManager.GetSecretValue(
+		ctx,
+		&secretsmanager.GetSecretValueInput{SecretId: sdkaws.String(id)},
+	)
+	if err != nil {
+		return "", fmt.Errorf("error getting secret %s: %w", id, err)
+	}
+	if out.SecretString == nil {
+		if out.SecretBinary != nil {
+			return string(out.SecretBinary), nil
+		}
+		return "", fmt.Errorf("secret has no value: %s", id)
+	}
+	return *out.SecretString, nil
+}
+
+func (c *Source) getParameter(ctx context.Context, id string) (string, error) {
+	if c.ssm == nil {
+		return "", fmt.Errorf("no SSM client for parameter: %s", id)
+	}
+	out, err := c.ssm.GetParameter(
+		ctx,
+		&ssm.GetParameterInput{
+			Name:           sdkaws.String(id),
+			WithDecryption: sdkaws.Bool(true),
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("error getting parameter %s: %w", id, err)
+	}
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return "", fmt.Errorf("parameter has no value: %s", id)
+	}
+	return *out.Parameter.Value, nil
+}
+
+// isParameter returns true if id refers to an SSM parameter.
+func isParameter(id string) bool {
+	if strings.HasPrefix(id, "/") {
+		return true
+	}
+	// arn:partition:service:region:account-id:resource
+	fields := strings.SplitN(id, ":", 4)
+	return len(fields) == 4 && fields[0] == "arn" && fields[2] == "ssm"
+}
@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	sdkaws "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/cavaliergopher/xflags"
+)
+
+type fakeClient struct {
+	values map[string]string
+	calls  int
+}
+
+func (c *fakeClient) get(id string) (*string, error) {
+	c.calls++
+	s, ok := c.values[id]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return sdkaws.String(s), nil
+}
+
+func (c *fakeClient) GetSecretValue(
+	ctx context.Context,
+	params *secretsmanager.GetSecretValueInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.GetSecretValueOutput, error) {
+	s, err := c.get(*params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: s}, nil
+}
+
+func (c *fakeClient) GetParameter(
+	ctx context.Context,
+	params *ssm.GetParameterInput,
+	optFns ...func(*ssm.Options),
+) (*ssm.GetParameterOutput, error) {
+	if !*params.WithDecryption {
+		return nil, errors.New("expected decryption")
+	}
+	s, err := c.get(*params.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Value: s}}, nil
+}
+
+func TestSource(t *testing.T) {
+	const secretARN = "arn:aws:secretsmanager:us-east-1:123456789012:secret:db"
+	const paramARN = "arn:aws:ssm:us-east-1:123456789012:parameter/myapp/token"
+	sm := &fakeClient{values: map[string]string{secretARN: "hunter2"}}
+	ps := &fakeClient{values: map[string]string{
+		"/myapp/key": "s3cr3t",
+		paramARN:     "t0k3n",
+	}}
+	src := NewSource(sm, ps).
+		FromSecret("password", secretARN).
+		FromSecret("deploy.db-password", secretARN).
+		FromSecret("deploy.key", "/myapp/key").
+		FromSecret("deploy.token", paramARN)
+
+	var password, key, token, deployPassword string
+	cmd := xflags.NewCommand("test", "").
+		Flags(xflags.String(&password, "password", "", "")).
+		Sources(src).
+		Subcommands(
+			xflags.NewCommand("deploy", "").Flags(
+				xflags.String(&deployPassword, "db-password", "", ""),
+				xflags.String(&key, "key", "", ""),
+				xflags.String(&token, "token", "", ""),
+			),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	for expect, actual := range map[string]string{
+		"hunter2": password,
+		"s3cr3t":  key,
+		"t0k3n":   token,
+	} {
+		if expect != actual {
+			t.Errorf("expected %q, got %q", expect, actual)
+		}
+	}
+	if deployPassword != "hunter2" {
+		t.Errorf("expected %q, got %q", "hunter2", deployPassword)
+	}
+	if sm.calls != 1 {
+		t.Errorf("expected secret to be fetched once, got: %d", sm.calls)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	var password string
+	src := NewSource(&fakeClient{}, nil).
+		FromSecret("password", "db").
+		FromSecret("key", "/myapp/key")
+	cmd := xflags.NewCommand("test", "").
+		Flags(xflags.String(&password, "password", "", "")).
+		Sources(src).
+		Must()
+	_, err := cmd.Parse(nil)
+	if err == nil || !strings.Contains(err.Error(), "--password: aws: error getting secret db") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, _, err := src.LookupErr("key"); err == nil {
+		t.Errorf("expected error with nil SSM client")
+	}
+}
+
+func TestFromSecret(t *testing.T) {
+	const secretARN = "arn:aws:secretsmanager:us-east-1:123456789012:secret:db"
+	sm := &fakeClient{values: map[string]string{secretARN: "hunter2"}}
+	ps := &fakeClient{values: map[string]string{"/myapp/key": "s3cr3t"}}
+
+	var password, key, user string
+	cmd := xflags.NewCommand("test", "").
+		Sources(NewSource(sm, ps)).
+		Subcommands(
+			xflags.NewCommand("deploy", "").Flags(
+				FromSecret(xflags.String(&password, "db-password", "", ""), secretARN),
+				FromSecret(xflags.String(&key, "key", "", ""), "/myapp/key"),
+				xflags.String(&user, "user", "admin", ""),
+			),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	if flag := cmd.Subcommands[0].FlagGroups[0].Flags[0]; !flag.Secret {
+		t.Errorf("expected %s to be secret", flag)
+	}
+	if password != "hunter2" {
+		t.Errorf("expected %q, got %q", "hunter2", password)
+	}
+	if key != "s3cr3t" {
+		t.Errorf("expected %q, got %q", "s3cr3t", key)
+	}
+	if user != "admin" {
+		t.Errorf("expected %q, got %q", "admin", user)
+	}
+
+	if _, err := cmd.Parse([]string{"deploy", "--db-password=override"}); err != nil {
+		t.Fatal(err)
+	}
+	if password != "override" {
+		t.Errorf("expected %q, got %q", "override", password)
+	}
+	if sm.calls != 1 {
+		t.Errorf("expected secret to be fetched once, got: %d", sm.calls)
+	}
+}
+
+// blockingClient is a SecretsManagerClient whose fetches of "slow" signal
+// started and block until release is closed.
+type blockingClient struct {
+	started chan struct{}
+	release chan struct{}
+	calls   int32
+}
+
+func (c *blockingClient) GetSecretValue(
+	ctx context.Context,
+	params *secretsmanager.GetSecretValueInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.GetSecretValueOutput, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if *params.SecretId == "slow" {
+		c.started <- struct{}{}
+		<-c.release
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: params.SecretId}, nil
+}
+
+func TestSourceConcurrentLookups(t *testing.T) {
+	client := &blockingClient{started: make(chan struct{}, 2), release: make(chan struct{})}
+	src := NewSource(client, nil).
+		FromSecret("a", "slow").
+		FromSecret("b", "slow").
+		FromSecret("c", "fast")
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			s, ok, err := src.LookupErr(path)
+			if err != nil || !ok || s != "slow" {
+				t.Errorf("%s: unexpected result: %q, %v, %v", path, s, ok, err)
+			}
+		}(path)
+	}
+
+	// other secrets are not blocked by a fetch in progress
+	<-client.started
+	if s, _, err := src.LookupErr("c"); err != nil || s != "fast" {
+		t.Errorf("unexpected result: %q, %v", s, err)
+	}
+	close(client.release)
+	wg.Wait()
+	if calls := atomic.LoadInt32(&client.calls); calls != 2 {
+		t.Errorf("expected 2 fetches, got %d", calls)
+	}
+}
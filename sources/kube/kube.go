@@ -0,0 +1,92 @@
+// Package kube provides an xflags.Source that resolves flag values from
+// Kubernetes ConfigMaps and Secrets which are mounted into a container as
+// directories.
+//
+// Each key of a mounted ConfigMap or Secret is a file in the mounted
+// directory. By convention, the name of each file is the path of the flag it
+// provides a value for. See xflags.Source for a description of flag paths.
+// For example, given the following ConfigMap mounted at /etc/myapp:
+//
+//	apiVersion: v1
+//	kind: ConfigMap
+//	metadata:
+//	  name: myapp
+//	data:
+//	  log-level: debug
+//	  deploy.replicas: "3"
+//
+// The following command would read the value of --log-level from
+// /etc/myapp/log-level and the value of --replicas for the deploy
+// subcommand from /etc/myapp/deploy.replicas:
+//
+//	var App = xflags.NewCommand("myapp", "").
+//		Sources(kube.NewSource("/etc/myapp", "/etc/myapp-secrets"))
+//
+// Directories that do not exist are ignored so the same binary behaves
+// identically outside of a cluster, where flags are specified on the command
+// line.
+package kube
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+// Source is an xflags.Source that reads flag values from files in mounted
+// directories.
+type Source struct {
+	// Dirs is the list of directories to search, in order of precedence.
+	Dirs []string
+}
+
+var _ xflags.FallibleSource = (*Source)(nil)
+
+// NewSource returns a Source that reads flag values from the given
+// directories, in order of precedence.
+func NewSource(dirs ...string) *Source {
+	return &Source{Dirs: dirs}
+}
+
+// InCluster returns true if the program is running in a Kubernetes pod.
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// Name implements xflags.Source.
+func (c *Source) Name() string { return "kubernetes" }
+
+// Lookup implements xflags.Source. Errors reading a file are ignored. The
+// xflags parser calls LookupErr instead.
+func (c *Source) Lookup(flagPath string) (string, bool) {
+	s, ok, err := c.LookupErr(flagPath)
+	if err != nil {
+		return "", false
+	}
+	return s, ok
+}
+
+// LookupErr implements xflags.FallibleSource. A single trailing newline is
+// removed from the contents of the file.
+func (c *Source) LookupErr(flagPath string) (string, bool, error) {
+	if flagPath == "" || strings.ContainsAny(flagPath, `/\`) || flagPath[0] == '.' {
+		return "", false, nil
+	}
+	for _, dir := range c.Dirs {
+		b, err := ioutil.ReadFile(filepath.Join(dir, flagPath))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", false, err
+		}
+		s := string(b)
+		s = strings.TrimSuffix(s, "\n")
+		s = strings.TrimSuffix(s, "\r")
+		return s, true, nil
+	}
+	return "", false, nil
+}
@@ -0,0 +1,62 @@
+package kube
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cavaliergopher/xflags"
+)
+
+func TestSource(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "xflags-kube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+	secretDir, err := ioutil.TempDir("", "xflags-kube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(secretDir)
+	files := map[string]string{
+		filepath.Join(configDir, "log-level"):       "debug\n",
+		filepath.Join(configDir, "deploy.replicas"): "3",
+		filepath.Join(configDir, "deploy.token"):    "not-secret",
+		filepath.Join(secretDir, "deploy.token"):    "secret",
+	}
+	for name, s := range files {
+		if err := ioutil.WriteFile(name, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var logLevel, token string
+	var replicas int
+	cmd := xflags.NewCommand("test", "").
+		Flags(xflags.String(&logLevel, "log-level", "info", "")).
+		Sources(NewSource(secretDir, configDir, "/does/not/exist")).
+		Subcommands(
+			xflags.NewCommand("deploy", "").Flags(
+				xflags.Int(&replicas, "replicas", 1, ""),
+				xflags.String(&token, "token", "", ""),
+			),
+		).
+		Must()
+	if _, err := cmd.Parse([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	if logLevel != "debug" {
+		t.Errorf("expected log-level: debug, got: %q", logLevel)
+	}
+	if replicas != 3 {
+		t.Errorf("expected replicas: 3, got: %d", replicas)
+	}
+	if token != "secret" {
+		t.Errorf("expected token: secret, got: %q", token)
+	}
+	if _, ok := NewSource(configDir).Lookup("../etc/passwd"); ok {
+		t.Errorf("expected lookup to ignore paths outside of the directory")
+	}
+}
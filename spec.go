@@ -0,0 +1,333 @@
+package xflags
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// specVersion is the version of the spec format written by WriteSpec.
+const specVersion = 1
+
+// commandSpec is the serialized form of a Command.
+type commandSpec struct {
+	Format           int               `json:"xflags,omitempty"`
+	Name             string            `json:"n"`
+	Usage            string            `json:"u,omitempty"`
+	Synopsis         string            `json:"syn,omitempty"`
+	UsageSyntax      string            `json:"usyn,omitempty"`
+	Hidden           bool              `json:"h,omitempty"`
+	WithTerminator   bool              `json:"term,omitempty"`
+	MaxSections      int               `json:"sect,omitempty"`
+	BoolSyntax       BoolSyntax        `json:"bool,omitempty"`
+	PrefixShortNames bool              `json:"prefix,omitempty"`
+	POSIXStrict      bool              `json:"posix,omitempty"`
+	Expression       bool              `json:"expr,omitempty"`
+	Annotations      map[string]string `json:"a,omitempty"`
+	ExitCodes        map[int]string    `json:"x,omitempty"`
+	Feature          string            `json:"feat,omitempty"`
+	Version          string            `json:"v,omitempty"`
+	Handler          bool              `json:"hf,omitempty"`
+	FlagGroups       []flagGroupSpec   `json:"g,omitempty"`
+	Subcommands      []*commandSpec    `json:"c,omitempty"`
+}
+
+// flagGroupSpec is the serialized form of a FlagGroup.
+type flagGroupSpec struct {
+	Name  string     `json:"n"`
+	Usage string     `json:"u,omitempty"`
+	Flags []flagSpec `json:"f,omitempty"`
+}
+
+// flagSpec is the serialized form of a Flag.
+type flagSpec struct {
+	Name            string            `json:"n,omitempty"`
+	ShortName       string            `json:"s,omitempty"`
+	Type            string            `json:"t"`
+	Default         string            `json:"d,omitempty"`
+	Defaults        []string          `json:"ds,omitempty"`
+	AppendToDefault bool              `json:"ad,omitempty"`
+	Usage           string            `json:"u,omitempty"`
+	ShowDefault     bool              `json:"sd,omitempty"`
+	Positional      bool              `json:"p,omitempty"`
+	MinCount        int               `json:"min,omitempty"`
+	MaxCount        int               `json:"max,omitempty"`
+	Hidden          bool              `json:"h,omitempty"`
+	Unique          bool              `json:"uq,omitempty"`
+	Sorted          bool              `json:"so,omitempty"`
+	Secret          bool              `json:"se,omitempty"`
+	EnvVar          string            `json:"e,omitempty"`
+	Feature         string            `json:"feat,omitempty"`
+	Choices         []string          `json:"ch,omitempty"`
+	Annotations     map[string]string `json:"a,omitempty"`
+}
+
+// WriteSpec writes a compact JSON description of cmd and all of its
+// subcommands to w that may be loaded with ReadSpec.
+//
+// Large generated command trees may be written to a spec at build time and
+// embedded in the program so that the cost of building the tree is not paid
+// on every invocation:
+//
+//	//go:embed cli.json
+//	var spec []byte
+//
+//	func main() {
+//	    cmd, err := xflags.ReadSpec(bytes.NewReader(spec), handlers)
+//	    ...
+//	}
+//
+// Only the declarative properties of commands and flags are written. Flags
+// must have one of the builtin value types returned by Bool, Duration,
+// Float64, Int, Int64, String, Strings, Uint and Uint64, or WriteSpec returns
+// an error. Functions such as Validate, other than those set by Choices, and
+// any middleware, sources and reporters are not written and must be added to
+// the loaded command.
+func WriteSpec(w io.Writer, cmd Commander) error {
+	c, err := cmd.Command()
+	if err != nil {
+		return err
+	}
+	spec, err := newCommandSpec(c)
+	if err != nil {
+		return err
+	}
+	spec.Format = specVersion
+	return json.NewEncoder(w).Encode(spec)
+}
+
+func newCommandSpec(cmd *Command) (*commandSpec, error) {
+	spec := &commandSpec{
+		Name:             cmd.Name,
+		Usage:            cmd.UsageText(),
+		Synopsis:         cmd.SynopsisText(),
+		UsageSyntax:      cmd.usageSyntax,
+		Hidden:           cmd.Hidden,
+		WithTerminator:   cmd.WithTerminator,
+		MaxSections:      cmd.MaxSections,
+		BoolSyntax:       cmd.BoolSyntax,
+		PrefixShortNames: cmd.PrefixShortNames,
+		POSIXStrict:      cmd.POSIXStrict,
+		Expression:       cmd.Expression,
+		Annotations:      cmd.Annotations,
+		ExitCodes:        cmd.ExitCodes,
+		Feature:          cmd.Feature,
+		Version:          cmd.Version,
+		Handler:          cmd.HasHandler(),
+	}
+	for _, group := range cmd.FlagGroups {
+		groupSpec := flagGroupSpec{Name: group.Name, Usage: group.Usage}
+		for _, flag := range group.Flags {
+			flagSpec, err := newFlagSpec(flag)
+			if err != nil {
+				return nil, errorf("%s: %s: %v", cmd.Name, flag, err)
+			}
+			groupSpec.Flags = append(groupSpec.Flags, flagSpec)
+		}
+		spec.FlagGroups = append(spec.FlagGroups, groupSpec)
+	}
+	for _, sub := range cmd.Subcommands {
+		subSpec, err := newCommandSpec(sub)
+		if err != nil {
+			return nil, err
+		}
+		spec.Subcommands = append(spec.Subcommands, subSpec)
+	}
+	return spec, nil
+}
+
+func newFlagSpec(flag *Flag) (flagSpec, error) {
+	spec := flagSpec{
+		Name:        flag.Name,
+		ShortName:   flag.ShortName,
+		Usage:       flag.UsageText(),
+		ShowDefault: flag.ShowDefault,
+		Positional:  flag.Positional,
+		MinCount:    flag.MinCount,
+		MaxCount:    flag.MaxCount,
+		Hidden:      flag.Hidden,
+		Unique:      flag.Unique,
+		Sorted:      flag.Sorted,
+		Secret:      flag.Secret,
+		EnvVar:      flag.EnvVar,
+		Feature:     flag.Feature,
+		Choices:     flag.choices,
+		Annotations: flag.Annotations,
+	}
+	spec.Type = valueType(flag.Value)
+	switch spec.Type {
+	case "":
+		return spec, errorf("cannot write value of type %T", flag.Value)
+	case "strings":
+		v := flag.Value.(*stringSliceValue)
+		spec.Defaults = v.defaultValue
+		spec.AppendToDefault = v.appendToDefault
+		return spec, nil
+	}
+	if s := flag.Value.(specValue).String(); s != newSpecValue(spec.Type).String() {
+		spec.Default = s
+	}
+	return spec, nil
+}
+
+// specValue is a builtin value that may be written to a spec.
+type specValue interface {
+	Value
+	String() string
+}
+
+// newSpecValue returns a new zero value of the named spec type or nil.
+func newSpecValue(typ string) specValue {
+	switch typ {
+	case "bool":
+		return newBoolValue(false, nil)
+	case "duration":
+		return newDurationValue(0, nil)
+	case "float64":
+		return newFloat64Value(0, nil)
+	case "int":
+		return newIntValue(0, nil)
+	case "int64":
+		return newInt64Value(0, nil)
+	case "percent":
+		return newPercentValue(0, nil)
+	case "string":
+		return newStringValue("", nil)
+	case "uint":
+		return newUintValue(0, nil)
+	case "uint64":
+		return newUint64Value(0, nil)
+	}
+	return nil
+}
+
+// ReadSpec reads a command tree written by WriteSpec from r. The handler of
+// each command is read from handlers by the path of the command, separated
+// by spaces, such as "app widgets create". Flag values are allocated by
+// ReadSpec and may be read by handlers with Invocation.Get.
+//
+// An error is returned if a command was written with a handler that is not in
+// handlers or if handlers contains a path that does not name a command.
+func ReadSpec(r io.Reader, handlers map[string]ContextHandlerFunc) (*Command, error) {
+	spec := &commandSpec{}
+	if err := json.NewDecoder(r).Decode(spec); err != nil {
+		return nil, errorf("spec: %v", err)
+	}
+	if spec.Format != specVersion {
+		return nil, errorf("spec: unsupported version: %d", spec.Format)
+	}
+	seen := make(map[string]bool, len(handlers))
+	cmd, err := spec.command(nil, handlers, seen)
+	if err != nil {
+		return nil, err
+	}
+	for path := range handlers {
+		if !seen[path] {
+			return nil, errorf("spec: no such command: %s", path)
+		}
+	}
+	return cmd, nil
+}
+
+func (c *commandSpec) command(
+	parent *Command,
+	handlers map[string]ContextHandlerFunc,
+	seen map[string]bool,
+) (*Command, error) {
+	cmd := &Command{
+		Parent:           parent,
+		Name:             c.Name,
+		Usage:            c.Usage,
+		Synopsis:         c.Synopsis,
+		Hidden:           c.Hidden,
+		WithTerminator:   c.WithTerminator,
+		MaxSections:      c.MaxSections,
+		BoolSyntax:       c.BoolSyntax,
+		PrefixShortNames: c.PrefixShortNames,
+		POSIXStrict:      c.POSIXStrict,
+		Expression:       c.Expression,
+		Annotations:      c.Annotations,
+		ExitCodes:        c.ExitCodes,
+		Feature:          c.Feature,
+		Version:          c.Version,
+	}
+	path := strings.Join(commandPath(cmd), " ")
+	seen[path] = true
+	if c.Handler {
+		cmd.ContextHandler = handlers[path]
+		if cmd.ContextHandler == nil {
+			return nil, errorf("spec: no handler for command: %s", path)
+		}
+	}
+	for _, groupSpec := range c.FlagGroups {
+		group := &FlagGroup{
+			Name:  groupSpec.Name,
+			Usage: groupSpec.Usage,
+			Flags: make([]*Flag, 0, len(groupSpec.Flags)),
+		}
+		for i := range groupSpec.Flags {
+			flag, err := groupSpec.Flags[i].flag()
+			if err != nil {
+				return nil, errorf("spec: %s: %v", path, err)
+			}
+			group.Flags = append(group.Flags, flag)
+		}
+		cmd.FlagGroups = append(cmd.FlagGroups, group)
+	}
+	if c.UsageSyntax != "" {
+		syntax, err := compileSyntax(cmd, c.UsageSyntax)
+		if err != nil {
+			return nil, err
+		}
+		cmd.usageSyntax, cmd.syntax = c.UsageSyntax, syntax
+	}
+	cmd.Subcommands = make([]*Command, 0, len(c.Subcommands))
+	for _, subSpec := range c.Subcommands {
+		sub, err := subSpec.command(cmd, handlers, seen)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Subcommands = append(cmd.Subcommands, sub)
+	}
+	return cmd, nil
+}
+
+func (c *flagSpec) flag() (*Flag, error) {
+	flag := &Flag{
+		Name:        c.Name,
+		ShortName:   c.ShortName,
+		Usage:       c.Usage,
+		ShowDefault: c.ShowDefault,
+		Positional:  c.Positional,
+		MinCount:    c.MinCount,
+		MaxCount:    c.MaxCount,
+		Hidden:      c.Hidden,
+		Unique:      c.Unique,
+		Sorted:      c.Sorted,
+		Secret:      c.Secret,
+		EnvVar:      c.EnvVar,
+		Feature:     c.Feature,
+		Annotations: c.Annotations,
+		choices:     c.Choices,
+	}
+	if c.Choices != nil {
+		flag.Validate = validateChoices(c.Choices)
+	}
+	if c.Type == "strings" {
+		v := newStringSliceValue(c.Defaults, nil)
+		v.appendToDefault = c.AppendToDefault
+		flag.Value = v
+		return flag, nil
+	}
+	v := newSpecValue(c.Type)
+	if v == nil {
+		return nil, errorf("%s: unsupported type: %q", flag, c.Type)
+	}
+	if c.Default != "" {
+		if err := v.Set(c.Default); err != nil {
+			return nil, errorf("%s: invalid default: %v", flag, err)
+		}
+	}
+	flag.Value = v
+	return flag, nil
+}
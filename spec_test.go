@@ -0,0 +1,123 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpec(t *testing.T) {
+	var (
+		verbose bool
+		timeout time.Duration
+		format  string
+		count   int
+		names   []string
+	)
+	cmd := NewCommand("app", "An app").
+		Synopsis("Does app things").
+		ExitCodes(map[int]string{1: "error", 2: "usage error"}).
+		Flags(
+			Bool(&verbose, "verbose", false, "Verbose output").ShortName("v"),
+			Duration(&timeout, "timeout", 30*time.Second, "Timeout").Env("APP_TIMEOUT"),
+		).
+		FlagGroup(
+			"output", "Output options",
+			String(&format, "format", "text", "Format").Choices("text", "json"),
+		).
+		Subcommands(
+			NewCommand("create", "Create things").
+				Annotate("group", "write").
+				ExitCodes(map[int]string{1: "failure", 3: "partial failure"}).
+				Flags(
+					Int(&count, "count", 1, "Count").Hidden(),
+					Strings(&names, "name", []string{"a"}, "").Positional().NArgs(1, 0),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+		)
+	w := new(bytes.Buffer)
+	if err := WriteSpec(w, cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	var invoked *Invocation
+	loaded, err := ReadSpec(bytes.NewReader(w.Bytes()), map[string]ContextHandlerFunc{
+		"app create": func(ctx context.Context, args []string) int {
+			invoked = InvocationFrom(ctx)
+			return 0
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// help output is identical
+	expect, actual := new(bytes.Buffer), new(bytes.Buffer)
+	for _, args := range [][]string{nil, {"create"}} {
+		expect.Reset()
+		actual.Reset()
+		assertHelp(t, cmd.Must(), args, expect)
+		assertHelp(t, loaded, args, actual)
+		assertString(t, expect.String(), actual.String())
+	}
+	if !strings.Contains(actual.String(), "Exit status:\n  1  failure\n  2  usage error\n  3  partial failure\n") {
+		t.Errorf("expected exit codes in help, got:\n%s", actual)
+	}
+
+	loaded.Stdout, loaded.Stderr = new(bytes.Buffer), new(bytes.Buffer)
+	code := loaded.Run([]string{"-v", "--format", "json", "create", "--count", "3", "x", "y"})
+	assertInt64(t, 0, int64(code))
+	if invoked == nil {
+		t.Fatal("handler was not called")
+	}
+	assertString(t, "create", invoked.Target().Name)
+	assertString(t, "write", invoked.Target().Annotations["group"])
+	assertBool(t, true, invoked.Get("verbose").(bool))
+	assertDuration(t, 30*time.Second, invoked.Get("timeout").(time.Duration))
+	assertString(t, "json", invoked.Get("format").(string))
+	assertInt64(t, 3, invoked.Get("count").(int64))
+	assertStrings(t, []string{"x", "y"}, invoked.Get("name").([]string))
+
+	_, err = loaded.Parse([]string{"--format", "xml"})
+	assertErrorAs(t, err, new(*ArgumentError))
+}
+
+// assertHelp writes the help message of the command specified by args to w.
+func assertHelp(t *testing.T, cmd *Command, args []string, w *bytes.Buffer) {
+	_, err := cmd.Parse(append(args, "--help"))
+	var helpErr *HelpError
+	if !assertErrorAs(t, err, &helpErr) {
+		return
+	}
+	if err := helpErr.Cmd.WriteUsage(w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpecErrors(t *testing.T) {
+	w := new(bytes.Buffer)
+	err := WriteSpec(w, NewCommand("app", "").Flags(Func("fn", "", nil)))
+	if err == nil || !strings.Contains(err.Error(), "cannot write value") {
+		t.Errorf("expected error writing func value, got: %v", err)
+	}
+
+	err = WriteSpec(w, NewCommand("app", "").HandleFunc(func(args []string) int { return 0 }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSpec(bytes.NewReader(w.Bytes()), nil); err == nil {
+		t.Error("expected error for missing handler")
+	}
+	_, err = ReadSpec(bytes.NewReader(w.Bytes()), map[string]ContextHandlerFunc{
+		"app":      func(ctx context.Context, args []string) int { return 0 },
+		"app nope": func(ctx context.Context, args []string) int { return 0 },
+	})
+	if err == nil {
+		t.Error("expected error for unknown command")
+	}
+	if _, err := ReadSpec(strings.NewReader(`{"n":"app"}`), nil); err == nil {
+		t.Error("expected error for missing version")
+	}
+}
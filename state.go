@@ -0,0 +1,205 @@
+package xflags
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StateDir returns the directory in which the named program may store state
+// that persists between invocations, such as history, and creates it if
+// necessary. On Unix-like systems, this is $XDG_STATE_HOME/app, or
+// ~/.local/state/app if XDG_STATE_HOME is not set. On Windows, this is
+// %LOCALAPPDATA%\app\State.
+//
+// The directory is only accessible by the current user.
+func StateDir(app string) (string, error) {
+	if app == "" || strings.ContainsAny(app, `/\`) || app == "." || app == ".." {
+		return "", errorf("invalid program name: %q", app)
+	}
+	home, err := stateHome()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, app)
+	if filepath.Separator == '\\' {
+		dir = filepath.Join(dir, "State")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// defaultHistorySize is the number of entries kept by a HistoryFile with no
+// MaxEntries.
+const defaultHistorySize = 1000
+
+// HistoryFile is a file of command lines, one per line, such as the
+// invocation history recorded by CommandBuilder.History. Each line is quoted
+// for a POSIX shell and may be split into arguments with SplitCommandLine.
+type HistoryFile struct {
+	// Path is the path of the file.
+	Path string
+
+	// MaxEntries is the number of entries to keep. The oldest entries are
+	// removed when more entries are appended. If MaxEntries is zero, 1000
+	// entries are kept.
+	MaxEntries int
+}
+
+// NewHistoryFile returns the HistoryFile named "history" in the StateDir of
+// the named program.
+func NewHistoryFile(app string) (*HistoryFile, error) {
+	dir, err := StateDir(app)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryFile{Path: filepath.Join(dir, "history")}, nil
+}
+
+// Entries returns the command lines in the file, from oldest to newest. If the
+// file does not exist, Entries returns no entries.
+func (c *HistoryFile) Entries() ([]string, error) {
+	f, err := os.Open(c.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	entries := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Append adds a command line to the end of the file and removes the oldest
+// entries if the file has more than MaxEntries.
+func (c *HistoryFile) Append(args []string) error {
+	line := quotePOSIX(args)
+	if line == "" {
+		return nil
+	}
+	max := c.MaxEntries
+	if max <= 0 {
+		max = defaultHistorySize
+	}
+	entries, err := c.Entries()
+	if err != nil {
+		return err
+	}
+	entries = append(entries, line)
+	if len(entries) <= max {
+		// no entries are removed
+		f, err := os.OpenFile(c.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	return c.write(entries[len(entries)-max:])
+}
+
+// Clear removes all entries from the file.
+func (c *HistoryFile) Clear() error {
+	err := os.Remove(c.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// write replaces the contents of the file with entries.
+func (c *HistoryFile) write(entries []string) error {
+	buf := new(bytes.Buffer)
+	for _, entry := range entries {
+		buf.WriteString(entry + "\n")
+	}
+	tmp := c.Path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.Path)
+}
+
+// historyOptions is the configuration of History.
+type historyOptions struct {
+	app string
+}
+
+// History records the command line of each invocation of this command and its
+// subcommands in the history file returned by NewHistoryFile for the named
+// program and adds a "history" subcommand that prints the recorded command
+// lines. The "history" subcommand is not recorded. The values of secret flags
+// are masked, the passwords of DSN flags are redacted and values read from
+// environment variables or sources are not recorded. Errors writing the
+// history are ignored.
+//
+// Since the history is a subcommand, the command cannot have positional
+// arguments.
+func (c *CommandBuilder) History(app string) *CommandBuilder {
+	if app == "" {
+		return c.error(errorf("%s: empty program name", c.cmd.Name))
+	}
+	c.cmd.historyOptions = &historyOptions{app: app}
+	return c.
+		Use(func(next ContextHandlerFunc) ContextHandlerFunc {
+			return func(ctx context.Context, args []string) int {
+				inv := InvocationFrom(ctx)
+				if inv != nil && !isHistoryCommand(inv.Target()) {
+					if history, err := NewHistoryFile(app); err == nil {
+						_ = history.Append(inv.command(true))
+					}
+				}
+				return next(ctx, args)
+			}
+		}).
+		Subcommands(newHistoryCommand(app))
+}
+
+// historyCommandName is the name of the subcommand added by History.
+const historyCommandName = "history"
+
+// isHistoryCommand reports whether cmd is the subcommand added by History.
+func isHistoryCommand(cmd *Command) bool {
+	return cmd.Name == historyCommandName &&
+		cmd.Parent != nil &&
+		cmd.Parent.historyOptions != nil
+}
+
+func newHistoryCommand(app string) *CommandBuilder {
+	return NewCommand(historyCommandName, "Show the command history").
+		Flags(Bool(nil, "clear", false, "Remove all entries from the history").builtin()).
+		HandleE(func(ctx context.Context, args []string) error {
+			history, err := NewHistoryFile(app)
+			if err != nil {
+				return err
+			}
+			if InvocationFrom(ctx).Get("clear").(bool) {
+				return history.Clear()
+			}
+			entries, err := history.Entries()
+			if err != nil {
+				return err
+			}
+			aw := newAggregatedWriter(OutputFrom(ctx).Stdout)
+			for i, entry := range entries {
+				fmt.Fprintf(aw, "%5d  %s\n", i+1, entry)
+			}
+			return aw.Err()
+		})
+}
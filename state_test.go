@@ -0,0 +1,120 @@
+//go:build !windows
+
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", home)
+	dir, err := StateDir("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, filepath.Join(home, "app"), dir)
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 0o700, int64(fi.Mode().Perm()))
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", home)
+	dir, err = StateDir("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, filepath.Join(home, ".local", "state", "app"), dir)
+
+	for _, app := range []string{"", "..", "a/b"} {
+		if _, err := StateDir(app); err == nil {
+			t.Errorf("expected error for %q", app)
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	history := &HistoryFile{Path: filepath.Join(t.TempDir(), "history"), MaxEntries: 2}
+	entries, err := history.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, nil, entries)
+	for _, args := range [][]string{{"app", "one"}, {"app", "two words"}, {"app", "three"}} {
+		if err := history.Append(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err = history.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, []string{"app 'two words'", "app three"}, entries)
+	assertStrings(t, []string{"app", "two words"}, SplitCommandLine(entries[0]))
+
+	if err := history.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = history.Entries()
+	assertStrings(t, nil, entries)
+}
+
+func TestHistory(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	stdout := new(bytes.Buffer)
+	cmd := NewCommand("app", "").
+		Output(stdout, new(bytes.Buffer)).
+		History("app").
+		Subcommands(
+			NewCommand("login", "").
+				Flags(
+					String(nil, "user", "", ""),
+					String(nil, "password", "", "").Secret(),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+	assertInt64(t, 0, int64(cmd.Run([]string{"login", "--user", "bob", "--password", "hunter2"})))
+	assertInt64(t, 0, int64(cmd.Run([]string{"login", "--user", "alice"})))
+	assertInt64(t, 0, int64(cmd.Run([]string{"history"})))
+	assertString(
+		t,
+		"    1  app login --user=bob '--password=********'\n"+
+			"    2  app login --user=alice\n",
+		stdout.String(),
+	)
+
+	stdout.Reset()
+	assertInt64(t, 0, int64(cmd.Run([]string{"history", "--clear"})))
+	assertInt64(t, 0, int64(cmd.Run([]string{"history"})))
+	assertString(t, "", stdout.String())
+}
+
+func TestHistoryExec(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	cmd := NewCommand("app", "").
+		Output(new(bytes.Buffer), new(bytes.Buffer)).
+		History("app").
+		Subcommands(
+			NewCommand("login", "").
+				Flags(String(nil, "user", "", "")).
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+	ctx := context.Background()
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"login", "--user", "bob"}, nil, nil, nil)))
+	stdout := new(bytes.Buffer)
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"history"}, nil, stdout, nil)))
+	assertString(t, "    1  app login --user=bob\n", stdout.String())
+
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"history", "--clear"}, nil, nil, nil)))
+	stdout.Reset()
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"history"}, nil, stdout, nil)))
+	assertString(t, "", stdout.String())
+}
@@ -0,0 +1,278 @@
+package xflags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// usageStats is the contents of the usage statistics file written by
+// UsageStats.
+type usageStats struct {
+	Enabled  bool           `json:"enabled"`
+	Commands map[string]int `json:"commands,omitempty"`
+	Flags    map[string]int `json:"flags,omitempty"`
+}
+
+// usageStatsFile returns the path of the usage statistics file of the named
+// program.
+func usageStatsFile(app string) (string, error) {
+	dir, err := StateDir(app)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "stats.json"), nil
+}
+
+// readUsageStats reads the usage statistics at path. If the file does not
+// exist, usage statistics are disabled.
+func readUsageStats(path string) (*usageStats, error) {
+	stats := &usageStats{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, stats); err != nil {
+		return nil, errorf("%s: %v", path, err)
+	}
+	return stats, nil
+}
+
+// usageStatsLockTimeout is how long updates of the usage statistics wait for
+// other invocations that update them.
+const usageStatsLockTimeout = 5 * time.Second
+
+// updateUsageStats reads the usage statistics at path, calls fn to modify them
+// and writes them back. The file is locked while it is updated, so that
+// concurrent invocations do not lose each other's counts.
+func updateUsageStats(path string, fn func(stats *usageStats) error) error {
+	unlock, err := waitLockFile(path+".lock", usageStatsLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	stats, err := readUsageStats(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(stats); err != nil {
+		return err
+	}
+	return stats.write(path)
+}
+
+// errUsageStatsDisabled is returned by the function passed to updateUsageStats
+// to leave the statistics unchanged.
+var errUsageStatsDisabled = errors.New("usage statistics are disabled")
+
+// write replaces the usage statistics file at path with c.
+func (c *usageStats) write(path string) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// record counts an invocation of cmd with the flags in trace.
+func (c *usageStats) record(cmd *Command, trace []TraceEntry) {
+	if c.Commands == nil {
+		c.Commands = make(map[string]int)
+	}
+	if c.Flags == nil {
+		c.Flags = make(map[string]int)
+	}
+	path := strings.Join(commandPath(cmd), " ")
+	c.Commands[path]++
+	seen := make(map[*Flag]bool)
+	for _, entry := range trace {
+		flag := entry.Flag
+		if flag == nil || flag.Positional || seen[flag] {
+			continue
+		}
+		seen[flag] = true
+		name := "--" + flag.Name
+		if flag.Name == "" {
+			name = "-" + flag.ShortName
+		}
+		c.Flags[path+" "+name]++
+	}
+}
+
+// usageStatsEntry is a row of the usage statistics printed by the "stats"
+// command of UsageStats.
+type usageStatsEntry struct {
+	Kind  string `json:"kind"` // "command" or "flag"
+	Name  string `json:"name"` // command path, followed by the flag name
+	Count int    `json:"count"`
+}
+
+// entries returns the usage statistics sorted by kind, descending count and
+// name.
+func (c *usageStats) entries() []usageStatsEntry {
+	entries := make([]usageStatsEntry, 0, len(c.Commands)+len(c.Flags))
+	for name, n := range c.Commands {
+		entries = append(entries, usageStatsEntry{Kind: "command", Name: name, Count: n})
+	}
+	for name, n := range c.Flags {
+		entries = append(entries, usageStatsEntry{Kind: "flag", Name: name, Count: n})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	return entries
+}
+
+// usageStatsOptions is the configuration of UsageStats.
+type usageStatsOptions struct {
+	app string
+}
+
+// UsageStats adds a "stats" subcommand and records how often this command, its
+// subcommands and their flags are invoked, so that the maintainers of a
+// program may learn which features matter to its users without any remote
+// telemetry. The statistics are aggregated in a file in the StateDir of the
+// named program and are never sent anywhere.
+//
+// Nothing is recorded until the user gives explicit consent with
+// "stats --enable". The statistics are printed by "stats" and recording is
+// stopped and all recorded statistics are deleted by "stats --disable".
+// Statistics may be cleared with "stats --reset". Only the names of
+// commands and flags specified on the command line are recorded; flag values
+// and arguments are not. The "stats" command is not recorded. Errors
+// recording statistics are ignored.
+//
+// Since the statistics are a subcommand, the command cannot have positional
+// arguments.
+func (c *CommandBuilder) UsageStats(app string) *CommandBuilder {
+	if app == "" {
+		return c.error(errorf("%s: empty program name", c.cmd.Name))
+	}
+	c.cmd.usageStatsOptions = &usageStatsOptions{app: app}
+	return c.
+		Use(func(next ContextHandlerFunc) ContextHandlerFunc {
+			return func(ctx context.Context, args []string) int {
+				inv := InvocationFrom(ctx)
+				if inv != nil && inv.parser != nil && !isStatsCommand(inv.Target()) {
+					recordUsageStats(app, inv)
+				}
+				return next(ctx, args)
+			}
+		}).
+		Subcommands(newStatsCommand(app))
+}
+
+// recordUsageStats counts an invocation in the usage statistics of the named
+// program if the user has consented.
+func recordUsageStats(app string, inv *Invocation) {
+	path, err := usageStatsFile(app)
+	if err != nil {
+		return
+	}
+	_ = updateUsageStats(path, func(stats *usageStats) error {
+		if !stats.Enabled {
+			return errUsageStatsDisabled
+		}
+		stats.record(inv.Target(), inv.parser.trace)
+		return nil
+	})
+}
+
+// statsCommandName is the name of the subcommand added by UsageStats.
+const statsCommandName = "stats"
+
+// isStatsCommand reports whether cmd is the subcommand added by UsageStats.
+func isStatsCommand(cmd *Command) bool {
+	return cmd.Name == statsCommandName &&
+		cmd.Parent != nil &&
+		cmd.Parent.usageStatsOptions != nil
+}
+
+func newStatsCommand(app string) *CommandBuilder {
+	return NewCommand(statsCommandName, "Show local usage statistics").
+		Flags(
+			Bool(nil, "enable", false, "Consent to recording usage statistics").builtin(),
+			Bool(nil, "disable", false, "Stop recording and delete usage statistics").builtin(),
+			Bool(nil, "reset", false, "Delete recorded usage statistics").builtin(),
+		).
+		HandleE(func(ctx context.Context, args []string) error {
+			inv := InvocationFrom(ctx)
+			enable := inv.Get("enable").(bool)
+			disable := inv.Get("disable").(bool)
+			reset := inv.Get("reset").(bool)
+			out := OutputFrom(ctx)
+			path, err := usageStatsFile(app)
+			if err != nil {
+				return err
+			}
+			switch {
+			case enable && disable:
+				return errorf("--enable and --disable cannot be specified together")
+			case disable:
+				unlock, err := waitLockFile(path+".lock", usageStatsLockTimeout)
+				if err != nil {
+					return err
+				}
+				defer unlock()
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				fmt.Fprintln(out.Stderr, "Usage statistics are disabled.")
+				return nil
+			case enable || reset:
+				err := updateUsageStats(path, func(stats *usageStats) error {
+					if enable {
+						stats.Enabled = true
+					}
+					if reset {
+						stats.Commands, stats.Flags = nil, nil
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				if enable {
+					fmt.Fprintf(out.Stderr, "Usage statistics are enabled and stored in %s.\n", path)
+				}
+				return nil
+			}
+			stats, err := readUsageStats(path)
+			if err != nil {
+				return err
+			}
+			if !stats.Enabled {
+				fmt.Fprintf(
+					out.Stderr,
+					"Usage statistics are disabled. Run \"%s --enable\" to record them.\n",
+					strings.Join(commandPath(inv.Target()), " "),
+				)
+				return nil
+			}
+			p, ok := ctx.Value(printerKey{}).(*Printer)
+			if !ok {
+				p = &Printer{Format: FormatTable, W: out.Stdout}
+			}
+			return p.Print(stats.entries())
+		})
+}
@@ -0,0 +1,116 @@
+//go:build !windows
+
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUsageStats(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	// each invocation runs a new command tree, as a program would
+	cmd := func() *Command {
+		return NewCommand("app", "").
+			Output(stdout, stderr).
+			UsageStats("app").
+			Subcommands(
+				NewCommand("deploy", "").
+					Flags(
+						Int(nil, "replicas", 1, ""),
+						Bool(nil, "force", false, "").ShortName("f"),
+					).
+					HandleFunc(func(args []string) int { return 0 }),
+			).
+			Must()
+	}
+
+	// nothing is recorded without consent
+	assertInt64(t, 0, int64(cmd().Run([]string{"deploy"})))
+	assertInt64(t, 0, int64(cmd().Run([]string{"stats"})))
+	assertString(t, "", stdout.String())
+	if !strings.Contains(stderr.String(), `Run "app stats --enable"`) {
+		t.Errorf("unexpected stderr: %s", stderr)
+	}
+
+	assertInt64(t, 0, int64(cmd().Run([]string{"stats", "--enable"})))
+	assertInt64(t, 0, int64(cmd().Run([]string{"deploy", "--replicas", "3", "-f"})))
+	assertInt64(t, 0, int64(cmd().Run([]string{"deploy", "--replicas", "4"})))
+	stdout.Reset()
+	assertInt64(t, 0, int64(cmd().Run([]string{"stats"})))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	expect := []string{
+		"KIND NAME COUNT",
+		"command app deploy 2",
+		"flag app deploy --replicas 2",
+		"flag app deploy --force 1",
+	}
+	if len(lines) != len(expect) {
+		t.Fatalf("unexpected output:\n%s\n%s", stdout, stderr)
+	}
+	for i := range expect {
+		assertString(t, expect[i], strings.Join(strings.Fields(lines[i]), " "))
+	}
+
+	assertInt64(t, 0, int64(cmd().Run([]string{"stats", "--reset"})))
+	stdout.Reset()
+	assertInt64(t, 0, int64(cmd().Run([]string{"stats"})))
+	assertString(t, "", stdout.String())
+
+	assertInt64(t, 0, int64(cmd().Run([]string{"stats", "--disable"})))
+	assertInt64(t, 0, int64(cmd().Run([]string{"deploy"})))
+	stdout.Reset()
+	assertInt64(t, 0, int64(cmd().Run([]string{"stats"})))
+	assertString(t, "", stdout.String())
+}
+
+func TestUsageStatsExec(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	cmd := NewCommand("app", "").
+		Output(io.Discard, io.Discard).
+		UsageStats("app").
+		Subcommands(
+			NewCommand("deploy", "").
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+	ctx := context.Background()
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"stats", "--enable"}, nil, nil, nil)))
+
+	// concurrent invocations do not lose each other's counts
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd.Exec(ctx, []string{"deploy"}, nil, nil, nil)
+		}()
+	}
+	wg.Wait()
+	path, err := usageStatsFile("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := readUsageStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, n, int64(stats.Commands["app deploy"]))
+
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"stats", "--reset"}, nil, nil, nil)))
+	stdout := new(bytes.Buffer)
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"stats"}, nil, stdout, nil)))
+	assertString(t, "", stdout.String())
+
+	assertInt64(t, 0, int64(cmd.Exec(ctx, []string{"stats", "--disable"}, nil, nil, nil)))
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be deleted, got: %v", path, err)
+	}
+}
@@ -0,0 +1,179 @@
+package xflags
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+)
+
+// Structs returns a FlagBuilder that can be used to define a repeatable flag
+// whose arguments are comma-separated key=value pairs, such as
+// "type=bind,src=/a,dst=/b", with specified name, default element and usage
+// string. Each argument is parsed into a struct of type T which is appended to
+// the slice that p points to.
+//
+//	type Mount struct {
+//		Type     string `key:"type" required:"true"`
+//		Source   string `key:"source,src"`
+//		Target   string `key:"target,dst" required:"true"`
+//		ReadOnly bool   `key:"readonly,ro"`
+//	}
+//
+//	var mounts []Mount
+//	xflags.Structs(&mounts, "mount", Mount{Type: "volume"}, "Attach a filesystem mount")
+//
+// The exported fields of T declare the keys. A field is named by its "key"
+// struct tag, a comma-separated list of the name and aliases of the key, or
+// by its name in lower case. Fields with the tag `key:"-"` are ignored and
+// fields with the tag `required:"true"` must be specified in every argument
+// unless the default element sets them.
+// Values are converted to the type of their field using the parsers of
+// NewValue and a bool key may be given without a value, as in "readonly".
+// Each element starts as a copy of the default element.
+//
+// Arguments are parsed as CSV records, so pairs may be quoted to contain
+// commas, as in `"source=/a,b"`.
+func Structs[T any](p *[]T, name string, defaults T, usage string) *FlagBuilder {
+	t := reflect.TypeOf(defaults)
+	if t == nil || t.Kind() != reflect.Struct {
+		c := Var(nil, name, usage)
+		c.err = errorf("%s: element type must be a struct: %T", name, defaults)
+		return c
+	}
+	if p == nil {
+		p = new([]T)
+	}
+	v := &structsValue[T]{p: p, defaults: defaults}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("key")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		key := structKey{index: i, required: field.Tag.Get("required") == "true"}
+		if tag == "" {
+			key.names = []string{strings.ToLower(field.Name)}
+		} else {
+			key.names = strings.Split(tag, ",")
+		}
+		for _, keyName := range key.names {
+			if _, ok := v.lookup(keyName); ok {
+				c := Var(nil, name, usage)
+				c.err = errorf("%s: duplicate key: %s", name, keyName)
+				return c
+			}
+		}
+		v.keys = append(v.keys, key)
+	}
+	return Var(v, name, usage).NArgs(0, 0)
+}
+
+// structKey is a key of a Structs flag.
+type structKey struct {
+	names    []string // name and aliases
+	index    int      // index of the struct field
+	required bool
+}
+
+type structsValue[T any] struct {
+	p        *[]T
+	defaults T
+	keys     []structKey
+}
+
+func (c *structsValue[T]) Get() interface{} { return *c.p }
+
+func (c *structsValue[T]) clone() Value {
+	v := *c
+	s := append([]T(nil), *c.p...)
+	v.p = &s
+	return &v
+}
+
+// String returns the elements of the slice in their argument notation,
+// separated by spaces.
+func (c *structsValue[T]) String() string {
+	elems := make([]string, 0, len(*c.p))
+	for i := range *c.p {
+		elems = append(elems, c.format(reflect.ValueOf(&(*c.p)[i]).Elem()))
+	}
+	return strings.Join(elems, " ")
+}
+
+// format returns the key=value pairs of the fields of v that are set.
+func (c *structsValue[T]) format(v reflect.Value) string {
+	var pairs []string
+	for _, key := range c.keys {
+		field := v.Field(key.index)
+		if !key.required && field.IsZero() {
+			continue
+		}
+		pairs = append(pairs, key.names[0]+"="+tupleElemString(field))
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	_ = w.Write(pairs)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (c *structsValue[T]) lookup(name string) (structKey, bool) {
+	for _, key := range c.keys {
+		for _, n := range key.names {
+			if n == name {
+				return key, true
+			}
+		}
+	}
+	return structKey{}, false
+}
+
+func (c *structsValue[T]) Set(s string) error {
+	r := csv.NewReader(strings.NewReader(s))
+	r.FieldsPerRecord = -1
+	pairs, err := r.Read()
+	if err != nil {
+		return errorf("invalid argument: %s: %v", s, errStr(err))
+	}
+	elem := c.defaults
+	v := reflect.ValueOf(&elem).Elem()
+	seen := make(map[int]bool)
+	for _, pair := range pairs {
+		name, value, hasValue := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		key, ok := c.lookup(name)
+		if !ok {
+			return errorf("unknown key: %s (expected one of %s)", name, quoteList(c.names()))
+		}
+		if seen[key.index] {
+			return errorf("duplicate key: %s", name)
+		}
+		seen[key.index] = true
+		field := v.Field(key.index)
+		if !hasValue {
+			if field.Kind() != reflect.Bool {
+				return errorf("missing value for key: %s", name)
+			}
+			value = "true"
+		}
+		if err := setReflect(field, value); err != nil {
+			return errorf("invalid value for key %s: %q: %v", name, value, errStr(err))
+		}
+	}
+	for _, key := range c.keys {
+		if key.required && !seen[key.index] && v.Field(key.index).IsZero() {
+			return errorf("missing required key: %s", key.names[0])
+		}
+	}
+	*c.p = append(*c.p, elem)
+	return nil
+}
+
+// names returns the primary names of the keys.
+func (c *structsValue[T]) names() []string {
+	names := make([]string, 0, len(c.keys))
+	for _, key := range c.keys {
+		names = append(names, key.names[0])
+	}
+	return names
+}
@@ -0,0 +1,73 @@
+package xflags
+
+import (
+	"testing"
+	"time"
+)
+
+type testMount struct {
+	Type     string `key:"type" required:"true"`
+	Source   string `key:"source,src"`
+	Target   string `key:"target,dst" required:"true"`
+	ReadOnly bool   `key:"readonly,ro"`
+	Timeout  time.Duration
+	internal string
+}
+
+func TestStructs(t *testing.T) {
+	var mounts []testMount
+	flag := Structs(&mounts, "mount", testMount{Type: "volume"}, "").Must()
+	if !assertFlagParses(
+		t,
+		flag,
+		"--mount", "type=bind,src=/a,dst=/b,readonly",
+		"--mount", `target=/c,"source=/d,e",timeout=5s`,
+	) {
+		return
+	}
+	if !assertInt64(t, 2, int64(len(mounts))) {
+		return
+	}
+	assertString(t, "bind", mounts[0].Type)
+	assertString(t, "/a", mounts[0].Source)
+	assertString(t, "/b", mounts[0].Target)
+	assertBool(t, true, mounts[0].ReadOnly)
+	assertString(t, "volume", mounts[1].Type)
+	assertString(t, "/d,e", mounts[1].Source)
+	assertString(t, "/c", mounts[1].Target)
+	assertBool(t, false, mounts[1].ReadOnly)
+	assertDuration(t, 5*time.Second, mounts[1].Timeout)
+	assertString(
+		t,
+		`type=bind,source=/a,target=/b,readonly=true type=volume,"source=/d,e",target=/c,timeout=5s`,
+		valueString(flag.Value),
+	)
+
+	tests := []struct {
+		Arg    string
+		Expect string
+	}{
+		{"dst=/b,size=1", `unknown key: size (expected one of "type", "source", "target", "readonly", "timeout")`},
+		{"dst=/b,dst=/c", "duplicate key: dst"},
+		{"dst=/b,src", "missing value for key: src"},
+		{"dst=/b,ro=maybe", `invalid value for key ro: "maybe": strconv.ParseBool: parsing "maybe": invalid syntax`},
+		{"type=bind,src=/a", "missing required key: target"},
+	}
+	for _, test := range tests {
+		err := parseFlag(Structs[testMount](nil, "mount", testMount{}, "").Must(), "--mount", test.Arg)
+		if assertBool(t, true, err != nil) {
+			assertString(t, "xflags: --mount: "+test.Expect, err.Error())
+		}
+	}
+
+	if _, err := Structs[int](nil, "n", 0, "").Flag(); err == nil {
+		t.Error("expected error for non-struct element")
+	}
+	type duplicate struct {
+		A string `key:"a"`
+		B string `key:"b,a"`
+	}
+	if _, err := Structs[duplicate](nil, "d", duplicate{}, "").Flag(); err == nil {
+		t.Error("expected error for duplicate key")
+	}
+}
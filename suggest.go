@@ -0,0 +1,111 @@
+package xflags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// suggest returns the candidates that are most similar to s, in order of
+// similarity. Candidates that are not similar enough to be a likely
+// misspelling of s are omitted. Keys of a single character, such as short
+// flag names, are too short for any candidate to be a likely misspelling.
+func suggest(s string, candidates []string) []string {
+	type match struct {
+		candidate string
+		distance  int
+	}
+	key := strings.TrimLeft(s, "-")
+	matches := make([]match, 0)
+	if utf8.RuneCountInString(key) < 2 {
+		return []string{}
+	}
+	for _, candidate := range candidates {
+		if candidate == s {
+			continue
+		}
+		d := levenshtein(key, strings.TrimLeft(candidate, "-"))
+		if d <= maxDistance(key) {
+			matches = append(matches, match{candidate, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance == matches[j].distance {
+			return matches[i].candidate < matches[j].candidate
+		}
+		return matches[i].distance < matches[j].distance
+	})
+	if len(matches) > 3 {
+		matches = matches[:3]
+	}
+	a := make([]string, len(matches))
+	for i, m := range matches {
+		a[i] = m.candidate
+	}
+	return a
+}
+
+// maxDistance returns the maximum edit distance for a candidate to be
+// considered a misspelling of s.
+func maxDistance(s string) int {
+	switch n := len(s); {
+	case n <= 3:
+		return 1
+	case n < 8:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// levenshtein returns the edit distance between a and b, counting
+// transpositions of adjacent characters as a single edit.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if t := d[i-2][j-2] + 1; t < d[i][j] {
+					d[i][j] = t
+				}
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// quoteList returns a comma separated list of quoted strings.
+func quoteList(a []string) string {
+	w := new(strings.Builder)
+	for i, s := range a {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		fmt.Fprintf(w, "%q", s)
+	}
+	return w.String()
+}
@@ -0,0 +1,196 @@
+package xflags
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	testCases := []struct {
+		a, b   string
+		expect int
+	}{
+		{"", "", 0},
+		{"yaml", "yaml", 0},
+		{"ymal", "yaml", 1},
+		{"yml", "yaml", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"héllo", "hello", 1},
+	}
+	for _, testCase := range testCases {
+		actual := levenshtein(testCase.a, testCase.b)
+		if actual != testCase.expect {
+			t.Errorf(
+				"expected distance from %q to %q: %d, got: %d",
+				testCase.a,
+				testCase.b,
+				testCase.expect,
+				actual,
+			)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"--verbose", "--version", "--format", "-v", "--dry-run"}
+	assertStrings(t, []string{"--verbose"}, suggest("--verbsoe", candidates))
+	assertStrings(t, []string{"--version"}, suggest("--versio", candidates))
+	assertStrings(t, []string{"--dry-run"}, suggest("--dryrun", candidates))
+	assertStrings(t, []string{}, suggest("--output", candidates))
+	assertStrings(t, []string{}, suggest("--verbose", candidates[:1]))
+	assertStrings(t, []string{}, suggest("-x", candidates))
+	assertStrings(t, []string{}, suggest("y", []string{"x", "yy"}))
+}
+
+func TestArgumentErrorSuggestions(t *testing.T) {
+	var format string
+	var verbose bool
+	cmd := NewCommand("test", "").
+		Flags(
+			String(&format, "format", "json", "").Choices("json", "yaml"),
+			Bool(&verbose, "verbose", false, ""),
+		).
+		Subcommands(NewCommand("create", ""), NewCommand("delete", "")).
+		Must()
+	testCases := []struct {
+		args   []string
+		index  int
+		token  string
+		expect string
+	}{
+		{
+			args:   []string{"--verbose", "--format=ymal"},
+			index:  1,
+			token:  "--format=ymal",
+			expect: `--format: invalid choice: "ymal" (did you mean "yaml"?)`,
+		},
+		{
+			args:   []string{"--format", "xml"},
+			index:  1,
+			token:  "xml",
+			expect: `--format: invalid choice: "xml", expected one of: "json", "yaml"`,
+		},
+		{
+			args:   []string{"--verbsoe"},
+			index:  0,
+			token:  "--verbsoe",
+			expect: `unrecognized argument: --verbsoe (did you mean "--verbose"?)`,
+		},
+		{
+			args:   []string{"--verbose", "craete"},
+			index:  1,
+			token:  "craete",
+			expect: `unrecognized command: craete (did you mean "create"?)`,
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := cmd.Parse(testCase.args)
+		var argErr *ArgumentError
+		if !assertErrorAs(t, err, &argErr) {
+			continue
+		}
+		assertInt64(t, int64(testCase.index), int64(argErr.Index))
+		assertString(t, testCase.token, argErr.Token)
+		assertString(t, testCase.expect, argErr.String())
+	}
+}
+
+func TestArgumentErrorFlagOwners(t *testing.T) {
+	cmd := NewCommand("app", "").
+		Flags(Bool(nil, "debug", false, "")).
+		Subcommands(
+			NewCommand("deploy", "").Flags(Int(nil, "replicas", 1, "").ShortName("r")),
+			NewCommand("scale", "").Flags(Int(nil, "replicas", 1, "")),
+			NewCommand("logs", "").Flags(Bool(nil, "follow", false, "").Hidden()),
+		).
+		Must()
+	testCases := []struct {
+		args   []string
+		expect string
+	}{
+		{
+			args:   []string{"--replicas", "3", "logs"},
+			expect: "--replicas is a flag of 'app deploy' and 'app scale', not 'app logs'",
+		},
+		{
+			args:   []string{"-r", "3"},
+			expect: "-r is a flag of 'app deploy', not 'app'",
+		},
+		{
+			args:   []string{"scale", "-r", "3"},
+			expect: "-r is a flag of 'app deploy', not 'app scale'",
+		},
+		{
+			args:   []string{"--follow"},
+			expect: "unrecognized argument: --follow",
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := cmd.Parse(testCase.args)
+		var argErr *ArgumentError
+		if assertErrorAs(t, err, &argErr) {
+			assertString(t, testCase.expect, argErr.String())
+		}
+	}
+}
+
+func TestArgumentErrorHighlight(t *testing.T) {
+	cmd := NewCommand("app", "").
+		Flags(Bool(nil, "verbose", false, "")).
+		Subcommands(
+			NewCommand("deploy", "").
+				Flags(
+					Int(nil, "replicas", 1, ""),
+					Strings(nil, "file", nil, "").Positional(),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+	long := strings.Repeat("x", 70)
+	tests := []struct {
+		Args   []string
+		Expect string
+	}{
+		{
+			Args: []string{"deploy", "--replicas=x"},
+			Expect: "app deploy --replicas=x\n" +
+				"                      ^",
+		},
+		{
+			Args: []string{"deploy", "--replicas", "xyz"},
+			Expect: "app deploy --replicas xyz\n" +
+				"                      ^~~",
+		},
+		{
+			Args: []string{"--nope", "deploy"},
+			Expect: "app --nope deploy\n" +
+				"    ^~~~~~",
+		},
+		{
+			Args: []string{"deploy it"},
+			Expect: "app 'deploy it'\n" +
+				"     ^~~~~~~~~",
+		},
+		{
+			Args: []string{"deploy", long, "--nope"},
+			Expect: "..." + long[41:] + " --nope\n" +
+				strings.Repeat(" ", 33) + "^~~~~~",
+		},
+	}
+	for _, test := range tests {
+		_, err := cmd.Parse(test.Args)
+		var argErr *ArgumentError
+		if assertErrorAs(t, err, &argErr) {
+			assertString(t, test.Expect, argErr.Highlight())
+		}
+	}
+
+	// errors that were not caused by an argument are not highlighted
+	_, err := cmd.Parse(nil)
+	var argErr *ArgumentError
+	if errors.As(err, &argErr) {
+		assertString(t, "", argErr.Highlight())
+	}
+}
@@ -0,0 +1,292 @@
+package xflags
+
+import (
+	"strings"
+	"unicode"
+)
+
+// UsageSyntax specifies the valid shapes of the arguments of this command
+// when they are not a simple list, such as alternative sets of arguments:
+//
+//	xflags.NewCommand("get", "Display a resource").
+//		Flags(
+//			xflags.String(&kind, "type", "", "Resource type").Positional().NArgs(0, 1),
+//			xflags.String(&name, "name", "", "Resource name").Positional().NArgs(0, 1),
+//			xflags.String(&file, "file", "", "Resource file").ShortName("f"),
+//		).
+//		UsageSyntax("(TYPE NAME | -f FILE)")
+//
+// The syntax replaces the list of positional arguments in the usage line of
+// the help message and the parser returns an error if the arguments that were
+// specified do not match it. The syntax is a sequence of the following
+// elements:
+//
+//   - The name of a positional argument in upper case, such as TYPE.
+//   - A flag, such as -f or --file, optionally followed by the name of its
+//     value, as in "-f FILE" or "--file=FILE".
+//   - Alternatives separated by "|", which match if exactly the arguments of
+//     one of them are specified.
+//   - Elements in brackets, such as "[-f FILE]", which are optional.
+//   - Elements in parentheses, which group alternatives.
+//
+// Any element may be followed by "..." to document that it may be repeated.
+// Only the flags of this command may be named and flags that are not named
+// may be specified with any arguments.
+func (c *CommandBuilder) UsageSyntax(spec string) *CommandBuilder {
+	c.cmd.usageSyntax = spec
+	return c
+}
+
+// syntaxKind is the kind of a syntaxNode.
+type syntaxKind int
+
+const (
+	syntaxFlag syntaxKind = iota // a flag or positional argument
+	syntaxSeq                    // all of nodes
+	syntaxAlt                    // one of nodes
+	syntaxOpt                    // nodes[0] or nothing
+)
+
+// syntaxNode is a node of a compiled UsageSyntax.
+type syntaxNode struct {
+	kind  syntaxKind
+	flag  *Flag
+	nodes []*syntaxNode
+}
+
+// compileSyntax compiles the usage syntax of cmd for its flags.
+func compileSyntax(cmd *Command, spec string) (*syntaxNode, error) {
+	p := &syntaxParser{cmd: cmd, tokens: tokenizeSyntax(spec)}
+	node, err := p.alt()
+	if err != nil {
+		return nil, errorf("%s: invalid usage syntax: %s: %v", cmd.Name, spec, errStr(err))
+	}
+	if len(p.tokens) > 0 {
+		return nil, errorf("%s: invalid usage syntax: %s: unexpected %s", cmd.Name, spec, p.tokens[0])
+	}
+	return node, nil
+}
+
+// tokenizeSyntax splits spec into words and the tokens "(", ")", "[", "]",
+// "|" and "...".
+func tokenizeSyntax(spec string) []string {
+	var tokens []string
+	word := func(s string) {
+		for _, part := range strings.Split(s, "=") {
+			if part != "" {
+				tokens = append(tokens, part)
+			}
+		}
+	}
+	start := -1
+	for i := 0; i < len(spec); i++ {
+		ch := spec[i]
+		isDelim := strings.IndexByte("()[]|", ch) >= 0 || strings.HasPrefix(spec[i:], "...")
+		if isDelim || unicode.IsSpace(rune(ch)) {
+			if start >= 0 {
+				word(spec[start:i])
+				start = -1
+			}
+			if strings.HasPrefix(spec[i:], "...") {
+				tokens = append(tokens, "...")
+				i += 2
+			} else if isDelim {
+				tokens = append(tokens, string(ch))
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		word(spec[start:])
+	}
+	return tokens
+}
+
+type syntaxParser struct {
+	cmd    *Command
+	tokens []string
+}
+
+func (c *syntaxParser) peek() string {
+	if len(c.tokens) == 0 {
+		return ""
+	}
+	return c.tokens[0]
+}
+
+func (c *syntaxParser) alt() (*syntaxNode, error) {
+	node := &syntaxNode{kind: syntaxAlt}
+	for {
+		seq, err := c.seq()
+		if err != nil {
+			return nil, err
+		}
+		node.nodes = append(node.nodes, seq)
+		if c.peek() != "|" {
+			break
+		}
+		c.tokens = c.tokens[1:]
+	}
+	if len(node.nodes) == 1 {
+		return node.nodes[0], nil
+	}
+	return node, nil
+}
+
+func (c *syntaxParser) seq() (*syntaxNode, error) {
+	node := &syntaxNode{kind: syntaxSeq}
+	for {
+		switch tok := c.peek(); tok {
+		case "", "|", ")", "]":
+			if len(node.nodes) == 0 {
+				if tok == "" {
+					return nil, errorf("unexpected end")
+				}
+				return nil, errorf("unexpected %s", tok)
+			}
+			if len(node.nodes) == 1 {
+				return node.nodes[0], nil
+			}
+			return node, nil
+		case "...":
+			if len(node.nodes) == 0 {
+				return nil, errorf("unexpected ...")
+			}
+			c.tokens = c.tokens[1:] // repetition is only documented
+		default:
+			item, err := c.item()
+			if err != nil {
+				return nil, err
+			}
+			node.nodes = append(node.nodes, item)
+		}
+	}
+}
+
+func (c *syntaxParser) item() (*syntaxNode, error) {
+	tok := c.tokens[0]
+	c.tokens = c.tokens[1:]
+	switch tok {
+	case "(", "[":
+		node, err := c.alt()
+		if err != nil {
+			return nil, err
+		}
+		end := map[string]string{"(": ")", "[": "]"}[tok]
+		if c.peek() != end {
+			return nil, errorf("missing %s", end)
+		}
+		c.tokens = c.tokens[1:]
+		if tok == "[" {
+			node = &syntaxNode{kind: syntaxOpt, nodes: []*syntaxNode{node}}
+		}
+		return node, nil
+	}
+	flag := c.lookup(tok)
+	if flag == nil {
+		return nil, errorf("unknown argument: %s", tok)
+	}
+	if !flag.Positional && !isBoolValue(flag.Value) {
+		// skip the name of the value, as in "-f FILE"
+		if next := c.peek(); isSyntaxValueName(next) && c.lookup(next) == nil {
+			c.tokens = c.tokens[1:]
+		}
+	}
+	return &syntaxNode{kind: syntaxFlag, flag: flag}, nil
+}
+
+// lookup returns the flag of the command named by tok, or nil.
+func (c *syntaxParser) lookup(tok string) *Flag {
+	for _, group := range c.cmd.FlagGroups {
+		for _, flag := range group.Flags {
+			switch {
+			case flag.Positional && tok == strings.ToUpper(flag.Name),
+				!flag.Positional && flag.Name != "" && tok == "--"+flag.Name,
+				!flag.Positional && flag.ShortName != "" && tok == "-"+flag.ShortName:
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+// isSyntaxValueName reports whether tok may be the name of a flag value.
+func isSyntaxValueName(tok string) bool {
+	return tok != "" && !strings.HasPrefix(tok, "-") && strings.IndexAny(tok, "()[]|") < 0 && tok != "..."
+}
+
+// mentions adds the flags named by c to m.
+func (c *syntaxNode) mentions(m map[*Flag]bool) map[*Flag]bool {
+	if c.kind == syntaxFlag {
+		m[c.flag] = true
+	}
+	for _, node := range c.nodes {
+		node.mentions(m)
+	}
+	return m
+}
+
+// match reports whether the flags in present, which are named by c, match c
+// exactly.
+func (c *syntaxNode) match(present map[*Flag]bool) bool {
+	switch c.kind {
+	case syntaxFlag:
+		return len(present) == 1 && present[c.flag]
+	case syntaxOpt:
+		return len(present) == 0 || c.nodes[0].match(present)
+	case syntaxSeq:
+		for _, node := range c.nodes {
+			if !node.match(intersect(present, node.mentions(map[*Flag]bool{}))) {
+				return false
+			}
+		}
+		return true
+	case syntaxAlt:
+		for _, node := range c.nodes {
+			m := node.mentions(map[*Flag]bool{})
+			if len(intersect(present, m)) == len(present) && node.match(present) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// intersect returns the flags that are in both a and b.
+func intersect(a, b map[*Flag]bool) map[*Flag]bool {
+	m := make(map[*Flag]bool)
+	for flag := range a {
+		if b[flag] {
+			m[flag] = true
+		}
+	}
+	return m
+}
+
+// checkUsageSyntax returns an error if the arguments of the command do not
+// match its UsageSyntax.
+func (c *argParser) checkUsageSyntax() error {
+	if c.cmd.syntax == nil {
+		return nil
+	}
+	present := make(map[*Flag]bool)
+	for flag := range c.cmd.syntax.mentions(map[*Flag]bool{}) {
+		if c.flagsSeen[flag.name()] > 0 {
+			present[flag] = true
+		}
+	}
+	if c.cmd.syntax.match(present) {
+		return nil
+	}
+	return newArgErr(
+		c.cmd,
+		nil,
+		"",
+		"invalid combination of arguments (usage: %s %s)",
+		strings.Join(commandPath(c.cmd), " "),
+		c.cmd.usageSyntax,
+	)
+}
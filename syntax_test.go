@@ -0,0 +1,110 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func newSyntaxTestCommand(compact bool) *Command {
+	b := NewCommand("app", "").
+		Subcommands(
+			NewCommand("get", "").
+				Flags(
+					String(nil, "type", "", "").Positional().NArgs(0, 1),
+					String(nil, "name", "", "").Positional().NArgs(0, 1),
+					String(nil, "file", "", "").ShortName("f"),
+					Bool(nil, "watch", false, "").ShortName("w"),
+					String(nil, "output", "", "").ShortName("o"),
+				).
+				UsageSyntax("(TYPE [NAME] | -f FILE...) [-w]").
+				HandleFunc(func(args []string) int { return 0 }),
+		)
+	if compact {
+		b.Compact()
+	}
+	return b.Must()
+}
+
+func TestUsageSyntax(t *testing.T) {
+	tests := []struct {
+		Args  []string
+		Valid bool
+	}{
+		{[]string{"get", "pods"}, true},
+		{[]string{"get", "pods", "web"}, true},
+		{[]string{"get", "-f", "pod.yaml"}, true},
+		{[]string{"get", "-f", "pod.yaml", "-w", "-o", "json"}, true},
+		{[]string{"get", "-o", "json", "pods"}, true},
+		{[]string{"get"}, false},
+		{[]string{"get", "-w"}, false},
+		{[]string{"get", "pods", "-f", "pod.yaml"}, false},
+	}
+	for _, compact := range []bool{false, true} {
+		for _, test := range tests {
+			_, err := newSyntaxTestCommand(compact).Parse(test.Args)
+			if !assertBool(t, test.Valid, err == nil) {
+				t.Logf("%v: %v", test.Args, err)
+				continue
+			}
+			if err != nil {
+				assertString(
+					t,
+					"xflags: invalid combination of arguments (usage: app get (TYPE [NAME] | -f FILE...) [-w])",
+					err.Error(),
+				)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	cmd := newSyntaxTestCommand(false).Subcommands[0]
+	if err := Format(&buf, cmd); err != nil {
+		t.Fatal(err)
+	}
+	line, _, _ := bytes.Cut(buf.Bytes(), []byte("\n"))
+	assertString(t, "Usage: app get [OPTIONS] (TYPE [NAME] | -f FILE...) [-w]", string(line))
+}
+
+func TestUsageSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		Spec   string
+		Expect string
+	}{
+		{"TYPE | --nope", "app: invalid usage syntax: TYPE | --nope: unknown argument: --nope"},
+		{"(TYPE", "app: invalid usage syntax: (TYPE: missing )"},
+		{"TYPE)", "app: invalid usage syntax: TYPE): unexpected )"},
+		{"TYPE |", "app: invalid usage syntax: TYPE |: unexpected end"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		_, err := NewCommand("app", "").
+			Flags(String(nil, "type", "", "").Positional()).
+			UsageSyntax(test.Spec).
+			Command()
+		if test.Expect == "" {
+			assertBool(t, true, err == nil)
+			continue
+		}
+		if assertBool(t, true, err != nil) {
+			assertString(t, "xflags: "+test.Expect, err.Error())
+		}
+	}
+}
+
+func TestUsageSyntaxSpec(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSpec(&buf, newSyntaxTestCommand(false)); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := ReadSpec(&buf, map[string]ContextHandlerFunc{
+		"app get": func(ctx context.Context, args []string) int { return 0 },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmd.Parse([]string{"get", "pods", "-f", "pod.yaml"})
+	assertBool(t, true, err != nil)
+	_, err = cmd.Parse([]string{"get", "-f", "pod.yaml"})
+	assertBool(t, true, err == nil)
+}
@@ -0,0 +1,148 @@
+package xflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrorClass classifies the outcome of a command invocation for telemetry.
+type ErrorClass string
+
+const (
+	ErrorNone     ErrorClass = ""         // the handler returned zero or help was shown
+	ErrorArgument ErrorClass = "argument" // the command line could not be parsed
+	ErrorUsage    ErrorClass = "usage"    // the invoked command has no handler
+	ErrorExit     ErrorClass = "exit"     // the handler returned a non-zero exit code
+	ErrorAborted  ErrorClass = "aborted"  // the user did not confirm the command
+)
+
+// Event describes one invocation of a command by Command.Run or Command.Exec.
+type Event struct {
+	// Command is the path of the invoked command from the root command, such
+	// as ["app", "widgets", "create"]. If the command line could not be
+	// parsed, it is the command that was being parsed.
+	Command []string
+
+	// Flags maps the path of each flag that was set from the command line,
+	// environment or a source to its value. Flags declared as Secret are
+	// never included.
+	Flags map[string]string
+
+	Start      time.Time
+	Duration   time.Duration
+	ExitCode   int
+	ErrorClass ErrorClass
+
+	secrets map[string]string // masked values of the secret flags that were set
+	user    string            // the user given with WithUser, if any
+}
+
+// Reporter receives an Event for each command invocation so that programs
+// may collect usage analytics or trace command invocations without changing
+// their handlers.
+type Reporter interface {
+	Report(e *Event)
+}
+
+// ReporterFunc is an adapter to allow the use of ordinary functions as a
+// Reporter.
+type ReporterFunc func(e *Event)
+
+// Report calls f(e).
+func (f ReporterFunc) Report(e *Event) { f(e) }
+
+// Instrument adds a Reporter that receives an Event after each invocation of
+// this command or any of its subcommands by Command.Run or Command.Exec.
+func (c *CommandBuilder) Instrument(r Reporter) *CommandBuilder {
+	if r == nil {
+		return c.error(errorf("%s: nil reporter", c.cmd.Name))
+	}
+	c.cmd.Reporters = append(c.cmd.Reporters, r)
+	return c
+}
+
+// report sends an Event describing an invocation of c with ctx to the reporters
+// of c and its parents. Flags are read from parser, which is nil if the command
+// line could not be parsed.
+func (c *Command) report(
+	ctx context.Context,
+	parser *argParser,
+	start time.Time,
+	exitCode int,
+	class ErrorClass,
+) {
+	reporters := make([]Reporter, 0)
+	for p := c; p != nil; p = p.Parent {
+		reporters = append(reporters, p.Reporters...)
+	}
+	if len(reporters) == 0 {
+		return
+	}
+	e := &Event{
+		Command:    commandPath(c),
+		Flags:      make(map[string]string),
+		Start:      start,
+		Duration:   time.Since(start),
+		ExitCode:   exitCode,
+		ErrorClass: class,
+		user:       UserFrom(ctx),
+	}
+	if parser != nil {
+		e.Flags = parser.setFlags()
+		parser.walkFlags(func(flag *Flag, path string) error {
+			if flag.Secret && parser.flagsSeen[flag.name()] > 0 {
+				if e.secrets == nil {
+					e.secrets = make(map[string]string)
+				}
+				e.secrets[path] = maskSecret(valueString(parser.value(flag)))
+			}
+			return nil
+		})
+	}
+	for _, r := range reporters {
+		r.Report(e)
+	}
+}
+
+// setFlags returns the path and value of each flag of the current command and
+// its parents that was set from the command line, environment or a source,
+// except flags declared as Secret.
+func (c *argParser) setFlags() map[string]string {
+	m := make(map[string]string)
+	c.walkFlags(func(flag *Flag, path string) error {
+		if c.flagsSeen[flag.name()] == 0 || flag.Secret {
+			return nil
+		}
+		if s, ok := c.value(flag).(fmt.Stringer); ok {
+			m[path] = s.String()
+		} else {
+			m[path] = ""
+		}
+		return nil
+	})
+	return m
+}
+
+// errorCommand returns the command that produced err.
+func errorCommand(err error) *Command {
+	var argErr *ArgumentError
+	if errors.As(err, &argErr) {
+		return argErr.Cmd
+	}
+	var helpErr *HelpError
+	if errors.As(err, &helpErr) {
+		return helpErr.Cmd
+	}
+	return nil
+}
+
+// commandPath returns the names of cmd and its parents from the root command.
+func commandPath(cmd *Command) []string {
+	path := make([]string, 0)
+	for p := cmd; p != nil; p = p.Parent {
+		path = append([]string{p.Name}, path...)
+	}
+	return path
+}
@@ -0,0 +1,75 @@
+package xflags
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	var events []*Event
+	var n int
+	var token string
+	var verbose bool
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		Instrument(ReporterFunc(func(e *Event) { events = append(events, e) })).
+		Flags(
+			Bool(&verbose, "verbose", false, ""),
+			String(&token, "token", "", "").Secret(),
+		).
+		Subcommands(
+			NewCommand("create", "").
+				Flags(Int(&n, "", 1, "").ShortName("n")).
+				HandleFunc(func(args []string) int { return n }),
+			NewCommand("noop", ""),
+		).
+		Must()
+
+	tests := []struct {
+		Args     []string
+		Command  []string
+		Flags    map[string]string
+		ExitCode int
+		Class    ErrorClass
+	}{
+		{
+			[]string{"--token=abc", "--verbose", "create", "-n", "0"},
+			[]string{"app", "create"},
+			map[string]string{"verbose": "true", "create.n": "0"},
+			0,
+			ErrorNone,
+		},
+		{
+			[]string{"create", "-n", "3"},
+			[]string{"app", "create"},
+			map[string]string{"create.n": "3"},
+			3,
+			ErrorExit,
+		},
+		{[]string{"create", "-n", "x"}, []string{"app", "create"}, nil, 1, ErrorArgument},
+		{[]string{"noop"}, []string{"app", "noop"}, nil, 1, ErrorUsage},
+	}
+	for _, test := range tests {
+		events = nil
+		if code := cmd.Run(test.Args); code != test.ExitCode {
+			t.Errorf("%q: expected exit code %d, got %d", test.Args, test.ExitCode, code)
+		}
+		if len(events) != 1 {
+			t.Errorf("%q: expected 1 event, got %d", test.Args, len(events))
+			continue
+		}
+		e := events[0]
+		assertStrings(t, test.Command, e.Command)
+		assertInt64(t, int64(test.ExitCode), int64(e.ExitCode))
+		assertString(t, string(test.Class), string(e.ErrorClass))
+		if len(e.Flags) != len(test.Flags) {
+			t.Errorf("%q: expected flags %q, got %q", test.Args, test.Flags, e.Flags)
+		}
+		for k, v := range test.Flags {
+			assertString(t, v, e.Flags[k])
+		}
+		if e.Start.IsZero() || e.Duration < 0 {
+			t.Errorf("%q: invalid timing: %v, %v", test.Args, e.Start, e.Duration)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package xflags
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+)
+
+// tlsOptions are the values of the flags registered by TLSFlags.
+type tlsOptions struct {
+	config   *tls.Config
+	prefix   string
+	cert     string
+	key      string
+	ca       string
+	insecure bool
+}
+
+// TLSFlags registers the --PREFIX-cert, --PREFIX-key, --PREFIX-ca and
+// --PREFIX-insecure-skip-verify flags for this command and its subcommands,
+// or --cert, --key, --ca and --insecure-skip-verify if prefix is empty.
+// TLSFlags may be called more than once with different prefixes, for example
+// to configure both a server and a client.
+//
+// Once the command line is parsed, the TLS configuration that p points to is
+// updated from the flags before the handler is called, so that other fields
+// of p, such as MinVersion, may be set by the program:
+//
+//   - The certificate and private key files in PEM format are loaded into
+//     Certificates. Both must be specified.
+//   - The certificates of the CA file in PEM format replace the system root
+//     CAs in RootCAs, to verify servers, and are also set as ClientCAs, to
+//     verify clients if ClientAuth requires it.
+//   - InsecureSkipVerify disables the verification of servers and may not be
+//     specified together with a CA file.
+//
+// Errors loading the files are returned by Parse as an ArgumentError that
+// names the flag.
+//
+// Command.ParseInvocation configures a copy of p for each invocation instead,
+// which handlers retrieve with TLSConfigFrom.
+func (c *CommandBuilder) TLSFlags(p *tls.Config, prefix string) *CommandBuilder {
+	if p == nil {
+		return c.error(errorf("%s: nil TLS config", c.cmd.Name))
+	}
+	opts := &tlsOptions{config: p, prefix: prefix}
+	c.cmd.tlsOptions = append(c.cmd.tlsOptions, opts)
+	usage := "TLS options"
+	if prefix != "" {
+		usage += " (" + prefix + ")"
+	}
+	return c.FlagGroup(
+		"tls-"+prefix,
+		usage,
+		String(&opts.cert, prefixedName(prefix, "cert"), "", "Path of the TLS certificate file").builtin(),
+		String(&opts.key, prefixedName(prefix, "key"), "", "Path of the TLS private key file").builtin(),
+		String(&opts.ca, prefixedName(prefix, "ca"), "", "Path of the CA certificates file").builtin(),
+		Bool(
+			&opts.insecure,
+			prefixedName(prefix, "insecure-skip-verify"),
+			false,
+			"Do not verify TLS certificates (insecure)",
+		).builtin(),
+	)
+}
+
+type tlsConfigKey struct{ prefix string }
+
+// TLSConfigFrom returns the TLS configuration updated from the flags
+// registered by TLSFlags with the given prefix for the command invocation that
+// ctx was created for, or nil if ctx has no such configuration. For commands
+// parsed by Command.Parse, it is the configuration given to TLSFlags.
+func TLSConfigFrom(ctx context.Context, prefix string) *tls.Config {
+	config, _ := ctx.Value(tlsConfigKey{prefix}).(*tls.Config)
+	return config
+}
+
+// withTLSConfigs returns a context derived from ctx that carries the TLS
+// configurations of inv, an invocation of c, and its parents.
+func (c *Command) withTLSConfigs(ctx context.Context, inv *Invocation) context.Context {
+	if inv.parser == nil {
+		return ctx
+	}
+	for p := c; p != nil; p = p.Parent {
+		for _, opts := range p.tlsOptions {
+			key := tlsConfigKey{opts.prefix}
+			if config := inv.parser.tlsConfigs[opts]; config != nil && ctx.Value(key) == nil {
+				ctx = context.WithValue(ctx, key, config)
+			}
+		}
+	}
+	return ctx
+}
+
+// configureTLS updates the TLS configuration of each call to TLSFlags by the
+// current command and its parents from the values of their flags, or a copy of
+// it if the parser was created by Command.ParseInvocation.
+func (c *argParser) configureTLS() error {
+	for p := c.cmd; p != nil; p = p.Parent {
+		for _, opts := range p.tlsOptions {
+			config := opts.config
+			if c.values != nil {
+				config = config.Clone()
+			}
+			if err := c.configureTLSOptions(p, opts, config); err != nil {
+				return err
+			}
+			if c.tlsConfigs == nil {
+				c.tlsConfigs = make(map[*tlsOptions]*tls.Config)
+			}
+			c.tlsConfigs[opts] = config
+		}
+	}
+	return nil
+}
+
+// configureTLSOptions updates config from the flags of opts, which were
+// registered by cmd.
+func (c *argParser) configureTLSOptions(cmd *Command, opts *tlsOptions, config *tls.Config) error {
+	flag := func(suffix string) *Flag { return c.lookupName(prefixedName(opts.prefix, suffix)) }
+	value := func(suffix string) interface{} { return c.optionValue(cmd, prefixedName(opts.prefix, suffix)) }
+	var (
+		cert     = value("cert").(string)
+		key      = value("key").(string)
+		ca       = value("ca").(string)
+		insecure = value("insecure-skip-verify").(bool)
+	)
+	switch {
+	case cert != "" && key == "":
+		return newArgErr(c.cmd, flag("key"), "", "missing argument: %s", flag("key"))
+	case key != "" && cert == "":
+		return newArgErr(c.cmd, flag("cert"), "", "missing argument: %s", flag("cert"))
+	case insecure && ca != "":
+		return newArgErr(
+			c.cmd,
+			flag("insecure-skip-verify"),
+			"",
+			"cannot be specified with %s",
+			flag("ca"),
+		)
+	}
+	if cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return wrapArgErr(err, c.cmd, flag("cert"), cert)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	if ca != "" {
+		b, err := os.ReadFile(ca)
+		if err != nil {
+			return wrapArgErr(err, c.cmd, flag("ca"), ca)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return newArgErr(c.cmd, flag("ca"), ca, "no certificates found in %s", ca)
+		}
+		config.RootCAs = pool
+		config.ClientCAs = pool
+	}
+	config.InsecureSkipVerify = insecure
+	return nil
+}
@@ -0,0 +1,129 @@
+package xflags
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate and its private key to a
+// temporary directory and returns their paths.
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "xflags test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSFlags(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	server := &tls.Config{MinVersion: tls.VersionTLS12}
+	client := &tls.Config{}
+	cmd := NewCommand("app", "").
+		TLSFlags(server, "").
+		TLSFlags(client, "upstream").
+		Must()
+	_, err := cmd.Parse([]string{
+		"--cert", certFile,
+		"--key", keyFile,
+		"--ca", certFile,
+		"--upstream-insecure-skip-verify",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertInt64(t, 1, int64(len(server.Certificates)))
+	assertBool(t, true, server.RootCAs != nil && server.ClientCAs != nil)
+	assertUint64(t, tls.VersionTLS12, uint64(server.MinVersion))
+	assertBool(t, false, server.InsecureSkipVerify)
+	assertInt64(t, 0, int64(len(client.Certificates)))
+	assertBool(t, true, client.InsecureSkipVerify)
+}
+
+func TestTLSFlagsErrors(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	tests := []struct {
+		Args []string
+		Flag string
+	}{
+		{[]string{"--tls-cert", certFile}, "tls-key"},
+		{[]string{"--tls-key", keyFile}, "tls-cert"},
+		{[]string{"--tls-cert", keyFile, "--tls-key", certFile}, "tls-cert"},
+		{[]string{"--tls-ca", keyFile}, "tls-ca"},
+		{[]string{"--tls-ca", filepath.Join(t.TempDir(), "missing")}, "tls-ca"},
+		{[]string{"--tls-ca", certFile, "--tls-insecure-skip-verify"}, "tls-insecure-skip-verify"},
+	}
+	for _, test := range tests {
+		_, err := NewCommand("app", "").
+			TLSFlags(&tls.Config{}, "tls").
+			Must().
+			Parse(test.Args)
+		var argErr *ArgumentError
+		if assertErrorAs(t, err, &argErr) {
+			assertString(t, test.Flag, argErr.Flag.Name)
+		}
+	}
+}
+
+func TestTLSFlagsExec(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	server := &tls.Config{MinVersion: tls.VersionTLS12}
+	var config *tls.Config
+	cmd := NewCommand("app", "").
+		TLSFlags(server, "").
+		HandleContext(func(ctx context.Context, args []string) int {
+			config = TLSConfigFrom(ctx, "")
+			return 0
+		}).
+		Must()
+	code := cmd.Exec(context.Background(), []string{"--cert", certFile, "--key", keyFile}, nil, nil, nil)
+	assertInt64(t, 0, int64(code))
+	if config == nil || config == server {
+		t.Fatal("expected a copy of the TLS config")
+	}
+	assertInt64(t, 1, int64(len(config.Certificates)))
+	assertUint64(t, tls.VersionTLS12, uint64(config.MinVersion))
+	assertInt64(t, 0, int64(len(server.Certificates)))
+
+	if code := cmd.Run(nil); code != 0 {
+		t.Fatalf("unexpected exit code: %d", code)
+	}
+	assertBool(t, true, config == server)
+}
@@ -0,0 +1,193 @@
+package xflags
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+const (
+	// FlagToken is the name of a flag, such as "--foo" or "-f".
+	FlagToken TokenKind = iota + 1
+
+	// ValueToken is the value of the preceding flag, whether it was a
+	// separate argument or attached to the flag, as in "--foo=bar".
+	ValueToken
+
+	// PositionalToken is a positional argument, including the arguments that
+	// follow the "--" terminator.
+	PositionalToken
+
+	// CommandToken is the name of a subcommand.
+	CommandToken
+
+	// OperatorToken is an operator of a command that accepts an Expression.
+	OperatorToken
+
+	// TerminatorToken is the "--" terminator of a command with
+	// WithTerminator.
+	TerminatorToken
+)
+
+// String returns the name of the token kind, such as "flag".
+func (c TokenKind) String() string {
+	switch c {
+	case FlagToken:
+		return "flag"
+	case ValueToken:
+		return "value"
+	case PositionalToken:
+		return "positional"
+	case CommandToken:
+		return "command"
+	case OperatorToken:
+		return "operator"
+	case TerminatorToken:
+		return "terminator"
+	}
+	return "unknown"
+}
+
+// Token is a part of a command line argument that was read by a
+// TokenScanner.
+type Token struct {
+	Kind   TokenKind
+	Text   string // text of the token, such as "--foo" or "bar"
+	Index  int    // index of the argument that contains the token
+	Offset int    // byte offset of the token in the argument
+	Len    int    // length in bytes of the token in the argument
+
+	// Command is the command that the parser had descended into when the
+	// token was read, or the subcommand named by a CommandToken.
+	Command *Command
+
+	// Flag is the flag named by a FlagToken, the flag of a ValueToken or the
+	// positional flag of a PositionalToken. It is nil if the flag is unknown
+	// or, for a PositionalToken, if the command declares no positional
+	// argument that accepts it.
+	Flag *Flag
+}
+
+// TokenScanner reads the arguments of a command line as a stream of tokens
+// with the tokenizer of the parser, so that custom grammars, syntax
+// highlighters and linters can be implemented on top of it. A TokenScanner is
+// created with Command.Scan.
+//
+// Unlike the parser, a TokenScanner does not set any values and does not stop
+// at errors, so that every argument of a command line can be classified: an
+// unknown flag is a FlagToken with a nil Flag, and an unknown subcommand is a
+// PositionalToken with a nil Flag. Aliases are not expanded.
+type TokenScanner struct {
+	p      *argParser
+	tokens []Token
+	token  Token
+}
+
+// Scan returns a TokenScanner that reads the tokens of args as they are read
+// by Parse.
+func (c *Command) Scan(args []string) *TokenScanner {
+	return &TokenScanner{p: newArgParser(c, args)}
+}
+
+// Next advances the scanner to the next token, which is then available
+// through the Token method. It returns false when there are no more tokens.
+func (c *TokenScanner) Next() bool {
+	if len(c.tokens) == 0 {
+		c.scan()
+	}
+	if len(c.tokens) == 0 {
+		return false
+	}
+	c.token, c.tokens = c.tokens[0], c.tokens[1:]
+	return true
+}
+
+// Token returns the token read by the most recent call to Next.
+func (c *TokenScanner) Token() Token { return c.token }
+
+// scan reads the next argument and queues its tokens, which are one token or
+// a flag token followed by a value token.
+func (c *TokenScanner) scan() {
+	p := c.p
+	token, ok := p.next()
+	if !ok {
+		return
+	}
+	switch {
+	case p.isTerminated:
+		c.emit(PositionalToken, token, nil)
+	case token == terminator && p.cmd.WithTerminator:
+		p.isTerminated = true
+		c.emit(TerminatorToken, token, nil)
+	case p.operands || isPositional(token) && !(p.cmd.Expression && isOperator(token)):
+		c.scanPositional(token)
+	case p.cmd.Expression && isOperator(token):
+		c.emit(OperatorToken, token, nil)
+	default:
+		c.scanFlag(token)
+	}
+}
+
+func (c *TokenScanner) scanPositional(token string) {
+	p := c.p
+	if len(p.positionals) > 0 {
+		flag := p.positionals[0]
+		if n := p.observe(flag); flag.MaxCount > 0 && n == flag.MaxCount {
+			p.positionals = p.positionals[1:]
+		}
+		p.operands = p.strict
+		c.emit(PositionalToken, token, flag)
+		return
+	}
+	cmd, ok := p.cmd.index().subcommands[token]
+	if !ok || !cmd.featureEnabled(cmd.Feature) {
+		c.emit(PositionalToken, token, nil)
+		return
+	}
+	p.setCommand(cmd)
+	c.emit(CommandToken, token, nil)
+}
+
+func (c *TokenScanner) scanFlag(token string) {
+	p := c.p
+	flag := p.lookupFlag(token)
+	isBool := flag != nil && isBoolValue(flag.Value)
+	if flag == nil {
+		if flags := p.subcommandFlags(token); len(flags) > 0 {
+			flag, isBool = flags[0], isBoolValue(flags[0].Value)
+		}
+	}
+	c.emit(FlagToken, token, flag)
+	value, ok := p.peek()
+	attached := ok && p.indexes[0] == p.index
+	switch {
+	case attached:
+	case !ok || flag == nil:
+		return
+	case isBool:
+		if p.boolSyntax() != BoolSeparate || p.strict || value != "true" && value != "false" {
+			return
+		}
+	case !isPositional(value):
+		return
+	}
+	p.next()
+	c.emit(ValueToken, value, flag)
+}
+
+// emit queues a token that was read from the current argument of the parser.
+// Tokens that were split from an argument, such as the value of "--foo=bar",
+// are always at its end.
+func (c *TokenScanner) emit(kind TokenKind, text string, flag *Flag) {
+	arg := c.p.rawArgs[c.p.index]
+	offset := 0
+	if kind == ValueToken && len(text) < len(arg) {
+		offset = len(arg) - len(text)
+	}
+	c.tokens = append(c.tokens, Token{
+		Kind:    kind,
+		Text:    text,
+		Index:   c.p.index,
+		Offset:  offset,
+		Len:     len(text),
+		Command: c.p.cmd,
+		Flag:    flag,
+	})
+}
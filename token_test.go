@@ -0,0 +1,69 @@
+package xflags
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	cmd := NewCommand("app", "").
+		WithTerminator().
+		PrefixShortNames().
+		Flags(
+			Bool(nil, "verbose", false, "").ShortName("v"),
+			String(nil, "heap", "", "").ShortName("Xmx"),
+		).
+		Subcommands(
+			NewCommand("get", "").
+				Flags(
+					Int(nil, "limit", 0, ""),
+					String(nil, "name", "", "").Positional(),
+				).
+				HandleFunc(func(args []string) int { return 0 }),
+		).
+		Must()
+	tests := []struct {
+		Args   []string
+		Expect string
+	}{
+		{
+			Args:   []string{"-v", "get", "--limit", "5", "foo", "bar"},
+			Expect: "flag:-v@0 command:get@1 flag:--limit@2 value:5@3 positional:foo@4 positional:bar@5",
+		},
+		{
+			Args:   []string{"--limit=5", "-Xmx512m", "get"},
+			Expect: "flag:--limit@0 value:5@0+8 flag:-Xmx@1 value:512m@1+4 command:get@2",
+		},
+		{
+			Args:   []string{"--nope", "x", "--verbose=false", "--", "-v"},
+			Expect: "flag:--nope@0 positional:x@1 flag:--verbose@2 value:false@2+10 terminator:--@3 positional:-v@4",
+		},
+	}
+	for _, test := range tests {
+		var tokens []string
+		scanner := cmd.Scan(test.Args)
+		for scanner.Next() {
+			tok := scanner.Token()
+			s := fmt.Sprintf("%v:%s@%d", tok.Kind, tok.Text, tok.Index)
+			if tok.Offset > 0 {
+				s += fmt.Sprintf("+%d", tok.Offset)
+			}
+			assertString(t, tok.Text, test.Args[tok.Index][tok.Offset:tok.Offset+tok.Len])
+			tokens = append(tokens, s)
+		}
+		assertString(t, test.Expect, strings.Join(tokens, " "))
+	}
+
+	// flags and positional arguments are resolved
+	scanner := cmd.Scan([]string{"get", "--limit", "5", "foo"})
+	var kinds []string
+	for scanner.Next() {
+		tok := scanner.Token()
+		if tok.Flag != nil {
+			kinds = append(kinds, tok.Flag.Name)
+		}
+		assertString(t, "get", tok.Command.Name)
+	}
+	assertStrings(t, []string{"limit", "limit", "name"}, kinds)
+}
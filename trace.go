@@ -0,0 +1,32 @@
+package xflags
+
+// TraceEntry describes one or more command line arguments that were consumed
+// by the parser. Entries are recorded in the order that the arguments were
+// specified.
+//
+// If the arguments named a subcommand, Command is the subcommand. If they set
+// a flag or positional argument, Flag is the flag and Value is the value that
+// was set. If the argument was an expression operator, Operator is the
+// operator. Entries with none of these describe the "--" terminator and the
+// arguments that follow it.
+type TraceEntry struct {
+	Index    int      // index of the first argument
+	Args     []string // raw arguments, such as ["--foo", "bar"] or ["--foo=bar"]
+	Command  *Command
+	Flag     *Flag
+	Value    string
+	Operator string
+}
+
+// ParseTrace returns an entry for each command line argument consumed by the
+// parser, in order, so that commands whose meaning depends on the order in
+// which flags and positional arguments are interleaved can be implemented.
+// Values read from environment variables or sources are not included.
+//
+// ParseTrace is only populated on the command returned by Parse.
+func (c *Command) ParseTrace() []TraceEntry {
+	if c.parser == nil {
+		return nil
+	}
+	return c.parser.trace
+}
@@ -0,0 +1,53 @@
+package xflags
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	var name, size []string
+	var verbose bool
+	var paths []string
+	cmd, err := NewCommand("find", "").
+		Subcommands(
+			NewCommand("files", "").
+				WithTerminator().
+				Flags(
+					Strings(&name, "name", nil, ""),
+					Strings(&size, "size", nil, ""),
+					Bool(&verbose, "", false, "").ShortName("v"),
+					Strings(&paths, "path", nil, "").Positional(),
+				),
+		).
+		Must().
+		Parse([]string{
+			"files", "--name", "a", ".", "--size=+1M", "-v", "--name=b", "--", "x",
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"0 [files] cmd files",
+		"1 [--name a] --name=a",
+		"3 [.] PATH=.",
+		"4 [--size=+1M] --size=+1M",
+		"5 [-v] -v=true",
+		"6 [--name=b] --name=b",
+		"7 [--] ",
+		"8 [x] ",
+	}
+	trace := cmd.ParseTrace()
+	actual := make([]string, len(trace))
+	for i, entry := range trace {
+		s := fmt.Sprintf("%d %v ", entry.Index, entry.Args)
+		if entry.Command != nil {
+			s += "cmd " + entry.Command.Name
+		}
+		if entry.Flag != nil {
+			s += entry.Flag.String() + "=" + entry.Value
+		}
+		actual[i] = s
+	}
+	assertStrings(t, expect, actual)
+}
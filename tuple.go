@@ -0,0 +1,215 @@
+package xflags
+
+import (
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Tuple returns a FlagBuilder that can be used to define a flag whose value is
+// a small tuple, such as "10,20" or "1:2:3", with specified name, separator
+// and usage string. The argument p must be a non-nil pointer to a struct whose
+// exported fields, in order, declare the elements of the tuple. The current
+// value of the struct is the default value of the flag.
+//
+//	var r struct{ Min, Max int }
+//	xflags.Tuple(&r, "range", ",", "Range of values")
+//
+// Each element is converted to the type of its field using the parsers of
+// NewValue. Elements are separated by sep, unless the "tuple" struct tag of a
+// field specifies the separator that precedes it, as in
+//
+//	var endpoint struct {
+//		Host  string
+//		Port  int
+//		Proto *string `tuple:"/"`
+//	}
+//
+// for "example.com:443/tcp" with the separator ":". Trailing pointer fields
+// are optional and left nil if their elements are omitted. If every
+// separator is a comma, the argument is parsed as a CSV record, so elements
+// may be quoted to contain commas.
+func Tuple(p any, name, sep, usage string) *FlagBuilder {
+	rv := reflect.ValueOf(p)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		c := Var(nil, name, usage)
+		c.err = errorf("%s: value must be a non-nil pointer to a struct: %T", name, p)
+		return c
+	}
+	if sep == "" {
+		c := Var(nil, name, usage)
+		c.err = errorf("%s: tuple separator cannot be empty", name)
+		return c
+	}
+	v := &tupleValue{p: rv}
+	t := rv.Elem().Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		fieldSep := sep
+		if tag := field.Tag.Get("tuple"); tag != "" {
+			fieldSep = tag
+		}
+		v.fields = append(v.fields, tupleField{
+			index:    i,
+			name:     field.Name,
+			sep:      fieldSep,
+			optional: field.Type.Kind() == reflect.Ptr,
+		})
+	}
+	if len(v.fields) == 0 {
+		c := Var(nil, name, usage)
+		c.err = errorf("%s: tuple struct has no exported fields: %T", name, p)
+		return c
+	}
+	v.csv = true
+	for i, field := range v.fields {
+		if i > 0 && field.sep != "," {
+			v.csv = false
+		}
+		if i > 0 && v.fields[i-1].optional && !field.optional {
+			c := Var(nil, name, usage)
+			c.err = errorf("%s: optional tuple field %s must not precede required field %s",
+				name, v.fields[i-1].name, field.name)
+			return c
+		}
+	}
+	return Var(v, name, usage)
+}
+
+// tupleField is an element of a tuple.
+type tupleField struct {
+	index    int    // index of the struct field
+	name     string // name of the struct field
+	sep      string // separator that precedes the element
+	optional bool   // element may be omitted
+}
+
+type tupleValue struct {
+	p      reflect.Value // pointer to the struct
+	fields []tupleField
+	csv    bool // parse arguments as CSV records
+}
+
+func (c *tupleValue) Get() interface{} { return c.p.Elem().Interface() }
+
+func (c *tupleValue) clone() Value {
+	p := reflect.New(c.p.Type().Elem())
+	p.Elem().Set(c.p.Elem())
+	return &tupleValue{p: p, fields: c.fields, csv: c.csv}
+}
+
+func (c *tupleValue) String() string {
+	var elems []string
+	for _, field := range c.fields {
+		v := c.p.Elem().Field(field.index)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				break
+			}
+			v = v.Elem()
+		}
+		elems = append(elems, tupleElemString(v))
+	}
+	if c.csv {
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		_ = w.Write(elems)
+		w.Flush()
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+	var b strings.Builder
+	for i, elem := range elems {
+		if i > 0 {
+			b.WriteString(c.fields[i].sep)
+		}
+		b.WriteString(elem)
+	}
+	return b.String()
+}
+
+// tupleElemString formats the element v with the formatter of NewValue, if
+// any.
+func tupleElemString(v reflect.Value) string {
+	if v.CanAddr() {
+		if value, err := NewValue(v.Addr().Interface()); err == nil {
+			return valueString(value)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func (c *tupleValue) Set(s string) error {
+	elems, err := c.split(s)
+	if err != nil {
+		return err
+	}
+	v := reflect.New(c.p.Type().Elem()).Elem()
+	for i, elem := range elems {
+		field := c.fields[i]
+		if err := setReflect(v.Field(field.index), elem); err != nil {
+			return errorf("invalid %s: %q: %v", field.name, elem, errStr(err))
+		}
+	}
+	c.p.Elem().Set(v)
+	return nil
+}
+
+// split returns the elements of the tuple s.
+func (c *tupleValue) split(s string) ([]string, error) {
+	var elems []string
+	if c.csv {
+		r := csv.NewReader(strings.NewReader(s))
+		r.FieldsPerRecord = -1
+		record, err := r.Read()
+		if err != nil {
+			return nil, errorf("invalid tuple: %s: %v", s, errStr(err))
+		}
+		elems = record
+	} else {
+		rest := s
+		for i := range c.fields {
+			if i == len(c.fields)-1 {
+				elems = append(elems, rest)
+				break
+			}
+			next := c.fields[i+1].sep
+			j := strings.Index(rest, next)
+			if j < 0 {
+				elems = append(elems, rest)
+				break
+			}
+			elems = append(elems, rest[:j])
+			rest = rest[j+len(next):]
+		}
+	}
+	required := 0
+	for _, field := range c.fields {
+		if !field.optional {
+			required++
+		}
+	}
+	if len(elems) < required || len(elems) > len(c.fields) {
+		return nil, errorf("invalid tuple: %s (expected %s)", s, c.syntax())
+	}
+	return elems, nil
+}
+
+// syntax returns the syntax of the tuple, such as "Min,Max".
+func (c *tupleValue) syntax() string {
+	var b strings.Builder
+	for i, field := range c.fields {
+		if i > 0 {
+			b.WriteString(field.sep)
+		}
+		if field.optional {
+			b.WriteString("[" + field.name + "]")
+		} else {
+			b.WriteString(field.name)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package xflags
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTuple(t *testing.T) {
+	var r struct{ Min, Max int }
+	r.Max = 100
+	flag := Tuple(&r, "range", ",", "").Must()
+	assertString(t, "0,100", valueString(flag.Value))
+	if assertFlagParses(t, flag, "--range", "10,20") {
+		assertInt64(t, 10, int64(r.Min))
+		assertInt64(t, 20, int64(r.Max))
+	}
+
+	var point struct{ X, Y, Z float64 }
+	if assertFlagParses(t, Tuple(&point, "point", ":", "").Must(), "--point=1:2.5:-3") {
+		assertFloat64(t, 1, point.X)
+		assertFloat64(t, 2.5, point.Y)
+		assertFloat64(t, -3, point.Z)
+	}
+
+	// CSV quoting
+	var label struct {
+		Key, Value string
+		TTL        time.Duration
+	}
+	flag = Tuple(&label, "label", ",", "").Must()
+	if assertFlagParses(t, flag, "--label", `team,"core, ops",1h`) {
+		assertString(t, "team", label.Key)
+		assertString(t, "core, ops", label.Value)
+		assertDuration(t, time.Hour, label.TTL)
+		assertString(t, `team,"core, ops",1h0m0s`, valueString(flag.Value))
+	}
+
+	// per-field separators and optional fields
+	type endpoint struct {
+		Host  string
+		Port  uint16
+		Proto *string `tuple:"/"`
+	}
+	var e endpoint
+	flag = Tuple(&e, "endpoint", ":", "").Must()
+	if assertFlagParses(t, flag, "--endpoint=example.com:53/udp") {
+		assertString(t, "example.com", e.Host)
+		assertUint64(t, 53, uint64(e.Port))
+		if assertBool(t, true, e.Proto != nil) {
+			assertString(t, "udp", *e.Proto)
+		}
+		assertString(t, "example.com:53/udp", valueString(flag.Value))
+	}
+	if assertFlagParses(t, flag, "--endpoint=example.com:443") {
+		assertBool(t, true, e.Proto == nil)
+	}
+
+	tests := []struct {
+		Arg    string
+		Expect string
+	}{
+		{"example.com", "xflags: --endpoint: invalid tuple: example.com (expected Host:Port/[Proto])"},
+		{"example.com:x", `xflags: --endpoint: invalid Port: "x": strconv.ParseUint: parsing "x": invalid syntax`},
+	}
+	for _, test := range tests {
+		err := parseFlag(flag, "--endpoint="+test.Arg)
+		if assertBool(t, true, err != nil) {
+			assertString(t, test.Expect, err.Error())
+		}
+	}
+	assertString(t, "example.com", e.Host) // unchanged by errors
+
+	if _, err := Tuple(&r, "range", "", "").Flag(); err == nil {
+		t.Error("expected error for empty separator")
+	}
+	var invalid struct {
+		A *int
+		B int
+	}
+	if _, err := Tuple(&invalid, "invalid", ",", "").Flag(); err == nil {
+		t.Error("expected error for optional field before required field")
+	}
+}
@@ -2,7 +2,9 @@ package xflags
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -29,17 +31,87 @@ func isBoolValue(v Value) bool {
 	return false
 }
 
+// Getter is an optional interface implemented by Values whose contents may be
+// retrieved. All Value types provided by this package implement Getter.
+type Getter interface {
+	Value
+	Get() interface{}
+}
+
 // ValidateFunc is a function that validates an argument before it is parsed.
 type ValidateFunc = func(arg string) error
 
+// valueString returns the string form of v or an empty string if v does not
+// implement fmt.Stringer.
+func valueString(v Value) string {
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return ""
+}
+
+// valueType returns the name of the type of a builtin value, such as "int" or
+// "strings", or an empty string if v is not a builtin value.
+func valueType(v Value) string {
+	switch v.(type) {
+	case *boolValue:
+		return "bool"
+	case *durationValue:
+		return "duration"
+	case *float64Value:
+		return "float64"
+	case *intValue:
+		return "int"
+	case *int64Value:
+		return "int64"
+	case *percentValue:
+		return "percent"
+	case *stringValue:
+		return "string"
+	case *uintValue:
+		return "uint"
+	case *uint64Value:
+		return "uint64"
+	case *stringSliceValue:
+		return "strings"
+	}
+	return ""
+}
+
+// cloner is implemented by builtin values that can be copied so that a command
+// line may be parsed without modifying the value of a flag. See
+// Command.ParseInvocation.
+type cloner interface {
+	clone() Value
+}
+
+// redacter is implemented by builtin values that hide sensitive parts of the
+// command line arguments they are set from, such as the password of a DSN, so
+// that command lines may be recorded. See Invocation.command.
+type redacter interface {
+	redact(s string) string
+}
+
+// resetter is implemented by builtin values which accumulate each call to Set,
+// so that they may be set again from scratch. See Command.Reload.
+type resetter interface {
+	reset()
+}
+
 type bitFieldValue struct {
 	p    *uint64
 	mask uint64
 }
 
 func newBitFieldValue(val bool, p *uint64, mask uint64) *bitFieldValue {
+	if p == nil {
+		p = new(uint64)
+	}
 	v := &bitFieldValue{p: p, mask: mask}
-	v.set(val)
+	if val {
+		// a false default leaves the bits of *p unchanged
+		v.set(true)
+	}
 	return v
 }
 
@@ -49,6 +121,11 @@ func (p *bitFieldValue) String() string { return fmt.Sprintf("0x%0x", *p.p) }
 
 func (p *bitFieldValue) Get() interface{} { return *p.p }
 
+func (p *bitFieldValue) clone() Value {
+	v := *p.p
+	return &bitFieldValue{p: &v, mask: p.mask}
+}
+
 func (p *bitFieldValue) Set(s string) error {
 	v, err := strconv.ParseBool(s)
 	if err != nil {
@@ -61,12 +138,17 @@ func (p *bitFieldValue) Set(s string) error {
 func (p *bitFieldValue) set(v bool) {
 	if v {
 		*p.p |= p.mask
+	} else {
+		*p.p &^= p.mask
 	}
 }
 
 type boolValue bool
 
 func newBoolValue(val bool, p *bool) *boolValue {
+	if p == nil {
+		p = new(bool)
+	}
 	*p = val
 	return (*boolValue)(p)
 }
@@ -77,6 +159,8 @@ func (p *boolValue) String() string { return strconv.FormatBool((bool)(*p)) }
 
 func (p *boolValue) Get() interface{} { return (bool)(*p) }
 
+func (p *boolValue) clone() Value { v := *p; return &v }
+
 func (p *boolValue) Set(s string) error {
 	v, err := strconv.ParseBool(s)
 	if err != nil {
@@ -89,6 +173,9 @@ func (p *boolValue) Set(s string) error {
 type durationValue time.Duration
 
 func newDurationValue(val time.Duration, p *time.Duration) *durationValue {
+	if p == nil {
+		p = new(time.Duration)
+	}
 	*p = val
 	return (*durationValue)(p)
 }
@@ -97,6 +184,8 @@ func (p *durationValue) String() string { return (time.Duration)(*p).String() }
 
 func (p *durationValue) Get() interface{} { return (time.Duration)(*p) }
 
+func (p *durationValue) clone() Value { v := *p; return &v }
+
 func (p *durationValue) Set(s string) error {
 	v, err := time.ParseDuration(s)
 	if err != nil {
@@ -109,6 +198,9 @@ func (p *durationValue) Set(s string) error {
 type float64Value float64
 
 func newFloat64Value(val float64, p *float64) *float64Value {
+	if p == nil {
+		p = new(float64)
+	}
 	*p = val
 	return (*float64Value)(p)
 }
@@ -119,6 +211,8 @@ func (p *float64Value) String() string {
 
 func (p *float64Value) Get() interface{} { return (float64)(*p) }
 
+func (p *float64Value) clone() Value { v := *p; return &v }
+
 func (p *float64Value) Set(s string) error {
 	v, err := strconv.ParseFloat(s, 64)
 	if err != nil {
@@ -135,6 +229,9 @@ func (f funcValue) Set(s string) error { return f(s) }
 type intValue int
 
 func newIntValue(val int, p *int) *intValue {
+	if p == nil {
+		p = new(int)
+	}
 	*p = val
 	return (*intValue)(p)
 }
@@ -145,8 +242,10 @@ func (p *intValue) String() string {
 
 func (p *intValue) Get() interface{} { return (int64)(*p) }
 
+func (p *intValue) clone() Value { v := *p; return &v }
+
 func (p *intValue) Set(s string) error {
-	v, err := strconv.ParseInt(s, 10, 64)
+	v, err := parseInt(s, strconv.IntSize)
 	if err != nil {
 		return err
 	}
@@ -157,6 +256,9 @@ func (p *intValue) Set(s string) error {
 type int64Value int64
 
 func newInt64Value(val int64, p *int64) *int64Value {
+	if p == nil {
+		p = new(int64)
+	}
 	*p = val
 	return (*int64Value)(p)
 }
@@ -167,8 +269,10 @@ func (p *int64Value) String() string {
 
 func (p *int64Value) Get() interface{} { return (int64)(*p) }
 
+func (p *int64Value) clone() Value { v := *p; return &v }
+
 func (p *int64Value) Set(s string) error {
-	v, err := strconv.ParseInt(s, 10, 64)
+	v, err := parseInt(s, 64)
 	if err != nil {
 		return err
 	}
@@ -176,9 +280,53 @@ func (p *int64Value) Set(s string) error {
 	return nil
 }
 
+type percentValue float64
+
+func newPercentValue(val float64, p *float64) *percentValue {
+	if p == nil {
+		p = new(float64)
+	}
+	*p = val
+	return (*percentValue)(p)
+}
+
+// String returns the value as a percentage, such as "75%".
+func (p *percentValue) String() string {
+	// round to remove errors of binary floating point, as in 0.07 * 100
+	v := math.Round(float64(*p)*100*1e9) / 1e9
+	return strconv.FormatFloat(v, 'f', -1, 64) + "%"
+}
+
+func (p *percentValue) Get() interface{} { return (float64)(*p) }
+
+func (p *percentValue) clone() Value { v := *p; return &v }
+
+// Set accepts a percentage, such as "75%", or a fraction, such as "0.75".
+func (p *percentValue) Set(s string) error {
+	var v float64
+	var err error
+	if f := strings.TrimSuffix(s, "%"); f != s {
+		v, err = strconv.ParseFloat(strings.TrimSpace(f), 64)
+		v /= 100
+	} else {
+		v, err = strconv.ParseFloat(s, 64)
+	}
+	if err != nil {
+		return errorf("invalid percentage: %q", s)
+	}
+	if v < 0 || v > 1 || math.IsNaN(v) {
+		return errorf("percentage out of range [0%%, 100%%]: %s", s)
+	}
+	*p = percentValue(v)
+	return nil
+}
+
 type stringValue string
 
 func newStringValue(val string, p *string) *stringValue {
+	if p == nil {
+		p = new(string)
+	}
 	*p = val
 	return (*stringValue)(p)
 }
@@ -187,19 +335,30 @@ func (p *stringValue) String() string { return (string)(*p) }
 
 func (p *stringValue) Get() interface{} { return (string)(*p) }
 
+func (p *stringValue) clone() Value { v := *p; return &v }
+
 func (p *stringValue) Set(s string) error {
 	*p = stringValue(s)
 	return nil
 }
 
 type stringSliceValue struct {
-	p   *[]string
-	hot bool
+	p               *[]string
+	defaultValue    []string
+	appendToDefault bool
+	isSet           bool
 }
 
 func newStringSliceValue(val []string, p *[]string) *stringSliceValue {
+	if p == nil {
+		p = new([]string)
+	}
+	if val != nil {
+		// copy the default so it cannot be modified by sorting or appending
+		val = append(make([]string, 0, len(val)), val...)
+	}
 	*p = val
-	return &stringSliceValue{p: p}
+	return &stringSliceValue{p: p, defaultValue: val}
 }
 
 func (p *stringSliceValue) String() string {
@@ -208,10 +367,31 @@ func (p *stringSliceValue) String() string {
 
 func (p *stringSliceValue) Get() interface{} { return *p.p }
 
+func (p *stringSliceValue) clone() Value {
+	v := newStringSliceValue(p.defaultValue, nil)
+	v.appendToDefault = p.appendToDefault
+	return v
+}
+
+func (p *stringSliceValue) Len() int { return len(*p.p) }
+
+func (p *stringSliceValue) Less(i, j int) bool { return (*p.p)[i] < (*p.p)[j] }
+
+func (p *stringSliceValue) Swap(i, j int) { (*p.p)[i], (*p.p)[j] = (*p.p)[j], (*p.p)[i] }
+
+// reset causes the next call to Set to replace the slice again.
+func (p *stringSliceValue) reset() { p.isSet = false }
+
+// Set appends s to the slice. The first call replaces the default value
+// unless appendToDefault is set.
 func (p *stringSliceValue) Set(s string) error {
-	if !p.hot {
-		*p.p = make([]string, 0, 1)
-		p.hot = true
+	if !p.isSet {
+		a := make([]string, 0, len(p.defaultValue)+1)
+		if p.appendToDefault {
+			a = append(a, p.defaultValue...)
+		}
+		*p.p = a
+		p.isSet = true
 	}
 	*p.p = append(*p.p, s)
 	return nil
@@ -220,6 +400,9 @@ func (p *stringSliceValue) Set(s string) error {
 type uintValue uint
 
 func newUintValue(val uint, p *uint) *uintValue {
+	if p == nil {
+		p = new(uint)
+	}
 	*p = val
 	return (*uintValue)(p)
 }
@@ -230,8 +413,10 @@ func (p *uintValue) String() string {
 
 func (p *uintValue) Get() interface{} { return (int64)(*p) }
 
+func (p *uintValue) clone() Value { v := *p; return &v }
+
 func (p *uintValue) Set(s string) error {
-	v, err := strconv.ParseInt(s, 10, 64)
+	v, err := parseUint(s, strconv.IntSize)
 	if err != nil {
 		return err
 	}
@@ -242,6 +427,9 @@ func (p *uintValue) Set(s string) error {
 type uint64Value uint64
 
 func newUint64Value(val uint64, p *uint64) *uint64Value {
+	if p == nil {
+		p = new(uint64)
+	}
 	*p = val
 	return (*uint64Value)(p)
 }
@@ -252,8 +440,10 @@ func (p *uint64Value) String() string {
 
 func (p *uint64Value) Get() interface{} { return (int64)(*p) }
 
+func (p *uint64Value) clone() Value { v := *p; return &v }
+
 func (p *uint64Value) Set(s string) error {
-	v, err := strconv.ParseInt(s, 10, 64)
+	v, err := parseUint(s, 64)
 	if err != nil {
 		return err
 	}
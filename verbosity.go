@@ -0,0 +1,114 @@
+package xflags
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// verbosityOptions are the values of the flags registered by
+// StandardVerbosityFlags.
+type verbosityOptions struct {
+	quiet   bool
+	verbose verboseValue
+}
+
+// verbosityLevel returns the verbosity level selected by the flags.
+func verbosityLevel(quiet bool, verbose int64) int {
+	if quiet {
+		return -1
+	}
+	return int(verbose)
+}
+
+// StandardVerbosityFlags registers the --quiet (-q) and --verbose (-v) flags
+// for this command and its subcommands, following a convention that users
+// can rely on across programs:
+//
+//   - --quiet sets the verbosity level to -1 and suppresses informational
+//     output, including the output of Run other than errors, such as
+//     notifications of new versions.
+//   - --verbose increments the verbosity level each time it is specified, as
+//     in "-v -v" or "-vv".
+//
+// The flags may not be specified together. Handlers retrieve the level with
+// VerbosityFrom and the Output returned by OutputFrom sets Quiet and Verbose
+// accordingly. StandardVerbosityFlags may not be used together with
+// OutputFlags, which registers flags with the same names.
+func (c *CommandBuilder) StandardVerbosityFlags() *CommandBuilder {
+	opts := &verbosityOptions{}
+	c.cmd.verbosityOptions = opts
+	return c.FlagGroup(
+		"verbosity",
+		"Verbosity options",
+		Bool(&opts.quiet, "quiet", false, "Suppress informational output").
+			ShortName("q").
+			builtin(),
+		Var(&opts.verbose, "verbose", "Print more detailed output; may be repeated").
+			ShortName("v").
+			NArgs(0, 0).
+			builtin(),
+	)
+}
+
+// VerbosityFrom returns the verbosity level selected by the flags registered
+// by StandardVerbosityFlags for the command invocation that ctx was created
+// for: -1 if --quiet was specified, 0 by default or the number of times that
+// --verbose was specified.
+func VerbosityFrom(ctx context.Context) int {
+	return OutputFrom(ctx).Verbosity
+}
+
+// verbosityCommand returns c or its nearest parent that registered
+// StandardVerbosityFlags, or nil.
+func (c *Command) verbosityCommand() *Command {
+	for p := c; p != nil; p = p.Parent {
+		if p.verbosityOptions != nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// checkVerbosity returns an error if both --quiet and --verbose were
+// specified.
+func (c *argParser) checkVerbosity() error {
+	p := c.cmd.verbosityCommand()
+	if p == nil || !c.optionValue(p, "quiet").(bool) || c.optionValue(p, "verbose").(int64) == 0 {
+		return nil
+	}
+	verbose, quiet := c.lookupName("verbose"), c.lookupName("quiet")
+	return newArgErr(c.cmd, verbose, "", "cannot be specified with %s", quiet)
+}
+
+// verboseValue is the value of the --verbose flag which counts the number of
+// times it is specified. Since "-vv" is parsed as the short name "-v" with the
+// value "v", a value that only contains the letter v also increments the
+// count once for the flag and once for each letter.
+type verboseValue int
+
+func (p *verboseValue) IsBoolFlag() bool { return true }
+
+func (p *verboseValue) String() string { return strconv.Itoa(int(*p)) }
+
+func (p *verboseValue) Get() interface{} { return (int64)(*p) }
+
+func (p *verboseValue) clone() Value { v := *p; return &v }
+
+// Set increments the count if s is true and resets it if s is false.
+func (p *verboseValue) Set(s string) error {
+	if s != "" && strings.Trim(s, "v") == "" {
+		*p += verboseValue(1 + len(s))
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if v {
+		*p++
+	} else {
+		*p = 0
+	}
+	return nil
+}
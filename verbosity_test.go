@@ -0,0 +1,61 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestStandardVerbosityFlags(t *testing.T) {
+	tests := []struct {
+		Args    []string
+		Level   int
+		Quiet   bool
+		Verbose bool
+	}{
+		{nil, 0, false, false},
+		{[]string{"-v"}, 1, false, true},
+		{[]string{"-vv", "--verbose"}, 3, false, true},
+		{[]string{"--quiet"}, -1, true, false},
+	}
+	for _, test := range tests {
+		var level int
+		var out *Output
+		code := NewCommand("app", "").
+			StandardVerbosityFlags().
+			HandleContext(func(ctx context.Context, args []string) int {
+				level, out = VerbosityFrom(ctx), OutputFrom(ctx)
+				return 0
+			}).
+			Must().
+			Run(test.Args)
+		assertInt64(t, 0, int64(code))
+		assertInt64(t, int64(test.Level), int64(level))
+		assertBool(t, test.Quiet, out.Quiet)
+		assertBool(t, test.Verbose, out.Verbose)
+	}
+
+	_, err := NewCommand("app", "").StandardVerbosityFlags().Must().Parse([]string{"-q", "-v"})
+	var argErr *ArgumentError
+	if assertErrorAs(t, err, &argErr) {
+		assertString(t, "verbose", argErr.Flag.Name)
+	}
+}
+
+func TestStandardVerbosityFlagsQuiet(t *testing.T) {
+	stderr := new(bytes.Buffer)
+	newCommand := func() *Command {
+		return NewCommand("app", "").
+			Output(new(bytes.Buffer), stderr).
+			StandardVerbosityFlags().
+			CheckVersion(func(current string) (string, bool) { return "v2.0.0", true }).
+			HandleFunc(func(args []string) int { return 0 }).
+			Must()
+	}
+	assertInt64(t, 0, int64(newCommand().Run([]string{"-q"})))
+	assertString(t, "", stderr.String())
+	assertInt64(t, 0, int64(newCommand().Run(nil)))
+	if stderr.Len() == 0 {
+		t.Error("expected version notice")
+	}
+}
@@ -0,0 +1,87 @@
+package xflags
+
+import (
+	"fmt"
+	"time"
+)
+
+// versionCheckWait is the maximum time that Run waits for a version check to
+// complete after the handler returns.
+const versionCheckWait = time.Second
+
+// CheckVersionFunc is a function that checks for a newer version of a program.
+// It receives the current version of the program and returns the latest
+// version and true if a newer version is available.
+type CheckVersionFunc func(current string) (latest string, ok bool)
+
+// Version sets the version of the program.
+func (c *CommandBuilder) Version(version string) *CommandBuilder {
+	c.cmd.Version = version
+	return c
+}
+
+// CheckVersion specifies a function that is called by Run, concurrently with
+// the handler of this command or any of its subcommands, to check for a newer
+// version of the program. If a newer version is available, a single line
+// notice is printed to stderr after the handler returns.
+//
+// Run waits no more than one second for fn to return after the handler
+// returns. The notice is not printed if the command line cannot be parsed,
+// the invoked command has no handler or --quiet was specified.
+func (c *CommandBuilder) CheckVersion(fn CheckVersionFunc) *CommandBuilder {
+	c.cmd.CheckVersionFunc = fn
+	return c
+}
+
+// checkVersion starts a version check if c or its parents define one and
+// returns a function that prints a notice if a newer version is available.
+func (c *Command) checkVersion() (notify func()) {
+	var fn CheckVersionFunc
+	var root *Command
+	for p := c; p != nil; p = p.Parent {
+		if fn == nil {
+			fn = p.CheckVersionFunc
+		}
+		root = p
+	}
+	if fn == nil {
+		return func() {}
+	}
+	version := c.version()
+	type result struct {
+		latest string
+		ok     bool
+	}
+	ch := make(chan result, 1)
+	go func() {
+		latest, ok := fn(version)
+		ch <- result{latest, ok}
+	}()
+	return func() {
+		select {
+		case r := <-ch:
+			if !r.ok || c.newOutput(c.parser).Quiet {
+				return
+			}
+			_, stderr := c.output()
+			fmt.Fprintf(
+				stderr,
+				"A new version of %s is available: %s (current version: %s)\n",
+				root.Name,
+				r.latest,
+				version,
+			)
+		case <-time.After(versionCheckWait):
+		}
+	}
+}
+
+// version returns the version of c, inherited from its parents.
+func (c *Command) version() string {
+	for p := c; p != nil; p = p.Parent {
+		if p.Version != "" {
+			return p.Version
+		}
+	}
+	return ""
+}
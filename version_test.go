@@ -0,0 +1,40 @@
+package xflags
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		Latest string
+		OK     bool
+		Expect string
+	}{
+		{"1.1.0", true, "A new version of app is available: 1.1.0 (current version: 1.0.0)\n"},
+		{"1.0.0", false, ""},
+	}
+	for _, test := range tests {
+		var current string
+		stderr := new(bytes.Buffer)
+		cmd := NewCommand("app", "").
+			Version("1.0.0").
+			Output(new(bytes.Buffer), stderr).
+			CheckVersion(func(v string) (string, bool) {
+				current = v
+				return test.Latest, test.OK
+			}).
+			Subcommands(
+				NewCommand("sub", "").HandleFunc(func(args []string) int {
+					stderr.WriteString("handled\n")
+					return 0
+				}),
+			).
+			Must()
+		if code := cmd.Run([]string{"sub"}); code != 0 {
+			t.Errorf("unexpected exit code: %d", code)
+		}
+		assertString(t, "1.0.0", current)
+		assertString(t, "handled\n"+test.Expect, stderr.String())
+	}
+}
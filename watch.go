@@ -0,0 +1,320 @@
+package xflags
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultWatchInterval is the interval of --watch if it is specified
+	// without a value.
+	defaultWatchInterval = 2 * time.Second
+
+	// watchPollInterval is the interval at which the paths of --watch-path
+	// are checked for changes if --watch is not specified.
+	watchPollInterval = 500 * time.Millisecond
+
+	// watchDebounce is how long the paths of --watch-path must not change
+	// before the command is run again.
+	watchDebounce = 200 * time.Millisecond
+)
+
+// watchOptions is the value of the flag registered by EnableWatch.
+type watchOptions struct {
+	interval time.Duration // zero if the flag was not specified
+	paths    []string      // paths of --watch-path
+	ignore   []string      // patterns of --watch-ignore
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (c *watchOptions) String() string {
+	if c.interval == 0 {
+		return ""
+	}
+	return c.interval.String()
+}
+
+func (c *watchOptions) clone() Value { v := *c; return &v }
+
+// IsBoolFlag allows the flag to be specified without a value.
+func (c *watchOptions) IsBoolFlag() bool { return true }
+
+func (c *watchOptions) Set(s string) error {
+	switch s {
+	case "true":
+		c.interval = defaultWatchInterval
+		return nil
+	case "false":
+		c.interval = 0
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return errorf("interval must be positive: %s", s)
+	}
+	c.interval = d
+	return nil
+}
+
+// EnableWatch registers the --watch flag for this command and its
+// subcommands. If it is specified, the handler of the invoked command is
+// called repeatedly, like watch(1), every 2 seconds or at the interval given
+// as its value, as in --watch=10s, until the program is interrupted. This is
+// useful for commands that print a status.
+//
+// EnableWatch also registers the --watch-path flag, which may be specified
+// more than once. If it is specified, the handler is called again whenever a
+// file in one of the given files or directories changes, which is useful for
+// development commands such as "build" or "test". Changes are detected by
+// polling the paths, every 500ms or at the interval of --watch, and the
+// handler is only called again once no change was seen for 200ms, so that a
+// burst of changes, such as a checkout, runs the command once. Changes are
+// compared to the state of the paths after the previous run returned, so that
+// files written by the handler do not trigger another run. Files and
+// directories that match a pattern of --watch-ignore, such as "*.tmp" or
+// "node_modules", are not watched. Patterns without a slash match the name of
+// a file, and patterns with a slash match its path relative to a watched
+// directory, where "**" matches any number of directories.
+//
+// If stdout is a terminal, the screen is cleared before each run and a header
+// that shows the interval or paths, the command and the time is printed. The
+// exit code is the exit code of the last run.
+func (c *CommandBuilder) EnableWatch() *CommandBuilder {
+	opts := &watchOptions{}
+	paths, ignore := newStringSliceValue(nil, nil), newStringSliceValue(nil, nil)
+	return c.
+		FlagGroup(
+			"watch",
+			"Watch options",
+			Var(
+				opts,
+				"watch",
+				"Run the command repeatedly, every 2s or at the given interval",
+			).builtin(),
+			Var(
+				paths,
+				"watch-path",
+				"Run the command again when files in this path change",
+			).
+				NArgs(0, 0).
+				builtin(),
+			Var(
+				ignore,
+				"watch-ignore",
+				"Ignore changes to files that match this pattern",
+			).
+				NArgs(0, 0).
+				Validate(validateWatchIgnore).
+				builtin(),
+		).
+		Use(func(next ContextHandlerFunc) ContextHandlerFunc {
+			return func(ctx context.Context, args []string) int {
+				// the flags are read from the parser of the invocation, so
+				// that each invocation by Exec has its own options
+				parser := InvocationFrom(ctx).parser
+				w := *opts
+				w.interval = parser.valueOf(opts).(*watchOptions).interval
+				w.paths = parser.valueOf(paths).(Getter).Get().([]string)
+				w.ignore = parser.valueOf(ignore).(Getter).Get().([]string)
+				switch {
+				case len(w.paths) > 0:
+					return w.watchPaths(ctx, args, next)
+				case w.interval > 0:
+					return w.watch(ctx, args, next)
+				}
+				return next(ctx, args)
+			}
+		})
+}
+
+func validateWatchIgnore(s string) error {
+	for _, elem := range strings.Split(s, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return errorf("invalid pattern: %s", s)
+		}
+	}
+	return nil
+}
+
+// watch calls next at the interval of c until ctx is done or the program is
+// interrupted.
+func (c *watchOptions) watch(ctx context.Context, args []string, next ContextHandlerFunc) int {
+	now, sleep := c.now, c.sleep
+	if now == nil {
+		now = time.Now
+	}
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	ctx, stop := notifyInterrupt(ctx)
+	defer stop()
+	for {
+		c.printHeader(ctx, fmt.Sprintf("Every %s", c.interval), now())
+		exitCode := next(ctx, args)
+		if sleep(ctx, c.interval) != nil {
+			return exitCode
+		}
+	}
+}
+
+// printHeader clears the screen and prints a header for the next run if
+// stdout is a terminal that processes ANSI escape sequences.
+func (c *watchOptions) printHeader(ctx context.Context, trigger string, t time.Time) {
+	out := OutputFrom(ctx)
+	if !out.ANSI {
+		return
+	}
+	command := quotePOSIX(InvocationFrom(ctx).command(true))
+	// move the cursor home and clear the screen
+	fmt.Fprintf(out.Stdout, "\x1b[H\x1b[2J")
+	fmt.Fprintf(out.Stdout, "%s: %s    %s\n\n", trigger, command, t.Format(time.Stamp))
+}
+
+// watchPaths calls next each time the paths of c change until ctx is done or
+// the program is interrupted.
+func (c *watchOptions) watchPaths(ctx context.Context, args []string, next ContextHandlerFunc) int {
+	now, sleep := c.now, c.sleep
+	if now == nil {
+		now = time.Now
+	}
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	poll := c.interval
+	if poll == 0 {
+		poll = watchPollInterval
+	}
+	ctx, stop := notifyInterrupt(ctx)
+	defer stop()
+	if _, err := c.snapshot(); err != nil {
+		fmt.Fprintf(OutputFrom(ctx).Stderr, "Error: --watch-path: %v\n", err)
+		return 1
+	}
+	trigger := "Watching " + strings.Join(c.paths, ", ")
+	for {
+		c.printHeader(ctx, trigger, now())
+		exitCode := next(ctx, args)
+		// files written by the run itself, such as build outputs in a
+		// watched directory, do not trigger another run
+		prev, _ := c.snapshot()
+		for changed := false; !changed; {
+			if sleep(ctx, poll) != nil {
+				return exitCode
+			}
+			cur, _ := c.snapshot()
+			if cur.equal(prev) {
+				continue
+			}
+			// wait until the paths stop changing
+			for {
+				if sleep(ctx, watchDebounce) != nil {
+					return exitCode
+				}
+				settled, _ := c.snapshot()
+				if settled.equal(cur) {
+					break
+				}
+				cur = settled
+			}
+			changed = true
+		}
+	}
+}
+
+// fileState is the state of a watched file that is compared to detect changes.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+// watchSnapshot is the state of each file in the watched paths.
+type watchSnapshot map[string]fileState
+
+func (c watchSnapshot) equal(other watchSnapshot) bool {
+	if len(c) != len(other) {
+		return false
+	}
+	for name, state := range c {
+		if o, ok := other[name]; !ok || !o.modTime.Equal(state.modTime) || o.size != state.size || o.mode != state.mode {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot returns the state of each file in the watched paths that is not
+// ignored. An error is returned if a path does not exist.
+func (c *watchOptions) snapshot() (watchSnapshot, error) {
+	snap := make(watchSnapshot)
+	for _, root := range c.paths {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if p == root {
+					return err
+				}
+				return nil // skip files that were removed or are unreadable
+			}
+			if p != root && c.ignored(root, p) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			snap[p] = fileState{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+			return nil
+		})
+		if err != nil {
+			return snap, err
+		}
+	}
+	return snap, nil
+}
+
+// ignored reports whether the path p in the watched path root matches a
+// pattern of --watch-ignore.
+func (c *watchOptions) ignored(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range c.ignore {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, elems[len(elems)-1]); ok {
+				return true
+			}
+			continue
+		}
+		if matchGlob(strings.Split(pattern, "/"), elems) {
+			return true
+		}
+	}
+	return false
+}
+
+// sleepContext waits for d or until ctx is done and returns the error of ctx.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,197 @@
+package xflags
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnableWatch(t *testing.T) {
+	var runs int
+	var sleeps []time.Duration
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		EnableWatch().
+		Subcommands(
+			NewCommand("status", "").HandleFunc(func(args []string) int {
+				runs++
+				return runs
+			}),
+		).
+		Must()
+	opts := cmd.index().flagsByName["--watch"].Value.(*watchOptions)
+	opts.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		if len(sleeps) == 3 {
+			return errors.New("interrupted")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		Args   []string
+		Runs   int
+		Sleeps []time.Duration
+	}{
+		{[]string{"status"}, 1, nil},
+		{[]string{"--watch", "status"}, 3, []time.Duration{2 * time.Second, 2 * time.Second, 2 * time.Second}},
+		{[]string{"status", "--watch=500ms"}, 3, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond}},
+	}
+	for _, test := range tests {
+		runs, sleeps = 0, nil
+		opts.interval = 0
+		assertInt64(t, int64(test.Runs), int64(cmd.Run(test.Args)))
+		assertInt64(t, int64(test.Runs), int64(runs))
+		if assertInt64(t, int64(len(test.Sleeps)), int64(len(sleeps))) {
+			for i, d := range test.Sleeps {
+				assertDuration(t, d, sleeps[i])
+			}
+		}
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd = NewCommand("app", "").
+		Output(ioutil.Discard, stderr).
+		EnableWatch().
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+	assertInt64(t, 1, int64(cmd.Run([]string{"--watch=0s"})))
+	assertString(t, "Argument error: --watch: interval must be positive: 0s\nSee 'app --help'.\n", stderr.String())
+}
+
+func TestEnableWatchPaths(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", "package a")
+	write("node_modules/x.js", "x")
+
+	var runs int
+	var sleeps []time.Duration
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		EnableWatch().
+		HandleFunc(func(args []string) int {
+			runs++
+			return 0
+		}).
+		Must()
+	opts := cmd.index().flagsByName["--watch"].Value.(*watchOptions)
+	opts.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		switch len(sleeps) {
+		case 2:
+			write("node_modules/x.js", "ignored")
+		case 3:
+			write("a.go", "package a // changed")
+		case 4:
+			write("b.go", "package a") // still changing
+		case 6:
+			return errors.New("interrupted")
+		}
+		return nil
+	}
+	code := cmd.Run([]string{"--watch-path", dir, "--watch-ignore=node_modules"})
+	assertInt64(t, 0, int64(code))
+	assertInt64(t, 2, int64(runs))
+	expect := []time.Duration{
+		watchPollInterval,
+		watchPollInterval,
+		watchPollInterval,
+		watchDebounce,
+		watchDebounce,
+		watchPollInterval,
+	}
+	if assertInt64(t, int64(len(expect)), int64(len(sleeps))) {
+		for i, d := range expect {
+			assertDuration(t, d, sleeps[i])
+		}
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd = NewCommand("app", "").
+		Output(ioutil.Discard, stderr).
+		EnableWatch().
+		HandleFunc(func(args []string) int { return 0 }).
+		Must()
+	assertInt64(t, 1, int64(cmd.Run([]string{"--watch-path", dir, "--watch-ignore=[x"})))
+	assertString(t, "Argument error: --watch-ignore: invalid pattern: [x\nSee 'app --help'.\n", stderr.String())
+}
+
+func TestEnableWatchPathsOwnWrites(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	var runs int
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		EnableWatch().
+		HandleFunc(func(args []string) int {
+			runs++
+			if err := os.WriteFile(out, []byte(fmt.Sprint(runs)), 0o644); err != nil {
+				t.Error(err)
+			}
+			return 0
+		}).
+		Must()
+	opts := cmd.index().flagsByName["--watch"].Value.(*watchOptions)
+	var sleeps int
+	opts.sleep = func(ctx context.Context, d time.Duration) error {
+		if sleeps++; sleeps == 4 {
+			return errors.New("interrupted")
+		}
+		return nil
+	}
+	code := cmd.Run([]string{"--watch-path", dir})
+	assertInt64(t, 0, int64(code))
+	assertInt64(t, 1, int64(runs))
+}
+
+func TestEnableWatchExec(t *testing.T) {
+	var runs int
+	var sleeps []time.Duration
+	cmd := NewCommand("app", "").
+		Output(ioutil.Discard, ioutil.Discard).
+		EnableWatch().
+		HandleFunc(func(args []string) int {
+			runs++
+			return 0
+		}).
+		Must()
+	opts := cmd.index().flagsByName["--watch"].Value.(*watchOptions)
+	opts.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		if len(sleeps) == 2 {
+			return errors.New("interrupted")
+		}
+		return nil
+	}
+	assertInt64(t, 0, int64(cmd.Exec(context.Background(), []string{"--watch=100ms"}, nil, nil, nil)))
+	assertInt64(t, 2, int64(runs))
+	assertDuration(t, 100*time.Millisecond, sleeps[len(sleeps)-1])
+
+	// the interval of one invocation does not carry over to the next
+	runs = 0
+	assertInt64(t, 0, int64(cmd.Exec(context.Background(), nil, nil, nil, nil)))
+	assertInt64(t, 1, int64(runs))
+
+	// --watch-path is read per invocation
+	dir := t.TempDir()
+	runs, sleeps = 0, nil
+	assertInt64(t, 0, int64(cmd.Exec(context.Background(), []string{"--watch-path", dir}, nil, nil, nil)))
+	assertInt64(t, 1, int64(runs))
+	if len(sleeps) == 0 {
+		t.Error("expected --watch-path to be polled")
+	}
+}
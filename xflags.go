@@ -13,8 +13,8 @@ import (
 //         os.Exit(xflags.Run(cmd))
 //     }
 //
-// If -h or --help are specified, usage information will be printed to os.Stdout
-// and the exit code will be 0.
+// If -h, --help or --help-all are specified, usage information will be printed
+// to os.Stdout and the exit code will be 0.
 //
 // If a command is invoked that has no handler, usage information will be
 // printed to os.Stderr and the exit code will be non-zero.
@@ -29,8 +29,8 @@ func Run(cmd Commander) int {
 //         os.Exit(xflags.RunWithArgs(cmd, "--foo", "--bar"))
 //     }
 //
-// If -h or --help are specified, usage information will be printed to os.Stdout
-// and the exit code will be 0.
+// If -h, --help or --help-all are specified, usage information will be printed
+// to os.Stdout and the exit code will be 0.
 //
 // If a command is invoked that has no handler, usage information will be
 // printed to os.Stderr and the exit code will be non-zero.
@@ -45,6 +45,10 @@ func RunWithArgs(cmd Commander, args ...string) int {
 
 // Var returns a FlagBuilder that can be used to define a command line flag with custom value
 // parsing.
+//
+// The name is always the long name of the flag, even if it is one character in length, so
+// that "n" is specified as "--n". To declare a flag that has only a short name, pass an empty
+// name and specify the short name with FlagBuilder.ShortName.
 func Var(value Value, name, usage string) *FlagBuilder {
 	c := &FlagBuilder{
 		flag: Flag{
@@ -55,10 +59,6 @@ func Var(value Value, name, usage string) *FlagBuilder {
 			Value:    value,
 		},
 	}
-	if len(name) == 1 {
-		c.flag.ShortName = c.flag.Name
-		c.flag.Name = ""
-	}
 	return c
 }
 
@@ -115,6 +115,15 @@ func Int64(p *int64, name string, value int64, usage string) *FlagBuilder {
 	return Var(newInt64Value(value, p), name, usage)
 }
 
+// Percent returns a FlagBuilder that can be used to define a percentage flag
+// with specified name, default value, and usage string. The argument p points
+// to a float64 variable in which to store the value of the flag as a fraction
+// between 0 and 1. The flag accepts a percentage, such as "75%", or a
+// fraction, such as "0.75". Values outside of 0% to 100% are rejected.
+func Percent(p *float64, name string, value float64, usage string) *FlagBuilder {
+	return Var(newPercentValue(value, p), name, usage)
+}
+
 // String returns a FlagBuilder that can be used to define a string flag with
 // specified name, default value, and usage string. The argument p points to a
 // string variable in which to store the value of the flag.
@@ -125,6 +134,9 @@ func String(p *string, name, value, usage string) *FlagBuilder {
 // Strings returns a FlagBuilder that can be used to define a string slice flag with specified name,
 // default value, and usage string. The argument p points to a string slice variable in which each
 // flag value will be stored in command line order.
+//
+// The first value specified for the flag replaces the default value. To append values to the
+// default value instead, call FlagBuilder.AppendToDefault.
 func Strings(p *[]string, name string, value []string, usage string) *FlagBuilder {
 	return Var(newStringSliceValue(value, p), name, usage).NArgs(0, 0)
 }
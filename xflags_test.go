@@ -81,8 +81,9 @@ func assertFlagParses(t *testing.T, flag *Flag, args ...string) bool {
 	}
 	return true
 }
-func assertErrorAs(t *testing.T, err error, target error) bool {
-	if errors.As(err, &target) {
+
+func assertErrorAs(t *testing.T, err error, target interface{}) bool {
+	if errors.As(err, target) {
 		return true
 	}
 	t.Errorf("expected: %T, got: %T: %v", target, err, err)